@@ -86,7 +86,7 @@ BuildWinArm64() {
   export CC=$(pwd)/wrapper/zcc-arm64
   export CXX=$(pwd)/wrapper/zcxx-arm64
   export CGO_ENABLED=1
-  go build -o "$1" -ldflags="$ldflags" -tags=jsoniter .
+  go build -o "$1" -ldflags="$ldflags" -tags=jsoniter,sqlite_fts5 .
 }
 
 BuildWin7() {
@@ -124,7 +124,7 @@ BuildWin7() {
     fi
     
     # Use the patched Go compiler for Win7 compatibility
-    $(pwd)/go-win7/bin/go build -o "${1}-${arch}.exe" -ldflags="$ldflags" -tags=jsoniter .
+    $(pwd)/go-win7/bin/go build -o "${1}-${arch}.exe" -ldflags="$ldflags" -tags=jsoniter,sqlite_fts5 .
   done
 }
 
@@ -149,9 +149,9 @@ BuildDev() {
     export GOARCH=${os_arch##*-}
     export CC=${cgo_cc}
     export CGO_ENABLED=1
-    go build -o ./dist/$appName-$os_arch -ldflags="$muslflags" -tags=jsoniter .
+    go build -o ./dist/$appName-$os_arch -ldflags="$muslflags" -tags=jsoniter,sqlite_fts5 .
   done
-  xgo -targets=windows/amd64,darwin/amd64,darwin/arm64 -out "$appName" -ldflags="$ldflags" -tags=jsoniter .
+  xgo -targets=windows/amd64,darwin/amd64,darwin/arm64 -out "$appName" -ldflags="$ldflags" -tags=jsoniter,sqlite_fts5 .
   mv "$appName"-* dist
   cd dist
   # cp ./"$appName"-windows-amd64.exe ./"$appName"-windows-amd64-upx.exe
@@ -161,7 +161,7 @@ BuildDev() {
 }
 
 BuildDocker() {
-  go build -o ./bin/"$appName" -ldflags="$ldflags" -tags=jsoniter .
+  go build -o ./bin/"$appName" -ldflags="$ldflags" -tags=jsoniter,sqlite_fts5 .
 }
 
 PrepareBuildDockerMusl() {
@@ -197,7 +197,7 @@ BuildDockerMultiplatform() {
     export GOARCH=$arch
     export CC=${cgo_cc}
     echo "building for $os_arch"
-    go build -o build/$os/$arch/"$appName" -ldflags="$docker_lflags" -tags=jsoniter .
+    go build -o build/$os/$arch/"$appName" -ldflags="$docker_lflags" -tags=jsoniter,sqlite_fts5 .
   done
 
   DOCKER_ARM_ARCHES=(linux-arm/v6 linux-arm/v7)
@@ -211,7 +211,7 @@ BuildDockerMultiplatform() {
     export GOARM=${GO_ARM[$i]}
     export CC=${cgo_cc}
     echo "building for $docker_arch"
-    go build -o build/${docker_arch%%-*}/${docker_arch##*-}/"$appName" -ldflags="$docker_lflags" -tags=jsoniter .
+    go build -o build/${docker_arch%%-*}/${docker_arch##*-}/"$appName" -ldflags="$docker_lflags" -tags=jsoniter,sqlite_fts5 .
   done
 }
 
@@ -220,7 +220,7 @@ BuildRelease() {
   mkdir -p "build"
   BuildWinArm64 ./build/"$appName"-windows-arm64.exe
   BuildWin7 ./build/"$appName"-windows7
-  xgo -out "$appName" -ldflags="$ldflags" -tags=jsoniter .
+  xgo -out "$appName" -ldflags="$ldflags" -tags=jsoniter,sqlite_fts5 .
   # why? Because some target platforms seem to have issues with upx compression
   # upx -9 ./"$appName"-linux-amd64
   # cp ./"$appName"-windows-amd64.exe ./"$appName"-windows-amd64-upx.exe
@@ -311,7 +311,7 @@ BuildLoongGLIBC() {
         CXX="$(pwd)/gcc8-loong64-abi1.0/bin/loongarch64-linux-gnu-g++" \
         CGO_ENABLED=1 \
         GOCACHE="$abi1_cache_dir" \
-        $(pwd)/go-loong64-abi1.0/bin/go build -a -o "$output_file" -ldflags="$ldflags" -tags=jsoniter .; then
+        $(pwd)/go-loong64-abi1.0/bin/go build -a -o "$output_file" -ldflags="$ldflags" -tags=jsoniter,sqlite_fts5 .; then
       echo "Error: Build failed with patched Go compiler"
       echo "Attempting retry with cache cleanup..."
       env GOCACHE="$abi1_cache_dir" $(pwd)/go-loong64-abi1.0/bin/go clean -cache
@@ -320,7 +320,7 @@ BuildLoongGLIBC() {
           CXX="$(pwd)/gcc8-loong64-abi1.0/bin/loongarch64-linux-gnu-g++" \
           CGO_ENABLED=1 \
           GOCACHE="$abi1_cache_dir" \
-          $(pwd)/go-loong64-abi1.0/bin/go build -a -o "$output_file" -ldflags="$ldflags" -tags=jsoniter .; then
+          $(pwd)/go-loong64-abi1.0/bin/go build -a -o "$output_file" -ldflags="$ldflags" -tags=jsoniter,sqlite_fts5 .; then
         echo "Error: Build failed again after cache cleanup"
         echo "Build environment details:"
         echo "GOOS=linux"
@@ -366,11 +366,11 @@ BuildLoongGLIBC() {
     
     # Use standard Go compiler for new-world build
     echo "Building with standard Go compiler for new-world ABI2.0..."
-    if ! go build -a -o "$output_file" -ldflags="$ldflags" -tags=jsoniter .; then
+    if ! go build -a -o "$output_file" -ldflags="$ldflags" -tags=jsoniter,sqlite_fts5 .; then
       echo "Error: Build failed with standard Go compiler"
       echo "Attempting retry with cache cleanup..."
       go clean -cache
-      if ! go build -a -o "$output_file" -ldflags="$ldflags" -tags=jsoniter .; then
+      if ! go build -a -o "$output_file" -ldflags="$ldflags" -tags=jsoniter,sqlite_fts5 .; then
         echo "Error: Build failed again after cache cleanup"
         echo "Build environment details:"
         echo "GOOS=$GOOS"
@@ -408,7 +408,7 @@ BuildReleaseLinuxMusl() {
     export GOARCH=${os_arch##*-}
     export CC=${cgo_cc}
     export CGO_ENABLED=1
-    go build -o ./build/$appName-$os_arch -ldflags="$muslflags" -tags=jsoniter .
+    go build -o ./build/$appName-$os_arch -ldflags="$muslflags" -tags=jsoniter,sqlite_fts5 .
   done
 }
 
@@ -437,7 +437,7 @@ BuildReleaseLinuxMuslArm() {
     export CC=${cgo_cc}
     export CGO_ENABLED=1
     export GOARM=${arm}
-    go build -o ./build/$appName-$os_arch -ldflags="$muslflags" -tags=jsoniter .
+    go build -o ./build/$appName-$os_arch -ldflags="$muslflags" -tags=jsoniter,sqlite_fts5 .
   done
 }
 
@@ -458,7 +458,7 @@ BuildReleaseAndroid() {
     export GOARCH=${os_arch##*-}
     export CC=${cgo_cc}
     export CGO_ENABLED=1
-    go build -o ./build/$appName-android-$os_arch -ldflags="$ldflags" -tags=jsoniter .
+    go build -o ./build/$appName-android-$os_arch -ldflags="$ldflags" -tags=jsoniter,sqlite_fts5 .
     android-ndk-r26b/toolchains/llvm/prebuilt/linux-x86_64/bin/llvm-strip ./build/$appName-android-$os_arch
   done
 }
@@ -500,7 +500,7 @@ BuildReleaseFreeBSD() {
     export CC=${cgo_cc}
     export CGO_ENABLED=1
     export CGO_LDFLAGS="-fuse-ld=lld"
-    go build -o ./build/$appName-freebsd-$os_arch -ldflags="$ldflags" -tags=jsoniter .
+    go build -o ./build/$appName-freebsd-$os_arch -ldflags="$ldflags" -tags=jsoniter,sqlite_fts5 .
   done
 }
 
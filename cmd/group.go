@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	imodel "github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// userCmd represents the user command
+var userCmd = &cobra.Command{
+	Use:   "user",
+	Short: "Manage users",
+}
+
+var userGroupCmd = &cobra.Command{
+	Use:   "group",
+	Short: "Manage groups and the memberships of users",
+}
+
+var listGroupCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all groups",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		Init()
+		defer Release()
+		groups, _, err := op.GetGroups(1, -1)
+		if err != nil {
+			return fmt.Errorf("failed to query groups: %+v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tName\tPermission\tBase Path\tQuota\tDescription")
+		for _, g := range groups {
+			fmt.Fprintf(w, "%d\t%s\t%d\t%s\t%d\t%s\n", g.ID, g.Name, g.Permission, g.BasePath, g.Quota, g.Description)
+		}
+		return w.Flush()
+	},
+}
+
+var createGroupCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a group",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		Init()
+		defer Release()
+		g := &imodel.Group{Name: args[0]}
+		g.Permission, _ = cmd.Flags().GetInt32("permission")
+		g.BasePath, _ = cmd.Flags().GetString("base-path")
+		g.Description, _ = cmd.Flags().GetString("description")
+		g.Quota, _ = cmd.Flags().GetInt64("quota")
+		if err := op.CreateGroup(g); err != nil {
+			return fmt.Errorf("failed to create group: %+v", err)
+		}
+		utils.Log.Infof("group [%s] has been created from CLI", g.Name)
+		fmt.Printf("Group [%s] has been created with id [%d]\n", g.Name, g.ID)
+		return nil
+	},
+}
+
+var updateGroupCmd = &cobra.Command{
+	Use:   "update [name]",
+	Short: "Update the permission, base path, quota or description of a group",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		Init()
+		defer Release()
+		g, err := op.GetGroupByName(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get group: %+v", err)
+		}
+		flags := cmd.Flags()
+		if flags.Changed("permission") {
+			g.Permission, _ = flags.GetInt32("permission")
+		}
+		if flags.Changed("base-path") {
+			g.BasePath, _ = flags.GetString("base-path")
+		}
+		if flags.Changed("description") {
+			g.Description, _ = flags.GetString("description")
+		}
+		if flags.Changed("quota") {
+			g.Quota, _ = flags.GetInt64("quota")
+		}
+		if err = op.UpdateGroup(g); err != nil {
+			return fmt.Errorf("failed to update group: %+v", err)
+		}
+		utils.Log.Infof("group [%s] has been updated from CLI", g.Name)
+		fmt.Printf("Group [%s] has been updated\n", g.Name)
+		delGroupMembersCacheOnline(g.ID)
+		return nil
+	},
+}
+
+var deleteGroupCmd = &cobra.Command{
+	Use:   "delete [name]",
+	Short: "Delete a group, its members are kept",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		Init()
+		defer Release()
+		g, err := op.GetGroupByName(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get group: %+v", err)
+		}
+		members, err := op.GetGroupMembers(g.ID)
+		if err != nil {
+			return fmt.Errorf("failed to get group members: %+v", err)
+		}
+		if err = op.DeleteGroupById(g.ID); err != nil {
+			return fmt.Errorf("failed to delete group: %+v", err)
+		}
+		utils.Log.Infof("group [%s] has been deleted from CLI", g.Name)
+		fmt.Printf("Group [%s] has been deleted\n", g.Name)
+		for _, u := range members {
+			DelUserCacheOnline(u.Username)
+		}
+		return nil
+	},
+}
+
+var joinGroupCmd = &cobra.Command{
+	Use:   "join [username] [group]...",
+	Short: "Add a user to groups",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeUserGroups(args[0], args[1:], true)
+	},
+}
+
+var leaveGroupCmd = &cobra.Command{
+	Use:   "leave [username] [group]...",
+	Short: "Remove a user from groups",
+	Args:  cobra.MinimumNArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return changeUserGroups(args[0], args[1:], false)
+	},
+}
+
+var showUserGroupsCmd = &cobra.Command{
+	Use:   "show [username]",
+	Short: "Show the groups and the effective permission of a user",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		Init()
+		defer Release()
+		u, err := op.GetUserByName(args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get user: %+v", err)
+		}
+		names := make([]string, len(u.Groups))
+		for i, g := range u.Groups {
+			names[i] = g.Name
+		}
+		fmt.Println("Username:", u.Username)
+		fmt.Println("Groups:", strings.Join(names, ", "))
+		fmt.Println("Permission:", u.Permission)
+		fmt.Println("Effective permission:", u.EffectivePermission())
+		fmt.Println("Effective base paths:", strings.Join(u.EffectiveBasePaths(), ", "))
+		fmt.Println("Effective quota:", u.EffectiveQuota())
+		return nil
+	},
+}
+
+func changeUserGroups(username string, groupNames []string, join bool) error {
+	Init()
+	defer Release()
+	u, err := op.GetUserByName(username)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %+v", err)
+	}
+	groupIds := slices.Clone(u.GroupIDs)
+	for _, name := range groupNames {
+		g, err := op.GetGroupByName(name)
+		if err != nil {
+			return fmt.Errorf("failed to get group [%s]: %+v", name, err)
+		}
+		if join {
+			if !slices.Contains(groupIds, g.ID) {
+				groupIds = append(groupIds, g.ID)
+			}
+		} else {
+			groupIds = slices.DeleteFunc(groupIds, func(id uint) bool { return id == g.ID })
+		}
+	}
+	if err = op.SetUserGroups(u, groupIds); err != nil {
+		return fmt.Errorf("failed to set user groups: %+v", err)
+	}
+	utils.Log.Infof("groups of user [%s] have been updated from CLI", username)
+	fmt.Printf("Groups of user [%s] have been updated\n", username)
+	DelUserCacheOnline(username)
+	return nil
+}
+
+func delGroupMembersCacheOnline(groupId uint) {
+	members, err := op.GetGroupMembers(groupId)
+	if err != nil {
+		utils.Log.Warnf("failed to get group members: %+v", err)
+		return
+	}
+	for _, u := range members {
+		DelUserCacheOnline(u.Username)
+	}
+}
+
+func init() {
+	RootCmd.AddCommand(userCmd)
+	userCmd.AddCommand(userGroupCmd)
+	userGroupCmd.AddCommand(listGroupCmd, createGroupCmd, updateGroupCmd, deleteGroupCmd,
+		joinGroupCmd, leaveGroupCmd, showUserGroupsCmd)
+	for _, c := range []*cobra.Command{createGroupCmd, updateGroupCmd} {
+		c.Flags().Int32P("permission", "p", 0, "permission bits of the group, same as the user's")
+		c.Flags().String("base-path", "", "base path of the members without their own")
+		c.Flags().String("description", "", "description of the group")
+		c.Flags().Int64("quota", 0, "quota in bytes of the members without their own, 0 means unlimited")
+	}
+}
@@ -0,0 +1,49 @@
+//go:build fuse
+
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os/signal"
+	"syscall"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/bootstrap"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fuse"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/spf13/cobra"
+)
+
+// MountCmd represents the mount command
+var MountCmd = &cobra.Command{
+	Use:   "mount [src] [mountpoint]",
+	Short: "Mount a path of the virtual tree as a local filesystem",
+	Long: `Mount a path of the virtual tree as a local filesystem via FUSE,
+the storages are loaded from the data directory like the server does.
+Requires building with "-tags fuse" and libfuse installed.`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		opts, _ := cmd.Flags().GetStringArray("option")
+		Init()
+		defer Release()
+		bootstrap.InitWebhook()
+		bootstrap.LoadStorages()
+		bootstrap.InitMountTaskManager()
+		<-conf.StoragesLoadSignal()
+		mountOpts := make([]string, 0, len(opts)*2)
+		for _, o := range opts {
+			mountOpts = append(mountOpts, "-o", o)
+		}
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+		fmt.Printf("mount %s @ %s\n", args[0], args[1])
+		utils.Log.Infof("mount %s @ %s", args[0], args[1])
+		return fuse.Mount(ctx, args[0], args[1], mountOpts)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(MountCmd)
+	MountCmd.Flags().StringArrayP("option", "o", nil, "mount options passed to fuse, e.g. -o allow_other")
+}
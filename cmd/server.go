@@ -43,9 +43,16 @@ the address is defined in config file`,
 			utils.Log.Infof("delayed start for %d seconds", conf.Conf.DelayedStart)
 			time.Sleep(time.Duration(conf.Conf.DelayedStart) * time.Second)
 		}
+		bootstrap.InitWebhook()
 		bootstrap.InitOfflineDownloadTools()
 		bootstrap.LoadStorages()
 		bootstrap.InitTaskManager()
+		bootstrap.InitUsageReconciler()
+		bootstrap.InitTrashCleaner()
+		bootstrap.InitSharingLogCleaner()
+		bootstrap.InitSharingLogWriter()
+		bootstrap.InitIndexScheduler()
+		bootstrap.InitPipelineScheduler()
 		if !flags.Debug && !flags.Dev {
 			gin.SetMode(gin.ReleaseMode)
 		}
@@ -5,5 +5,6 @@ import (
 	_ "github.com/OpenListTeam/OpenList/v4/internal/archive/iso9660"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/archive/rardecode"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/archive/sevenzip"
+	_ "github.com/OpenListTeam/OpenList/v4/internal/archive/tarball"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/archive/zip"
 )
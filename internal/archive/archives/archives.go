@@ -1,6 +1,7 @@
 package archives
 
 import (
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
@@ -19,8 +20,8 @@ type Archives struct {
 
 func (Archives) AcceptedExtensions() []string {
 	return []string{
-		".br", ".bz2", ".gz", ".lz4", ".lz", ".mz", ".sz", ".s2", ".xz", ".zz", ".zst", ".tar",
-		".tgz", ".tlz4", ".tlz", ".tbz2", ".txz", ".tzst",
+		".br", ".bz2", ".gz", ".lz4", ".lz", ".mz", ".sz", ".s2", ".xz", ".zz", ".zst",
+		".tlz4", ".tlz",
 	}
 }
 
@@ -139,8 +140,24 @@ func (Archives) Decompress(ss []*stream.SeekableStream, outputPath string, args
 	return filterPassword(err)
 }
 
+func (Archives) AcceptedCompressExtensions() []string {
+	return []string{
+		".tar", ".tar.gz", ".tgz", ".tar.bz2", ".tbz2", ".tar.xz", ".txz", ".tar.zst", ".tzst",
+	}
+}
+
+// Compress creates a tar archive compressed by the extension
+func (Archives) Compress(w io.Writer, ext string, files []tool.CompressFile, args model.ArchiveCompressArgs) error {
+	if args.Password != "" {
+		return errors.New("tar archives can't be encrypted")
+	}
+	return compress(w, ext, files)
+}
+
 var _ tool.Tool = (*Archives)(nil)
+var _ tool.Compressor = (*Archives)(nil)
 
 func init() {
 	tool.RegisterTool(Archives{})
+	tool.RegisterCompressor(Archives{})
 }
@@ -1,6 +1,7 @@
 package archives
 
 import (
+	"archive/tar"
 	"fmt"
 	"io"
 	fs2 "io/fs"
@@ -8,6 +9,7 @@ import (
 	"path/filepath"
 	"strings"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/archive/tool"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
@@ -88,3 +90,65 @@ func decompress(fsys fs2.FS, filePath, targetPath string, up model.UpdateProgres
 	})
 	return err
 }
+
+// compressions are the compressors of the tar archives by the extensions
+var compressions = map[string]archives.Compressor{
+	".tar.gz":  archives.Gz{},
+	".tgz":     archives.Gz{},
+	".tar.bz2": archives.Bz2{},
+	".tbz2":    archives.Bz2{},
+	".tar.xz":  archives.Xz{},
+	".txz":     archives.Xz{},
+	".tar.zst": archives.Zstd{},
+	".tzst":    archives.Zstd{},
+}
+
+func compress(w io.Writer, ext string, files []tool.CompressFile) error {
+	if c, ok := compressions[ext]; ok {
+		cw, err := c.OpenWriter(w)
+		if err != nil {
+			return err
+		}
+		if err = writeTar(cw, files); err != nil {
+			_ = cw.Close()
+			return err
+		}
+		return cw.Close()
+	}
+	return writeTar(w, files)
+}
+
+func writeTar(w io.Writer, files []tool.CompressFile) error {
+	tw := tar.NewWriter(w)
+	for _, file := range files {
+		header := &tar.Header{
+			Name:    file.Name,
+			ModTime: file.Obj.ModTime(),
+		}
+		if file.Obj.IsDir() {
+			header.Typeflag = tar.TypeDir
+			header.Name += "/"
+			header.Mode = 0o755
+			if err := tw.WriteHeader(header); err != nil {
+				return err
+			}
+			continue
+		}
+		header.Typeflag = tar.TypeReg
+		header.Mode = 0o644
+		header.Size = file.Obj.GetSize()
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		rc, err := file.Open()
+		if err != nil {
+			return err
+		}
+		_, err = utils.CopyWithBufferN(tw, rc, header.Size)
+		_ = rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
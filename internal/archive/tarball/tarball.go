@@ -0,0 +1,90 @@
+package tarball
+
+import (
+	"io"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/archive/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// Tarball reads the tar and cpio archives, which may be compressed and split,
+// by an offset index built once. An inner file is read from the nearest
+// checkpoint before it, and the checkpoints are only at the starts of the
+// gzip members and zstd frames. So the uncompressed archives and the ones of
+// many members, e.g. by bgzip or zstd --seekable, are read from near the
+// file, while the others of a single member, and all of xz and bzip2, are
+// still decompressed from the start for each file read out of order.
+type Tarball struct{}
+
+func (Tarball) AcceptedExtensions() []string {
+	exts := make([]string, 0, len(formats))
+	for ext := range formats {
+		exts = append(exts, ext)
+	}
+	return exts
+}
+
+func (Tarball) AcceptedMultipartExtensions() map[string]tool.MultipartExtension {
+	exts := make(map[string]tool.MultipartExtension, len(formats))
+	for ext := range formats {
+		exts[ext+".001"] = tool.MultipartExtension{PartFileFormat: ext + ".%.3d", SecondPartIndex: 2}
+	}
+	return exts
+}
+
+func (Tarball) GetMeta(ss []*stream.SeekableStream, args model.ArchiveArgs) (model.ArchiveMeta, error) {
+	r, err := getReader(ss)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	_, tree := tool.GenerateMetaTreeFromFolderTraversal(r)
+	return &model.ArchiveMetaInfo{
+		Comment:   "",
+		Encrypted: false,
+		Tree:      tree,
+	}, nil
+}
+
+func (Tarball) List(ss []*stream.SeekableStream, args model.ArchiveInnerArgs) ([]model.Obj, error) {
+	return nil, errs.NotSupport
+}
+
+func (Tarball) Extract(ss []*stream.SeekableStream, args model.ArchiveInnerArgs) (io.ReadCloser, int64, error) {
+	r, err := getReader(ss)
+	if err != nil {
+		return nil, 0, err
+	}
+	e, ok := r.idx.find(strings.TrimPrefix(args.InnerPath, "/"))
+	if !ok || e.info.IsDir() {
+		return nil, 0, errs.ObjectNotFound
+	}
+	rc, err := r.open(e)
+	if err != nil {
+		r.Close()
+		return nil, 0, err
+	}
+	return utils.NewReadCloser(rc, func() error {
+		r.Close()
+		return nil
+	}), e.info.Size(), nil
+}
+
+func (Tarball) Decompress(ss []*stream.SeekableStream, outputPath string, args model.ArchiveInnerArgs, up model.UpdateProgress) error {
+	r, err := getReader(ss)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return tool.DecompressFromFolderTraversal(r, outputPath, args, up)
+}
+
+var _ tool.Tool = (*Tarball)(nil)
+
+func init() {
+	tool.RegisterTool(Tarball{})
+}
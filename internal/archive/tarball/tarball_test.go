@@ -0,0 +1,163 @@
+package tarball
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+func testFiles() map[string][]byte {
+	files := make(map[string][]byte)
+	for i := 0; i < 20; i++ {
+		files[fmt.Sprintf("dir/file%02d.bin", i)] = bytes.Repeat([]byte{byte(i)}, 1000*(i+1))
+	}
+	return files
+}
+
+func testNames() []string {
+	names := make([]string, 0, 20)
+	for i := 0; i < 20; i++ {
+		names = append(names, fmt.Sprintf("dir/file%02d.bin", i))
+	}
+	return names
+}
+
+func testOrder(n int, reverse bool) []int {
+	order := make([]int, n)
+	for i := range order {
+		order[i] = i
+		if reverse {
+			order[i] = n - 1 - i
+		}
+	}
+	return order
+}
+
+func makeTar(t *testing.T, files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	tw := tar.NewWriter(&buf)
+	if err := tw.WriteHeader(&tar.Header{Name: "dir/", Typeflag: tar.TypeDir, Mode: 0o755, ModTime: time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range testNames() {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Typeflag: tar.TypeReg, Mode: 0o644, Size: int64(len(files[name]))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(files[name]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func makeCPIO(files map[string][]byte) []byte {
+	var buf bytes.Buffer
+	pad := func() {
+		for buf.Len()%4 != 0 {
+			buf.WriteByte(0)
+		}
+	}
+	write := func(name string, mode int, data []byte) {
+		_, _ = fmt.Fprintf(&buf, "070701%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X%08X",
+			0, mode, 0, 0, 1, 0, len(data), 0, 0, 0, 0, len(name)+1, 0)
+		buf.WriteString(name + "\x00")
+		pad()
+		buf.Write(data)
+		pad()
+	}
+	write("dir", 0o040755, nil)
+	for _, name := range testNames() {
+		write(name, 0o100644, files[name])
+	}
+	write(cpioTrailer, 0, nil)
+	return buf.Bytes()
+}
+
+// split compresses the chunks of b as the separate members
+func split(t *testing.T, b []byte, chunk int, compress func(w io.Writer, b []byte) error) []byte {
+	var buf bytes.Buffer
+	for len(b) > 0 {
+		n := min(chunk, len(b))
+		if err := compress(&buf, b[:n]); err != nil {
+			t.Fatal(err)
+		}
+		b = b[n:]
+	}
+	return buf.Bytes()
+}
+
+func gzipCompress(w io.Writer, b []byte) error {
+	zw := gzip.NewWriter(w)
+	if _, err := zw.Write(b); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func zstdCompress(w io.Writer, b []byte) error {
+	zw, err := zstd.NewWriter(w)
+	if err != nil {
+		return err
+	}
+	if _, err = zw.Write(b); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+func TestIndex(t *testing.T) {
+	checkpointInterval = 16 * 1024
+	defer func() { checkpointInterval = 1024 * 1024 }()
+	files := testFiles()
+	tarball := makeTar(t, files)
+	for ext, archive := range map[string][]byte{
+		".tar":      tarball,
+		".tar.gz":   split(t, tarball, 10000, gzipCompress),
+		".tar.zst":  split(t, tarball, 10000, zstdCompress),
+		".cpio":     makeCPIO(files),
+		".cpio.zst": split(t, makeCPIO(files), 10000, zstdCompress),
+	} {
+		f, err := getFormat("archive" + ext + ".001")
+		if err != nil {
+			t.Fatalf("%s: %+v", ext, err)
+		}
+		idx, err := buildIndex(bytes.NewReader(archive), int64(len(archive)), f)
+		if err != nil {
+			t.Fatalf("%s: failed build index: %+v", ext, err)
+		}
+		if len(idx.entries) != len(files)+1 || !idx.entries[0].info.IsDir() {
+			t.Fatalf("%s: got %d entries", ext, len(idx.entries))
+		}
+		if f.newCodec != nil && len(idx.checkpoints) < 2 {
+			t.Errorf("%s: got %d checkpoints", ext, len(idx.checkpoints))
+		}
+		r := &reader{ra: bytes.NewReader(archive), size: int64(len(archive)), format: f, idx: idx}
+		// forwards the decompressed stream is reused, backwards every entry
+		// is read from a checkpoint
+		names := testNames()
+		for _, i := range append(testOrder(len(names), false), testOrder(len(names), true)...) {
+			e, ok := idx.find(names[i])
+			if !ok {
+				t.Fatalf("%s: %s not found", ext, names[i])
+			}
+			rc, err := r.open(e)
+			if err != nil {
+				t.Fatalf("%s: failed open %s: %+v", ext, names[i], err)
+			}
+			b, err := io.ReadAll(rc)
+			if err != nil || !bytes.Equal(b, files[names[i]]) || e.info.Size() != int64(len(b)) {
+				t.Errorf("%s: %s got %d bytes, %v", ext, names[i], len(b), err)
+			}
+		}
+		r.Close()
+	}
+}
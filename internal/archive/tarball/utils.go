@@ -0,0 +1,725 @@
+package tarball
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/bzip2"
+	"compress/gzip"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/fs"
+	stdpath "path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/archive/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"github.com/ulikunitz/xz"
+)
+
+// checkpointInterval is the least distance of the checkpoints in the
+// decompressed stream, so the index of the archives with many small members
+// is kept small
+var checkpointInterval int64 = utils.MB
+
+// entry is a file or folder in the archive
+type entry struct {
+	// name is the cleaned slash separated path without the leading slash
+	name string
+	info fs.FileInfo
+	// header is the offset of the header in the decompressed stream
+	header int64
+}
+
+// checkpoint is the start of a member of the compressed stream, where the
+// decompression can begin. The state of the decompressor inside a member
+// isn't saved, so there is no checkpoint in the middle of a member.
+type checkpoint struct {
+	in  int64
+	out int64
+}
+
+// index is the offsets of the entries and the checkpoints of an archive, so
+// an entry is read by decompressing from the nearest checkpoint before it,
+// which is the start of the archive if it's compressed as a single member
+type index struct {
+	entries     []entry
+	checkpoints []checkpoint
+	// names maps the names to the positions in entries
+	names map[string]int
+}
+
+func (idx *index) find(name string) (*entry, bool) {
+	i, ok := idx.names[name]
+	if !ok {
+		return nil, false
+	}
+	return &idx.entries[i], true
+}
+
+var indexCache = cache.NewKeyedCache[*index](time.Hour)
+
+// format is the container and the compression of an archive extension
+type format struct {
+	container container
+	// newCodec is nil if the archive is not compressed
+	newCodec func() codec
+}
+
+var formats = map[string]format{
+	".tar":      {tarContainer{}, nil},
+	".tar.gz":   {tarContainer{}, newGzipCodec},
+	".tgz":      {tarContainer{}, newGzipCodec},
+	".tar.bz2":  {tarContainer{}, newBzip2Codec},
+	".tbz2":     {tarContainer{}, newBzip2Codec},
+	".tar.xz":   {tarContainer{}, newXzCodec},
+	".txz":      {tarContainer{}, newXzCodec},
+	".tar.zst":  {tarContainer{}, newZstdCodec},
+	".tzst":     {tarContainer{}, newZstdCodec},
+	".cpio":     {cpioContainer{}, nil},
+	".cpio.gz":  {cpioContainer{}, newGzipCodec},
+	".cpio.bz2": {cpioContainer{}, newBzip2Codec},
+	".cpio.xz":  {cpioContainer{}, newXzCodec},
+	".cpio.zst": {cpioContainer{}, newZstdCodec},
+}
+
+var partSuffix = regexp.MustCompile(`\.\d{3}$`)
+
+// getFormat returns the format of the archive name by the longest matched
+// extension, the suffix of the split archives is ignored
+func getFormat(name string) (*format, error) {
+	name = partSuffix.ReplaceAllString(strings.ToLower(name), "")
+	var (
+		ext string
+		f   format
+	)
+	for e, fm := range formats {
+		if strings.HasSuffix(name, e) && len(e) > len(ext) {
+			ext, f = e, fm
+		}
+	}
+	if ext == "" {
+		return nil, errs.UnknownArchiveFormat
+	}
+	return &f, nil
+}
+
+func indexKey(ss []*stream.SeekableStream) string {
+	if ss[0].GetPath() == "" && ss[0].GetID() == "" {
+		return ""
+	}
+	var b strings.Builder
+	for _, s := range ss {
+		_, _ = fmt.Fprintf(&b, "%s|%s|%s|%d|%d;", s.GetPath(), s.GetID(), s.GetName(), s.GetSize(), s.ModTime().UnixNano())
+	}
+	return b.String()
+}
+
+// getReader returns the reader of the split parts of the archive, the index
+// is built by reading the whole archive once and cached
+func getReader(ss []*stream.SeekableStream) (*reader, error) {
+	f, err := getFormat(ss[0].GetName())
+	if err != nil {
+		return nil, err
+	}
+	ra, err := stream.NewMultiReaderAt(ss)
+	if err != nil {
+		return nil, err
+	}
+	key := indexKey(ss)
+	idx, ok := indexCache.Get(key)
+	if !ok || key == "" {
+		idx, err = buildIndex(ra, ra.Size(), f)
+		if err != nil {
+			return nil, err
+		}
+		if key != "" {
+			indexCache.Set(key, idx)
+		}
+	}
+	return &reader{ra: ra, size: ra.Size(), format: f, idx: idx}, nil
+}
+
+func buildIndex(ra io.ReaderAt, size int64, f *format) (*index, error) {
+	idx := &index{}
+	c := &chainReader{codec: newCodec(f), r: newCountReader(io.NewSectionReader(ra, 0, size), 0)}
+	defer c.codec.Close()
+	if f.newCodec != nil {
+		c.onMember = func(in, out int64) {
+			if n := len(idx.checkpoints); n == 0 || out-idx.checkpoints[n-1].out >= checkpointInterval {
+				idx.checkpoints = append(idx.checkpoints, checkpoint{in: in, out: out})
+			}
+		}
+	}
+	entries, err := f.container.index(c, func() int64 { return c.out })
+	if err != nil {
+		return nil, err
+	}
+	idx.entries = entries
+	idx.names = make(map[string]int, len(entries))
+	for i := range entries {
+		// the first of the entries with the same name is kept
+		if _, ok := idx.names[entries[i].name]; !ok {
+			idx.names[entries[i].name] = i
+		}
+	}
+	return idx, nil
+}
+
+func newCodec(f *format) codec {
+	if f.newCodec == nil {
+		return plainCodec{}
+	}
+	return f.newCodec()
+}
+
+// reader reads the entries of an archive by the index. The decompressed
+// stream is kept and reused if the next entry is after the last one, so
+// reading the entries in order decompresses the archive only once.
+type reader struct {
+	ra     io.ReaderAt
+	size   int64
+	format *format
+	idx    *index
+	chain  *chainReader
+}
+
+// at returns the decompressed stream from the offset
+func (r *reader) at(off int64) (io.Reader, error) {
+	cp := checkpoint{in: off, out: off}
+	if r.format.newCodec != nil {
+		i := sort.Search(len(r.idx.checkpoints), func(i int) bool {
+			return r.idx.checkpoints[i].out > off
+		}) - 1
+		if i < 0 {
+			return nil, errors.Errorf("no checkpoint before offset %d", off)
+		}
+		cp = r.idx.checkpoints[i]
+	}
+	if r.chain == nil || off < r.chain.out || cp.out > r.chain.out+checkpointInterval {
+		r.Close()
+		r.chain = &chainReader{
+			codec: newCodec(r.format),
+			r:     newCountReader(io.NewSectionReader(r.ra, cp.in, r.size-cp.in), cp.in),
+			out:   cp.out,
+		}
+	}
+	if _, err := utils.CopyWithBufferN(io.Discard, r.chain, off-r.chain.out); err != nil {
+		return nil, err
+	}
+	return r.chain, nil
+}
+
+func (r *reader) open(e *entry) (io.Reader, error) {
+	rr, err := r.at(e.header)
+	if err != nil {
+		return nil, err
+	}
+	return r.format.container.open(rr)
+}
+
+func (r *reader) Close() {
+	if r.chain != nil {
+		r.chain.codec.Close()
+		r.chain = nil
+	}
+}
+
+func (r *reader) Files() []tool.SubFile {
+	ret := make([]tool.SubFile, 0, len(r.idx.entries))
+	for i := range r.idx.entries {
+		ret = append(ret, &subFile{r: r, e: &r.idx.entries[i]})
+	}
+	return ret
+}
+
+type subFile struct {
+	r *reader
+	e *entry
+}
+
+func (f *subFile) Name() string {
+	if f.e.info.IsDir() {
+		return f.e.name + "/"
+	}
+	return f.e.name
+}
+
+func (f *subFile) FileInfo() fs.FileInfo {
+	return f.e.info
+}
+
+func (f *subFile) Open() (io.ReadCloser, error) {
+	rc, err := f.r.open(f.e)
+	if err != nil {
+		return nil, err
+	}
+	// the decompressed stream is closed with the reader
+	return io.NopCloser(rc), nil
+}
+
+// countReader counts the bytes read from the compressed stream, it's a
+// flate.Reader so gzip doesn't read beyond the end of a member
+type countReader struct {
+	br *bufio.Reader
+	n  int64
+}
+
+func newCountReader(r io.Reader, off int64) *countReader {
+	return &countReader{br: bufio.NewReader(r), n: off}
+}
+
+func (r *countReader) Read(p []byte) (int, error) {
+	n, err := r.br.Read(p)
+	r.n += int64(n)
+	return n, err
+}
+
+func (r *countReader) ReadByte() (byte, error) {
+	b, err := r.br.ReadByte()
+	if err == nil {
+		r.n++
+	}
+	return b, err
+}
+
+func (r *countReader) Peek(n int) ([]byte, error) {
+	return r.br.Peek(n)
+}
+
+func (r *countReader) Discard(n int) (int, error) {
+	d, err := r.br.Discard(n)
+	r.n += int64(d)
+	return d, err
+}
+
+// codec splits the compressed stream into the members which can be
+// decompressed independently, the start of a member is a checkpoint
+type codec interface {
+	// member returns the decompressed content of the member starting at r,
+	// io.EOF if there is no member left
+	member(r *countReader) (io.Reader, error)
+	Close()
+}
+
+// chainReader decompresses the members one after another
+type chainReader struct {
+	codec codec
+	r     *countReader
+	cur   io.Reader
+	// out is the offset in the decompressed stream
+	out int64
+	// onMember is called with the offsets of the start of each member
+	onMember func(in, out int64)
+}
+
+func (c *chainReader) Read(p []byte) (int, error) {
+	for {
+		if c.cur == nil {
+			if _, err := c.r.Peek(1); err != nil {
+				return 0, err
+			}
+			in := c.r.n
+			cur, err := c.codec.member(c.r)
+			if err != nil {
+				return 0, err
+			}
+			if c.onMember != nil {
+				c.onMember(in, c.out)
+			}
+			c.cur = cur
+		}
+		n, err := c.cur.Read(p)
+		c.out += int64(n)
+		if err == io.EOF {
+			c.cur = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
+
+type plainCodec struct{}
+
+func (plainCodec) member(r *countReader) (io.Reader, error) {
+	return r, nil
+}
+
+func (plainCodec) Close() {}
+
+// gzipCodec reads the gzip members, e.g. of bgzip or pigz --independent
+type gzipCodec struct {
+	zr *gzip.Reader
+}
+
+func newGzipCodec() codec {
+	return &gzipCodec{}
+}
+
+func (c *gzipCodec) member(r *countReader) (io.Reader, error) {
+	if c.zr == nil {
+		zr, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, err
+		}
+		c.zr = zr
+	} else if err := c.zr.Reset(r); err != nil {
+		return nil, err
+	}
+	c.zr.Multistream(false)
+	return c.zr, nil
+}
+
+func (c *gzipCodec) Close() {
+	if c.zr != nil {
+		_ = c.zr.Close()
+	}
+}
+
+// zstdCodec reads the zstd frames, e.g. of the zstd seekable format
+type zstdCodec struct {
+	d *zstd.Decoder
+}
+
+func newZstdCodec() codec {
+	return &zstdCodec{}
+}
+
+func (c *zstdCodec) member(r *countReader) (io.Reader, error) {
+	// the skippable frames have no content
+	for {
+		b, err := r.Peek(8)
+		if err != nil {
+			return nil, err
+		}
+		if binary.LittleEndian.Uint32(b)&0xFFFFFFF0 != 0x184D2A50 {
+			break
+		}
+		if _, err = r.Discard(8 + int(binary.LittleEndian.Uint32(b[4:]))); err != nil {
+			return nil, err
+		}
+		if _, err = r.Peek(1); err != nil {
+			return nil, err
+		}
+	}
+	fr := &zstdFrame{r: r}
+	var err error
+	if c.d == nil {
+		c.d, err = zstd.NewReader(fr, zstd.WithDecoderConcurrency(1))
+	} else {
+		err = c.d.Reset(fr)
+	}
+	return c.d, err
+}
+
+func (c *zstdCodec) Close() {
+	if c.d != nil {
+		c.d.Close()
+	}
+}
+
+const (
+	zstdFrameHeader = iota
+	zstdBlockHeader
+	zstdChecksum
+	zstdDone
+)
+
+// zstdFrame passes through the bytes of one zstd frame, so the decoder
+// stops at the end of the frame
+type zstdFrame struct {
+	r        *countReader
+	state    int
+	pending  []byte
+	remain   int64
+	checksum bool
+}
+
+func (f *zstdFrame) Read(p []byte) (int, error) {
+	for len(f.pending) == 0 && f.remain == 0 {
+		switch f.state {
+		case zstdFrameHeader:
+			b := make([]byte, 5)
+			if _, err := io.ReadFull(f.r, b); err != nil {
+				return 0, err
+			}
+			if binary.LittleEndian.Uint32(b) != 0xFD2FB528 {
+				return 0, errors.New("invalid zstd frame")
+			}
+			fhd := b[4]
+			single := fhd>>5&1 == 1
+			f.checksum = fhd>>2&1 == 1
+			size := []int{0, 1, 2, 4}[fhd&3] + []int{0, 2, 4, 8}[fhd>>6]
+			if !single {
+				size++
+			} else if fhd>>6 == 0 {
+				size++
+			}
+			f.pending = make([]byte, 5+size)
+			copy(f.pending, b)
+			if _, err := io.ReadFull(f.r, f.pending[5:]); err != nil {
+				return 0, err
+			}
+			f.state = zstdBlockHeader
+		case zstdBlockHeader:
+			f.pending = make([]byte, 3)
+			if _, err := io.ReadFull(f.r, f.pending); err != nil {
+				return 0, err
+			}
+			v := uint32(f.pending[0]) | uint32(f.pending[1])<<8 | uint32(f.pending[2])<<16
+			switch v >> 1 & 3 {
+			case 1:
+				f.remain = 1
+			case 3:
+				return 0, errors.New("invalid zstd block")
+			default:
+				f.remain = int64(v >> 3)
+			}
+			if v&1 == 1 {
+				f.state = zstdChecksum
+			}
+		case zstdChecksum:
+			if f.checksum {
+				f.remain = 4
+			}
+			f.state = zstdDone
+		default:
+			return 0, io.EOF
+		}
+	}
+	if len(f.pending) > 0 {
+		n := copy(p, f.pending)
+		f.pending = f.pending[n:]
+		return n, nil
+	}
+	if int64(len(p)) > f.remain {
+		p = p[:f.remain]
+	}
+	n, err := f.r.Read(p)
+	f.remain -= int64(n)
+	if err == io.EOF && f.remain > 0 {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+// singleCodec decompresses the whole stream as one member, as the members
+// of xz and bzip2 can't be found without decompressing
+type singleCodec struct {
+	open func(r io.Reader) (io.Reader, error)
+	used bool
+}
+
+func newXzCodec() codec {
+	return &singleCodec{open: func(r io.Reader) (io.Reader, error) {
+		return xz.NewReader(r)
+	}}
+}
+
+func newBzip2Codec() codec {
+	return &singleCodec{open: func(r io.Reader) (io.Reader, error) {
+		return bzip2.NewReader(r), nil
+	}}
+}
+
+func (c *singleCodec) member(r *countReader) (io.Reader, error) {
+	if c.used {
+		return nil, io.EOF
+	}
+	c.used = true
+	return c.open(r)
+}
+
+func (c *singleCodec) Close() {}
+
+// container reads the entries of the decompressed stream
+type container interface {
+	// index returns the entries read from r, pos returns the offset in r
+	index(r io.Reader, pos func() int64) ([]entry, error)
+	// open returns the content of the entry whose header is at the start of r
+	open(r io.Reader) (io.Reader, error)
+}
+
+func cleanName(name string) string {
+	return strings.TrimPrefix(stdpath.Clean("/"+name), "/")
+}
+
+type tarContainer struct{}
+
+func (tarContainer) index(r io.Reader, pos func() int64) ([]entry, error) {
+	var entries []entry
+	tr := tar.NewReader(r)
+	for {
+		// the headers start at the blocks of 512 bytes after the data
+		header := (pos() + 511) &^ 511
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if name := cleanName(hdr.Name); name != "" {
+			switch hdr.Typeflag {
+			case tar.TypeReg, tar.TypeGNUSparse, tar.TypeDir:
+				entries = append(entries, entry{name: name, info: hdr.FileInfo(), header: header})
+			}
+		}
+		if _, err = utils.CopyWithBuffer(io.Discard, tr); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (tarContainer) open(r io.Reader) (io.Reader, error) {
+	tr := tar.NewReader(r)
+	if _, err := tr.Next(); err != nil {
+		return nil, err
+	}
+	return tr, nil
+}
+
+// cpioContainer reads the cpio archives of the newc, crc and odc formats
+type cpioContainer struct{}
+
+const cpioTrailer = "TRAILER!!!"
+
+type cpioHeader struct {
+	name    string
+	mode    int64
+	mtime   int64
+	size    int64
+	dataPad int64
+}
+
+func readCPIOHeader(r io.Reader) (*cpioHeader, error) {
+	magic := make([]byte, 6)
+	if _, err := io.ReadFull(r, magic); err != nil {
+		return nil, err
+	}
+	var (
+		h        cpioHeader
+		fields   []int64
+		nameSize int64
+		namePad  int64
+	)
+	parse := func(widths []int, base int) error {
+		b := make([]byte, 0, 128)
+		for _, w := range widths {
+			b = b[:w]
+			if _, err := io.ReadFull(r, b); err != nil {
+				return err
+			}
+			v, err := strconv.ParseInt(string(b), base, 64)
+			if err != nil {
+				return errors.Wrap(err, "invalid cpio header")
+			}
+			fields = append(fields, v)
+		}
+		return nil
+	}
+	switch string(magic) {
+	case "070701", "070702":
+		widths := make([]int, 13)
+		for i := range widths {
+			widths[i] = 8
+		}
+		if err := parse(widths, 16); err != nil {
+			return nil, err
+		}
+		h.mode, h.mtime, h.size, nameSize = fields[1], fields[5], fields[6], fields[11]
+		namePad = (4 - (110+nameSize)%4) % 4
+		h.dataPad = (4 - h.size%4) % 4
+	case "070707":
+		if err := parse([]int{6, 6, 6, 6, 6, 6, 6, 11, 6, 11}, 8); err != nil {
+			return nil, err
+		}
+		h.mode, h.mtime, nameSize, h.size = fields[2], fields[7], fields[8], fields[9]
+	default:
+		return nil, errors.New("unsupported cpio format")
+	}
+	name := make([]byte, nameSize+namePad)
+	if _, err := io.ReadFull(r, name); err != nil {
+		return nil, err
+	}
+	h.name = strings.TrimRight(string(name[:nameSize]), "\x00")
+	return &h, nil
+}
+
+func (cpioContainer) index(r io.Reader, pos func() int64) ([]entry, error) {
+	var entries []entry
+	for {
+		header := pos()
+		h, err := readCPIOHeader(r)
+		if err != nil {
+			return nil, err
+		}
+		if h.name == cpioTrailer {
+			return entries, nil
+		}
+		if name := cleanName(h.name); name != "" {
+			switch h.mode & 0o170000 {
+			case 0o040000, 0o100000:
+				entries = append(entries, entry{name: name, info: &cpioFileInfo{h: h}, header: header})
+			}
+		}
+		if _, err = utils.CopyWithBufferN(io.Discard, r, h.size+h.dataPad); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (cpioContainer) open(r io.Reader) (io.Reader, error) {
+	h, err := readCPIOHeader(r)
+	if err != nil {
+		return nil, err
+	}
+	return io.LimitReader(r, h.size), nil
+}
+
+type cpioFileInfo struct {
+	h *cpioHeader
+}
+
+func (i *cpioFileInfo) Name() string {
+	return stdpath.Base(i.h.name)
+}
+
+func (i *cpioFileInfo) Size() int64 {
+	if i.IsDir() {
+		return 0
+	}
+	return i.h.size
+}
+
+func (i *cpioFileInfo) Mode() fs.FileMode {
+	mode := fs.FileMode(i.h.mode & 0o777)
+	if i.IsDir() {
+		mode |= fs.ModeDir
+	}
+	return mode
+}
+
+func (i *cpioFileInfo) ModTime() time.Time {
+	return time.Unix(i.h.mtime, 0)
+}
+
+func (i *cpioFileInfo) IsDir() bool {
+	return i.h.mode&0o170000 == 0o040000
+}
+
+func (i *cpioFileInfo) Sys() any {
+	return nil
+}
@@ -20,3 +20,19 @@ type Tool interface {
 	Extract(ss []*stream.SeekableStream, args model.ArchiveInnerArgs) (io.ReadCloser, int64, error)
 	Decompress(ss []*stream.SeekableStream, outputPath string, args model.ArchiveInnerArgs, up model.UpdateProgress) error
 }
+
+// CompressFile is a file or folder put into the archive being created
+type CompressFile struct {
+	// Name is the slash separated path of it in the archive
+	Name string
+	Obj  model.Obj
+	// Open opens the content of the file, it's not called for the folders
+	Open func() (io.ReadCloser, error)
+}
+
+// Compressor creates the archives of the accepted extensions, the archive
+// is written to w while the files are read one by one
+type Compressor interface {
+	AcceptedCompressExtensions() []string
+	Compress(w io.Writer, ext string, files []CompressFile, args model.ArchiveCompressArgs) error
+}
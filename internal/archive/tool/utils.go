@@ -1,12 +1,15 @@
 package tool
 
 import (
+	"strings"
+
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 )
 
 var (
 	Tools               = make(map[string]Tool)
 	MultipartExtensions = make(map[string]MultipartExtension)
+	Compressors         = make(map[string]Compressor)
 )
 
 func RegisterTool(tool Tool) {
@@ -30,3 +33,28 @@ func GetArchiveTool(ext string) (*MultipartExtension, Tool, error) {
 	}
 	return &partExt, t, nil
 }
+
+func RegisterCompressor(c Compressor) {
+	for _, ext := range c.AcceptedCompressExtensions() {
+		Compressors[ext] = c
+	}
+}
+
+// GetCompressor returns the compressor of the archive name by the longest
+// matched extension, e.g. ".tar.gz" rather than ".gz"
+func GetCompressor(name string) (string, Compressor, error) {
+	name = strings.ToLower(name)
+	var (
+		ext string
+		c   Compressor
+	)
+	for e, compressor := range Compressors {
+		if strings.HasSuffix(name, e) && len(e) > len(ext) {
+			ext, c = e, compressor
+		}
+	}
+	if c == nil {
+		return "", nil, errs.UnknownArchiveFormat
+	}
+	return ext, c, nil
+}
@@ -0,0 +1,52 @@
+package zip
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/archive/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/yeka/zip"
+)
+
+func TestCompress(t *testing.T) {
+	content := "hello world"
+	files := []tool.CompressFile{
+		{Name: "dir", Obj: &model.Object{Name: "dir", IsFolder: true, Modified: time.Now()}},
+		{
+			Name: "dir/文件.txt",
+			Obj:  &model.Object{Name: "文件.txt", Size: int64(len(content)), Modified: time.Now()},
+			Open: func() (io.ReadCloser, error) {
+				return io.NopCloser(strings.NewReader(content)), nil
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := (Zip{}).Compress(&buf, ".zip", files, model.ArchiveCompressArgs{Password: "secret"}); err != nil {
+		t.Fatalf("failed compress: %+v", err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatalf("failed read zip: %+v", err)
+	}
+	if len(r.File) != 2 || r.File[0].Name != "dir/" || r.File[1].Name != "dir/文件.txt" {
+		t.Fatalf("unexpected files in zip: %v", r.File)
+	}
+	f := r.File[1]
+	if !f.IsEncrypted() {
+		t.Fatalf("file is not encrypted")
+	}
+	f.SetPassword("secret")
+	rc, err := f.Open()
+	if err != nil {
+		t.Fatalf("failed open file: %+v", err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil || string(b) != content {
+		t.Fatalf("content: got %q, %v", b, err)
+	}
+}
@@ -6,10 +6,12 @@ import (
 	"io/fs"
 	stdpath "path"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/archive/tool"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/saintfish/chardet"
 	"github.com/yeka/zip"
 	"golang.org/x/text/encoding"
@@ -193,3 +195,45 @@ func getEncoding(name string) (enc encoding.Encoding) {
 	}
 	return
 }
+
+func compressFile(w *zip.Writer, file tool.CompressFile, password string) error {
+	header := &zip.FileHeader{
+		Name:   file.Name,
+		Method: zip.Deflate,
+	}
+	header.SetModTime(file.Obj.ModTime())
+	if !isASCII(file.Name) {
+		// the name is encoded in utf-8
+		header.Flags |= 0x800
+	}
+	if file.Obj.IsDir() {
+		header.Name += "/"
+		header.Method = zip.Store
+		_, err := w.CreateHeader(header)
+		return err
+	}
+	if password != "" {
+		header.SetPassword(password)
+		header.SetEncryptionMethod(zip.AES256Encryption)
+	}
+	fw, err := w.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+	rc, err := file.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	_, err = utils.CopyWithBuffer(fw, rc)
+	return err
+}
+
+func isASCII(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] >= utf8.RuneSelf {
+			return false
+		}
+	}
+	return true
+}
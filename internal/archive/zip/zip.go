@@ -9,6 +9,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/yeka/zip"
 )
 
 type Zip struct {
@@ -125,8 +126,24 @@ func (Zip) Decompress(ss []*stream.SeekableStream, outputPath string, args model
 	return tool.DecompressFromFolderTraversal(&WrapReader{Reader: zipReader}, outputPath, args, up)
 }
 
+func (Zip) AcceptedCompressExtensions() []string {
+	return []string{".zip"}
+}
+
+func (Zip) Compress(w io.Writer, ext string, files []tool.CompressFile, args model.ArchiveCompressArgs) error {
+	zipWriter := zip.NewWriter(w)
+	for _, file := range files {
+		if err := compressFile(zipWriter, file, args.Password); err != nil {
+			return err
+		}
+	}
+	return zipWriter.Close()
+}
+
 var _ tool.Tool = (*Zip)(nil)
+var _ tool.Compressor = (*Zip)(nil)
 
 func init() {
 	tool.RegisterTool(Zip{})
+	tool.RegisterCompressor(Zip{})
 }
@@ -167,15 +167,3 @@ func initURL() {
 	}
 	conf.URL = u
 }
-
-func CleanTempDir() {
-	files, err := os.ReadDir(conf.Conf.TempDir)
-	if err != nil {
-		log.Errorln("failed list temp file: ", err)
-	}
-	for _, file := range files {
-		if err := os.RemoveAll(filepath.Join(conf.Conf.TempDir, file.Name())); err != nil {
-			log.Errorln("failed delete temp file: ", err)
-		}
-	}
-}
@@ -16,3 +16,8 @@ func InitIndex() {
 		search.WriteProgress(progress)
 	}
 }
+
+// InitIndexScheduler starts the scheduled index jobs of the storages
+func InitIndexScheduler() {
+	search.StartIndexScheduler()
+}
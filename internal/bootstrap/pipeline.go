@@ -0,0 +1,8 @@
+package bootstrap
+
+import "github.com/OpenListTeam/OpenList/v4/internal/pipeline"
+
+// InitPipelineScheduler starts the scheduled runs of the pipelines
+func InitPipelineScheduler() {
+	pipeline.StartScheduler()
+}
@@ -0,0 +1,36 @@
+package bootstrap
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	log "github.com/sirupsen/logrus"
+)
+
+// InitSharingLogCleaner deletes the access logs of the sharings older than the retention
+func InitSharingLogCleaner() {
+	cleaner := cron.NewCron(time.Hour)
+	cleaner.Do(func() {
+		days := setting.GetInt(conf.SharingLogRetentionDays, 90)
+		if days <= 0 {
+			return
+		}
+		n, err := db.DeleteSharingAccessLogsBefore(time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			log.Errorf("failed delete expired sharing access logs: %+v", err)
+		} else if n > 0 {
+			log.Debugf("deleted %d expired sharing access logs", n)
+		}
+	})
+}
+
+// InitSharingLogWriter writes the queued access logs of the sharings every
+// few seconds
+func InitSharingLogWriter() {
+	writer := cron.NewCron(5 * time.Second)
+	writer.Do(op.FlushSharingAccessLogs)
+}
@@ -4,17 +4,21 @@ import (
 	"context"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/prometheus/client_golang/prometheus"
 	"golang.org/x/time/rate"
 )
 
 type blockBurstLimiter struct {
 	*rate.Limiter
+	bytes prometheus.Counter
 }
 
 func (l blockBurstLimiter) WaitN(ctx context.Context, total int) error {
+	l.bytes.Add(float64(total))
 	for total > 0 {
 		n := l.Burst()
 		if l.Limiter.Limit() == rate.Inf || n > total {
@@ -36,9 +40,12 @@ func streamFilterNegative(limit int) (rate.Limit, int) {
 	return rate.Limit(limit) * 1024.0, limit * 1024
 }
 
-func initLimiter(limiter *stream.Limiter, s string) {
+func initLimiter(limiter *stream.Limiter, name, s string) {
 	clientDownLimit, burst := streamFilterNegative(setting.GetInt(s, -1))
-	*limiter = blockBurstLimiter{Limiter: rate.NewLimiter(clientDownLimit, burst)}
+	*limiter = blockBurstLimiter{
+		Limiter: rate.NewLimiter(clientDownLimit, burst),
+		bytes:   metrics.LimiterBytes(name),
+	}
 	op.RegisterSettingChangingCallback(func() {
 		newLimit, newBurst := streamFilterNegative(setting.GetInt(s, -1))
 		(*limiter).SetLimit(newLimit)
@@ -47,8 +54,8 @@ func initLimiter(limiter *stream.Limiter, s string) {
 }
 
 func InitStreamLimit() {
-	initLimiter(&stream.ClientDownloadLimit, conf.StreamMaxClientDownloadSpeed)
-	initLimiter(&stream.ClientUploadLimit, conf.StreamMaxClientUploadSpeed)
-	initLimiter(&stream.ServerDownloadLimit, conf.StreamMaxServerDownloadSpeed)
-	initLimiter(&stream.ServerUploadLimit, conf.StreamMaxServerUploadSpeed)
+	initLimiter(&stream.ClientDownloadLimit, "client_download", conf.StreamMaxClientDownloadSpeed)
+	initLimiter(&stream.ClientUploadLimit, "client_upload", conf.StreamMaxClientUploadSpeed)
+	initLimiter(&stream.ServerDownloadLimit, "server_download", conf.StreamMaxServerDownloadSpeed)
+	initLimiter(&stream.ServerUploadLimit, "server_upload", conf.StreamMaxServerUploadSpeed)
 }
@@ -1,13 +1,20 @@
 package bootstrap
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
+
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/pipeline"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/tache"
+	log "github.com/sirupsen/logrus"
 )
 
 func taskFilterNegative(num int) int64 {
@@ -18,35 +25,135 @@ func taskFilterNegative(num int) int64 {
 }
 
 func InitTaskManager() {
-	fs.UploadTaskManager = tache.NewManager[*fs.UploadTask](tache.WithWorks(setting.GetInt(conf.TaskUploadThreadsNum, conf.Conf.Tasks.Upload.Workers)), tache.WithMaxRetry(conf.Conf.Tasks.Upload.MaxRetry)) //upload will not support persist
+	initTaskManagers(true)
+}
+
+// InitMountTaskManager creates the task managers for the mount command. The
+// server may be running on the same data dir, so the tasks are neither
+// restored nor persisted and the temp dir is left alone.
+func InitMountTaskManager() {
+	initTaskManagers(false)
+}
+
+func initTaskManagers(persist bool) {
+	fs.UploadTaskManager = tache.NewManager[*fs.UploadTask](tache.WithWorks(setting.GetInt(conf.TaskUploadThreadsNum, conf.Conf.Tasks.Upload.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("upload", persist && conf.Conf.Tasks.Upload.TaskPersistant), db.UpdateTaskDataFunc("upload", persist && conf.Conf.Tasks.Upload.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Upload.MaxRetry))
 	op.RegisterSettingChangingCallback(func() {
 		fs.UploadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskUploadThreadsNum, conf.Conf.Tasks.Upload.Workers)))
 	})
-	fs.CopyTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(setting.GetInt(conf.TaskCopyThreadsNum, conf.Conf.Tasks.Copy.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("copy", conf.Conf.Tasks.Copy.TaskPersistant), db.UpdateTaskDataFunc("copy", conf.Conf.Tasks.Copy.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Copy.MaxRetry))
+	fs.CopyTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(setting.GetInt(conf.TaskCopyThreadsNum, conf.Conf.Tasks.Copy.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("copy", persist && conf.Conf.Tasks.Copy.TaskPersistant), db.UpdateTaskDataFunc("copy", persist && conf.Conf.Tasks.Copy.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Copy.MaxRetry))
 	op.RegisterSettingChangingCallback(func() {
 		fs.CopyTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskCopyThreadsNum, conf.Conf.Tasks.Copy.Workers)))
 	})
-	fs.MoveTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(setting.GetInt(conf.TaskMoveThreadsNum, conf.Conf.Tasks.Move.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("move", conf.Conf.Tasks.Move.TaskPersistant), db.UpdateTaskDataFunc("move", conf.Conf.Tasks.Move.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Move.MaxRetry))
+	fs.MoveTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(setting.GetInt(conf.TaskMoveThreadsNum, conf.Conf.Tasks.Move.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("move", persist && conf.Conf.Tasks.Move.TaskPersistant), db.UpdateTaskDataFunc("move", persist && conf.Conf.Tasks.Move.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Move.MaxRetry))
 	op.RegisterSettingChangingCallback(func() {
 		fs.MoveTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskMoveThreadsNum, conf.Conf.Tasks.Move.Workers)))
 	})
-	tool.DownloadTaskManager = tache.NewManager[*tool.DownloadTask](tache.WithWorks(setting.GetInt(conf.TaskOfflineDownloadThreadsNum, conf.Conf.Tasks.Download.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("download", conf.Conf.Tasks.Download.TaskPersistant), db.UpdateTaskDataFunc("download", conf.Conf.Tasks.Download.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Download.MaxRetry))
+	tool.DownloadTaskManager = tache.NewManager[*tool.DownloadTask](tache.WithWorks(setting.GetInt(conf.TaskOfflineDownloadThreadsNum, conf.Conf.Tasks.Download.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("download", persist && conf.Conf.Tasks.Download.TaskPersistant), db.UpdateTaskDataFunc("download", persist && conf.Conf.Tasks.Download.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Download.MaxRetry))
 	op.RegisterSettingChangingCallback(func() {
 		tool.DownloadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskOfflineDownloadThreadsNum, conf.Conf.Tasks.Download.Workers)))
 	})
-	tool.TransferTaskManager = tache.NewManager[*tool.TransferTask](tache.WithWorks(setting.GetInt(conf.TaskOfflineDownloadTransferThreadsNum, conf.Conf.Tasks.Transfer.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("transfer", conf.Conf.Tasks.Transfer.TaskPersistant), db.UpdateTaskDataFunc("transfer", conf.Conf.Tasks.Transfer.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Transfer.MaxRetry))
+	tool.TransferTaskManager = tache.NewManager[*tool.TransferTask](tache.WithWorks(setting.GetInt(conf.TaskOfflineDownloadTransferThreadsNum, conf.Conf.Tasks.Transfer.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("transfer", persist && conf.Conf.Tasks.Transfer.TaskPersistant), db.UpdateTaskDataFunc("transfer", persist && conf.Conf.Tasks.Transfer.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Transfer.MaxRetry))
 	op.RegisterSettingChangingCallback(func() {
 		tool.TransferTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskOfflineDownloadTransferThreadsNum, conf.Conf.Tasks.Transfer.Workers)))
 	})
-	if len(tool.TransferTaskManager.GetAll()) == 0 { //prevent offline downloaded files from being deleted
-		CleanTempDir()
-	}
-	fs.ArchiveDownloadTaskManager = tache.NewManager[*fs.ArchiveDownloadTask](tache.WithWorks(setting.GetInt(conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("decompress", conf.Conf.Tasks.Decompress.TaskPersistant), db.UpdateTaskDataFunc("decompress", conf.Conf.Tasks.Decompress.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Decompress.MaxRetry))
+	fs.ArchiveDownloadTaskManager = tache.NewManager[*fs.ArchiveDownloadTask](tache.WithWorks(setting.GetInt(conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("decompress", persist && conf.Conf.Tasks.Decompress.TaskPersistant), db.UpdateTaskDataFunc("decompress", persist && conf.Conf.Tasks.Decompress.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Decompress.MaxRetry))
 	op.RegisterSettingChangingCallback(func() {
 		fs.ArchiveDownloadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskDecompressDownloadThreadsNum, conf.Conf.Tasks.Decompress.Workers)))
 	})
-	fs.ArchiveContentUploadTaskManager.Manager = tache.NewManager[*fs.ArchiveContentUploadTask](tache.WithWorks(setting.GetInt(conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)), tache.WithMaxRetry(conf.Conf.Tasks.DecompressUpload.MaxRetry)) //decompress upload will not support persist
+	fs.ArchiveContentUploadTaskManager.Manager = tache.NewManager[*fs.ArchiveContentUploadTask](tache.WithWorks(setting.GetInt(conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("decompress_upload", persist && conf.Conf.Tasks.DecompressUpload.TaskPersistant), db.UpdateTaskDataFunc("decompress_upload", persist && conf.Conf.Tasks.DecompressUpload.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.DecompressUpload.MaxRetry))
 	op.RegisterSettingChangingCallback(func() {
 		fs.ArchiveContentUploadTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskDecompressUploadThreadsNum, conf.Conf.Tasks.DecompressUpload.Workers)))
 	})
+	fs.ArchiveCompressTaskManager = tache.NewManager[*fs.ArchiveCompressTask](tache.WithWorks(setting.GetInt(conf.TaskCompressThreadsNum, conf.Conf.Tasks.Compress.Workers)), tache.WithPersistFunction(db.GetTaskDataFunc("compress", persist && conf.Conf.Tasks.Compress.TaskPersistant), db.UpdateTaskDataFunc("compress", persist && conf.Conf.Tasks.Compress.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Compress.MaxRetry))
+	op.RegisterSettingChangingCallback(func() {
+		fs.ArchiveCompressTaskManager.SetWorkersNumActive(taskFilterNegative(setting.GetInt(conf.TaskCompressThreadsNum, conf.Conf.Tasks.Compress.Workers)))
+	})
+	// the temp files of the restored tasks are kept
+	if persist {
+		ReconcileTempDir()
+	}
+	// pipelines are created at last since the restored ones wait for the tasks of the others
+	pipeline.TaskManager = tache.NewManager[*pipeline.Task](tache.WithWorks(conf.Conf.Tasks.Pipeline.Workers), tache.WithPersistFunction(db.GetTaskDataFunc("pipeline", persist && conf.Conf.Tasks.Pipeline.TaskPersistant), db.UpdateTaskDataFunc("pipeline", persist && conf.Conf.Tasks.Pipeline.TaskPersistant)), tache.WithMaxRetry(conf.Conf.Tasks.Pipeline.MaxRetry))
+	metrics.RegisterTaskManager("upload", fs.UploadTaskManager)
+	metrics.RegisterTaskManager("copy", fs.CopyTaskManager)
+	metrics.RegisterTaskManager("move", fs.MoveTaskManager)
+	metrics.RegisterTaskManager("download", tool.DownloadTaskManager)
+	metrics.RegisterTaskManager("transfer", tool.TransferTaskManager)
+	metrics.RegisterTaskManager("decompress", fs.ArchiveDownloadTaskManager)
+	metrics.RegisterTaskManager("decompress_upload", fs.ArchiveContentUploadTaskManager)
+	metrics.RegisterTaskManager("compress", fs.ArchiveCompressTaskManager)
+	metrics.RegisterTaskManager("pipeline", pipeline.TaskManager)
+}
+
+// tempFilesInUse returns the files in the temp dir used by the restored tasks
+// not succeeded, which may run again
+func tempFilesInUse() []string {
+	var files []string
+	notSucceeded := func(s tache.State) bool { return s != tache.StateSucceeded }
+	for _, t := range fs.UploadTaskManager.GetAll() {
+		if notSucceeded(t.GetState()) && t.TempFile != "" {
+			files = append(files, t.TempFile)
+		}
+	}
+	for _, t := range fs.ArchiveContentUploadTaskManager.GetAll() {
+		if notSucceeded(t.GetState()) {
+			files = append(files, t.FilePath)
+		}
+	}
+	for _, t := range tool.DownloadTaskManager.GetAll() {
+		if notSucceeded(t.GetState()) && t.TempDir != "" {
+			files = append(files, t.TempDir)
+		}
+	}
+	for _, t := range tool.TransferTaskManager.GetAll() {
+		// the source of an offline download transfer is a local temp file
+		if notSucceeded(t.GetState()) && t.SrcStorageMp == "" {
+			files = append(files, t.SrcActualPath)
+		}
+	}
+	// the S3 multipart uploads expire by themselves
+	files = append(files, filepath.Join(conf.Conf.TempDir, conf.S3MultipartDir))
+	return files
+}
+
+// ReconcileTempDir removes the files in the temp dir not used by any restored
+// task or the S3 multipart uploads
+func ReconcileTempDir() {
+	var inUse []string
+	for _, f := range tempFilesInUse() {
+		if abs, err := filepath.Abs(f); err == nil {
+			inUse = append(inUse, abs)
+		}
+	}
+	reconcileDir(conf.Conf.TempDir, inUse)
+}
+
+func reconcileDir(dir string, inUse []string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		log.Errorln("failed list temp file: ", err)
+		return
+	}
+	for _, entry := range entries {
+		p := filepath.Join(dir, entry.Name())
+		used, parent := false, false
+		for _, f := range inUse {
+			if f == p || strings.HasPrefix(p, f+string(filepath.Separator)) {
+				used = true
+				break
+			}
+			if strings.HasPrefix(f, p+string(filepath.Separator)) {
+				parent = true
+			}
+		}
+		switch {
+		case used:
+		case parent && entry.IsDir():
+			reconcileDir(p, inUse)
+		default:
+			if err := os.RemoveAll(p); err != nil {
+				log.Errorln("failed delete temp file: ", err)
+			}
+		}
+	}
 }
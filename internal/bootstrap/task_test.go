@@ -0,0 +1,37 @@
+package bootstrap
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReconcileDir(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"file-used", "file-orphan", "dir-used/a", "aria2/1/x", "aria2/2/y"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reconcileDir(dir, []string{
+		filepath.Join(dir, "file-used"),
+		filepath.Join(dir, "dir-used"),
+		filepath.Join(dir, "aria2", "1"),
+	})
+	for name, want := range map[string]bool{
+		"file-used":   true,
+		"file-orphan": false,
+		"dir-used/a":  true,
+		"aria2/1/x":   true,
+		"aria2/2":     false,
+	} {
+		_, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name)))
+		if got := err == nil; got != want {
+			t.Errorf("%s: exists %v, want %v", name, got, want)
+		}
+	}
+}
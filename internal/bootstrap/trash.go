@@ -0,0 +1,29 @@
+package bootstrap
+
+import (
+	"context"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	log "github.com/sirupsen/logrus"
+)
+
+// InitTrashCleaner purges the objects kept in the trash longer than the retention
+func InitTrashCleaner() {
+	cleaner := cron.NewCron(time.Hour)
+	cleaner.Do(func() {
+		days := setting.GetInt(conf.TrashRetentionDays, 30)
+		if days <= 0 {
+			return
+		}
+		n, err := op.PurgeExpiredTrash(context.Background(), time.Now().AddDate(0, 0, -days))
+		if err != nil {
+			log.Errorf("failed purge expired trash: %+v", err)
+		} else if n > 0 {
+			log.Debugf("purged %d expired trash items", n)
+		}
+	})
+}
@@ -0,0 +1,36 @@
+package bootstrap
+
+import (
+	"context"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	log "github.com/sirupsen/logrus"
+)
+
+// InitUsageReconciler rescans the dirty usages frequently and all the
+// tracked usages daily, to correct the drift of the incremental accounting
+func InitUsageReconciler() {
+	// the usages just tracked read 0 until reconciled
+	op.RegisterUsageTrackedHook(func(paths []string) {
+		go func() {
+			if err := fs.ReconcileUsages(context.Background(), false); err != nil {
+				log.Errorf("failed reconcile usages of %v: %+v", paths, err)
+			}
+		}()
+	})
+	dirty := cron.NewCron(10 * time.Minute)
+	dirty.Do(func() {
+		if err := fs.ReconcileUsages(context.Background(), false); err != nil {
+			log.Errorf("failed reconcile dirty usages: %+v", err)
+		}
+	})
+	all := cron.NewCron(24 * time.Hour)
+	all.Do(func() {
+		if err := fs.ReconcileUsages(context.Background(), true); err != nil {
+			log.Errorf("failed reconcile usages: %+v", err)
+		}
+	})
+}
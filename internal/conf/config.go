@@ -73,6 +73,8 @@ type TasksConfig struct {
 	Move               TaskConfig `json:"move" envPrefix:"MOVE_"`
 	Decompress         TaskConfig `json:"decompress" envPrefix:"DECOMPRESS_"`
 	DecompressUpload   TaskConfig `json:"decompress_upload" envPrefix:"DECOMPRESS_UPLOAD_"`
+	Compress           TaskConfig `json:"compress" envPrefix:"COMPRESS_"`
+	Pipeline           TaskConfig `json:"pipeline" envPrefix:"PIPELINE_"`
 	AllowRetryCanceled bool       `json:"allow_retry_canceled" env:"ALLOW_RETRY_CANCELED"`
 }
 
@@ -101,6 +103,20 @@ type FTP struct {
 	EnablePasvConnIPCheck   bool   `json:"enable_pasv_conn_ip_check" env:"ENABLE_PASV_CONN_IP_CHECK"`
 }
 
+type WebDAV struct {
+	// LockSystem is either "memory" or "db", the latter persists the locks
+	// and shares them between instances using the same database
+	LockSystem string `json:"lock_system" env:"LOCK_SYSTEM"`
+	// MaxLockTimeout caps the timeout of locks saved in db, in seconds
+	MaxLockTimeout int `json:"max_lock_timeout" env:"MAX_LOCK_TIMEOUT"`
+}
+
+type Metrics struct {
+	Enable bool `json:"enable" env:"ENABLE"`
+	// Token protects the metrics endpoint with a bearer token if set
+	Token string `json:"token" env:"TOKEN"`
+}
+
 type SFTP struct {
 	Enable bool   `json:"enable" env:"ENABLE"`
 	Listen string `json:"listen" env:"LISTEN"`
@@ -130,6 +146,8 @@ type Config struct {
 	S3                    S3          `json:"s3" envPrefix:"S3_"`
 	FTP                   FTP         `json:"ftp" envPrefix:"FTP_"`
 	SFTP                  SFTP        `json:"sftp" envPrefix:"SFTP_"`
+	WebDAV                WebDAV      `json:"webdav" envPrefix:"WEBDAV_"`
+	Metrics               Metrics     `json:"metrics" envPrefix:"METRICS_"`
 	LastLaunchedVersion   string      `json:"last_launched_version"`
 }
 
@@ -195,6 +213,7 @@ func DefaultConfig(dataDir string) *Config {
 			},
 			Upload: TaskConfig{
 				Workers: 5,
+				// TaskPersistant: true,
 			},
 			Copy: TaskConfig{
 				Workers:  5,
@@ -214,6 +233,17 @@ func DefaultConfig(dataDir string) *Config {
 			DecompressUpload: TaskConfig{
 				Workers:  5,
 				MaxRetry: 2,
+				// TaskPersistant: true,
+			},
+			Compress: TaskConfig{
+				Workers:  5,
+				MaxRetry: 2,
+				// TaskPersistant: true,
+			},
+			Pipeline: TaskConfig{
+				Workers: 5,
+				// the steps are retried by themselves
+				// TaskPersistant: true,
 			},
 			AllowRetryCanceled: false,
 		},
@@ -243,6 +273,13 @@ func DefaultConfig(dataDir string) *Config {
 			Enable: false,
 			Listen: ":5222",
 		},
+		WebDAV: WebDAV{
+			LockSystem:     "memory",
+			MaxLockTimeout: 86400,
+		},
+		Metrics: Metrics{
+			Enable: false,
+		},
 		LastLaunchedVersion: "",
 	}
 }
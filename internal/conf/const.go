@@ -55,12 +55,17 @@ const (
 	ShareArchivePreview     = "share_archive_preview"
 	ShareForceProxy         = "share_force_proxy"
 	ShareSummaryContent     = "share_summary_content"
+	TrashRetentionDays      = "trash_retention_days"
+	SharingLogRetentionDays = "sharing_log_retention_days"
 
 	// index
 	SearchIndex     = "search_index"
 	AutoUpdateIndex = "auto_update_index"
 	IgnorePaths     = "ignore_paths"
 	MaxIndexDepth   = "max_index_depth"
+	IndexContent    = "index_content"
+	// max size of the files to extract the content, in MB
+	IndexContentMaxSize = "index_content_max_size"
 
 	// aria2
 	Aria2Uri    = "aria2_uri"
@@ -149,12 +154,17 @@ const (
 	TaskMoveThreadsNum                    = "move_task_threads_num"
 	TaskDecompressDownloadThreadsNum      = "decompress_download_task_threads_num"
 	TaskDecompressUploadThreadsNum        = "decompress_upload_task_threads_num"
+	TaskCompressThreadsNum                = "compress_task_threads_num"
 	StreamMaxClientDownloadSpeed          = "max_client_download_speed"
 	StreamMaxClientUploadSpeed            = "max_client_upload_speed"
 	StreamMaxServerDownloadSpeed          = "max_server_download_speed"
 	StreamMaxServerUploadSpeed            = "max_server_upload_speed"
 )
 
+// S3MultipartDir is the dir in the temp dir where the parts of the S3
+// multipart uploads are staged until they are completed or aborted
+const S3MultipartDir = "s3_multipart"
+
 const (
 	UNKNOWN = iota
 	FOLDER
@@ -182,4 +192,9 @@ const (
 	UserAgentKey
 	PathKey
 	SharingIDKey
+	// TaskRootKey is the root id the submitted tasks are counted in
+	TaskRootKey
+	// NoOverwriteKey makes the put fail with errs.ObjectAlreadyExists
+	// rather than overwrite the existing file
+	NoOverwriteKey
 )
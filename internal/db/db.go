@@ -12,7 +12,7 @@ var db *gorm.DB
 
 func Init(d *gorm.DB) {
 	db = d
-	err := AutoMigrate(new(model.Storage), new(model.User), new(model.Meta), new(model.SettingItem), new(model.SearchNode), new(model.TaskItem), new(model.SSHPublicKey), new(model.SharingDB))
+	err := AutoMigrate(new(model.Storage), new(model.User), new(model.Meta), new(model.MetaACL), new(model.SettingItem), new(model.SearchNode), new(model.TaskItem), new(model.SSHPublicKey), new(model.S3AccessKey), new(model.SharingDB), new(model.SharingAccessLog), new(model.WebdavLock), new(model.DeadProp), new(model.S3ObjectMeta), new(model.Webhook), new(model.WebhookDelivery), new(model.Group), new(model.UserGroup), new(model.PathUsage), new(model.TrashItem), new(model.IndexJob), new(model.Pipeline))
 	if err != nil {
 		log.Fatalf("failed migrate database: %s", err.Error())
 	}
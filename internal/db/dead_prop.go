@@ -0,0 +1,114 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func GetDeadPropsByPath(path string) (props []model.DeadProp, err error) {
+	if err := db.Where("path = ?", path).Find(&props).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find dead props")
+	}
+	return props, nil
+}
+
+// GetDeadPropsInDir returns the dead props of dir and its direct children
+func GetDeadPropsInDir(dir string) ([]model.DeadProp, error) {
+	prefix := strings.TrimSuffix(dir, "/") + "/"
+	var props []model.DeadProp
+	if err := db.Where("path = ? OR (path LIKE ? AND path NOT LIKE ?)", dir, prefix+"%", prefix+"%/%").
+		Find(&props).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find dead props")
+	}
+	// LIKE wildcards in prefix are not escaped, filter the result again
+	ret := props[:0]
+	for _, p := range props {
+		if rest, ok := strings.CutPrefix(p.Path, prefix); p.Path == dir || (ok && rest != "" && !strings.Contains(rest, "/")) {
+			ret = append(ret, p)
+		}
+	}
+	return ret, nil
+}
+
+// PatchDeadProps removes the props in remove and then saves the props in set
+// in a single transaction, the props are identified by their Space and Name
+func PatchDeadProps(path string, set, remove []model.DeadProp) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		for _, props := range [][]model.DeadProp{remove, set} {
+			for _, p := range props {
+				if err := tx.Where("path = ? AND space = ? AND name = ?", path, p.Space, p.Name).
+					Delete(&model.DeadProp{}).Error; err != nil {
+					return err
+				}
+			}
+		}
+		for i := range set {
+			set[i].ID = 0
+			set[i].Path = path
+		}
+		if len(set) == 0 {
+			return nil
+		}
+		return tx.Create(&set).Error
+	}))
+}
+
+// getDeadPropsUnder returns the dead props of path and all its descendants
+func getDeadPropsUnder(tx *gorm.DB, path string) ([]model.DeadProp, error) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	var props []model.DeadProp
+	if err := tx.Where("path = ? OR path LIKE ?", path, prefix+"%").Find(&props).Error; err != nil {
+		return nil, err
+	}
+	// LIKE wildcards in prefix are not escaped, filter the result again
+	ret := props[:0]
+	for _, p := range props {
+		if p.Path == path || strings.HasPrefix(p.Path, prefix) {
+			ret = append(ret, p)
+		}
+	}
+	return ret, nil
+}
+
+// MoveDeadProps moves the dead props of srcPath and its descendants to dstPath
+func MoveDeadProps(srcPath, dstPath string) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		props, err := getDeadPropsUnder(tx, srcPath)
+		if err != nil || len(props) == 0 {
+			return err
+		}
+		// drop the stale props left at the destination
+		if err = deleteDeadPropsUnder(tx, dstPath); err != nil {
+			return err
+		}
+		for _, p := range props {
+			newPath := dstPath + strings.TrimPrefix(p.Path, srcPath)
+			if err = tx.Model(&model.DeadProp{}).Where("id = ?", p.ID).Update("path", newPath).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// DeleteDeadProps deletes the dead props of path and its descendants
+func DeleteDeadProps(path string) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		return deleteDeadPropsUnder(tx, path)
+	}))
+}
+
+func deleteDeadPropsUnder(tx *gorm.DB, path string) error {
+	props, err := getDeadPropsUnder(tx, path)
+	if err != nil || len(props) == 0 {
+		return err
+	}
+	ids := make([]uint, len(props))
+	for i, p := range props {
+		ids[i] = p.ID
+	}
+	return tx.Delete(&model.DeadProp{}, ids).Error
+}
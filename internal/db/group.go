@@ -0,0 +1,147 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func GetGroupById(id uint) (*model.Group, error) {
+	var g model.Group
+	if err := db.First(&g, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get group")
+	}
+	return &g, nil
+}
+
+func GetGroupByName(name string) (*model.Group, error) {
+	g := model.Group{Name: name}
+	if err := db.Where(g).First(&g).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find group")
+	}
+	return &g, nil
+}
+
+func GetGroups(pageIndex, pageSize int) (groups []model.Group, count int64, err error) {
+	groupDB := db.Model(&model.Group{})
+	if err = groupDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get groups count")
+	}
+	if err = groupDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&groups).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find groups")
+	}
+	return groups, count, nil
+}
+
+func CreateGroup(g *model.Group) error {
+	return errors.WithStack(db.Create(g).Error)
+}
+
+func UpdateGroup(g *model.Group) error {
+	return errors.WithStack(db.Save(g).Error)
+}
+
+func DeleteGroupById(id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("group_id")), id).Delete(&model.UserGroup{}).Error; err != nil {
+			return errors.Wrapf(err, "failed delete group members")
+		}
+		if err := deleteSubjectACL(tx, model.ACLSubjectGroup, id); err != nil {
+			return errors.Wrapf(err, "failed delete group acl")
+		}
+		return errors.WithStack(tx.Delete(&model.Group{}, id).Error)
+	})
+}
+
+// GetGroupMembers returns the users in the group with their groups loaded
+func GetGroupMembers(groupId uint) ([]model.User, error) {
+	members := db.Model(&model.UserGroup{}).Select(columnName("user_id")).
+		Where(fmt.Sprintf("%s = ?", columnName("group_id")), groupId)
+	var users []model.User
+	if err := db.Where(fmt.Sprintf("%s IN (?)", columnName("id")), members).
+		Order(columnName("id")).Find(&users).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get group members")
+	}
+	ptrs := make([]*model.User, len(users))
+	for i := range users {
+		ptrs[i] = &users[i]
+	}
+	return users, loadUserGroups(ptrs...)
+}
+
+// SetUserGroups replaces the groups of the user with groupIds
+func SetUserGroups(userId uint, groupIds []uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return setUserGroups(tx, userId, groupIds)
+	})
+}
+
+func setUserGroups(tx *gorm.DB, userId uint, groupIds []uint) error {
+	if err := tx.Where(fmt.Sprintf("%s = ?", columnName("user_id")), userId).Delete(&model.UserGroup{}).Error; err != nil {
+		return errors.Wrapf(err, "failed delete user groups")
+	}
+	if len(groupIds) == 0 {
+		return nil
+	}
+	var count int64
+	if err := tx.Model(&model.Group{}).Where(fmt.Sprintf("%s IN ?", columnName("id")), groupIds).Count(&count).Error; err != nil {
+		return errors.Wrapf(err, "failed check groups")
+	}
+	members := make([]model.UserGroup, 0, len(groupIds))
+	seen := make(map[uint]struct{}, len(groupIds))
+	for _, id := range groupIds {
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		members = append(members, model.UserGroup{UserID: userId, GroupID: id})
+	}
+	if int(count) != len(members) {
+		return errors.New("some groups don't exist")
+	}
+	return errors.WithStack(tx.Create(&members).Error)
+}
+
+// loadUserGroups fills the groups of the users
+func loadUserGroups(users ...*model.User) error {
+	if len(users) == 0 {
+		return nil
+	}
+	userIds := make([]uint, len(users))
+	for i, u := range users {
+		userIds[i] = u.ID
+	}
+	var members []model.UserGroup
+	if err := db.Where(fmt.Sprintf("%s IN ?", columnName("user_id")), userIds).Find(&members).Error; err != nil {
+		return errors.Wrapf(err, "failed get user groups")
+	}
+	groupIds := make([]uint, 0, len(members))
+	joined := make(map[uint]map[uint]struct{}, len(users))
+	for _, m := range members {
+		groupIds = append(groupIds, m.GroupID)
+		if joined[m.UserID] == nil {
+			joined[m.UserID] = make(map[uint]struct{})
+		}
+		joined[m.UserID][m.GroupID] = struct{}{}
+	}
+	var groups []model.Group
+	if len(groupIds) > 0 {
+		if err := db.Order(columnName("id")).Find(&groups, groupIds).Error; err != nil {
+			return errors.Wrapf(err, "failed get groups")
+		}
+	}
+	// the groups are kept in the order of id
+	for _, u := range users {
+		u.GroupIDs = []uint{}
+		u.Groups = nil
+		for _, g := range groups {
+			if _, ok := joined[u.ID][g.ID]; ok {
+				u.GroupIDs = append(u.GroupIDs, g.ID)
+				u.Groups = append(u.Groups, g)
+			}
+		}
+	}
+	return nil
+}
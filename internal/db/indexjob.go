@@ -0,0 +1,35 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetIndexJobs() ([]model.IndexJob, error) {
+	var jobs []model.IndexJob
+	if err := db.Find(&jobs).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get index jobs")
+	}
+	return jobs, nil
+}
+
+func GetIndexJobByStorageId(storageId uint) (*model.IndexJob, error) {
+	var job model.IndexJob
+	if err := db.First(&job, storageId).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get index job")
+	}
+	return &job, nil
+}
+
+func SaveIndexJob(job *model.IndexJob) error {
+	return errors.WithStack(db.Save(job).Error)
+}
+
+// ResetRunningIndexJobs marks the jobs interrupted by the last shutdown as failed
+func ResetRunningIndexJobs() error {
+	return errors.WithStack(db.Model(&model.IndexJob{}).
+		Where(fmt.Sprintf("%s = ?", columnName("running")), true).
+		Updates(map[string]any{"running": false, "error": "interrupted"}).Error)
+}
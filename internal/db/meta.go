@@ -1,13 +1,17 @@
 package db
 
 import (
+	"fmt"
+
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 func GetMetaByPath(path string) (*model.Meta, error) {
 	meta := model.Meta{Path: path}
-	if err := db.Where(meta).First(&meta).Error; err != nil {
+	if err := db.Preload("ACL").Where(meta).First(&meta).Error; err != nil {
 		return nil, errors.Wrapf(err, "failed select meta")
 	}
 	return &meta, nil
@@ -15,18 +19,54 @@ func GetMetaByPath(path string) (*model.Meta, error) {
 
 func GetMetaById(id uint) (*model.Meta, error) {
 	var u model.Meta
-	if err := db.First(&u, id).Error; err != nil {
+	if err := db.Preload("ACL").First(&u, id).Error; err != nil {
 		return nil, errors.Wrapf(err, "failed get old meta")
 	}
 	return &u, nil
 }
 
 func CreateMeta(u *model.Meta) error {
-	return errors.WithStack(db.Create(u).Error)
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("ACL").Create(u).Error; err != nil {
+			return err
+		}
+		return createMetaACL(tx, u)
+	}))
 }
 
 func UpdateMeta(u *model.Meta) error {
-	return errors.WithStack(db.Save(u).Error)
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("ACL").Save(u).Error; err != nil {
+			return err
+		}
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("meta_id")), u.ID).Delete(&model.MetaACL{}).Error; err != nil {
+			return err
+		}
+		return createMetaACL(tx, u)
+	}))
+}
+
+func createMetaACL(tx *gorm.DB, u *model.Meta) error {
+	if len(u.ACL) == 0 {
+		return nil
+	}
+	for i := range u.ACL {
+		u.ACL[i].ID = 0
+		u.ACL[i].MetaID = u.ID
+	}
+	return tx.Create(&u.ACL).Error
+}
+
+// GetMetaPathsWithACLUnder returns the paths of the metas having ACL
+// entries strictly under the path
+func GetMetaPathsWithACLUnder(path string) ([]string, error) {
+	var paths []string
+	withACL := db.Model(&model.MetaACL{}).Select(columnName("meta_id"))
+	err := db.Model(&model.Meta{}).
+		Where(fmt.Sprintf("%s LIKE ?", columnName("path")), utils.PathAddSeparatorSuffix(path)+"%").
+		Where(fmt.Sprintf("%s IN (?)", columnName("id")), withACL).
+		Pluck("path", &paths).Error
+	return paths, errors.Wrapf(err, "failed get metas under [%s]", path)
 }
 
 func GetMetas(pageIndex, pageSize int) (metas []model.Meta, count int64, err error) {
@@ -34,12 +74,24 @@ func GetMetas(pageIndex, pageSize int) (metas []model.Meta, count int64, err err
 	if err = metaDB.Count(&count).Error; err != nil {
 		return nil, 0, errors.Wrapf(err, "failed get metas count")
 	}
-	if err = metaDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&metas).Error; err != nil {
+	if err = metaDB.Preload("ACL").Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&metas).Error; err != nil {
 		return nil, 0, errors.Wrapf(err, "failed get find metas")
 	}
 	return metas, count, nil
 }
 
 func DeleteMetaById(id uint) error {
-	return errors.WithStack(db.Delete(&model.Meta{}, id).Error)
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("meta_id")), id).Delete(&model.MetaACL{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&model.Meta{}, id).Error
+	}))
+}
+
+// deleteSubjectACL removes the entries of a deleted user or group,
+// so that they don't apply to another one reusing its id
+func deleteSubjectACL(tx *gorm.DB, subject string, id uint) error {
+	return tx.Where(fmt.Sprintf("%s = ? AND %s = ?", columnName("subject"), columnName("subject_id")), subject, id).
+		Delete(&model.MetaACL{}).Error
 }
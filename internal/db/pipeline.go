@@ -0,0 +1,57 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetPipelineById(id uint) (*model.Pipeline, error) {
+	var p model.Pipeline
+	if err := db.First(&p, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get pipeline")
+	}
+	return &p, nil
+}
+
+// GetPipelines returns the pipelines of a user, userId 0 means pipelines of all users
+func GetPipelines(userId uint, pageIndex, pageSize int) (pipelines []model.Pipeline, count int64, err error) {
+	pipelineDB := db.Model(&model.Pipeline{})
+	if userId != 0 {
+		pipelineDB = pipelineDB.Where(fmt.Sprintf("%s = ?", columnName("user_id")), userId)
+	}
+	if err = pipelineDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get pipelines count")
+	}
+	if err = pipelineDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&pipelines).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find pipelines")
+	}
+	return pipelines, count, nil
+}
+
+// GetScheduledPipelines returns the enabled pipelines with a cron expression
+func GetScheduledPipelines() (pipelines []model.Pipeline, err error) {
+	if err = db.Where(fmt.Sprintf("%s <> '' AND %s = ?", columnName("cron"), columnName("disabled")), false).
+		Find(&pipelines).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get scheduled pipelines")
+	}
+	return pipelines, nil
+}
+
+func CreatePipeline(p *model.Pipeline) error {
+	return errors.WithStack(db.Create(p).Error)
+}
+
+func UpdatePipeline(p *model.Pipeline) error {
+	return errors.WithStack(db.Save(p).Error)
+}
+
+func UpdatePipelineLastRunTime(id uint, t time.Time) error {
+	return errors.WithStack(db.Model(&model.Pipeline{ID: id}).Update("last_run_time", t).Error)
+}
+
+func DeletePipelineById(id uint) error {
+	return errors.WithStack(db.Delete(&model.Pipeline{}, id).Error)
+}
@@ -0,0 +1,101 @@
+package db
+
+import (
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+// GetS3ObjectMeta returns the metadata of the object at path, nil if there is none
+func GetS3ObjectMeta(path string) (map[string]string, error) {
+	var metas []model.S3ObjectMeta
+	if err := db.Where("path = ?", path).Limit(1).Find(&metas).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find s3 object meta")
+	}
+	if len(metas) == 0 {
+		return nil, nil
+	}
+	return metas[0].Meta, nil
+}
+
+// HasS3ObjectMetas returns whether the metadata of any object is saved
+func HasS3ObjectMetas() (bool, error) {
+	var metas []model.S3ObjectMeta
+	if err := db.Select("id").Limit(1).Find(&metas).Error; err != nil {
+		return false, errors.Wrapf(err, "failed find s3 object meta")
+	}
+	return len(metas) > 0, nil
+}
+
+// SaveS3ObjectMeta replaces the metadata of the object at path, an empty
+// meta deletes it
+func SaveS3ObjectMeta(path string, meta map[string]string) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("path = ?", path).Delete(&model.S3ObjectMeta{}).Error; err != nil {
+			return err
+		}
+		if len(meta) == 0 {
+			return nil
+		}
+		return tx.Create(&model.S3ObjectMeta{Path: path, Meta: meta}).Error
+	}))
+}
+
+// getS3ObjectMetasUnder returns the metadata of path and all its descendants
+func getS3ObjectMetasUnder(tx *gorm.DB, path string) ([]model.S3ObjectMeta, error) {
+	prefix := strings.TrimSuffix(path, "/") + "/"
+	var metas []model.S3ObjectMeta
+	if err := tx.Where("path = ? OR path LIKE ?", path, prefix+"%").Find(&metas).Error; err != nil {
+		return nil, err
+	}
+	// LIKE wildcards in prefix are not escaped, filter the result again
+	ret := metas[:0]
+	for _, m := range metas {
+		if m.Path == path || strings.HasPrefix(m.Path, prefix) {
+			ret = append(ret, m)
+		}
+	}
+	return ret, nil
+}
+
+// MoveS3ObjectMetas moves the metadata of srcPath and its descendants to dstPath
+func MoveS3ObjectMetas(srcPath, dstPath string) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		metas, err := getS3ObjectMetasUnder(tx, srcPath)
+		if err != nil || len(metas) == 0 {
+			return err
+		}
+		// drop the stale metadata left at the destination
+		if err = deleteS3ObjectMetasUnder(tx, dstPath); err != nil {
+			return err
+		}
+		for _, m := range metas {
+			newPath := dstPath + strings.TrimPrefix(m.Path, srcPath)
+			if err = tx.Model(&model.S3ObjectMeta{}).Where("id = ?", m.ID).Update("path", newPath).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+}
+
+// DeleteS3ObjectMetas deletes the metadata of path and its descendants
+func DeleteS3ObjectMetas(path string) error {
+	return errors.WithStack(db.Transaction(func(tx *gorm.DB) error {
+		return deleteS3ObjectMetasUnder(tx, path)
+	}))
+}
+
+func deleteS3ObjectMetasUnder(tx *gorm.DB, path string) error {
+	metas, err := getS3ObjectMetasUnder(tx, path)
+	if err != nil || len(metas) == 0 {
+		return err
+	}
+	ids := make([]uint, len(metas))
+	for i, m := range metas {
+		ids[i] = m.ID
+	}
+	return tx.Delete(&model.S3ObjectMeta{}, ids).Error
+}
@@ -0,0 +1,51 @@
+package db
+
+import "testing"
+
+func TestS3ObjectMetas(t *testing.T) {
+	initTestDB(t)
+	if exist, err := HasS3ObjectMetas(); err != nil || exist {
+		t.Fatalf("empty: got %v, %v", exist, err)
+	}
+	meta := map[string]string{"Content-Type": "text/css", "X-Amz-Meta-Mtime": "1700000000.5"}
+	for _, p := range []string{"/s3/a/style.css", "/s3/a/sub/x.txt", "/s3/ab.txt"} {
+		if err := SaveS3ObjectMeta(p, meta); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if exist, err := HasS3ObjectMetas(); err != nil || !exist {
+		t.Fatalf("saved: got %v, %v", exist, err)
+	}
+	// saving again replaces the meta
+	if err := SaveS3ObjectMeta("/s3/a/style.css", map[string]string{"Content-Type": "text/plain"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := GetS3ObjectMeta("/s3/a/style.css"); err != nil || len(got) != 1 || got["Content-Type"] != "text/plain" {
+		t.Fatalf("got %v, %v", got, err)
+	}
+	if err := MoveS3ObjectMetas("/s3/a", "/s3/b"); err != nil {
+		t.Fatal(err)
+	}
+	for p, want := range map[string]bool{
+		"/s3/a/style.css": false,
+		"/s3/b/style.css": true,
+		"/s3/b/sub/x.txt": true,
+		"/s3/ab.txt":      true,
+	} {
+		if got, err := GetS3ObjectMeta(p); err != nil || (got != nil) != want {
+			t.Errorf("%s: got %v, %v", p, got, err)
+		}
+	}
+	if err := DeleteS3ObjectMetas("/s3/b"); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := GetS3ObjectMeta("/s3/b/sub/x.txt"); got != nil {
+		t.Errorf("deleted meta: got %v", got)
+	}
+	if err := SaveS3ObjectMeta("/s3/ab.txt", nil); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := GetS3ObjectMeta("/s3/ab.txt"); got != nil {
+		t.Errorf("empty meta: got %v", got)
+	}
+}
@@ -21,6 +21,43 @@ func whereInParent(parent string) *gorm.DB {
 		Or(fmt.Sprintf("%s = ?", columnName("parent")), parent)
 }
 
+// whereInParents matches the nodes under any of the parents
+func whereInParents(parents []string) *gorm.DB {
+	tx := db.Where("1 = 0")
+	for _, parent := range parents {
+		if parent == "/" {
+			return db.Where("1 = 1")
+		}
+		tx = tx.Or(whereInParent(parent))
+	}
+	return tx
+}
+
+func whereSearchFilter(tx *gorm.DB, f model.SearchFilter) *gorm.DB {
+	if f.MinSize > 0 {
+		tx = tx.Where(fmt.Sprintf("%s >= ?", columnName("size")), f.MinSize)
+	}
+	if f.MaxSize > 0 {
+		tx = tx.Where(fmt.Sprintf("%s <= ?", columnName("size")), f.MaxSize)
+	}
+	if !f.ModifiedAfter.IsZero() {
+		tx = tx.Where(fmt.Sprintf("%s >= ?", columnName("modified")), f.ModifiedAfter)
+	}
+	if !f.ModifiedBefore.IsZero() {
+		tx = tx.Where(fmt.Sprintf("%s <= ?", columnName("modified")), f.ModifiedBefore)
+	}
+	if len(f.Exts) > 0 {
+		tx = tx.Where(fmt.Sprintf("%s IN ?", columnName("ext")), f.Exts)
+	}
+	if len(f.Types) > 0 {
+		tx = tx.Where(fmt.Sprintf("%s IN ?", columnName("type")), f.Types)
+	}
+	if f.Hash != "" {
+		tx = tx.Where(fmt.Sprintf("%s = ?", columnName("hash")), f.Hash)
+	}
+	return tx
+}
+
 func CreateSearchNode(node *model.SearchNode) error {
 	return db.Create(node).Error
 }
@@ -35,7 +72,7 @@ func DeleteSearchNodesByParent(path string) error {
 	if err != nil {
 		return err
 	}
-	dir, name := stdpath.Split(path)
+	dir, name := stdpath.Dir(path), stdpath.Base(path)
 	return db.Where(fmt.Sprintf("%s = ? AND %s = ?",
 		columnName("parent"), columnName("name")),
 		dir, name).Delete(&model.SearchNode{}).Error
@@ -47,7 +84,7 @@ func ClearSearchNodes() error {
 
 func GetSearchNodesByParent(parent string) ([]model.SearchNode, error) {
 	var nodes []model.SearchNode
-	if err := db.Where(fmt.Sprintf("%s = ?",
+	if err := db.Omit("content").Where(fmt.Sprintf("%s = ?",
 		columnName("parent")), parent).Find(&nodes).Error; err != nil {
 		return nil, err
 	}
@@ -56,19 +93,20 @@ func GetSearchNodesByParent(parent string) ([]model.SearchNode, error) {
 
 func SearchNode(req model.SearchReq, useFullText bool) ([]model.SearchNode, int64, error) {
 	var searchDB *gorm.DB
-	if !useFullText || conf.Conf.Database.Type == "sqlite3" {
+	// filtering without keywords needs no full text search
+	if !useFullText || conf.Conf.Database.Type == "sqlite3" || strings.TrimSpace(req.Keywords) == "" {
 		keywordsClause := db.Where("1 = 1")
 		for _, keyword := range strings.Fields(req.Keywords) {
 			keywordsClause = keywordsClause.Where("name LIKE ?", fmt.Sprintf("%%%s%%", keyword))
 		}
-		searchDB = db.Model(&model.SearchNode{}).Where(whereInParent(req.Parent)).Where(keywordsClause)
+		searchDB = db.Model(&model.SearchNode{}).Where(whereInParents(req.ParentDirs())).Where(keywordsClause)
 	} else {
 		switch conf.Conf.Database.Type {
 		case "mysql":
-			searchDB = db.Model(&model.SearchNode{}).Where(whereInParent(req.Parent)).
+			searchDB = db.Model(&model.SearchNode{}).Where(whereInParents(req.ParentDirs())).
 				Where("MATCH (name) AGAINST (? IN BOOLEAN MODE)", "'*"+req.Keywords+"*'")
 		case "postgres":
-			searchDB = db.Model(&model.SearchNode{}).Where(whereInParent(req.Parent)).
+			searchDB = db.Model(&model.SearchNode{}).Where(whereInParents(req.ParentDirs())).
 				Where("to_tsvector(name) @@ to_tsquery(?)", strings.Join(strings.Fields(req.Keywords), " & "))
 		}
 	}
@@ -77,13 +115,14 @@ func SearchNode(req model.SearchReq, useFullText bool) ([]model.SearchNode, int6
 		isDir := req.Scope == 1
 		searchDB.Where(db.Where("is_dir = ?", isDir))
 	}
+	searchDB = whereSearchFilter(searchDB, req.SearchFilter)
 
 	var count int64
 	if err := searchDB.Count(&count).Error; err != nil {
 		return nil, 0, errors.Wrapf(err, "failed get search items count")
 	}
 	var files []model.SearchNode
-	if err := searchDB.Order("name asc").Offset((req.Page - 1) * req.PerPage).Limit(req.PerPage).
+	if err := searchDB.Omit("content").Order("name asc").Offset((req.Page - 1) * req.PerPage).Limit(req.PerPage).
 		Find(&files).Error; err != nil {
 		return nil, 0, err
 	}
@@ -0,0 +1,157 @@
+package db
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// searchNodesTable returns the table name of the nodes with the prefix
+func searchNodesTable() string {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(&model.SearchNode{}); err != nil {
+		return conf.Conf.Database.TablePrefix + "search_nodes"
+	}
+	return stmt.Schema.Table
+}
+
+func searchNodesFTSTable() string {
+	return searchNodesTable() + "_fts"
+}
+
+// InitSearchNodeFTS creates the native full-text index of the search nodes:
+// a FTS5 table synced by triggers for sqlite3, which needs the sqlite3 built
+// with the sqlite_fts5 tag, a generated tsvector column with GIN index for
+// postgres and a FULLTEXT index for mysql.
+func InitSearchNodeFTS() error {
+	table := searchNodesTable()
+	switch conf.Conf.Database.Type {
+	case "sqlite3":
+		fts := searchNodesFTSTable()
+		var exists int64
+		if err := db.Raw("SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", fts).
+			Scan(&exists).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		// the external content table reads the name and content from the nodes by rowid
+		statements := []string{
+			fmt.Sprintf("CREATE VIRTUAL TABLE IF NOT EXISTS `%s` USING fts5(name, content, content='%s', tokenize='unicode61 remove_diacritics 2')", fts, table),
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS `%s_ai` AFTER INSERT ON `%s` BEGIN "+
+				"INSERT INTO `%s`(rowid, name, content) VALUES (new.rowid, new.name, new.content); END", fts, table, fts),
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS `%s_ad` AFTER DELETE ON `%s` BEGIN "+
+				"INSERT INTO `%s`(`%s`, rowid, name, content) VALUES ('delete', old.rowid, old.name, old.content); END", fts, table, fts, fts),
+			fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS `%s_au` AFTER UPDATE ON `%s` BEGIN "+
+				"INSERT INTO `%s`(`%s`, rowid, name, content) VALUES ('delete', old.rowid, old.name, old.content); "+
+				"INSERT INTO `%s`(rowid, name, content) VALUES (new.rowid, new.name, new.content); END", fts, table, fts, fts, fts),
+		}
+		if exists == 0 {
+			// index the nodes created before
+			statements = append(statements, fmt.Sprintf("INSERT INTO `%s`(`%s`) VALUES ('rebuild')", fts, fts))
+		}
+		for _, s := range statements {
+			if err := db.Exec(s).Error; err != nil {
+				if strings.Contains(err.Error(), "no such module: fts5") {
+					return errors.New("sqlite3 is not built with fts5, build with -tags sqlite_fts5")
+				}
+				return errors.Wrapf(err, "failed create full-text index")
+			}
+		}
+	case "postgres":
+		statements := []string{
+			fmt.Sprintf(`ALTER TABLE "%s" ADD COLUMN IF NOT EXISTS fts tsvector GENERATED ALWAYS AS (`+
+				`setweight(to_tsvector('simple', regexp_replace(coalesce(name, ''), '[^[:alnum:]]+', ' ', 'g')), 'A') || `+
+				`setweight(to_tsvector('simple', coalesce(content, '')), 'B')) STORED`, table),
+			fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_%s_fts" ON "%s" USING GIN (fts)`, table, table),
+		}
+		for _, s := range statements {
+			if err := db.Exec(s).Error; err != nil {
+				return errors.Wrapf(err, "failed create full-text index")
+			}
+		}
+	case "mysql":
+		err := db.Exec(fmt.Sprintf("CREATE FULLTEXT INDEX `idx_%s_fts` ON `%s`(name, content)", table, table)).Error
+		// 1061 for the duplicate index
+		if err != nil && !strings.Contains(err.Error(), "Error 1061") {
+			return errors.Wrapf(err, "failed create full-text index")
+		}
+	default:
+		return errors.Errorf("full-text search is not supported for %s", conf.Conf.Database.Type)
+	}
+	return nil
+}
+
+// FullTextTerms splits the keywords into the terms of letters and numbers,
+// the same as the tokenizers of the full-text indexes
+func FullTextTerms(keywords string) []string {
+	return strings.FieldsFunc(strings.ToLower(keywords), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// SearchNodeFTS searches the nodes by the full-text index created by
+// InitSearchNodeFTS, every term matches as a prefix and the results
+// are ordered by the relevance, the name weighs more than the content.
+func SearchNodeFTS(req model.SearchReq) ([]model.SearchNode, int64, error) {
+	terms := FullTextTerms(req.Keywords)
+	if len(terms) == 0 {
+		return SearchNode(req, false)
+	}
+	var (
+		searchDB *gorm.DB
+		order    clause.Expr
+		columns  = "*"
+	)
+	switch conf.Conf.Database.Type {
+	case "sqlite3":
+		fts := searchNodesFTSTable()
+		query := make([]string, len(terms))
+		for i, term := range terms {
+			query[i] = fmt.Sprintf(`"%s"*`, term)
+		}
+		columns = fmt.Sprintf("`%s`.*", searchNodesTable())
+		searchDB = db.Table(searchNodesTable()).
+			Joins(fmt.Sprintf("JOIN `%s` ON `%s`.rowid = `%s`.rowid", fts, fts, searchNodesTable())).
+			Where(fmt.Sprintf("`%s` MATCH ?", fts), strings.Join(query, " "))
+		order = clause.Expr{SQL: fmt.Sprintf("bm25(`%s`, 10.0, 1.0)", fts)}
+	case "postgres":
+		query := make([]string, len(terms))
+		for i, term := range terms {
+			query[i] = term + ":*"
+		}
+		q := strings.Join(query, " & ")
+		searchDB = db.Model(&model.SearchNode{}).Where("fts @@ to_tsquery('simple', ?)", q)
+		order = clause.Expr{SQL: "ts_rank(fts, to_tsquery('simple', ?)) DESC", Vars: []any{q}}
+	case "mysql":
+		query := make([]string, len(terms))
+		for i, term := range terms {
+			query[i] = "+" + term + "*"
+		}
+		q := strings.Join(query, " ")
+		searchDB = db.Model(&model.SearchNode{}).Where("MATCH (name, content) AGAINST (? IN BOOLEAN MODE)", q)
+		order = clause.Expr{SQL: "MATCH (name, content) AGAINST (? IN BOOLEAN MODE) DESC", Vars: []any{q}}
+	default:
+		return nil, 0, errors.Errorf("full-text search is not supported for %s", conf.Conf.Database.Type)
+	}
+	searchDB = searchDB.Where(whereInParents(req.ParentDirs()))
+	if req.Scope != 0 {
+		searchDB = searchDB.Where(fmt.Sprintf("%s = ?", columnName("is_dir")), req.Scope == 1)
+	}
+	searchDB = whereSearchFilter(searchDB, req.SearchFilter)
+
+	var count int64
+	if err := searchDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get search items count")
+	}
+	var nodes []model.SearchNode
+	if err := searchDB.Select(columns).Order(order).Offset((req.Page - 1) * req.PerPage).Limit(req.PerPage).
+		Find(&nodes).Error; err != nil {
+		return nil, 0, errors.WithStack(err)
+	}
+	return nodes, count, nil
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func TestSearchNodeFTS(t *testing.T) {
+	initTestDB(t)
+	if err := InitSearchNodeFTS(); err != nil {
+		if strings.Contains(err.Error(), "fts5") {
+			t.Skipf("skip: %v", err)
+		}
+		t.Fatalf("failed to init full-text index: %+v", err)
+	}
+	nodes := []model.SearchNode{
+		{Parent: "/docs", Name: "Annual_Report-2024.pdf"},
+		{Parent: "/docs", Name: "notes.txt", Content: "the annual meeting is in march"},
+		{Parent: "/docs/old", Name: "reporting.md", Content: "nothing"},
+		{Parent: "/other", Name: "annual.txt"},
+	}
+	if err := BatchCreateSearchNodes(&nodes); err != nil {
+		t.Fatalf("failed to create nodes: %v", err)
+	}
+	search := func(parent, keywords string) []string {
+		t.Helper()
+		res, total, err := SearchNodeFTS(model.SearchReq{Parent: parent, Keywords: keywords,
+			PageReq: model.PageReq{Page: 1, PerPage: 10}})
+		if err != nil {
+			t.Fatalf("search %q: %+v", keywords, err)
+		}
+		if int(total) != len(res) {
+			t.Errorf("search %q: total %d, got %d", keywords, total, len(res))
+		}
+		names := make([]string, len(res))
+		for i, node := range res {
+			names[i] = node.Name
+		}
+		return names
+	}
+	testCases := []struct {
+		parent, keywords string
+		want             []string
+	}{
+		// the name ranks higher than the content
+		{"/docs", "annu", []string{"Annual_Report-2024.pdf", "notes.txt"}},
+		{"/", "report", []string{"Annual_Report-2024.pdf", "reporting.md"}},
+		{"/docs", "report 2024", []string{"Annual_Report-2024.pdf"}},
+		{"/docs", "MARCH", []string{"notes.txt"}},
+		{"/docs", "\"march\" OR", []string{}},
+	}
+	for _, tc := range testCases {
+		got := search(tc.parent, tc.keywords)
+		if strings.Join(got, ",") != strings.Join(tc.want, ",") {
+			t.Errorf("search %q in %s: got %v, want %v", tc.keywords, tc.parent, got, tc.want)
+		}
+	}
+	if err := DeleteSearchNodesByParent("/docs/notes.txt"); err != nil {
+		t.Fatalf("failed to delete node: %v", err)
+	}
+	if got := search("/", "march"); len(got) != 0 {
+		t.Errorf("deleted node is searched: %v", got)
+	}
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func initTestDB(t *testing.T) {
+	t.Helper()
+	dB, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	Init(dB)
+}
+
+func TestSearchNodeFilter(t *testing.T) {
+	initTestDB(t)
+	now := time.Now()
+	nodes := []model.SearchNode{
+		{Parent: "/videos", Name: "trip.MP4", Size: 3 << 30, Modified: now.Add(-3 * 24 * time.Hour), Type: conf.VIDEO, Ext: "mp4", Hash: "aa"},
+		{Parent: "/videos", Name: "old.mkv", Size: 2 << 30, Modified: now.Add(-60 * 24 * time.Hour), Type: conf.VIDEO, Ext: "mkv", Hash: "bb"},
+		{Parent: "/videos", Name: "clip.mp4", Size: 1 << 20, Modified: now.Add(-time.Hour), Type: conf.VIDEO, Ext: "mp4", Hash: "cc"},
+		{Parent: "/docs", Name: "trip.txt", Size: 10, Modified: now, Type: conf.TEXT, Ext: "txt", Hash: "dd"},
+	}
+	if err := BatchCreateSearchNodes(&nodes); err != nil {
+		t.Fatalf("failed to create nodes: %v", err)
+	}
+	testCases := []struct {
+		name   string
+		filter model.SearchFilter
+		want   []string
+	}{
+		{"big video last week", model.SearchFilter{MinSize: 1 << 30, ModifiedAfter: now.Add(-7 * 24 * time.Hour), Types: []int{conf.VIDEO}}, []string{"trip.MP4"}},
+		{"size range", model.SearchFilter{MinSize: 1 << 20, MaxSize: 2 << 30}, []string{"clip.mp4", "old.mkv"}},
+		{"modified before", model.SearchFilter{ModifiedBefore: now.Add(-30 * 24 * time.Hour)}, []string{"old.mkv"}},
+		{"ext", model.SearchFilter{Exts: []string{"MP4", ".txt"}}, []string{"clip.mp4", "trip.MP4", "trip.txt"}},
+		{"hash", model.SearchFilter{Hash: "DD"}, []string{"trip.txt"}},
+	}
+	for _, tc := range testCases {
+		req := model.SearchReq{Parent: "/", SearchFilter: tc.filter, PageReq: model.PageReq{Page: 1, PerPage: 10}}
+		if err := req.Validate(); err != nil {
+			t.Fatalf("%s: invalid req: %v", tc.name, err)
+		}
+		res, total, err := SearchNode(req, false)
+		if err != nil {
+			t.Fatalf("%s: failed to search: %v", tc.name, err)
+		}
+		var got []string
+		for _, node := range res {
+			got = append(got, node.Name)
+		}
+		if int(total) != len(tc.want) || len(got) != len(tc.want) {
+			t.Errorf("%s: got %v (total %d), want %v", tc.name, got, total, tc.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tc.want[i] {
+				t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+				break
+			}
+		}
+	}
+
+	// keywords and filters are combined
+	req := model.SearchReq{Parent: "/", Keywords: "trip", SearchFilter: model.SearchFilter{Exts: []string{"txt"}}, PageReq: model.PageReq{Page: 1, PerPage: 10}}
+	if res, _, err := SearchNode(req, true); err != nil || len(res) != 1 || res[0].Parent != "/docs" {
+		t.Errorf("keywords with filter: got %+v, %v", res, err)
+	}
+
+	// the roots of the virtual root limit the search
+	req = model.SearchReq{Parent: "/", Parents: []string{"/docs", "/music"}, Keywords: "trip", PageReq: model.PageReq{Page: 1, PerPage: 10}}
+	if res, total, err := SearchNode(req, false); err != nil || total != 1 || len(res) != 1 || res[0].Name != "trip.txt" {
+		t.Errorf("parents: got %+v (total %d), %v", res, total, err)
+	}
+}
@@ -1,9 +1,12 @@
 package db
 
 import (
+	"fmt"
+
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 func GetSharingById(id string) (*model.SharingDB, error) {
@@ -52,15 +55,43 @@ func CreateSharing(s *model.SharingDB) (string, error) {
 	return "", errors.New("failed find valid id")
 }
 
+// UpdateSharing saves the sharing except the uploaded size, which is only
+// changed by AddSharingUploaded, so a stale copy doesn't undo the uploads
 func UpdateSharing(s *model.SharingDB) error {
-	return errors.WithStack(db.Save(s).Error)
+	return errors.WithStack(db.Omit("uploaded").Save(s).Error)
+}
+
+// AddSharingUploaded adds size to the uploaded size of the sharing if the
+// result doesn't exceed the quota, quota <= 0 means unlimited. It returns
+// false if the size doesn't fit.
+func AddSharingUploaded(id string, size, quota int64) (bool, error) {
+	tx := db.Model(&model.SharingDB{}).Where("id = ?", id)
+	if quota > 0 && size > 0 {
+		tx = tx.Where(columnName("uploaded")+" + ? <= ?", size, quota)
+	}
+	res := tx.UpdateColumn("uploaded", gorm.Expr(columnName("uploaded")+" + ?", size))
+	if res.Error != nil {
+		return false, errors.WithStack(res.Error)
+	}
+	return res.RowsAffected > 0, nil
 }
 
 func DeleteSharingById(id string) error {
-	s := model.SharingDB{ID: id}
-	return errors.WithStack(db.Where(s).Delete(&s).Error)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("sharing_id")), id).Delete(&model.SharingAccessLog{}).Error; err != nil {
+			return errors.Wrapf(err, "failed delete sharing access logs")
+		}
+		s := model.SharingDB{ID: id}
+		return errors.WithStack(tx.Where(s).Delete(&s).Error)
+	})
 }
 
 func DeleteSharingsByCreatorId(creatorId uint) error {
-	return errors.WithStack(db.Where("creator_id = ?", creatorId).Delete(&model.SharingDB{}).Error)
+	return db.Transaction(func(tx *gorm.DB) error {
+		ids := tx.Model(&model.SharingDB{}).Select("id").Where("creator_id = ?", creatorId)
+		if err := tx.Where(fmt.Sprintf("%s IN (?)", columnName("sharing_id")), ids).Delete(&model.SharingAccessLog{}).Error; err != nil {
+			return errors.Wrapf(err, "failed delete sharing access logs")
+		}
+		return errors.WithStack(tx.Where("creator_id = ?", creatorId).Delete(&model.SharingDB{}).Error)
+	})
 }
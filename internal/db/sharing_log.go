@@ -0,0 +1,79 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func CreateSharingAccessLogs(logs []model.SharingAccessLog) error {
+	return errors.WithStack(db.CreateInBatches(logs, 1000).Error)
+}
+
+// sharingAccessLogs returns the query of the logs of the sharing between
+// since and until, the zero times are unbounded
+func sharingAccessLogs(sid string, since, until time.Time) *gorm.DB {
+	tx := db.Model(&model.SharingAccessLog{}).Where(fmt.Sprintf("%s = ?", columnName("sharing_id")), sid)
+	if !since.IsZero() {
+		tx = tx.Where(fmt.Sprintf("%s >= ?", columnName("time")), since)
+	}
+	if !until.IsZero() {
+		tx = tx.Where(fmt.Sprintf("%s < ?", columnName("time")), until)
+	}
+	return tx
+}
+
+// GetSharingAccessLogs returns the logs of the sharing from the newest
+func GetSharingAccessLogs(sid string, since, until time.Time, pageIndex, pageSize int) (logs []model.SharingAccessLog, count int64, err error) {
+	if err = sharingAccessLogs(sid, since, until).Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get sharing access logs count")
+	}
+	if err = sharingAccessLogs(sid, since, until).Order(columnName("id") + " DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&logs).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find sharing access logs")
+	}
+	return logs, count, nil
+}
+
+// WalkSharingAccessLogs calls fn with the logs of the sharing from the oldest
+// in batches, so all of them can be exported without loading them at once
+func WalkSharingAccessLogs(sid string, since, until time.Time, fn func(logs []model.SharingAccessLog) error) error {
+	var logs []model.SharingAccessLog
+	res := sharingAccessLogs(sid, since, until).FindInBatches(&logs, 500, func(tx *gorm.DB, batch int) error {
+		return fn(logs)
+	})
+	return errors.WithStack(res.Error)
+}
+
+func GetSharingAccessStats(sid string, since, until time.Time) (*model.SharingAccessStats, error) {
+	stats := &model.SharingAccessStats{Actions: make(map[string]int64)}
+	var total struct {
+		Total int64
+		Bytes int64
+	}
+	if err := sharingAccessLogs(sid, since, until).Select(fmt.Sprintf("COUNT(*) AS total, COALESCE(SUM(%s), 0) AS bytes", columnName("bytes"))).Scan(&total).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed sum sharing access logs")
+	}
+	stats.Total, stats.Bytes = total.Total, total.Bytes
+	if err := sharingAccessLogs(sid, since, until).Distinct("ip").Count(&stats.UniqueIPs).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed count sharing access ips")
+	}
+	var actions []struct {
+		Action string
+		Count  int64
+	}
+	if err := sharingAccessLogs(sid, since, until).Select(fmt.Sprintf("%s AS action, COUNT(*) AS count", columnName("action"))).Group(columnName("action")).Scan(&actions).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed count sharing access actions")
+	}
+	for _, a := range actions {
+		stats.Actions[a.Action] = a.Count
+	}
+	return stats, nil
+}
+
+func DeleteSharingAccessLogsBefore(t time.Time) (int64, error) {
+	res := db.Where(fmt.Sprintf("%s < ?", columnName("time")), t).Delete(&model.SharingAccessLog{})
+	return res.RowsAffected, errors.WithStack(res.Error)
+}
@@ -0,0 +1,102 @@
+package db
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func TestAddSharingUploaded(t *testing.T) {
+	initTestDB(t)
+	id, err := CreateSharing(&model.SharingDB{FilesRaw: `["/drop"]`, Upload: true, UploadQuota: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tc := range []struct {
+		size int64
+		ok   bool
+	}{{60, true}, {50, false}, {40, true}, {1, false}, {-30, true}, {30, true}} {
+		if ok, err := AddSharingUploaded(id, tc.size, 100); err != nil || ok != tc.ok {
+			t.Errorf("add %d: got %v, %v", tc.size, ok, err)
+		}
+	}
+	// a stale copy saved by the access counting keeps the uploaded size
+	s, err := GetSharingById(id)
+	if err != nil || s.Uploaded != 100 {
+		t.Fatalf("got %+v, %v", s, err)
+	}
+	s.Uploaded = 0
+	s.Accessed = 1
+	if err = UpdateSharing(s); err != nil {
+		t.Fatal(err)
+	}
+	if s, err = GetSharingById(id); err != nil || s.Uploaded != 100 || s.Accessed != 1 {
+		t.Errorf("got %+v, %v", s, err)
+	}
+}
+
+func TestSharingAccessLogs(t *testing.T) {
+	initTestDB(t)
+	id, err := CreateSharing(&model.SharingDB{FilesRaw: `["/docs"]`})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	logs := []model.SharingAccessLog{
+		{SharingID: id, Time: now.AddDate(0, 0, -10), IP: "1.1.1.1", Action: model.SharingActionList, Path: "/"},
+		{SharingID: id, Time: now.Add(-time.Hour), IP: "1.1.1.1", Action: model.SharingActionDownload, Path: "/a.pdf", Bytes: 100},
+		{SharingID: id, Time: now, IP: "2.2.2.2", Action: model.SharingActionDownload, Path: "/b.pdf", Bytes: 50},
+		{SharingID: "other", Time: now, IP: "3.3.3.3", Action: model.SharingActionList, Path: "/"},
+	}
+	if err = CreateSharingAccessLogs(logs); err != nil {
+		t.Fatal(err)
+	}
+	got, count, err := GetSharingAccessLogs(id, time.Time{}, time.Time{}, 1, 2)
+	if err != nil || count != 3 || len(got) != 2 || got[0].Path != "/b.pdf" {
+		t.Fatalf("got %+v, %d, %v", got, count, err)
+	}
+	if _, count, err = GetSharingAccessLogs(id, now.AddDate(0, 0, -1), now, 1, 10); err != nil || count != 1 {
+		t.Errorf("between a day ago and now: got %d, %v", count, err)
+	}
+	stats, err := GetSharingAccessStats(id, time.Time{}, time.Time{})
+	if err != nil || stats.Total != 3 || stats.UniqueIPs != 2 || stats.Bytes != 150 ||
+		stats.Actions[model.SharingActionDownload] != 2 || stats.Actions[model.SharingActionList] != 1 {
+		t.Errorf("got stats %+v, %v", stats, err)
+	}
+	var walked []string
+	err = WalkSharingAccessLogs(id, time.Time{}, time.Time{}, func(logs []model.SharingAccessLog) error {
+		for _, l := range logs {
+			walked = append(walked, l.Path)
+		}
+		return nil
+	})
+	if err != nil || len(walked) != 3 || walked[0] != "/" {
+		t.Errorf("walked %v, %v", walked, err)
+	}
+	if n, err := DeleteSharingAccessLogsBefore(now.AddDate(0, 0, -1)); err != nil || n != 1 {
+		t.Errorf("deleted %d expired, %v", n, err)
+	}
+	if err = DeleteSharingById(id); err != nil {
+		t.Fatal(err)
+	}
+	if _, count, err = GetSharingAccessLogs(id, time.Time{}, time.Time{}, 1, 10); err != nil || count != 0 {
+		t.Errorf("logs of the deleted sharing: got %d, %v", count, err)
+	}
+	owned, err := CreateSharing(&model.SharingDB{FilesRaw: `["/docs"]`, CreatorId: 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = CreateSharingAccessLogs([]model.SharingAccessLog{{SharingID: owned, Time: now}}); err != nil {
+		t.Fatal(err)
+	}
+	if err = DeleteSharingsByCreatorId(7); err != nil {
+		t.Fatal(err)
+	}
+	if _, count, err = GetSharingAccessLogs(owned, time.Time{}, time.Time{}, 1, 10); err != nil || count != 0 {
+		t.Errorf("logs of the sharing of the deleted creator: got %d, %v", count, err)
+	}
+	if _, count, err = GetSharingAccessLogs("other", time.Time{}, time.Time{}, 1, 10); err != nil || count != 1 {
+		t.Errorf("logs of the other sharing: got %d, %v", count, err)
+	}
+}
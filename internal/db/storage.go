@@ -5,6 +5,7 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 // why don't need `cache` for storage?
@@ -24,7 +25,16 @@ func UpdateStorage(storage *model.Storage) error {
 
 // DeleteStorageById just delete storage from database by id
 func DeleteStorageById(id uint) error {
-	return errors.WithStack(db.Delete(&model.Storage{}, id).Error)
+	return db.Transaction(func(tx *gorm.DB) error {
+		// the trashed objects are left in the storage
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("storage_id")), id).Delete(&model.TrashItem{}).Error; err != nil {
+			return errors.Wrapf(err, "failed delete trash items")
+		}
+		if err := tx.Delete(&model.IndexJob{}, id).Error; err != nil {
+			return errors.Wrapf(err, "failed delete index job")
+		}
+		return errors.WithStack(tx.Delete(&model.Storage{}, id).Error)
+	})
 }
 
 // GetStorages Get all storages from database order by index
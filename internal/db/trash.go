@@ -0,0 +1,47 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetTrashItemById(id uint) (*model.TrashItem, error) {
+	var t model.TrashItem
+	if err := db.First(&t, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get trash item")
+	}
+	return &t, nil
+}
+
+// GetTrashItems returns the trashed items of the deleter, or all if deleterId is 0
+func GetTrashItems(deleterId uint, pageIndex, pageSize int) (items []model.TrashItem, count int64, err error) {
+	trashDB := db.Model(&model.TrashItem{})
+	if deleterId != 0 {
+		trashDB = trashDB.Where(fmt.Sprintf("%s = ?", columnName("deleter_id")), deleterId)
+	}
+	if err = trashDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get trash items count")
+	}
+	if err = trashDB.Order(columnName("id") + " DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&items).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find trash items")
+	}
+	return items, count, nil
+}
+
+func GetTrashItemsBefore(t time.Time) (items []model.TrashItem, err error) {
+	if err = db.Where(fmt.Sprintf("%s < ?", columnName("deleted_at")), t).Find(&items).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get expired trash items")
+	}
+	return items, nil
+}
+
+func CreateTrashItem(t *model.TrashItem) error {
+	return errors.WithStack(db.Create(t).Error)
+}
+
+func DeleteTrashItemById(id uint) error {
+	return errors.WithStack(db.Delete(&model.TrashItem{}, id).Error)
+}
@@ -0,0 +1,64 @@
+package db
+
+import (
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func GetPathUsages(paths []string) (usages []model.PathUsage, err error) {
+	if err = db.Where(fmt.Sprintf("%s IN ?", columnName("path")), paths).Find(&usages).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get path usages")
+	}
+	return usages, nil
+}
+
+func GetAllPathUsages() (usages []model.PathUsage, err error) {
+	if err = db.Find(&usages).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get path usages")
+	}
+	return usages, nil
+}
+
+func GetDirtyPathUsages() (usages []model.PathUsage, err error) {
+	if err = db.Where(fmt.Sprintf("%s = ?", columnName("dirty")), true).Find(&usages).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get dirty path usages")
+	}
+	return usages, nil
+}
+
+// CreatePathUsage tracks a new path, it's dirty until reconciled
+func CreatePathUsage(path string) error {
+	u := model.PathUsage{Path: path, Dirty: true}
+	return errors.WithStack(db.Where(fmt.Sprintf("%s = ?", columnName("path")), path).FirstOrCreate(&u).Error)
+}
+
+// AddPathUsage adds delta to the tracked ones of the paths
+func AddPathUsage(paths []string, delta int64) error {
+	err := db.Model(&model.PathUsage{}).Where(fmt.Sprintf("%s IN ?", columnName("path")), paths).
+		Update("used", gorm.Expr(fmt.Sprintf("%s + ?", columnName("used")), delta)).Error
+	return errors.WithStack(err)
+}
+
+// MarkPathUsageDirty marks the tracked ones of the paths and the tracked
+// paths under prefix dirty, prefix is ignored if empty
+func MarkPathUsageDirty(paths []string, prefix string) error {
+	tx := db.Model(&model.PathUsage{}).Where(fmt.Sprintf("%s IN ?", columnName("path")), paths)
+	if prefix != "" {
+		tx = tx.Or(fmt.Sprintf("%s LIKE ?", columnName("path")), prefix+"%")
+	}
+	return errors.WithStack(tx.Update("dirty", true).Error)
+}
+
+// SetPathUsage saves the reconciled usage of the path
+func SetPathUsage(path string, used int64) error {
+	err := db.Model(&model.PathUsage{}).Where(fmt.Sprintf("%s = ?", columnName("path")), path).
+		Updates(map[string]any{"used": used, "dirty": false}).Error
+	return errors.WithStack(err)
+}
+
+func DeletePathUsages(paths []string) error {
+	return errors.WithStack(db.Where(fmt.Sprintf("%s IN ?", columnName("path")), paths).Delete(&model.PathUsage{}).Error)
+}
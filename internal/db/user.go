@@ -2,11 +2,13 @@ package db
 
 import (
 	"encoding/base64"
+	"fmt"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/go-webauthn/webauthn/webauthn"
 	"github.com/pkg/errors"
+	"gorm.io/gorm"
 )
 
 func GetUserByRole(role int) (*model.User, error) {
@@ -14,7 +16,7 @@ func GetUserByRole(role int) (*model.User, error) {
 	if err := db.Where(user).Take(&user).Error; err != nil {
 		return nil, err
 	}
-	return &user, nil
+	return &user, loadUserGroups(&user)
 }
 
 func GetUserByName(username string) (*model.User, error) {
@@ -22,7 +24,7 @@ func GetUserByName(username string) (*model.User, error) {
 	if err := db.Where(user).First(&user).Error; err != nil {
 		return nil, errors.Wrapf(err, "failed find user")
 	}
-	return &user, nil
+	return &user, loadUserGroups(&user)
 }
 
 func GetUserBySSOID(ssoID string) (*model.User, error) {
@@ -30,7 +32,7 @@ func GetUserBySSOID(ssoID string) (*model.User, error) {
 	if err := db.Where(user).First(&user).Error; err != nil {
 		return nil, errors.Wrapf(err, "The single sign on platform is not bound to any users")
 	}
-	return &user, nil
+	return &user, loadUserGroups(&user)
 }
 
 func GetUserById(id uint) (*model.User, error) {
@@ -38,11 +40,20 @@ func GetUserById(id uint) (*model.User, error) {
 	if err := db.First(&u, id).Error; err != nil {
 		return nil, errors.Wrapf(err, "failed get old user")
 	}
-	return &u, nil
+	return &u, loadUserGroups(&u)
 }
 
+// CreateUser creates the user with its groups
 func CreateUser(u *model.User) error {
-	return errors.WithStack(db.Create(u).Error)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(u).Error; err != nil {
+			return errors.WithStack(err)
+		}
+		if len(u.GroupIDs) == 0 {
+			return nil
+		}
+		return setUserGroups(tx, u.ID, u.GroupIDs)
+	})
 }
 
 func UpdateUser(u *model.User) error {
@@ -57,11 +68,26 @@ func GetUsers(pageIndex, pageSize int) (users []model.User, count int64, err err
 	if err := userDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&users).Error; err != nil {
 		return nil, 0, errors.Wrapf(err, "failed get find users")
 	}
-	return users, count, nil
+	ptrs := make([]*model.User, len(users))
+	for i := range users {
+		ptrs[i] = &users[i]
+	}
+	return users, count, loadUserGroups(ptrs...)
 }
 
 func DeleteUserById(id uint) error {
-	return errors.WithStack(db.Delete(&model.User{}, id).Error)
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("user_id")), id).Delete(&model.UserGroup{}).Error; err != nil {
+			return errors.Wrapf(err, "failed delete user groups")
+		}
+		if err := deleteSubjectACL(tx, model.ACLSubjectUser, id); err != nil {
+			return errors.Wrapf(err, "failed delete user acl")
+		}
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("user_id")), id).Delete(&model.Pipeline{}).Error; err != nil {
+			return errors.Wrapf(err, "failed delete user pipelines")
+		}
+		return errors.WithStack(tx.Delete(&model.User{}, id).Error)
+	})
 }
 
 func UpdateAuthn(userID uint, authn string) error {
@@ -0,0 +1,50 @@
+package db
+
+import (
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+)
+
+func GetWebdavLockByToken(token string, now time.Time) (*model.WebdavLock, error) {
+	var l model.WebdavLock
+	if err := db.Where("token = ? AND expires_at > ?", token, now).First(&l).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find webdav lock")
+	}
+	return &l, nil
+}
+
+// GetWebdavLocksByRoots returns the unexpired locks whose root is one of roots
+func GetWebdavLocksByRoots(roots []string, now time.Time) (locks []model.WebdavLock, err error) {
+	if err := db.Where("root IN ? AND expires_at > ?", roots, now).Find(&locks).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find webdav locks")
+	}
+	return locks, nil
+}
+
+// GetWebdavLocksByRootPrefix returns the unexpired locks whose root starts with prefix,
+// the caller should check the result since the prefix is not escaped
+func GetWebdavLocksByRootPrefix(prefix string, now time.Time) (locks []model.WebdavLock, err error) {
+	if err := db.Where("root LIKE ? AND expires_at > ?", prefix+"%", now).Find(&locks).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed find webdav locks")
+	}
+	return locks, nil
+}
+
+func CreateWebdavLock(l *model.WebdavLock) error {
+	return errors.WithStack(db.Create(l).Error)
+}
+
+func UpdateWebdavLock(l *model.WebdavLock) error {
+	return errors.WithStack(db.Save(l).Error)
+}
+
+func DeleteWebdavLockByToken(token string) error {
+	return errors.WithStack(db.Where("token = ?", token).Delete(&model.WebdavLock{}).Error)
+}
+
+func DeleteExpiredWebdavLocks(now time.Time) (int64, error) {
+	res := db.Where("expires_at <= ?", now).Delete(&model.WebdavLock{})
+	return res.RowsAffected, errors.WithStack(res.Error)
+}
@@ -0,0 +1,82 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/pkg/errors"
+	"gorm.io/gorm"
+)
+
+func GetWebhookById(id uint) (*model.Webhook, error) {
+	var w model.Webhook
+	if err := db.First(&w, id).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get webhook")
+	}
+	return &w, nil
+}
+
+func GetEnabledWebhooks() (webhooks []model.Webhook, err error) {
+	if err = db.Where(fmt.Sprintf("%s = ?", columnName("disabled")), false).Find(&webhooks).Error; err != nil {
+		return nil, errors.Wrapf(err, "failed get enabled webhooks")
+	}
+	return webhooks, nil
+}
+
+func GetWebhooks(pageIndex, pageSize int) (webhooks []model.Webhook, count int64, err error) {
+	webhookDB := db.Model(&model.Webhook{})
+	if err = webhookDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get webhooks count")
+	}
+	if err = webhookDB.Order(columnName("id")).Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&webhooks).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find webhooks")
+	}
+	return webhooks, count, nil
+}
+
+func CreateWebhook(w *model.Webhook) error {
+	return errors.WithStack(db.Create(w).Error)
+}
+
+func UpdateWebhook(w *model.Webhook) error {
+	return errors.WithStack(db.Save(w).Error)
+}
+
+func DeleteWebhookById(id uint) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(fmt.Sprintf("%s = ?", columnName("webhook_id")), id).Delete(&model.WebhookDelivery{}).Error; err != nil {
+			return errors.Wrapf(err, "failed delete webhook deliveries")
+		}
+		return errors.WithStack(tx.Delete(&model.Webhook{}, id).Error)
+	})
+}
+
+func CreateWebhookDelivery(d *model.WebhookDelivery) error {
+	return errors.WithStack(db.Create(d).Error)
+}
+
+func UpdateWebhookDelivery(d *model.WebhookDelivery) error {
+	return errors.WithStack(db.Save(d).Error)
+}
+
+// GetWebhookDeliveries returns the deliveries of a webhook from the newest,
+// webhookId 0 means deliveries of all webhooks
+func GetWebhookDeliveries(webhookId uint, pageIndex, pageSize int) (deliveries []model.WebhookDelivery, count int64, err error) {
+	deliveryDB := db.Model(&model.WebhookDelivery{})
+	if webhookId != 0 {
+		deliveryDB = deliveryDB.Where(fmt.Sprintf("%s = ?", columnName("webhook_id")), webhookId)
+	}
+	if err = deliveryDB.Count(&count).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed get webhook deliveries count")
+	}
+	if err = deliveryDB.Order(columnName("id") + " DESC").Offset((pageIndex - 1) * pageSize).Limit(pageSize).Find(&deliveries).Error; err != nil {
+		return nil, 0, errors.Wrapf(err, "failed find webhook deliveries")
+	}
+	return deliveries, count, nil
+}
+
+func DeleteWebhookDeliveriesBefore(t time.Time) (int64, error) {
+	res := db.Where(fmt.Sprintf("%s < ?", columnName("created_at")), t).Delete(&model.WebhookDelivery{})
+	return res.RowsAffected, errors.WithStack(res.Error)
+}
@@ -26,6 +26,10 @@ var (
 	WrongShareCode  = errors.New("wrong share code")
 	InvalidSharing  = errors.New("invalid sharing")
 	SharingNotFound = errors.New("sharing not found")
+
+	SharingListNotAllowed   = errors.New("listing the sharing is not allowed")
+	SharingUploadNotAllowed = errors.New("uploading to the sharing is not allowed")
+	SharingUploadRejected   = errors.New("the file is rejected by the sharing")
 )
 
 // NewErr wrap constant error with an extra message
@@ -4,4 +4,6 @@ import "errors"
 
 var (
 	PermissionDenied = errors.New("permission denied")
+	VirtualRoot      = errors.New("can't operate on the virtual root")
+	QuotaExceeded    = errors.New("storage quota exceeded")
 )
@@ -0,0 +1,102 @@
+package event
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/google/uuid"
+)
+
+type Type string
+
+const (
+	FsPut     Type = "fs.put"
+	FsMakeDir Type = "fs.mkdir"
+	FsMove    Type = "fs.move"
+	FsRename  Type = "fs.rename"
+	FsRemove  Type = "fs.remove"
+	FsCopy    Type = "fs.copy"
+
+	TaskSucceeded Type = "task.succeeded"
+	TaskFailed    Type = "task.failed"
+
+	SharingAccess Type = "sharing.access"
+)
+
+var Types = []Type{
+	FsPut, FsMakeDir, FsMove, FsRename, FsRemove, FsCopy,
+	TaskSucceeded, TaskFailed,
+	SharingAccess,
+}
+
+type Event struct {
+	ID       string    `json:"id"`
+	Type     Type      `json:"type"`
+	Time     time.Time `json:"time"`
+	Username string    `json:"username,omitempty"`
+	Data     any       `json:"data"`
+}
+
+// ObjData is the data of fs.* events, paths are full paths in the virtual tree
+type ObjData struct {
+	Path    string `json:"path"`
+	DstPath string `json:"dst_path,omitempty"`
+	Name    string `json:"name"`
+	Size    int64  `json:"size"`
+	IsDir   bool   `json:"is_dir"`
+}
+
+// TaskData is the data of task.* events
+type TaskData struct {
+	ID    string `json:"id"`
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Error string `json:"error,omitempty"`
+}
+
+// SharingData is the data of sharing.* events
+type SharingData struct {
+	ID       string   `json:"id"`
+	Files    []string `json:"files"`
+	IP       string   `json:"ip"`
+	Accessed int      `json:"accessed"`
+}
+
+type Handler func(e *Event)
+
+var (
+	mu       sync.RWMutex
+	handlers []Handler
+)
+
+// Subscribe registers a handler receiving all the published events.
+// Handlers are called synchronously by the publisher, so they must not block.
+func Subscribe(h Handler) {
+	mu.Lock()
+	defer mu.Unlock()
+	handlers = append(handlers, h)
+}
+
+// Publish emits an event, the user in ctx (if any) is recorded as the actor
+func Publish(ctx context.Context, typ Type, data any) {
+	mu.RLock()
+	defer mu.RUnlock()
+	if len(handlers) == 0 {
+		return
+	}
+	e := &Event{
+		ID:   uuid.NewString(),
+		Type: typ,
+		Time: time.Now(),
+		Data: data,
+	}
+	if user, ok := ctx.Value(conf.UserKey).(*model.User); ok {
+		e.Username = user.Username
+	}
+	for _, h := range handlers {
+		h(e)
+	}
+}
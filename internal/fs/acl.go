@@ -0,0 +1,59 @@
+package fs
+
+import (
+	"context"
+	stdpath "path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+// checkACL checks the action on the paths against the meta ACL for the user
+// of the context, the requests of every protocol reach the storages through
+// this package so it's the single place to enforce it.
+func checkACL(ctx context.Context, action string, paths ...string) error {
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	if user == nil {
+		return nil
+	}
+	for _, p := range paths {
+		if err := op.CheckACL(user, p, action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkACLUnder checks the action on everything under the paths, for the
+// operations done on a whole folder at once
+func checkACLUnder(ctx context.Context, action string, paths ...string) error {
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	for _, p := range paths {
+		if err := op.CheckACLUnder(user, p, action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// filterACL removes the objects in the dir that the user is denied to read
+func filterACL(ctx context.Context, dir string, objs []model.Obj) []model.Obj {
+	return filterACLAction(ctx, model.ACLRead, dir, objs)
+}
+
+// filterACLAction removes the objects in the dir that the user is denied
+// the action on, objs is reused
+func filterACLAction(ctx context.Context, action, dir string, objs []model.Obj) []model.Obj {
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	if user == nil || user.IsAdmin() {
+		return objs
+	}
+	res := objs[:0]
+	for _, obj := range objs {
+		if op.CheckACL(user, stdpath.Join(dir, obj.GetName()), action) == nil {
+			res = append(res, obj)
+		}
+	}
+	return res
+}
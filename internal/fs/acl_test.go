@@ -0,0 +1,63 @@
+package fs_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func init() {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		panic("failed to connect database")
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+}
+
+func TestACLOnChildren(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "parent", "child"), 0o755); err != nil {
+		t.Fatalf("failed to make dir: %v", err)
+	}
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/acl_fs",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, root),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	err = op.CreateMeta(&model.Meta{Path: "/acl_fs/parent/child", ACL: []model.MetaACL{
+		{Subject: model.ACLSubjectEveryone, Deny: true, Actions: "write,delete"},
+	}})
+	if err != nil {
+		t.Fatalf("failed to create meta: %+v", err)
+	}
+	ctx = context.WithValue(ctx, conf.UserKey, &model.User{ID: 100, Username: "visitor"})
+
+	// the protected child can't be deleted or moved with the parent
+	if err = fs.Remove(ctx, "/acl_fs/parent"); !errors.Is(err, errs.PermissionDenied) {
+		t.Errorf("remove parent: got %v, want permission denied", err)
+	}
+	if err = fs.Rename(ctx, "/acl_fs/parent", "renamed"); !errors.Is(err, errs.PermissionDenied) {
+		t.Errorf("rename parent: got %v, want permission denied", err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "parent", "child")); err != nil {
+		t.Errorf("protected child is gone: %v", err)
+	}
+}
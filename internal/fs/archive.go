@@ -37,6 +37,16 @@ func (t *ArchiveDownloadTask) GetName() string {
 		t.InnerPath, t.DstStorageMp, t.DstActualPath, t.Password)
 }
 
+func (t *ArchiveDownloadTask) OnSucceeded() {
+	task.PublishFinished("decompress", t)
+	t.RootDone(true)
+}
+
+func (t *ArchiveDownloadTask) OnFailed() {
+	task.PublishFinished("decompress", t)
+	t.RootDone(false)
+}
+
 func (t *ArchiveDownloadTask) Run() error {
 	if err := t.ReinitCtx(); err != nil {
 		return err
@@ -62,6 +72,7 @@ func (t *ArchiveDownloadTask) Run() error {
 	}
 	uploadTask.groupID = stdpath.Join(uploadTask.DstStorageMp, uploadTask.DstActualPath)
 	task_group.TransferCoordinator.AddTask(uploadTask.groupID, nil)
+	uploadTask.TrackRoot(t.Ctx())
 	ArchiveContentUploadTaskManager.Add(uploadTask)
 	return nil
 }
@@ -144,6 +155,10 @@ type ArchiveContentUploadTask struct {
 	groupID       string
 }
 
+func (t *ArchiveContentUploadTask) GetPaths() []string {
+	return []string{stdpath.Join(t.DstStorageMp, t.DstActualPath)}
+}
+
 func (t *ArchiveContentUploadTask) GetName() string {
 	return fmt.Sprintf("upload %s to [%s](%s)", t.ObjName, t.DstStorageMp, t.DstActualPath)
 }
@@ -159,18 +174,39 @@ func (t *ArchiveContentUploadTask) Run() error {
 	t.ClearEndTime()
 	t.SetStartTime(time.Now())
 	defer func() { t.SetEndTime(time.Now()) }()
+	if t.dstStorage == nil {
+		dstStorage, _, err := op.GetStorageAndActualPath(t.DstStorageMp)
+		if err != nil {
+			return errors.WithMessage(err, "failed get dst storage")
+		}
+		t.dstStorage = dstStorage
+	}
 	return t.RunWithNextTaskCallback(func(nextTsk *ArchiveContentUploadTask) error {
+		nextTsk.TrackRoot(t.Ctx())
 		ArchiveContentUploadTaskManager.Add(nextTsk)
 		return nil
 	})
 }
 
+// Recoverable reports whether the task can run again after restart,
+// it can't if the decompressed file is lost
+func (t *ArchiveContentUploadTask) Recoverable() bool {
+	if utils.SliceContains([]tache.State{tache.StateSucceeded, tache.StateCanceled, tache.StateFailed}, t.GetState()) {
+		return true
+	}
+	return utils.Exists(t.FilePath)
+}
+
 func (t *ArchiveContentUploadTask) OnSucceeded() {
+	task.PublishFinished("decompress_upload", t)
 	task_group.TransferCoordinator.Done(t.groupID, true)
+	t.RootDone(true)
 }
 
 func (t *ArchiveContentUploadTask) OnFailed() {
+	task.PublishFinished("decompress_upload", t)
 	task_group.TransferCoordinator.Done(t.groupID, false)
+	t.RootDone(false)
 }
 
 func (t *ArchiveContentUploadTask) SetRetry(retry int, maxRetry int) {
@@ -347,6 +383,9 @@ var ArchiveContentUploadTaskManager = &archiveContentUploadTaskManagerType{
 }
 
 func archiveMeta(ctx context.Context, path string, args model.ArchiveMetaArgs) (*model.ArchiveMetaProvider, error) {
+	if err := checkACL(ctx, model.ACLArchive, path); err != nil {
+		return nil, err
+	}
 	storage, actualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed get storage")
@@ -355,6 +394,9 @@ func archiveMeta(ctx context.Context, path string, args model.ArchiveMetaArgs) (
 }
 
 func archiveList(ctx context.Context, path string, args model.ArchiveListArgs) ([]model.Obj, error) {
+	if err := checkACL(ctx, model.ACLArchive, path); err != nil {
+		return nil, err
+	}
 	storage, actualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed get storage")
@@ -363,6 +405,12 @@ func archiveList(ctx context.Context, path string, args model.ArchiveListArgs) (
 }
 
 func archiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args model.ArchiveDecompressArgs, lazyCache ...bool) (task.TaskExtensionInfo, error) {
+	if err := checkACL(ctx, model.ACLArchive, srcObjPath); err != nil {
+		return nil, err
+	}
+	if err := checkACL(ctx, model.ACLWrite, dstDirPath); err != nil {
+		return nil, err
+	}
 	srcStorage, srcObjActualPath, err := op.GetStorageAndActualPath(srcObjPath)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed get src storage")
@@ -407,12 +455,16 @@ func archiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args
 	} else {
 		tsk.Creator, _ = ctx.Value(conf.UserKey).(*model.User)
 		tsk.ApiUrl = common.GetApiUrl(ctx)
+		tsk.TrackRoot(ctx)
 		ArchiveDownloadTaskManager.Add(tsk)
 		return tsk, nil
 	}
 }
 
 func archiveDriverExtract(ctx context.Context, path string, args model.ArchiveInnerArgs) (*model.Link, model.Obj, error) {
+	if err := checkACL(ctx, model.ACLArchive, path); err != nil {
+		return nil, nil, err
+	}
 	storage, actualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil {
 		return nil, nil, errors.WithMessage(err, "failed get storage")
@@ -421,6 +473,9 @@ func archiveDriverExtract(ctx context.Context, path string, args model.ArchiveIn
 }
 
 func archiveInternalExtract(ctx context.Context, path string, args model.ArchiveInnerArgs) (io.ReadCloser, int64, error) {
+	if err := checkACL(ctx, model.ACLArchive, path); err != nil {
+		return nil, 0, err
+	}
 	storage, actualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil {
 		return nil, 0, errors.WithMessage(err, "failed get storage")
@@ -0,0 +1,262 @@
+package fs
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/archive/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/tache"
+	"github.com/pkg/errors"
+)
+
+// ArchiveCompressTask packs the source files and folders, which may be in
+// different storages, into an archive in the destination folder. The archive
+// is written to a temp file while the sources are read one by one, then it's
+// uploaded to the destination.
+type ArchiveCompressTask struct {
+	task.TaskExtension
+	model.ArchiveCompressArgs
+	SrcPaths   []string `json:"src_paths"`
+	DstDirPath string   `json:"dst_dir_path"`
+	Name       string   `json:"name"`
+	Status     string   `json:"-"`
+}
+
+func (t *ArchiveCompressTask) GetName() string {
+	return fmt.Sprintf("compress %s to %s", strings.Join(t.SrcPaths, ", "), stdpath.Join(t.DstDirPath, t.Name))
+}
+
+func (t *ArchiveCompressTask) GetStatus() string {
+	return t.Status
+}
+
+func (t *ArchiveCompressTask) GetPaths() []string {
+	return append([]string{stdpath.Join(t.DstDirPath, t.Name)}, t.SrcPaths...)
+}
+
+func (t *ArchiveCompressTask) OnSucceeded() {
+	task.PublishFinished("compress", t)
+}
+
+func (t *ArchiveCompressTask) OnFailed() {
+	task.PublishFinished("compress", t)
+}
+
+func (t *ArchiveCompressTask) Run() error {
+	if err := t.ReinitCtx(); err != nil {
+		return err
+	}
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	defer func() { t.SetEndTime(time.Now()) }()
+	ext, compressor, err := tool.GetCompressor(t.Name)
+	if err != nil {
+		return err
+	}
+	dstStorage, dstDirActualPath, err := op.GetStorageAndActualPath(t.DstDirPath)
+	if err != nil {
+		return errors.WithMessage(err, "failed get dst storage")
+	}
+	t.Status = "listing src objects"
+	var files []tool.CompressFile
+	var total int64
+	for _, srcPath := range t.SrcPaths {
+		fs, err := t.walk(srcPath)
+		if err != nil {
+			return errors.WithMessagef(err, "failed list [%s]", srcPath)
+		}
+		for _, f := range fs {
+			if !f.Obj.IsDir() {
+				total += f.Obj.GetSize()
+			}
+		}
+		files = append(files, fs...)
+	}
+	t.SetTotalBytes(total)
+
+	t.Status = "compressing"
+	tmpF, err := os.CreateTemp(conf.Conf.TempDir, "file-*")
+	if err != nil {
+		return err
+	}
+	r := &progressReader{total: total, up: model.UpdateProgressWithRange(t.SetProgress, 0, 50)}
+	for i := range files {
+		open := files[i].Open
+		if open == nil {
+			continue
+		}
+		files[i].Open = func() (io.ReadCloser, error) {
+			rc, err := open()
+			if err != nil {
+				return nil, err
+			}
+			return &stream.ReaderWithCtx{Reader: r.wrap(rc), Ctx: t.Ctx()}, nil
+		}
+	}
+	err = compressor.Compress(tmpF, ext, files, t.ArchiveCompressArgs)
+	var size int64
+	if err == nil {
+		size, err = tmpF.Seek(0, io.SeekEnd)
+	}
+	if err == nil {
+		_, err = tmpF.Seek(0, io.SeekStart)
+	}
+	if err == nil {
+		err = op.CheckQuota(t.Creator, size)
+	}
+	if err != nil {
+		_ = tmpF.Close()
+		_ = os.Remove(tmpF.Name())
+		return err
+	}
+
+	t.Status = "uploading"
+	file := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     t.Name,
+			Size:     size,
+			Modified: time.Now(),
+		},
+		Mimetype:     utils.GetMimeType(t.Name),
+		WebPutAsTask: true,
+	}
+	file.SetTmpFile(tmpF)
+	return op.Put(t.Ctx(), dstStorage, dstDirActualPath, file, model.UpdateProgressWithRange(t.SetProgress, 50, 100), true)
+}
+
+// walk returns the object of the path and all the objects under it if it's a folder
+func (t *ArchiveCompressTask) walk(path string) ([]tool.CompressFile, error) {
+	storage, actualPath, err := op.GetStorageAndActualPath(path)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := op.Get(t.Ctx(), storage, actualPath)
+	if err != nil {
+		return nil, err
+	}
+	name := obj.GetName()
+	if actualPath == "/" {
+		name = stdpath.Base(storage.GetStorage().MountPath)
+	}
+	var files []tool.CompressFile
+	var walk func(name, actualPath string, obj model.Obj) error
+	walk = func(name, actualPath string, obj model.Obj) error {
+		if utils.IsCanceled(t.Ctx()) {
+			return t.Ctx().Err()
+		}
+		if !obj.IsDir() {
+			files = append(files, tool.CompressFile{
+				Name: name,
+				Obj:  obj,
+				Open: func() (io.ReadCloser, error) {
+					link, _, err := op.Link(t.Ctx(), storage, actualPath, model.LinkArgs{})
+					if err != nil {
+						return nil, errors.WithMessagef(err, "failed get [%s] link", actualPath)
+					}
+					ss, err := stream.NewSeekableStream(&stream.FileStream{
+						Obj: obj,
+						Ctx: t.Ctx(),
+					}, link)
+					if err != nil {
+						_ = link.Close()
+						return nil, errors.WithMessagef(err, "failed get [%s] stream", actualPath)
+					}
+					return ss, nil
+				},
+			})
+			return nil
+		}
+		files = append(files, tool.CompressFile{Name: name, Obj: obj})
+		objs, err := listVisible(t.Ctx(), storage, actualPath)
+		if err != nil {
+			return err
+		}
+		for _, o := range objs {
+			if err = walk(stdpath.Join(name, o.GetName()), stdpath.Join(actualPath, o.GetName()), o); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return files, walk(name, actualPath, obj)
+}
+
+// progressReader updates the progress by the bytes read from all the files
+type progressReader struct {
+	total int64
+	read  int64
+	up    model.UpdateProgress
+}
+
+func (p *progressReader) wrap(rc io.ReadCloser) io.ReadCloser {
+	return &progressReadCloser{ReadCloser: rc, p: p}
+}
+
+type progressReadCloser struct {
+	io.ReadCloser
+	p *progressReader
+}
+
+func (r *progressReadCloser) Read(b []byte) (int, error) {
+	n, err := r.ReadCloser.Read(b)
+	r.p.read += int64(n)
+	if r.p.total > 0 {
+		r.p.up(float64(r.p.read) * 100 / float64(r.p.total))
+	}
+	return n, err
+}
+
+var ArchiveCompressTaskManager *tache.Manager[*ArchiveCompressTask]
+
+func archiveCompress(ctx context.Context, srcPaths []string, dstDirPath, name string, args model.ArchiveCompressArgs) (task.TaskExtensionInfo, error) {
+	if name == "" || strings.Contains(name, "/") {
+		return nil, errors.New("invalid archive name")
+	}
+	if _, _, err := tool.GetCompressor(name); err != nil {
+		return nil, errors.WithMessagef(err, "can't create %s", name)
+	}
+	if len(srcPaths) == 0 {
+		return nil, errors.New("nothing to compress")
+	}
+	if err := checkACL(ctx, model.ACLRead, srcPaths...); err != nil {
+		return nil, err
+	}
+	if err := checkACL(ctx, model.ACLWrite, dstDirPath, stdpath.Join(dstDirPath, name)); err != nil {
+		return nil, err
+	}
+	for _, srcPath := range srcPaths {
+		if _, err := get(ctx, srcPath, &GetArgs{}); err != nil {
+			return nil, errors.WithMessagef(err, "failed get src [%s]", srcPath)
+		}
+	}
+	dstStorage, _, err := op.GetStorageAndActualPath(dstDirPath)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get dst storage")
+	}
+	if dstStorage.Config().NoUpload {
+		return nil, errors.WithStack(errs.UploadNotSupported)
+	}
+	t := &ArchiveCompressTask{
+		ArchiveCompressArgs: args,
+		SrcPaths:            srcPaths,
+		DstDirPath:          dstDirPath,
+		Name:                name,
+	}
+	t.Creator, _ = ctx.Value(conf.UserKey).(*model.User)
+	t.ApiUrl = common.GetApiUrl(ctx)
+	ArchiveCompressTaskManager.Add(t)
+	return t, nil
+}
@@ -67,6 +67,7 @@ func (t *FileTransferTask) Run() error {
 	return t.RunWithNextTaskCallback(func(nextTask *FileTransferTask) error {
 		nextTask.groupID = t.groupID
 		task_group.TransferCoordinator.AddTask(t.groupID, nil)
+		nextTask.TrackRoot(t.Ctx())
 		if t.TaskType == copy {
 			CopyTaskManager.Add(nextTask)
 		} else {
@@ -77,11 +78,15 @@ func (t *FileTransferTask) Run() error {
 }
 
 func (t *FileTransferTask) OnSucceeded() {
+	task.PublishFinished(t.TaskType.String(), t)
 	task_group.TransferCoordinator.Done(t.groupID, true)
+	t.RootDone(true)
 }
 
 func (t *FileTransferTask) OnFailed() {
+	task.PublishFinished(t.TaskType.String(), t)
 	task_group.TransferCoordinator.Done(t.groupID, false)
+	t.RootDone(false)
 }
 
 func (t *FileTransferTask) SetRetry(retry int, maxRetry int) {
@@ -99,6 +104,16 @@ func (t *FileTransferTask) SetRetry(retry int, maxRetry int) {
 }
 
 func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath string, lazyCache ...bool) (task.TaskExtensionInfo, error) {
+	srcAction := model.ACLRead
+	if taskType == move {
+		srcAction = model.ACLDelete
+	}
+	if err := checkACL(ctx, srcAction, srcObjPath); err != nil {
+		return nil, err
+	}
+	if err := checkACL(ctx, model.ACLWrite, dstDirPath, stdpath.Join(dstDirPath, stdpath.Base(srcObjPath))); err != nil {
+		return nil, err
+	}
 	srcStorage, srcObjActualPath, err := op.GetStorageAndActualPath(srcObjPath)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed get src storage")
@@ -108,7 +123,21 @@ func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath str
 		return nil, errors.WithMessage(err, "failed get dst storage")
 	}
 
-	if srcStorage.GetStorage() == dstStorage.GetStorage() {
+	// the storage copies or moves a folder as a whole, so the one having
+	// denied objects inside is transferred by the task skipping them
+	sameStorage := srcStorage.GetStorage() == dstStorage.GetStorage()
+	if sameStorage {
+		err = checkACLUnder(ctx, model.ACLRead, srcObjPath)
+		if err == nil && taskType == move {
+			err = checkACLUnder(ctx, model.ACLDelete, srcObjPath)
+		}
+		if errors.Is(err, errs.PermissionDenied) {
+			sameStorage = false
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	if sameStorage {
 		if taskType == copy {
 			err = op.Copy(ctx, srcStorage, srcObjActualPath, dstDirActualPath, lazyCache...)
 			if !errors.Is(err, errs.NotImplement) && !errors.Is(err, errs.NotSupport) {
@@ -161,6 +190,7 @@ func transfer(ctx context.Context, taskType taskType, srcObjPath, dstDirPath str
 	t.Creator, _ = ctx.Value(conf.UserKey).(*model.User)
 	t.ApiUrl = common.GetApiUrl(ctx)
 	t.groupID = dstDirPath
+	t.TrackRoot(ctx)
 	if taskType == copy {
 		task_group.TransferCoordinator.AddTask(dstDirPath, nil)
 		CopyTaskManager.Add(t)
@@ -183,6 +213,13 @@ func (t *FileTransferTask) RunWithNextTaskCallback(f func(nextTask *FileTransfer
 		if err != nil {
 			return errors.WithMessagef(err, "failed list src [%s] objs", t.SrcActualPath)
 		}
+		// the objects denied to the creator are skipped, and so are not
+		// removed from the source after moved
+		srcDir := stdpath.Join(t.SrcStorageMp, t.SrcActualPath)
+		objs = filterACL(t.Ctx(), srcDir, append([]model.Obj(nil), objs...))
+		if t.TaskType == move {
+			objs = filterACLAction(t.Ctx(), model.ACLDelete, srcDir, objs)
+		}
 		dstActualPath := stdpath.Join(t.DstActualPath, srcObj.GetName())
 		if t.TaskType == copy {
 			if t.Ctx().Value(conf.NoTaskKey) != nil {
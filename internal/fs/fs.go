@@ -3,6 +3,7 @@ package fs
 import (
 	"context"
 	"io"
+	stdpath "path"
 
 	log "github.com/sirupsen/logrus"
 
@@ -140,6 +141,14 @@ func ArchiveDecompress(ctx context.Context, srcObjPath, dstDirPath string, args
 	return t, err
 }
 
+func ArchiveCompress(ctx context.Context, srcPaths []string, dstDirPath, name string, args model.ArchiveCompressArgs) (task.TaskExtensionInfo, error) {
+	t, err := archiveCompress(ctx, srcPaths, dstDirPath, name, args)
+	if err != nil {
+		log.Errorf("failed compress %v to [%s]%s: %+v", srcPaths, dstDirPath, name, err)
+	}
+	return t, err
+}
+
 func ArchiveDriverExtract(ctx context.Context, path string, args model.ArchiveInnerArgs) (*model.Link, model.Obj, error) {
 	l, obj, err := archiveDriverExtract(ctx, path, args)
 	if err != nil {
@@ -176,6 +185,9 @@ func Other(ctx context.Context, args model.FsOtherArgs) (interface{}, error) {
 }
 
 func PutURL(ctx context.Context, path, dstName, urlStr string) error {
+	if err := checkACL(ctx, model.ACLWrite, path, stdpath.Join(path, dstName)); err != nil {
+		return err
+	}
 	storage, dstDirActualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
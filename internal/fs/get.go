@@ -5,6 +5,7 @@ import (
 	stdpath "path"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
@@ -13,6 +14,9 @@ import (
 
 func get(ctx context.Context, path string, args *GetArgs) (model.Obj, error) {
 	path = utils.FixAndCleanPath(path)
+	if err := checkACL(ctx, model.ACLRead, path); err != nil {
+		return nil, err
+	}
 	// maybe a virtual file
 	if path != "/" {
 		virtualFiles := op.GetStorageVirtualFilesWithDetailsByPath(ctx, stdpath.Dir(path), !args.WithStorageDetails, false)
@@ -35,5 +39,9 @@ func get(ctx context.Context, path string, args *GetArgs) (model.Obj, error) {
 		}
 		return nil, errors.WithMessage(err, "failed get storage")
 	}
+	// the trash is only reachable through the trash api
+	if op.IsTrashPath(actualPath) {
+		return nil, errors.WithStack(errs.ObjectNotFound)
+	}
 	return op.Get(ctx, storage, actualPath)
 }
@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
@@ -15,6 +17,9 @@ import (
 func list(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error) {
 	meta, _ := ctx.Value(conf.MetaKey).(*model.Meta)
 	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	if err := checkACL(ctx, model.ACLRead, path); err != nil {
+		return nil, err
+	}
 	virtualFiles := op.GetStorageVirtualFilesWithDetailsByPath(ctx, path, !args.WithStorageDetails, args.Refresh)
 	storage, actualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil && len(virtualFiles) == 0 {
@@ -22,6 +27,9 @@ func list(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error)
 	}
 
 	var _objs []model.Obj
+	if storage != nil && op.IsTrashPath(actualPath) {
+		return nil, errors.WithStack(errs.ObjectNotFound)
+	}
 	if storage != nil {
 		_objs, err = op.List(ctx, storage, actualPath, model.ListArgs{
 			ReqPath:            path,
@@ -38,12 +46,49 @@ func list(ctx context.Context, path string, args *ListArgs) ([]model.Obj, error)
 		}
 	}
 
+	if storage != nil && actualPath == "/" {
+		_objs = hideTrash(_objs)
+	}
 	om := model.NewObjMerge()
 	if whetherHide(user, meta, path) {
 		om.InitHideReg(meta.Hide)
 	}
 	objs := om.Merge(_objs, virtualFiles...)
-	return objs, nil
+	return filterACL(ctx, path, objs), nil
+}
+
+// listVisible lists the objects in the dir at actualPath of the storage like
+// list, with the hide rules of the nearest meta and the ACL applied for the
+// user of the context. It's used to walk a folder in a task.
+func listVisible(ctx context.Context, storage driver.Driver, actualPath string) ([]model.Obj, error) {
+	objs, err := op.List(ctx, storage, actualPath, model.ListArgs{})
+	if err != nil {
+		return nil, err
+	}
+	if actualPath == "/" {
+		objs = hideTrash(objs)
+	}
+	dir := utils.GetFullPath(storage.GetStorage().MountPath, actualPath)
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	meta, err := op.GetNearestMeta(dir)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return nil, err
+	}
+	om := model.NewObjMerge()
+	if whetherHide(user, meta, dir) {
+		om.InitHideReg(meta.Hide)
+	}
+	return filterACL(ctx, dir, om.Merge(objs)), nil
+}
+
+// hideTrash removes the trash folder from the objects at the storage root
+func hideTrash(objs []model.Obj) []model.Obj {
+	for i, obj := range objs {
+		if obj.GetName() == model.TrashDirName {
+			return append(objs[:i:i], objs[i+1:]...)
+		}
+	}
+	return objs
 }
 
 func whetherHide(user *model.User, meta *model.Meta, path string) bool {
@@ -2,8 +2,10 @@ package fs
 
 import (
 	"context"
+	stdpath "path"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
@@ -11,6 +13,9 @@ import (
 )
 
 func makeDir(ctx context.Context, path string, lazyCache ...bool) error {
+	if err := checkACL(ctx, model.ACLWrite, path); err != nil {
+		return err
+	}
 	storage, actualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
@@ -19,6 +24,14 @@ func makeDir(ctx context.Context, path string, lazyCache ...bool) error {
 }
 
 func rename(ctx context.Context, srcPath, dstName string, lazyCache ...bool) error {
+	if err := checkACL(ctx, model.ACLWrite, srcPath, stdpath.Join(stdpath.Dir(srcPath), dstName)); err != nil {
+		return err
+	}
+	// the entries are keyed by path, so the children renamed with the folder
+	// would leave theirs behind
+	if err := checkACLUnder(ctx, model.ACLWrite, srcPath); err != nil {
+		return err
+	}
 	storage, srcActualPath, err := op.GetStorageAndActualPath(srcPath)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
@@ -27,14 +40,29 @@ func rename(ctx context.Context, srcPath, dstName string, lazyCache ...bool) err
 }
 
 func remove(ctx context.Context, path string) error {
+	if err := checkACL(ctx, model.ACLDelete, path); err != nil {
+		return err
+	}
+	if err := checkACLUnder(ctx, model.ACLDelete, path); err != nil {
+		return err
+	}
 	storage, actualPath, err := op.GetStorageAndActualPath(path)
 	if err != nil {
 		return errors.WithMessage(err, "failed get storage")
 	}
+	if op.IsTrashPath(actualPath) {
+		return errors.WithStack(errs.ObjectNotFound)
+	}
+	if op.TrashEnabled(storage) {
+		return op.MoveToTrash(ctx, storage, actualPath)
+	}
 	return op.Remove(ctx, storage, actualPath)
 }
 
 func other(ctx context.Context, args model.FsOtherArgs) (interface{}, error) {
+	if err := checkACL(ctx, model.ACLRead, args.Path); err != nil {
+		return nil, err
+	}
 	storage, actualPath, err := op.GetStorageAndActualPath(args.Path)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed get storage")
@@ -57,3 +85,12 @@ type TaskData struct {
 func (t *TaskData) GetStatus() string {
 	return t.Status
 }
+
+func (t *TaskData) GetPaths() []string {
+	paths := []string{stdpath.Join(t.DstStorageMp, t.DstActualPath)}
+	// the source of an offline download transfer is a local temp file
+	if t.SrcStorageMp != "" {
+		paths = append(paths, stdpath.Join(t.SrcStorageMp, t.SrcActualPath))
+	}
+	return paths
+}
@@ -3,7 +3,10 @@ package fs
 import (
 	"context"
 	"fmt"
+	"io"
+	"os"
 	stdpath "path"
+	"path/filepath"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/server/common"
@@ -13,21 +16,38 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/internal/task"
 	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/tache"
 	"github.com/pkg/errors"
 )
 
 type UploadTask struct {
 	task.TaskExtension
-	storage          driver.Driver
-	dstDirActualPath string
-	file             model.FileStreamer
+	storage driver.Driver
+	file    model.FileStreamer
+	groupID string
+	// the fields below are persisted to restore the task after restart
+	StorageMp        string    `json:"storage_mp"`
+	DstDirActualPath string    `json:"dst_dir_actual_path"`
+	FileName         string    `json:"file_name"`
+	FileSize         int64     `json:"file_size"`
+	Mimetype         string    `json:"mimetype"`
+	Modified         time.Time `json:"modified"`
+	Hash             string    `json:"hash"`
+	// TempFile is the file in the temp dir holding the content to upload,
+	// it's empty if the content isn't staged on disk
+	TempFile string `json:"temp_file"`
+}
+
+func (t *UploadTask) GetPaths() []string {
+	return []string{stdpath.Join(t.StorageMp, t.DstDirActualPath, t.FileName)}
 }
 
 func (t *UploadTask) GetName() string {
-	return fmt.Sprintf("upload %s to [%s](%s)", t.file.GetName(), t.storage.GetStorage().MountPath, t.dstDirActualPath)
+	return fmt.Sprintf("upload %s to [%s](%s)", t.FileName, t.StorageMp, t.DstDirActualPath)
 }
 
 func (t *UploadTask) GetStatus() string {
@@ -38,22 +58,72 @@ func (t *UploadTask) Run() error {
 	t.ClearEndTime()
 	t.SetStartTime(time.Now())
 	defer func() { t.SetEndTime(time.Now()) }()
-	return op.Put(t.Ctx(), t.storage, t.dstDirActualPath, t.file, t.SetProgress, true)
+	if t.storage == nil {
+		storage, _, err := op.GetStorageAndActualPath(t.StorageMp)
+		if err != nil {
+			return errors.WithMessage(err, "failed get storage")
+		}
+		t.storage = storage
+	}
+	if t.file == nil {
+		file, err := t.restoreFile()
+		if err != nil {
+			return err
+		}
+		t.file = file
+	}
+	return op.Put(t.Ctx(), t.storage, t.DstDirActualPath, t.file, t.SetProgress, true)
+}
+
+// restoreFile reopens the staged temp file of the task restored after restart
+func (t *UploadTask) restoreFile() (model.FileStreamer, error) {
+	if t.TempFile == "" {
+		return nil, errors.New("the content to upload is not staged")
+	}
+	f, err := os.Open(t.TempFile)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed open the staged file")
+	}
+	file := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     t.FileName,
+			Size:     t.FileSize,
+			Modified: t.Modified,
+			HashInfo: utils.FromString(t.Hash),
+		},
+		Mimetype:     t.Mimetype,
+		WebPutAsTask: true,
+	}
+	file.SetTmpFile(f)
+	return file, nil
+}
+
+// Recoverable reports whether the task can run again after restart,
+// it can't if the staged file is lost
+func (t *UploadTask) Recoverable() bool {
+	if utils.SliceContains([]tache.State{tache.StateSucceeded, tache.StateCanceled, tache.StateFailed}, t.GetState()) {
+		return true
+	}
+	return t.TempFile != "" && utils.Exists(t.TempFile)
 }
 
 func (t *UploadTask) OnSucceeded() {
-	task_group.TransferCoordinator.Done(stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath), true)
+	task.PublishFinished("upload", t)
+	task_group.TransferCoordinator.Done(t.groupID, true)
 }
 
 func (t *UploadTask) OnFailed() {
-	task_group.TransferCoordinator.Done(stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath), false)
+	task.PublishFinished("upload", t)
+	task_group.TransferCoordinator.Done(t.groupID, false)
 }
 
 func (t *UploadTask) SetRetry(retry int, maxRetry int) {
 	t.TaskExtension.SetRetry(retry, maxRetry)
 	if retry == 0 &&
-		(t.GetErr() == nil && t.GetState() != tache.StatePending) { // 手动重试
-		task_group.TransferCoordinator.AddTask(stdpath.Join(t.storage.GetStorage().MountPath, t.dstDirActualPath), nil)
+		(len(t.groupID) == 0 || // 重启恢复
+			(t.GetErr() == nil && t.GetState() != tache.StatePending)) { // 手动重试
+		t.groupID = stdpath.Join(t.StorageMp, t.DstDirActualPath)
+		task_group.TransferCoordinator.AddTask(t.groupID, nil)
 	}
 }
 
@@ -61,6 +131,12 @@ var UploadTaskManager *tache.Manager[*UploadTask]
 
 // putAsTask add as a put task and return immediately
 func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer) (task.TaskExtensionInfo, error) {
+	if err := checkACL(ctx, model.ACLWrite, dstDirPath, stdpath.Join(dstDirPath, file.GetName())); err != nil {
+		return nil, err
+	}
+	if err := checkQuota(ctx, file.GetSize()); err != nil {
+		return nil, err
+	}
 	storage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
 	if err != nil {
 		return nil, errors.WithMessage(err, "failed get storage")
@@ -76,6 +152,13 @@ func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 		//file.SetReader(tempFile)
 		//file.SetTmpFile(tempFile)
 	}
+	var tempFile string
+	if conf.Conf.Tasks.Upload.TaskPersistant {
+		// stage the content on disk so the task can be restored after restart
+		if tempFile, err = stageFile(file); err != nil {
+			return nil, errors.WithMessage(err, "failed to stage the file")
+		}
+	}
 	taskCreator, _ := ctx.Value(conf.UserKey).(*model.User) // taskCreator is nil when convert failed
 	t := &UploadTask{
 		TaskExtension: task.TaskExtension{
@@ -83,17 +166,59 @@ func putAsTask(ctx context.Context, dstDirPath string, file model.FileStreamer)
 			ApiUrl:  common.GetApiUrl(ctx),
 		},
 		storage:          storage,
-		dstDirActualPath: dstDirActualPath,
 		file:             file,
+		groupID:          stdpath.Join(storage.GetStorage().MountPath, dstDirActualPath),
+		StorageMp:        storage.GetStorage().MountPath,
+		DstDirActualPath: dstDirActualPath,
+		FileName:         file.GetName(),
+		FileSize:         file.GetSize(),
+		Mimetype:         file.GetMimetype(),
+		Modified:         file.ModTime(),
+		Hash:             file.GetHash().String(),
+		TempFile:         tempFile,
 	}
 	t.SetTotalBytes(file.GetSize())
-	task_group.TransferCoordinator.AddTask(dstDirPath, nil)
+	task_group.TransferCoordinator.AddTask(t.groupID, nil)
 	UploadTaskManager.Add(t)
 	return t, nil
 }
 
+// stageFile returns the temp file holding the content of the cached file,
+// the content cached in memory is written to a new temp file first
+func stageFile(file model.FileStreamer) (string, error) {
+	cache := file.GetFile()
+	if cache == nil {
+		return "", nil
+	}
+	if f, ok := cache.(*os.File); ok {
+		return filepath.Abs(f.Name())
+	}
+	tmpF, err := os.CreateTemp(conf.Conf.TempDir, "file-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err = utils.CopyWithBuffer(tmpF, io.NewSectionReader(cache, 0, file.GetSize())); err == nil {
+		_, err = tmpF.Seek(0, io.SeekStart)
+	}
+	if err != nil {
+		_ = tmpF.Close()
+		_ = os.Remove(tmpF.Name())
+		return "", err
+	}
+	file.SetTmpFile(tmpF)
+	return filepath.Abs(tmpF.Name())
+}
+
 // putDirect put the file and return after finish
 func putDirectly(ctx context.Context, dstDirPath string, file model.FileStreamer, lazyCache ...bool) error {
+	if err := checkACL(ctx, model.ACLWrite, dstDirPath, stdpath.Join(dstDirPath, file.GetName())); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := checkQuota(ctx, file.GetSize()); err != nil {
+		_ = file.Close()
+		return err
+	}
 	storage, dstDirActualPath, err := op.GetStorageAndActualPath(dstDirPath)
 	if err != nil {
 		_ = file.Close()
@@ -0,0 +1,87 @@
+package fs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	log "github.com/sirupsen/logrus"
+)
+
+// checkQuota checks whether the user of the context can upload size bytes
+func checkQuota(ctx context.Context, size int64) error {
+	user, _ := ctx.Value(conf.UserKey).(*model.User)
+	return op.CheckQuota(user, size)
+}
+
+// ScanUsage returns the total size of the files under the path by walking it
+func ScanUsage(ctx context.Context, path string) (int64, error) {
+	obj, err := Get(ctx, path, &GetArgs{NoLog: true})
+	if err != nil {
+		return 0, err
+	}
+	var used int64
+	err = WalkFS(ctx, -1, path, obj, func(reqPath string, info model.Obj) error {
+		if !info.IsDir() {
+			used += info.GetSize()
+		}
+		return ctx.Err()
+	})
+	return used, err
+}
+
+var reconcileMu sync.Mutex
+
+// ReconcileUsages rescans the tracked usages which are dirty, or all of them
+// if all is true. The paths no longer being a root of any user having a
+// quota are untracked in the latter case.
+func ReconcileUsages(ctx context.Context, all bool) error {
+	reconcileMu.Lock()
+	defer reconcileMu.Unlock()
+	var usages []model.PathUsage
+	var err error
+	if !all {
+		usages, err = db.GetDirtyPathUsages()
+	} else if usages, err = db.GetAllPathUsages(); err == nil {
+		var roots map[string]struct{}
+		if roots, err = op.GetQuotaRoots(); err != nil {
+			return err
+		}
+		var stale []string
+		tracked := usages[:0]
+		for _, u := range usages {
+			if _, ok := roots[u.Path]; ok {
+				tracked = append(tracked, u)
+			} else {
+				stale = append(stale, u.Path)
+			}
+		}
+		usages = tracked
+		if len(stale) > 0 {
+			if err = op.UntrackUsages(stale); err != nil {
+				return err
+			}
+		}
+	}
+	if err != nil {
+		return err
+	}
+	for _, u := range usages {
+		used, err := ScanUsage(ctx, u.Path)
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			log.Warnf("failed scan usage of [%s]: %+v", u.Path, err)
+			continue
+		}
+		if err = db.SetPathUsage(u.Path, used); err != nil {
+			return err
+		}
+		log.Debugf("reconciled usage of [%s]: %d bytes", u.Path, used)
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package fs
+
+import (
+	"context"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+// VirtualRoot is the object of the virtual root directory of a user
+// having more than one root
+func VirtualRoot() model.Obj {
+	return &model.Object{
+		Name:     "root",
+		IsFolder: true,
+	}
+}
+
+// ListVirtualRoot lists the roots of the user as the entries of its virtual
+// root, the roots that can't be got, e.g. denied by the meta ACL, are left out
+func ListVirtualRoot(ctx context.Context, user *model.User) []model.Obj {
+	roots := user.VirtualRoots()
+	objs := make([]model.Obj, 0, len(roots))
+	for _, r := range roots {
+		obj, err := Get(ctx, r.Path, &GetArgs{NoLog: true})
+		if err != nil {
+			continue
+		}
+		objs = append(objs, &model.ObjWrapName{Name: r.Name, Obj: obj})
+	}
+	return objs
+}
@@ -0,0 +1,38 @@
+//go:build fuse
+
+package fuse
+
+import (
+	"errors"
+	"os"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	pkgerr "github.com/pkg/errors"
+	"github.com/winfsp/cgofuse/fuse"
+)
+
+// toErrno converts an error from internal/fs to a negated errno
+// as expected by the fuse callbacks.
+func toErrno(err error) int {
+	if err == nil {
+		return 0
+	}
+	cause := pkgerr.Cause(err)
+	switch {
+	case errs.IsNotFoundError(err), errors.Is(cause, os.ErrNotExist):
+		return -fuse.ENOENT
+	case errors.Is(cause, errs.PermissionDenied), errors.Is(cause, os.ErrPermission):
+		return -fuse.EACCES
+	case errors.Is(cause, errs.UploadNotSupported):
+		return -fuse.EROFS
+	case errs.IsNotSupportError(err), errs.IsNotImplementError(err):
+		return -fuse.ENOSYS
+	case errors.Is(cause, errs.NotFolder):
+		return -fuse.ENOTDIR
+	case errors.Is(cause, errs.NotFile):
+		return -fuse.EISDIR
+	case errors.Is(cause, os.ErrExist):
+		return -fuse.EEXIST
+	}
+	return -fuse.EIO
+}
@@ -1,170 +1,461 @@
+//go:build fuse
+
 package fuse
 
-import "github.com/winfsp/cgofuse/fuse"
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	stdpath "path"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/winfsp/cgofuse/fuse"
+)
 
 type Fs struct {
 	RootFolder string
 	fuse.FileSystemBase
-}
-
-func (fs *Fs) Init() {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Destroy() {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Statfs(path string, stat *fuse.Statfs_t) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Mknod(path string, mode uint32, dev uint64) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Mkdir(path string, mode uint32) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Unlink(path string) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Rmdir(path string) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Link(oldpath string, newpath string) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Symlink(target string, newpath string) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Readlink(path string) (int, string) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Rename(oldpath string, newpath string) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Chmod(path string, mode uint32) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Chown(path string, uid uint32, gid uint32) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Utimens(path string, tmsp []fuse.Timespec) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Access(path string, mask uint32) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Create(path string, flags int, mode uint32) (int, uint64) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Open(path string, flags int) (int, uint64) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Getattr(path string, stat *fuse.Stat_t, fh uint64) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Truncate(path string, size int64, fh uint64) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Read(path string, buff []byte, ofst int64, fh uint64) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Write(path string, buff []byte, ofst int64, fh uint64) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Flush(path string, fh uint64) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Release(path string, fh uint64) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Fsync(path string, datasync bool, fh uint64) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Opendir(path string) (int, uint64) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Readdir(path string, fill func(name string, stat *fuse.Stat_t, ofst int64) bool, ofst int64, fh uint64) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Releasedir(path string, fh uint64) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Fsyncdir(path string, datasync bool, fh uint64) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Setxattr(path string, name string, value []byte, flags int) int {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Getxattr(path string, name string) (int, []byte) {
-	//TODO implement me
-	panic("implement me")
-}
-
-func (fs *Fs) Removexattr(path string, name string) int {
-	//TODO implement me
-	panic("implement me")
-}
 
-func (fs *Fs) Listxattr(path string, fill func(name string) bool) int {
-	//TODO implement me
-	panic("implement me")
+	ctx  context.Context
+	user *model.User
+	uid  uint32
+	gid  uint32
+
+	mu      sync.Mutex
+	nextFh  uint64
+	handles map[uint64]*handle
+	// staged holds the files created through this mount that have not
+	// been uploaded yet, so they can be stat'ed and listed meanwhile
+	staged map[string]*handle
+}
+
+// NewFs returns the filesystem of rootFolder, operated as the user
+func NewFs(rootFolder string, user *model.User) *Fs {
+	return &Fs{
+		RootFolder: utils.FixAndCleanPath(rootFolder),
+		user:       user,
+		handles:    make(map[uint64]*handle),
+		staged:     make(map[string]*handle),
+	}
+}
+
+func (f *Fs) Init() {
+	f.ctx = context.WithValue(context.Background(), conf.UserKey, f.user)
+	f.ctx = context.WithValue(f.ctx, conf.NoTaskKey, struct{}{})
+	f.uid, f.gid = uint32(os.Getuid()), uint32(os.Getgid())
+}
+
+func (f *Fs) Destroy() {
+	f.mu.Lock()
+	handles := f.handles
+	f.handles = make(map[uint64]*handle)
+	clear(f.staged)
+	f.mu.Unlock()
+	for _, h := range handles {
+		h.mu.Lock()
+		if err := h.flush(f.ctx); err != nil {
+			utils.Log.Errorf("[fuse] failed upload %s: %+v", h.path, err)
+		}
+		h.close()
+		h.mu.Unlock()
+	}
+}
+
+func (f *Fs) Statfs(path string, stat *fuse.Statfs_t) int {
+	const blockSize = 4096
+	stat.Bsize = blockSize
+	stat.Frsize = blockSize
+	stat.Blocks = 1 << 40 / blockSize
+	stat.Bfree = stat.Blocks
+	stat.Bavail = stat.Blocks
+	stat.Files = 1 << 20
+	stat.Ffree = stat.Files
+	stat.Favail = stat.Files
+	stat.Namemax = 255
+	return 0
+}
+
+func (f *Fs) Mknod(path string, mode uint32, dev uint64) int {
+	if mode&fuse.S_IFMT != fuse.S_IFREG && mode&fuse.S_IFMT != 0 {
+		return -fuse.ENOSYS
+	}
+	h := &handle{path: f.realPath(path), dirty: true}
+	if err := h.stage(f.ctx, false); err != nil {
+		return toErrno(err)
+	}
+	defer h.close()
+	return toErrno(h.flush(f.ctx))
+}
+
+func (f *Fs) Mkdir(path string, mode uint32) int {
+	return toErrno(fs.MakeDir(f.ctx, f.realPath(path)))
+}
+
+func (f *Fs) Unlink(path string) int {
+	path = f.realPath(path)
+	f.mu.Lock()
+	h, ok := f.staged[path]
+	delete(f.staged, path)
+	f.mu.Unlock()
+	if ok {
+		// not uploaded yet, drop the pending content. The handle may be
+		// still open, it's kept usable but never uploaded.
+		h.mu.Lock()
+		h.discard()
+		h.mu.Unlock()
+		return 0
+	}
+	return toErrno(fs.Remove(f.ctx, path))
+}
+
+func (f *Fs) Rmdir(path string) int {
+	path = f.realPath(path)
+	objs, err := fs.List(f.ctx, path, &fs.ListArgs{NoLog: true})
+	if err != nil {
+		return toErrno(err)
+	}
+	if len(objs) > 0 {
+		return -fuse.ENOTEMPTY
+	}
+	return toErrno(fs.Remove(f.ctx, path))
+}
+
+func (f *Fs) Rename(oldpath string, newpath string) int {
+	src, dst := f.realPath(oldpath), f.realPath(newpath)
+	if src == dst {
+		return 0
+	}
+	if f.isStaged(src) {
+		return -fuse.EBUSY
+	}
+	// a move across storages copies the content, let the caller do it
+	srcStorage, _, err := op.GetStorageAndActualPath(src)
+	if err != nil {
+		return toErrno(err)
+	}
+	dstStorage, _, err := op.GetStorageAndActualPath(dst)
+	if err != nil {
+		return toErrno(err)
+	}
+	if srcStorage.GetStorage() != dstStorage.GetStorage() {
+		return -fuse.EXDEV
+	}
+	obj, err := fs.Get(f.ctx, dst, &fs.GetArgs{NoLog: true})
+	exists := err == nil
+	if exists && obj.IsDir() {
+		return -fuse.EEXIST
+	}
+	srcDir, srcName := stdpath.Split(src)
+	dstDir, dstName := stdpath.Split(dst)
+	if !exists && srcDir == dstDir {
+		return toErrno(fs.Rename(f.ctx, src, dstName))
+	}
+	if !exists && srcName == dstName {
+		_, err = fs.Move(f.ctx, src, dstDir)
+		return toErrno(err)
+	}
+	// the source is moved under a temporary name, so neither dst nor another
+	// file named as the source in dstDir is replaced before the move succeeds
+	tmpName := "." + dstName + ".openlist_rename_" + random.String(8)
+	if err = fs.Rename(f.ctx, src, tmpName); err != nil {
+		return toErrno(err)
+	}
+	staged := stdpath.Join(srcDir, tmpName)
+	// undo puts the source back from where it's staged at
+	undo := func(at string) {
+		if at != staged {
+			if _, err := fs.Move(f.ctx, at, srcDir); err != nil {
+				return
+			}
+		}
+		_ = fs.Rename(f.ctx, staged, srcName)
+	}
+	moved := stdpath.Join(dstDir, tmpName)
+	if srcDir != dstDir {
+		if _, err = fs.Move(f.ctx, staged, dstDir); err != nil {
+			undo(staged)
+			return toErrno(err)
+		}
+	}
+	if exists {
+		if err = fs.Remove(f.ctx, dst); err != nil {
+			undo(moved)
+			return toErrno(err)
+		}
+	}
+	return toErrno(fs.Rename(f.ctx, moved, dstName))
+}
+
+// Chmod, Chown and Utimens are accepted and ignored, since the storages
+// have no notion of them. Failing here would break plain cp and rsync.
+func (f *Fs) Chmod(path string, mode uint32) int {
+	return 0
+}
+
+func (f *Fs) Chown(path string, uid uint32, gid uint32) int {
+	return 0
+}
+
+func (f *Fs) Utimens(path string, tmsp []fuse.Timespec) int {
+	return 0
+}
+
+func (f *Fs) Access(path string, mask uint32) int {
+	return 0
+}
+
+func (f *Fs) Create(path string, flags int, mode uint32) (int, uint64) {
+	h := &handle{path: f.realPath(path), dirty: true}
+	if err := h.stage(f.ctx, false); err != nil {
+		return toErrno(err), ^uint64(0)
+	}
+	return 0, f.addHandle(h)
+}
+
+func (f *Fs) Open(path string, flags int) (int, uint64) {
+	path = f.realPath(path)
+	obj, err := fs.Get(f.ctx, path, &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return toErrno(err), ^uint64(0)
+	}
+	if obj.IsDir() {
+		return -fuse.EISDIR, ^uint64(0)
+	}
+	h := &handle{path: path, obj: obj}
+	if flags&fuse.O_ACCMODE != fuse.O_RDONLY {
+		trunc := flags&fuse.O_TRUNC != 0
+		if err = h.stage(f.ctx, !trunc); err != nil {
+			return toErrno(err), ^uint64(0)
+		}
+		h.dirty = trunc
+	}
+	return 0, f.addHandle(h)
+}
+
+func (f *Fs) Getattr(path string, stat *fuse.Stat_t, fh uint64) int {
+	path = f.realPath(path)
+	if h := f.getHandle(fh); h != nil && h.writable() {
+		f.fillStat(stat, &model.Object{Name: stdpath.Base(path), Size: h.size(), Modified: time.Now()})
+		return 0
+	}
+	f.mu.Lock()
+	h, ok := f.staged[path]
+	f.mu.Unlock()
+	if ok {
+		f.fillStat(stat, &model.Object{Name: stdpath.Base(path), Size: h.size(), Modified: time.Now()})
+		return 0
+	}
+	obj, err := fs.Get(f.ctx, path, &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return toErrno(err)
+	}
+	f.fillStat(stat, obj)
+	return 0
+}
+
+func (f *Fs) Truncate(path string, size int64, fh uint64) int {
+	h := f.getHandle(fh)
+	if h == nil || !h.writable() {
+		ret, tfh := f.Open(path, fuse.O_WRONLY)
+		if ret != 0 {
+			return ret
+		}
+		defer f.Release(path, tfh)
+		h = f.getHandle(tfh)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if err := h.tmp.Truncate(size); err != nil {
+		return toErrno(err)
+	}
+	h.dirty = true
+	return 0
+}
+
+func (f *Fs) Read(path string, buff []byte, ofst int64, fh uint64) int {
+	h := f.getHandle(fh)
+	if h == nil {
+		return -fuse.EBADF
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n, err := h.readAt(f.ctx, buff, ofst)
+	if n > 0 || err == nil || errors.Is(err, io.EOF) {
+		return n
+	}
+	return toErrno(err)
+}
+
+func (f *Fs) Write(path string, buff []byte, ofst int64, fh uint64) int {
+	h := f.getHandle(fh)
+	if h == nil || !h.writable() {
+		return -fuse.EBADF
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	n, err := h.tmp.WriteAt(buff, ofst)
+	if n > 0 {
+		h.dirty = true
+		if e := stream.ClientUploadLimit.WaitN(f.ctx, n); e != nil {
+			return toErrno(e)
+		}
+	}
+	if err != nil {
+		return toErrno(err)
+	}
+	return n
+}
+
+func (f *Fs) Flush(path string, fh uint64) int {
+	h := f.getHandle(fh)
+	if h == nil {
+		return -fuse.EBADF
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	err := h.flush(f.ctx)
+	if err == nil {
+		f.mu.Lock()
+		if f.staged[h.path] == h {
+			delete(f.staged, h.path)
+		}
+		f.mu.Unlock()
+	}
+	return toErrno(err)
+}
+
+func (f *Fs) Release(path string, fh uint64) int {
+	ret := f.Flush(path, fh)
+	f.mu.Lock()
+	h := f.handles[fh]
+	delete(f.handles, fh)
+	if h != nil && f.staged[h.path] == h {
+		delete(f.staged, h.path)
+	}
+	f.mu.Unlock()
+	if h != nil {
+		h.mu.Lock()
+		h.close()
+		h.mu.Unlock()
+	}
+	return ret
+}
+
+func (f *Fs) Fsync(path string, datasync bool, fh uint64) int {
+	return f.Flush(path, fh)
+}
+
+func (f *Fs) Opendir(path string) (int, uint64) {
+	obj, err := fs.Get(f.ctx, f.realPath(path), &fs.GetArgs{NoLog: true})
+	if err != nil {
+		return toErrno(err), ^uint64(0)
+	}
+	if !obj.IsDir() {
+		return -fuse.ENOTDIR, ^uint64(0)
+	}
+	return 0, ^uint64(0)
+}
+
+func (f *Fs) Readdir(path string, fill func(name string, stat *fuse.Stat_t, ofst int64) bool, ofst int64, fh uint64) int {
+	path = f.realPath(path)
+	objs, err := fs.List(f.ctx, path, &fs.ListArgs{NoLog: true})
+	if err != nil {
+		return toErrno(err)
+	}
+	fill(".", nil, 0)
+	fill("..", nil, 0)
+	names := make(map[string]struct{}, len(objs))
+	for _, obj := range objs {
+		names[obj.GetName()] = struct{}{}
+		stat := &fuse.Stat_t{}
+		f.fillStat(stat, obj)
+		if !fill(obj.GetName(), stat, 0) {
+			return 0
+		}
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for p, h := range f.staged {
+		dir, name := stdpath.Split(p)
+		if _, ok := names[name]; ok || utils.FixAndCleanPath(dir) != path {
+			continue
+		}
+		stat := &fuse.Stat_t{}
+		f.fillStat(stat, &model.Object{Name: name, Size: h.size(), Modified: time.Now()})
+		if !fill(name, stat, 0) {
+			break
+		}
+	}
+	return 0
+}
+
+func (f *Fs) Releasedir(path string, fh uint64) int {
+	return 0
+}
+
+func (f *Fs) Fsyncdir(path string, datasync bool, fh uint64) int {
+	return 0
+}
+
+func (f *Fs) realPath(path string) string {
+	return utils.FixAndCleanPath(stdpath.Join(f.RootFolder, path))
+}
+
+func (f *Fs) addHandle(h *handle) uint64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.nextFh++
+	f.handles[f.nextFh] = h
+	if h.dirty && h.obj == nil {
+		f.staged[h.path] = h
+	}
+	return f.nextFh
+}
+
+func (f *Fs) getHandle(fh uint64) *handle {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.handles[fh]
+}
+
+func (f *Fs) isStaged(path string) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	_, ok := f.staged[path]
+	return ok
+}
+
+func (f *Fs) fillStat(stat *fuse.Stat_t, obj model.Obj) {
+	if obj.IsDir() {
+		stat.Mode = fuse.S_IFDIR | 0o755
+		stat.Nlink = 2
+	} else {
+		stat.Mode = fuse.S_IFREG | 0o644
+		stat.Nlink = 1
+		stat.Size = obj.GetSize()
+	}
+	stat.Uid = f.uid
+	stat.Gid = f.gid
+	stat.Blksize = 4096
+	stat.Blocks = (stat.Size + 511) / 512
+	mtime := obj.ModTime()
+	ctime := obj.CreateTime()
+	if ctime.IsZero() {
+		ctime = mtime
+	}
+	stat.Mtim = fuse.NewTimespec(mtime)
+	stat.Atim = stat.Mtim
+	stat.Ctim = fuse.NewTimespec(ctime)
+	stat.Birthtim = stat.Ctim
 }
 
 var _ fuse.FileSystemInterface = (*Fs)(nil)
@@ -0,0 +1,132 @@
+//go:build fuse
+
+package fuse
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers/local"
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	pkgerr "github.com/pkg/errors"
+	"github.com/winfsp/cgofuse/fuse"
+	"golang.org/x/time/rate"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestToErrno(t *testing.T) {
+	testCases := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{pkgerr.WithStack(errs.ObjectNotFound), -fuse.ENOENT},
+		{pkgerr.WithMessage(errs.PermissionDenied, "denied by acl"), -fuse.EACCES},
+		{errs.UploadNotSupported, -fuse.EROFS},
+		{errs.NotSupport, -fuse.ENOSYS},
+		{os.ErrExist, -fuse.EEXIST},
+		{pkgerr.New("unknown"), -fuse.EIO},
+	}
+	for _, tc := range testCases {
+		if got := toErrno(tc.err); got != tc.want {
+			t.Errorf("%v: got %d, want %d", tc.err, got, tc.want)
+		}
+	}
+}
+
+func TestUnlinkStaged(t *testing.T) {
+	conf.Conf = conf.DefaultConfig(t.TempDir())
+	conf.Conf.TempDir = t.TempDir()
+	stream.ClientUploadLimit = rate.NewLimiter(rate.Inf, 0)
+	f := NewFs("/", &model.User{Role: model.ADMIN})
+	f.Init()
+	ret, fh := f.Create("/new.txt", fuse.O_WRONLY, 0o644)
+	if ret != 0 {
+		t.Fatalf("create: got %d", ret)
+	}
+	if n := f.Write("/new.txt", []byte("hello"), 0, fh); n != 5 {
+		t.Fatalf("write: got %d", n)
+	}
+	tmp := f.getHandle(fh).tmp.Name()
+	if ret = f.Unlink("/new.txt"); ret != 0 {
+		t.Fatalf("unlink: got %d", ret)
+	}
+	if _, err := os.Stat(tmp); !os.IsNotExist(err) {
+		t.Errorf("the staged file is kept after unlink: %v", err)
+	}
+	if f.isStaged("/new.txt") {
+		t.Errorf("the unlinked file is still listed")
+	}
+	// the handle is still writable, but nothing is uploaded on release
+	if n := f.Write("/new.txt", []byte("world"), 5, fh); n != 5 {
+		t.Errorf("write after unlink: got %d", n)
+	}
+	if ret = f.Release("/new.txt", fh); ret != 0 {
+		t.Errorf("release: got %d", ret)
+	}
+}
+
+func TestRename(t *testing.T) {
+	conf.Conf = conf.DefaultConfig(t.TempDir())
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	db.Init(dB)
+	root := t.TempDir()
+	for name, content := range map[string]string{"a.txt": "a", "b.txt": "b", "x.txt": "x", "dir/x.txt": "dir x"} {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, mp := range []string{"/rename", "/other"} {
+		_, err = op.CreateStorage(context.Background(), model.Storage{
+			Driver:    "Local",
+			MountPath: mp,
+			Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, root),
+		})
+		if err != nil {
+			t.Fatalf("failed to create storage: %+v", err)
+		}
+	}
+	f := NewFs("/", &model.User{Role: model.ADMIN})
+	f.Init()
+	read := func(name string) string {
+		b, _ := os.ReadFile(filepath.Join(root, name))
+		return string(b)
+	}
+
+	// dst is replaced
+	if ret := f.Rename("/rename/a.txt", "/rename/b.txt"); ret != 0 {
+		t.Fatalf("rename over a file: got %d", ret)
+	}
+	if got := read("b.txt"); got != "a" {
+		t.Errorf("replaced file: got %q", got)
+	}
+	// the file in dstDir named as the source is kept
+	if ret := f.Rename("/rename/dir/x.txt", "/rename/y.txt"); ret != 0 {
+		t.Fatalf("rename to another dir: got %d", ret)
+	}
+	if got := read("y.txt"); got != "dir x" {
+		t.Errorf("moved file: got %q", got)
+	}
+	if got := read("x.txt"); got != "x" {
+		t.Errorf("unrelated file: got %q", got)
+	}
+	if ret := f.Rename("/rename/y.txt", "/other/y.txt"); ret != -fuse.EXDEV {
+		t.Errorf("rename across storages: got %d, want %d", ret, -fuse.EXDEV)
+	}
+}
@@ -0,0 +1,174 @@
+//go:build fuse
+
+package fuse
+
+import (
+	"context"
+	"io"
+	"os"
+	stdpath "path"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// handle is an opened file. Reads go through the storage link with range
+// requests, while writes are staged into a local temp file and uploaded
+// when the handle is flushed or released.
+type handle struct {
+	mu   sync.Mutex
+	path string
+	obj  model.Obj
+
+	// read side, created lazily on first Read
+	reader model.File
+	ss     *stream.SeekableStream
+
+	// write side, nil for read-only handles
+	tmp   *os.File
+	dirty bool
+	// unlinked is set when the staged file is unlinked before the upload
+	unlinked bool
+}
+
+func (h *handle) writable() bool {
+	return h.tmp != nil
+}
+
+func (h *handle) size() int64 {
+	if h.tmp != nil {
+		if info, err := h.tmp.Stat(); err == nil {
+			return info.Size()
+		}
+	}
+	if h.obj != nil {
+		return h.obj.GetSize()
+	}
+	return 0
+}
+
+func (h *handle) openReader(ctx context.Context) error {
+	if h.reader != nil {
+		return nil
+	}
+	link, obj, err := fs.Link(ctx, h.path, model.LinkArgs{})
+	if err != nil {
+		return err
+	}
+	ss, err := stream.NewSeekableStream(&stream.FileStream{
+		Obj: obj,
+		Ctx: ctx,
+	}, link)
+	if err != nil {
+		_ = link.Close()
+		return err
+	}
+	reader, err := stream.NewReadAtSeeker(ss, 0)
+	if err != nil {
+		_ = ss.Close()
+		return err
+	}
+	h.ss = ss
+	h.reader = reader
+	return nil
+}
+
+func (h *handle) readAt(ctx context.Context, p []byte, off int64) (int, error) {
+	if h.tmp != nil {
+		return h.tmp.ReadAt(p, off)
+	}
+	if off >= h.size() {
+		return 0, io.EOF
+	}
+	if err := h.openReader(ctx); err != nil {
+		return 0, err
+	}
+	n, err := h.reader.ReadAt(p, off)
+	if n > 0 {
+		if e := stream.ClientDownloadLimit.WaitN(ctx, n); e != nil {
+			return n, e
+		}
+	}
+	return n, err
+}
+
+// stage creates the local temp file for writing. When fill is true,
+// the current content of the object is downloaded into it first so
+// that partial writes keep the rest of the file intact.
+func (h *handle) stage(ctx context.Context, fill bool) error {
+	tmp, err := os.CreateTemp(conf.Conf.TempDir, "fuse-*")
+	if err != nil {
+		return err
+	}
+	if fill && h.obj != nil && h.obj.GetSize() > 0 {
+		if err = h.openReader(ctx); err == nil {
+			_, err = utils.CopyWithBuffer(tmp, io.NewSectionReader(h.reader, 0, h.obj.GetSize()))
+		}
+		h.closeReader()
+		if err != nil {
+			_ = tmp.Close()
+			_ = os.Remove(tmp.Name())
+			return errors.WithMessage(err, "failed fill staging file")
+		}
+	}
+	h.tmp = tmp
+	return nil
+}
+
+// flush uploads the staged content if it has changed since last flush.
+func (h *handle) flush(ctx context.Context) error {
+	if h.tmp == nil || !h.dirty || h.unlinked {
+		return nil
+	}
+	size := h.size()
+	dir, name := stdpath.Split(h.path)
+	s := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     name,
+			Size:     size,
+			Modified: time.Now(),
+		},
+		Reader:   io.NewSectionReader(h.tmp, 0, size),
+		Mimetype: utils.GetMimeType(name),
+	}
+	if err := fs.PutDirectly(ctx, dir, s); err != nil {
+		return err
+	}
+	h.dirty = false
+	h.obj = s.Obj
+	return nil
+}
+
+// discard deletes the staged file, which is never uploaded then. The temp
+// file is removed at once, an open handle keeps reading and writing it by
+// the descriptor where the os allows.
+func (h *handle) discard() {
+	h.dirty = false
+	h.unlinked = true
+	if h.tmp != nil {
+		_ = os.Remove(h.tmp.Name())
+	}
+}
+
+func (h *handle) closeReader() {
+	if h.ss != nil {
+		_ = h.ss.Close()
+	}
+	h.ss = nil
+	h.reader = nil
+}
+
+func (h *handle) close() {
+	h.closeReader()
+	if h.tmp != nil {
+		_ = h.tmp.Close()
+		_ = os.Remove(h.tmp.Name())
+		h.tmp = nil
+	}
+}
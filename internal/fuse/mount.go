@@ -1,9 +1,43 @@
+//go:build fuse
+
 package fuse
 
-import "github.com/winfsp/cgofuse/fuse"
+import (
+	"context"
+	"fmt"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	"github.com/winfsp/cgofuse/fuse"
+)
 
-func Mount(mountSrc, mountDst string, opts []string) {
-	fs := &Fs{RootFolder: mountSrc}
+// Mount mounts mountSrc of the virtual tree at mountDst, and blocks until
+// ctx is canceled or the filesystem is unmounted from outside.
+func Mount(ctx context.Context, mountSrc, mountDst string, opts []string) error {
+	admin, err := op.GetAdmin()
+	if err != nil {
+		return errors.WithMessage(err, "failed get admin user")
+	}
+	fs := NewFs(mountSrc, admin)
 	host := fuse.NewFileSystemHost(fs)
-	go host.Mount(mountDst, opts)
+	host.SetCapReaddirPlus(true)
+	done := make(chan bool, 1)
+	go func() {
+		done <- host.Mount(mountDst, opts)
+	}()
+	select {
+	case ok := <-done:
+		if !ok {
+			return fmt.Errorf("failed mount %s to %s", mountSrc, mountDst)
+		}
+		return nil
+	case <-ctx.Done():
+		utils.Log.Infof("unmounting %s", mountDst)
+		if !host.Unmount() {
+			return fmt.Errorf("failed unmount %s", mountDst)
+		}
+		<-done
+		return nil
+	}
 }
@@ -0,0 +1,106 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const namespace = "openlist"
+
+// Registry holds all the collectors exported on the metrics endpoint
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "Total number of HTTP requests by route and status code.",
+	}, []string{"method", "route", "code"})
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Duration of HTTP requests by route.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	driverDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "driver",
+		Name:      "call_duration_seconds",
+		Help:      "Duration of storage driver calls by storage and operation.",
+		Buckets:   []float64{.01, .05, .1, .25, .5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"storage", "driver", "op"})
+	driverErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "driver",
+		Name:      "call_errors_total",
+		Help:      "Total number of failed storage driver calls by storage and operation.",
+	}, []string{"storage", "driver", "op"})
+
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "cache",
+		Name:      "requests_total",
+		Help:      "Total number of cache lookups by cache and result (hit or miss).",
+	}, []string{"cache", "result"})
+
+	limiterBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "stream",
+		Name:      "limiter_bytes_total",
+		Help:      "Total number of bytes passed through the stream rate limiters.",
+	}, []string{"limiter"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		driverDuration,
+		driverErrors,
+		cacheRequests,
+		limiterBytes,
+		tasks,
+	)
+}
+
+// ObserveHTTPRequest records a finished HTTP request
+func ObserveHTTPRequest(method, route, code string, start time.Time) {
+	httpRequests.WithLabelValues(method, route, code).Inc()
+	httpDuration.WithLabelValues(method, route).Observe(time.Since(start).Seconds())
+}
+
+// ObserveDriverCall records a finished driver call of the storage mounted at mountPath
+func ObserveDriverCall(mountPath, driver, op string, start time.Time, err error) {
+	driverDuration.WithLabelValues(mountPath, driver, op).Observe(time.Since(start).Seconds())
+	if err != nil {
+		driverErrors.WithLabelValues(mountPath, driver, op).Inc()
+	}
+}
+
+// DeleteStorage removes the driver metrics of the storage mounted at mountPath
+func DeleteStorage(mountPath string) {
+	labels := prometheus.Labels{"storage": mountPath}
+	driverDuration.DeletePartialMatch(labels)
+	driverErrors.DeletePartialMatch(labels)
+}
+
+// CacheLookup records a lookup of the named cache
+func CacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	cacheRequests.WithLabelValues(cache, result).Inc()
+}
+
+// LimiterBytes returns the counter of bytes passed through the named limiter
+func LimiterBytes(limiter string) prometheus.Counter {
+	return limiterBytes.WithLabelValues(limiter)
+}
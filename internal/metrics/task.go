@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"sync"
+
+	"github.com/OpenListTeam/tache"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var stateNames = map[tache.State]string{
+	tache.StatePending:      "pending",
+	tache.StateRunning:      "running",
+	tache.StateSucceeded:    "succeeded",
+	tache.StateCanceling:    "canceling",
+	tache.StateCanceled:     "canceled",
+	tache.StateErrored:      "errored",
+	tache.StateFailing:      "failing",
+	tache.StateFailed:       "failed",
+	tache.StateWaitingRetry: "waiting_retry",
+	tache.StateBeforeRetry:  "before_retry",
+}
+
+var tasks = &taskCollector{
+	desc: prometheus.NewDesc(
+		prometheus.BuildFQName(namespace, "task", "count"),
+		"Number of tasks held by the task managers by state.",
+		[]string{"manager", "state"}, nil,
+	),
+}
+
+// taskCollector counts the tasks of the registered managers on every scrape,
+// so that the numbers are always consistent with the task api.
+type taskCollector struct {
+	desc     *prometheus.Desc
+	mu       sync.RWMutex
+	names    []string
+	managers map[string]func() []tache.State
+}
+
+func (c *taskCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *taskCollector) Collect(ch chan<- prometheus.Metric) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, name := range c.names {
+		counts := make(map[tache.State]int, len(stateNames))
+		for _, state := range c.managers[name]() {
+			counts[state]++
+		}
+		for state, stateName := range stateNames {
+			ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(counts[state]), name, stateName)
+		}
+	}
+}
+
+// RegisterTaskManager exports the task states of the manager under the given name,
+// registering the same name again replaces the previous manager.
+func RegisterTaskManager[T interface{ GetState() tache.State }](name string, manager interface{ GetAll() []T }) {
+	tasks.mu.Lock()
+	defer tasks.mu.Unlock()
+	if tasks.managers == nil {
+		tasks.managers = make(map[string]func() []tache.State)
+	}
+	if _, ok := tasks.managers[name]; !ok {
+		tasks.names = append(tasks.names, name)
+	}
+	tasks.managers[name] = func() []tache.State {
+		all := manager.GetAll()
+		states := make([]tache.State, len(all))
+		for i, t := range all {
+			states[i] = t.GetState()
+		}
+		return states
+	}
+}
@@ -0,0 +1,56 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/OpenListTeam/tache"
+)
+
+type testTask struct {
+	tache.Base
+}
+
+type testManager []*testTask
+
+func (m testManager) GetAll() []*testTask {
+	return m
+}
+
+func TestTaskCollector(t *testing.T) {
+	running := &testTask{}
+	running.SetState(tache.StateRunning)
+	failed := &testTask{}
+	failed.SetState(tache.StateFailed)
+	RegisterTaskManager("test", testManager{running, failed, &testTask{}})
+
+	families, err := Registry.Gather()
+	if err != nil {
+		t.Fatalf("failed gather: %v", err)
+	}
+	got := make(map[string]float64)
+	for _, f := range families {
+		if f.GetName() != "openlist_task_count" {
+			continue
+		}
+		for _, m := range f.GetMetric() {
+			var manager, state string
+			for _, l := range m.GetLabel() {
+				switch l.GetName() {
+				case "manager":
+					manager = l.GetValue()
+				case "state":
+					state = l.GetValue()
+				}
+			}
+			if manager == "test" {
+				got[state] = m.GetGauge().GetValue()
+			}
+		}
+	}
+	want := map[string]float64{"pending": 1, "running": 1, "failed": 1, "succeeded": 0}
+	for state, n := range want {
+		if got[state] != n {
+			t.Errorf("state %s: got %v, want %v", state, got[state], n)
+		}
+	}
+}
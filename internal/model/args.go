@@ -79,6 +79,11 @@ type ArchiveDecompressArgs struct {
 	PutIntoNewDir bool
 }
 
+type ArchiveCompressArgs struct {
+	// Password encrypts the content, only zip supports it
+	Password string
+}
+
 type SharingListArgs struct {
 	Refresh bool
 	Pwd     string
@@ -0,0 +1,12 @@
+package model
+
+// DeadProp is a WebDAV dead property of the object at Path,
+// Path is the full path in the virtual tree.
+type DeadProp struct {
+	ID       uint   `json:"id" gorm:"primaryKey"`
+	Path     string `json:"path" gorm:"size:767;index"`
+	Space    string `json:"space"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	InnerXML string `json:"inner_xml" gorm:"type:text"`
+}
@@ -0,0 +1,20 @@
+package model
+
+// Group is a named set of permissions shared by its members
+type Group struct {
+	ID          uint   `json:"id" gorm:"primaryKey"`
+	Name        string `json:"name" gorm:"unique" binding:"required"`
+	Description string `json:"description"`
+	// BasePath is used by the members without their own base path
+	BasePath string `json:"base_path"`
+	// Permission has the same bits as User.Permission
+	Permission int32 `json:"permission"`
+	// Quota is used by the members without their own quota, 0 means unlimited
+	Quota int64 `json:"quota"`
+}
+
+// UserGroup is the membership of a user in a group
+type UserGroup struct {
+	UserID  uint `gorm:"primaryKey;autoIncrement:false"`
+	GroupID uint `gorm:"primaryKey;autoIncrement:false;index"`
+}
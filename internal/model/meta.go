@@ -1,5 +1,7 @@
 package model
 
+import "strings"
+
 type Meta struct {
 	ID        uint   `json:"id" gorm:"primaryKey"`
 	Path      string `json:"path" gorm:"unique" binding:"required"`
@@ -13,4 +15,87 @@ type Meta struct {
 	RSub      bool   `json:"r_sub"`
 	Header    string `json:"header"`
 	HeaderSub bool   `json:"header_sub"`
+	// ACL narrows the global permissions of the users on the path
+	ACL    []MetaACL `json:"acl" gorm:"foreignKey:MetaID"`
+	ACLSub bool      `json:"acl_sub"`
+}
+
+const (
+	ACLRead    = "read"
+	ACLWrite   = "write"
+	ACLDelete  = "delete"
+	ACLShare   = "share"
+	ACLArchive = "archive"
+)
+
+var ACLActions = []string{ACLRead, ACLWrite, ACLDelete, ACLShare, ACLArchive}
+
+const (
+	ACLSubjectUser     = "user"
+	ACLSubjectGroup    = "group"
+	ACLSubjectEveryone = "everyone"
+)
+
+// MetaACL is an access control entry of a meta
+type MetaACL struct {
+	ID     uint `json:"id" gorm:"primaryKey"`
+	MetaID uint `json:"meta_id" gorm:"index"`
+	// Subject is one of user, group and everyone,
+	// SubjectID is the id of the user or group
+	Subject   string `json:"subject"`
+	SubjectID uint   `json:"subject_id"`
+	Deny      bool   `json:"deny"`
+	// Actions is comma separated, see ACLActions
+	Actions string `json:"actions"`
+}
+
+func (a *MetaACL) HasAction(action string) bool {
+	for _, act := range strings.Split(a.Actions, ",") {
+		if strings.TrimSpace(act) == action {
+			return true
+		}
+	}
+	return false
+}
+
+// specificity returns how specific the entry is for the user, 0 if it doesn't apply
+func (a *MetaACL) specificity(u *User) int {
+	switch a.Subject {
+	case ACLSubjectUser:
+		if a.SubjectID == u.ID {
+			return 3
+		}
+	case ACLSubjectGroup:
+		for _, g := range u.Groups {
+			if g.ID == a.SubjectID {
+				return 2
+			}
+		}
+	case ACLSubjectEveryone:
+		return 1
+	}
+	return 0
+}
+
+// CheckACL decides whether the entries allow the user to perform the action.
+// The entries of the most specific subject win, user over group over everyone,
+// and deny wins over allow at the same level. ok is false if no entry matches.
+func (m *Meta) CheckACL(u *User, action string) (allow, ok bool) {
+	level := 0
+	for i := range m.ACL {
+		a := &m.ACL[i]
+		if !a.HasAction(action) {
+			continue
+		}
+		l := a.specificity(u)
+		if l == 0 || l < level {
+			continue
+		}
+		if l > level {
+			level, allow = l, !a.Deny
+		} else if a.Deny {
+			allow = false
+		}
+	}
+	return allow, level > 0
 }
@@ -0,0 +1,66 @@
+package model
+
+import "time"
+
+// the actions of the pipeline steps
+const (
+	PipelineOfflineDownload = "offline_download"
+	PipelineDecompress      = "decompress"
+	PipelineCopy            = "copy"
+	PipelineMove            = "move"
+	PipelineRemove          = "remove"
+)
+
+// the failure policies of the pipeline steps
+const (
+	// PipelineAbort stops the pipeline when the step fails, it's the default
+	PipelineAbort = "abort"
+	// PipelineContinue goes on with the steps not depending on the failed one
+	PipelineContinue = "continue"
+)
+
+type PipelineStep struct {
+	// Name is shown in the status of the pipeline, empty means the action
+	Name   string `json:"name"`
+	Action string `json:"action"`
+	// SrcPath and DstPath are relative to the base path of the user,
+	// DstPath is the folder the objects are put into
+	SrcPath string `json:"src_path"`
+	DstPath string `json:"dst_path"`
+	// for offline_download
+	URL          string `json:"url"`
+	Tool         string `json:"tool"`
+	DeletePolicy string `json:"delete_policy"`
+	// for decompress
+	ArchivePass   string `json:"archive_pass"`
+	InnerPath     string `json:"inner_path"`
+	CacheFull     bool   `json:"cache_full"`
+	PutIntoNewDir bool   `json:"put_into_new_dir"`
+	// DependsOn is the indexes of the earlier steps which must succeed before
+	// the step runs, null means the previous step and [] means no dependency
+	DependsOn []int  `json:"depends_on"`
+	OnFailure string `json:"on_failure"`
+	// Retry is the times to run the failed step again
+	Retry int `json:"retry"`
+}
+
+// Deps returns the indexes of the steps the i-th step depends on
+func (s PipelineStep) Deps(i int) []int {
+	if s.DependsOn == nil && i > 0 {
+		return []int{i - 1}
+	}
+	return s.DependsOn
+}
+
+type Pipeline struct {
+	ID     uint           `json:"id" gorm:"primaryKey"`
+	Name   string         `json:"name" binding:"required"`
+	UserID uint           `json:"user_id" gorm:"index"`
+	Steps  []PipelineStep `json:"steps" gorm:"serializer:json;type:text"`
+	// Cron is the cron expression to run the pipeline on schedule,
+	// e.g. "0 3 * * *", empty means it only runs manually
+	Cron        string     `json:"cron"`
+	Disabled    bool       `json:"disabled"`
+	LastRunTime *time.Time `json:"last_run_time"`
+	Modified    time.Time  `json:"modified"`
+}
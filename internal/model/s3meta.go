@@ -0,0 +1,10 @@
+package model
+
+// S3ObjectMeta is the metadata of the object at Path set by the S3 clients,
+// e.g. Content-Type and the X-Amz-Meta-* headers. Path is the full path in
+// the virtual tree.
+type S3ObjectMeta struct {
+	ID   uint              `json:"id" gorm:"primaryKey"`
+	Path string            `json:"path" gorm:"size:767;index"`
+	Meta map[string]string `json:"meta" gorm:"serializer:json;type:text"`
+}
@@ -2,6 +2,7 @@ package model
 
 import (
 	"fmt"
+	"strings"
 	"time"
 )
 
@@ -12,19 +13,69 @@ type IndexProgress struct {
 	Error        string     `json:"error"`
 }
 
+// IndexJob is the progress of the scheduled index job of a storage
+type IndexJob struct {
+	StorageID    uint       `json:"storage_id" gorm:"primaryKey;autoIncrement:false"`
+	Running      bool       `json:"running"`
+	ObjCount     uint64     `json:"obj_count"`
+	Added        uint64     `json:"added"`
+	Updated      uint64     `json:"updated"`
+	Removed      uint64     `json:"removed"`
+	StartTime    *time.Time `json:"start_time"`
+	LastDoneTime *time.Time `json:"last_done_time"`
+	Error        string     `json:"error"`
+}
+
 type SearchReq struct {
-	Parent   string `json:"parent"`
-	Keywords string `json:"keywords"`
+	Parent string `json:"parent"`
+	// Parents limits the search to any of the dirs instead of Parent,
+	// they are the roots of the user searching the virtual root
+	Parents  []string `json:"-"`
+	Keywords string   `json:"keywords"`
 	// 0 for all, 1 for dir, 2 for file
 	Scope int `json:"scope"`
+	SearchFilter
 	PageReq
 }
 
+// SearchFilter narrows the search results, the zero values mean no limit
+type SearchFilter struct {
+	MinSize        int64     `json:"min_size"`
+	MaxSize        int64     `json:"max_size"`
+	ModifiedAfter  time.Time `json:"modified_after"`
+	ModifiedBefore time.Time `json:"modified_before"`
+	// Exts are the extensions without dot, matched case-insensitively
+	Exts []string `json:"exts"`
+	// Types are the categories of conf.FOLDER, conf.VIDEO etc.
+	Types []int  `json:"types"`
+	Hash  string `json:"hash"`
+}
+
 type SearchNode struct {
-	Parent string `json:"parent" gorm:"index"`
-	Name   string `json:"name"`
-	IsDir  bool   `json:"is_dir"`
-	Size   int64  `json:"size"`
+	Parent   string    `json:"parent" gorm:"index"`
+	Name     string    `json:"name"`
+	IsDir    bool      `json:"is_dir"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+	// Hash is the content hash given by the storage, md5 preferred
+	Hash string `json:"hash" gorm:"index"`
+	// Type is the category by the extension, the same as the type of objs
+	Type int `json:"type"`
+	// Ext is the lower case extension without dot
+	Ext string `json:"ext"`
+	// Content is the text extracted from the file, only filled for the full-text searchers
+	Content string `json:"content,omitempty"`
+	// Snippet is the escaped html fragment of the content matching the keywords,
+	// highlighted with <mark>
+	Snippet string `json:"snippet,omitempty" gorm:"-"`
+}
+
+// ParentDirs returns the dirs the search is limited to, "/" means all
+func (p *SearchReq) ParentDirs() []string {
+	if len(p.Parents) > 0 {
+		return p.Parents
+	}
+	return []string{p.Parent}
 }
 
 func (p *SearchReq) Validate() error {
@@ -34,9 +85,20 @@ func (p *SearchReq) Validate() error {
 	if p.PerPage < 1 {
 		return fmt.Errorf("per_page can't < 1")
 	}
+	if p.MaxSize > 0 && p.MinSize > p.MaxSize {
+		return fmt.Errorf("min_size can't > max_size")
+	}
+	if !p.ModifiedBefore.IsZero() && p.ModifiedAfter.After(p.ModifiedBefore) {
+		return fmt.Errorf("modified_after can't > modified_before")
+	}
+	for i := range p.Exts {
+		p.Exts[i] = strings.ToLower(strings.TrimPrefix(p.Exts[i], "."))
+	}
+	p.Hash = strings.ToLower(p.Hash)
 	return nil
 }
 
-func (s *SearchNode) Type() string {
+// BleveType is the document type of the search node in the bleve index
+func (s *SearchNode) BleveType() string {
 	return "SearchNode"
 }
@@ -1,6 +1,11 @@
 package model
 
-import "time"
+import (
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+)
 
 type SharingDB struct {
 	ID          string     `json:"id" gorm:"type:char(12);primaryKey"`
@@ -14,6 +19,20 @@ type SharingDB struct {
 	Remark      string     `json:"remark"`
 	Readme      string     `json:"readme" gorm:"type:text"`
 	Header      string     `json:"header" gorm:"type:text"`
+	// Upload makes the sharing a drop folder the visitors can put files into,
+	// with the permissions of the creator. It shares exactly one folder.
+	Upload bool `json:"upload"`
+	// UploadMaxSize is the max size of each file, 0 means unlimited
+	UploadMaxSize int64 `json:"upload_max_size"`
+	// UploadExts are the allowed extensions separated by commas, empty
+	// means any
+	UploadExts string `json:"upload_exts"`
+	// UploadNoList hides the files of the drop folder from the visitors
+	UploadNoList bool `json:"upload_no_list"`
+	// UploadQuota is the max total size of the uploaded files, 0 means unlimited
+	UploadQuota int64 `json:"upload_quota"`
+	// Uploaded is the total size uploaded, only counted by the uploads
+	Uploaded int64 `json:"uploaded"`
 	Sort
 }
 
@@ -45,3 +64,40 @@ func (s *Sharing) Valid() bool {
 func (s *Sharing) Verify(pwd string) bool {
 	return s.Pwd == "" || s.Pwd == pwd
 }
+
+// CanList reports whether the visitors can see the files of the sharing
+func (s *Sharing) CanList() bool {
+	return !s.Upload || !s.UploadNoList
+}
+
+// CheckUpload checks whether a file of the name and size can be put into the
+// drop folder, the size must be known to check the limits
+func (s *Sharing) CheckUpload(name string, size int64) error {
+	if !s.Upload {
+		return errs.SharingUploadNotAllowed
+	}
+	if size < 0 {
+		return errs.NewErr(errs.SharingUploadRejected, "the file size is unknown")
+	}
+	if s.UploadMaxSize > 0 && size > s.UploadMaxSize {
+		return errs.NewErr(errs.SharingUploadRejected, "the file is larger than %d bytes", s.UploadMaxSize)
+	}
+	if s.UploadExts != "" {
+		allowed := false
+		lowerName := strings.ToLower(name)
+		for _, ext := range strings.Split(s.UploadExts, ",") {
+			ext = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), ".")
+			if ext != "" && strings.HasSuffix(lowerName, "."+ext) {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return errs.NewErr(errs.SharingUploadRejected, "the extension of [%s] is not allowed", name)
+		}
+	}
+	if s.UploadQuota > 0 && s.Uploaded+size > s.UploadQuota {
+		return errs.NewErr(errs.QuotaExceeded, "%d of %d bytes uploaded to the sharing", s.Uploaded, s.UploadQuota)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// the actions recorded in the access logs of the sharings
+const (
+	SharingActionList     = "list"
+	SharingActionGet      = "get"
+	SharingActionDownload = "download"
+	SharingActionArchive  = "archive"
+	SharingActionUpload   = "upload"
+)
+
+// SharingAccessLog is an access to a sharing. Path is the path inside the
+// sharing, and Bytes is the size served by the server, which is 0 for the
+// redirected downloads, or received for the uploads.
+type SharingAccessLog struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	SharingID string    `json:"sharing_id" gorm:"type:char(12);index"`
+	Time      time.Time `json:"time" gorm:"index"`
+	IP        string    `json:"ip" gorm:"size:64"`
+	UserAgent string    `json:"user_agent" gorm:"size:512"`
+	Action    string    `json:"action" gorm:"size:16"`
+	Path      string    `json:"path" gorm:"type:text"`
+	Bytes     int64     `json:"bytes"`
+}
+
+// SharingAccessStats sums up the access logs of a sharing
+type SharingAccessStats struct {
+	Total     int64            `json:"total"`
+	UniqueIPs int64            `json:"unique_ips"`
+	Bytes     int64            `json:"bytes"`
+	Actions   map[string]int64 `json:"actions"`
+}
@@ -0,0 +1,47 @@
+package model
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+)
+
+func TestSharingCheckUpload(t *testing.T) {
+	s := &Sharing{SharingDB: &SharingDB{
+		Upload:        true,
+		UploadMaxSize: 100,
+		UploadExts:    " .PDF, tar.gz ,",
+		UploadQuota:   300,
+		Uploaded:      250,
+	}}
+	testCases := []struct {
+		name string
+		size int64
+		err  error
+	}{
+		{"report.pdf", 10, nil},
+		{"backup.TAR.GZ", 50, nil},
+		{"report.pdf", -1, errs.SharingUploadRejected},
+		{"report.pdf", 101, errs.SharingUploadRejected},
+		{"image.png", 10, errs.SharingUploadRejected},
+		{"gz", 10, errs.SharingUploadRejected},
+		{"report.pdf", 51, errs.QuotaExceeded},
+	}
+	for _, tc := range testCases {
+		if err := s.CheckUpload(tc.name, tc.size); !errors.Is(err, tc.err) {
+			t.Errorf("%s of %d: got %v, want %v", tc.name, tc.size, err, tc.err)
+		}
+	}
+	s.Upload = false
+	if err := s.CheckUpload("report.pdf", 10); !errors.Is(err, errs.SharingUploadNotAllowed) {
+		t.Errorf("not a drop folder: got %v", err)
+	}
+	if !s.CanList() {
+		t.Errorf("the files of a sharing without upload must be listed")
+	}
+	s.Upload, s.UploadNoList = true, true
+	if s.CanList() {
+		t.Errorf("the files of a drop folder without listing must not be listed")
+	}
+}
@@ -17,8 +17,10 @@ type Storage struct {
 	Disabled        bool      `json:"disabled"` // if disabled
 	DisableIndex    bool      `json:"disable_index"`
 	EnableSign      bool      `json:"enable_sign"`
+	EnableTrash     bool      `json:"enable_trash"` // move to the trash instead of removing
 	Sort
 	Proxy
+	IndexSchedule
 }
 
 type Sort struct {
@@ -27,6 +29,16 @@ type Sort struct {
 	ExtractFolder  string `json:"extract_folder"`
 }
 
+// IndexSchedule is the scheduled incremental index job of the storage
+type IndexSchedule struct {
+	// cron expression, empty to disable
+	IndexCron string `json:"index_cron"`
+	// 0 to use the max index depth setting
+	IndexDepth int `json:"index_depth"`
+	// paths relative to the mount path, one per line
+	IndexIgnore string `json:"index_ignore" gorm:"type:text"`
+}
+
 type Proxy struct {
 	WebProxy     bool   `json:"web_proxy"`
 	WebdavPolicy string `json:"webdav_policy"`
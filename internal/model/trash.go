@@ -0,0 +1,34 @@
+package model
+
+import (
+	"fmt"
+	stdpath "path"
+	"time"
+)
+
+// TrashDirName is the folder holding the trashed objects at the root of
+// each storage having the trash enabled
+const TrashDirName = ".openlist_trash"
+
+// TrashItem records an object moved into the trash of its storage
+type TrashItem struct {
+	ID        uint   `json:"id" gorm:"primaryKey"`
+	StorageID uint   `json:"storage_id" gorm:"index"`
+	Path      string `json:"path"` // original full path in the virtual tree
+	Size      int64  `json:"size"`
+	IsDir     bool   `json:"is_dir"`
+	DeleterID uint   `json:"deleter_id" gorm:"index"`
+	Deleter   string `json:"deleter"`
+	// DeletedAt is not a gorm.DeletedAt, the trashed items are not soft deleted rows
+	DeletedAt time.Time `json:"deleted_at" gorm:"index"`
+}
+
+// Dir is the folder holding the object in the storage
+func (t *TrashItem) Dir() string {
+	return fmt.Sprintf("/%s/%d", TrashDirName, t.ID)
+}
+
+// ActualPath is the path of the trashed object in the storage
+func (t *TrashItem) ActualPath() string {
+	return stdpath.Join(t.Dir(), stdpath.Base(t.Path))
+}
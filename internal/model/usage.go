@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// PathUsage is the total size of the files under a path of the virtual tree,
+// tracked for the roots of the users having a quota
+type PathUsage struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Path string `json:"path" gorm:"unique"`
+	Used int64  `json:"used"`
+	// Dirty means Used can't be updated incrementally, e.g. a folder was
+	// removed, and needs to be reconciled by scanning the path
+	Dirty     bool      `json:"dirty"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
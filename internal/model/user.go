@@ -4,6 +4,8 @@ import (
 	"encoding/binary"
 	"encoding/json"
 	"fmt"
+	stdpath "path"
+	"strings"
 	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
@@ -36,10 +38,11 @@ type User struct {
 	PwdTS    int64  `json:"-"`                                         // password timestamp
 	Salt     string `json:"-"`                                         // unique salt
 	Password string `json:"password"`                                  // password
-	BasePath string `json:"base_path"`                                 // base path
+	BasePath string `json:"base_path"`                                 // base path, empty to use the groups'
 	Role     int    `json:"role"`                                      // user's role
 	Disabled bool   `json:"disabled"`
-	// Determine permissions by bit
+	// Determine permissions by bit, the effective permissions
+	// are the union of the user's own and its groups'
 	//   0:  can see hidden files
 	//   1:  can access without password
 	//   2:  can add offline download tasks
@@ -59,6 +62,15 @@ type User struct {
 	OtpSecret  string `json:"-"`
 	SsoID      string `json:"sso_id"` // unique by sso platform
 	Authn      string `gorm:"type:text" json:"-"`
+	// Quota is the max bytes stored under the user's roots, 0 to use the groups'
+	Quota int64 `json:"quota"`
+	// BasePaths are the roots besides BasePath, a user having more than one
+	// root sees a virtual root directory containing each of them
+	BasePaths []string `json:"base_paths" gorm:"serializer:json"`
+	// GroupIDs is the groups the user belongs to, nil means unchanged on update
+	GroupIDs []uint `json:"group_ids" gorm:"-"`
+	// Groups is loaded with the user to resolve the effective permissions
+	Groups []Group `json:"-" gorm:"-"`
 }
 
 func (u *User) IsGuest() bool {
@@ -90,68 +102,228 @@ func (u *User) SetPassword(pwd string) *User {
 	return u
 }
 
+// EffectivePermission is the union of the user's own permission and its groups'
+func (u *User) EffectivePermission() int32 {
+	permission := u.Permission
+	for _, g := range u.Groups {
+		permission |= g.Permission
+	}
+	return permission
+}
+
+// EffectiveQuota is the user's own quota, or the largest quota of its
+// groups if the user has none. 0 means unlimited.
+func (u *User) EffectiveQuota() int64 {
+	if u.Quota > 0 {
+		return u.Quota
+	}
+	var quota int64
+	for _, g := range u.Groups {
+		if g.Quota > quota {
+			quota = g.Quota
+		}
+	}
+	return quota
+}
+
+// EffectiveBasePaths are the user's own roots, or the union of its groups'
+// base paths if the user has none. The roots under another are dropped.
+// It's empty if none is left, e.g. the groups giving the roots are deleted,
+// and the user can access nothing then.
+func (u *User) EffectiveBasePaths() []string {
+	var paths []string
+	if u.BasePath != "" || len(u.BasePaths) > 0 {
+		paths = append(paths, u.BasePath)
+		paths = append(paths, u.BasePaths...)
+	} else {
+		for _, g := range u.Groups {
+			paths = append(paths, g.BasePath)
+		}
+	}
+	var roots []string
+	for _, p := range paths {
+		if p == "" {
+			continue
+		}
+		p = utils.FixAndCleanPath(p)
+		covered := false
+		for i := 0; i < len(roots); i++ {
+			if utils.IsSubPath(roots[i], p) {
+				covered = true
+				break
+			}
+			if utils.IsSubPath(p, roots[i]) {
+				roots = append(roots[:i], roots[i+1:]...)
+				i--
+			}
+		}
+		if !covered {
+			roots = append(roots, p)
+		}
+	}
+	return roots
+}
+
+// EffectiveBasePath is the only root of the user, "/" for the virtual root
+// or empty if the user has no root at all
+func (u *User) EffectiveBasePath() string {
+	switch roots := u.EffectiveBasePaths(); len(roots) {
+	case 0:
+		return ""
+	case 1:
+		return roots[0]
+	}
+	return "/"
+}
+
+// VirtualRoot is an entry of the virtual root directory
+type VirtualRoot struct {
+	Name string
+	Path string
+}
+
+// VirtualRoots returns the entries of the virtual root directory named by
+// the base name of each root, or nil if the user has only one root
+func (u *User) VirtualRoots() []VirtualRoot {
+	roots := u.EffectiveBasePaths()
+	if len(roots) == 1 {
+		return nil
+	}
+	res := make([]VirtualRoot, 0, len(roots))
+	names := make(map[string]struct{}, len(roots))
+	for _, p := range roots {
+		name := stdpath.Base(p)
+		for i := 2; ; i++ {
+			if _, ok := names[name]; !ok {
+				break
+			}
+			name = fmt.Sprintf("%s_%d", stdpath.Base(p), i)
+		}
+		names[name] = struct{}{}
+		res = append(res, VirtualRoot{Name: name, Path: p})
+	}
+	return res
+}
+
+// IsVirtualRoot reports whether the request path is the virtual root of the user
+func (u *User) IsVirtualRoot(reqPath string) bool {
+	return utils.FixAndCleanPath(reqPath) == "/" && len(u.EffectiveBasePaths()) > 1
+}
+
+// InBasePaths reports whether the absolute path is under any root of the user
+func (u *User) InBasePaths(path string) bool {
+	for _, root := range u.EffectiveBasePaths() {
+		if utils.IsSubPath(root, path) {
+			return true
+		}
+	}
+	return false
+}
+
 func (u *User) CanSeeHides() bool {
-	return u.Permission&1 == 1
+	return u.EffectivePermission()&1 == 1
 }
 
 func (u *User) CanAccessWithoutPassword() bool {
-	return (u.Permission>>1)&1 == 1
+	return (u.EffectivePermission()>>1)&1 == 1
 }
 
 func (u *User) CanAddOfflineDownloadTasks() bool {
-	return (u.Permission>>2)&1 == 1
+	return (u.EffectivePermission()>>2)&1 == 1
 }
 
 func (u *User) CanWrite() bool {
-	return (u.Permission>>3)&1 == 1
+	return (u.EffectivePermission()>>3)&1 == 1
 }
 
 func (u *User) CanRename() bool {
-	return (u.Permission>>4)&1 == 1
+	return (u.EffectivePermission()>>4)&1 == 1
 }
 
 func (u *User) CanMove() bool {
-	return (u.Permission>>5)&1 == 1
+	return (u.EffectivePermission()>>5)&1 == 1
 }
 
 func (u *User) CanCopy() bool {
-	return (u.Permission>>6)&1 == 1
+	return (u.EffectivePermission()>>6)&1 == 1
 }
 
 func (u *User) CanRemove() bool {
-	return (u.Permission>>7)&1 == 1
+	return (u.EffectivePermission()>>7)&1 == 1
 }
 
 func (u *User) CanWebdavRead() bool {
-	return (u.Permission>>8)&1 == 1
+	return (u.EffectivePermission()>>8)&1 == 1
 }
 
 func (u *User) CanWebdavManage() bool {
-	return (u.Permission>>9)&1 == 1
+	return (u.EffectivePermission()>>9)&1 == 1
 }
 
 func (u *User) CanFTPAccess() bool {
-	return (u.Permission>>10)&1 == 1
+	return (u.EffectivePermission()>>10)&1 == 1
 }
 
 func (u *User) CanFTPManage() bool {
-	return (u.Permission>>11)&1 == 1
+	return (u.EffectivePermission()>>11)&1 == 1
 }
 
 func (u *User) CanReadArchives() bool {
-	return (u.Permission>>12)&1 == 1
+	return (u.EffectivePermission()>>12)&1 == 1
 }
 
 func (u *User) CanDecompress() bool {
-	return (u.Permission>>13)&1 == 1
+	return (u.EffectivePermission()>>13)&1 == 1
 }
 
 func (u *User) CanShare() bool {
-	return (u.Permission>>14)&1 == 1
+	return (u.EffectivePermission()>>14)&1 == 1
 }
 
+// JoinPath converts the request path of the user to the absolute path,
+// the first element selects the root if the user has the virtual root
 func (u *User) JoinPath(reqPath string) (string, error) {
-	return utils.JoinBasePath(u.BasePath, reqPath)
+	if len(u.EffectiveBasePaths()) == 0 {
+		return "", errors.WithStack(errs.PermissionDenied)
+	}
+	roots := u.VirtualRoots()
+	if roots == nil {
+		return utils.JoinBasePath(u.EffectiveBasePath(), reqPath)
+	}
+	reqPath, err := utils.JoinBasePath("/", reqPath)
+	if err != nil {
+		return "", err
+	}
+	if reqPath == "/" {
+		return "", errors.WithStack(errs.VirtualRoot)
+	}
+	name, rest, _ := strings.Cut(reqPath[1:], "/")
+	for _, r := range roots {
+		if r.Name == name {
+			return stdpath.Join(r.Path, rest), nil
+		}
+	}
+	return "", errors.WithStack(errs.ObjectNotFound)
+}
+
+// RelPath converts the absolute path to the request path of the user,
+// the reverse of JoinPath. ok is false if the path is under no root.
+func (u *User) RelPath(path string) (rel string, ok bool) {
+	path = utils.FixAndCleanPath(path)
+	roots := u.VirtualRoots()
+	if roots == nil {
+		root := u.EffectiveBasePath()
+		if !utils.IsSubPath(root, path) {
+			return "", false
+		}
+		return utils.FixAndCleanPath(strings.TrimPrefix(path, root)), true
+	}
+	for _, r := range roots {
+		if utils.IsSubPath(r.Path, path) {
+			return stdpath.Join("/", r.Name, strings.TrimPrefix(path, r.Path)), true
+		}
+	}
+	return "", false
 }
 
 func StaticHash(password string) string {
@@ -0,0 +1,71 @@
+package model
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+)
+
+func TestEffectiveBasePaths(t *testing.T) {
+	testCases := []struct {
+		user *User
+		want []string
+	}{
+		{&User{}, nil},
+		{&User{Groups: []Group{{}}}, nil},
+		{&User{BasePath: "/a"}, []string{"/a"}},
+		{&User{BasePath: "/a", BasePaths: []string{"/b", "/a/c"}}, []string{"/a", "/b"}},
+		{&User{BasePaths: []string{"/a/c", "/a"}}, []string{"/a"}},
+		{&User{BasePath: "/a", BasePaths: []string{"/"}}, []string{"/"}},
+		{&User{Groups: []Group{{BasePath: "/g1"}, {}, {BasePath: "/g2"}}}, []string{"/g1", "/g2"}},
+		{&User{BasePath: "/a", Groups: []Group{{BasePath: "/g1"}}}, []string{"/a"}},
+	}
+	for _, tc := range testCases {
+		if got := tc.user.EffectiveBasePaths(); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%+v: got %v, want %v", tc.user, got, tc.want)
+		}
+	}
+}
+
+func TestVirtualRootJoinPath(t *testing.T) {
+	u := &User{BasePath: "/projects/a", BasePaths: []string{"/shared/specs", "/old/specs"}}
+	if !u.IsVirtualRoot("") || u.IsVirtualRoot("/a") {
+		t.Errorf("unexpected virtual root")
+	}
+	testCases := []struct {
+		reqPath string
+		want    string
+		err     error
+	}{
+		{"/a/x.txt", "/projects/a/x.txt", nil},
+		{"/specs", "/shared/specs", nil},
+		{"/specs_2/y", "/old/specs/y", nil},
+		{"/", "", errs.VirtualRoot},
+		{"/b", "", errs.ObjectNotFound},
+		{"/a/../specs", "", errs.RelativePath},
+	}
+	for _, tc := range testCases {
+		got, err := u.JoinPath(tc.reqPath)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("JoinPath(%q): got error %v, want %v", tc.reqPath, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("JoinPath(%q): got %q, %v, want %q", tc.reqPath, got, err, tc.want)
+			continue
+		}
+		if rel, ok := u.RelPath(got); !ok || rel != tc.reqPath {
+			t.Errorf("RelPath(%q): got %q, want %q", got, rel, tc.reqPath)
+		}
+	}
+	if _, ok := u.RelPath("/projects"); ok {
+		t.Errorf("RelPath of a path out of the roots")
+	}
+	if !u.InBasePaths("/shared/specs/z") || u.InBasePaths("/shared/specsz") {
+		t.Errorf("unexpected InBasePaths")
+	}
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// WebdavLock is a WebDAV lock persisted in the database, so that the locks
+// survive restarts and can be shared between instances using the same db.
+type WebdavLock struct {
+	Token     string    `json:"token" gorm:"primaryKey;size:64"`
+	Root      string    `json:"root" gorm:"size:767;uniqueIndex"`
+	ZeroDepth bool      `json:"zero_depth"`
+	OwnerXML  string    `json:"owner_xml" gorm:"type:text"`
+	Duration  int64     `json:"duration"` // in seconds, negative means infinite
+	ExpiresAt time.Time `json:"expires_at" gorm:"index"`
+}
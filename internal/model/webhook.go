@@ -0,0 +1,53 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+type Webhook struct {
+	ID   uint   `json:"id" gorm:"primaryKey"`
+	Name string `json:"name"`
+	URL  string `json:"url" binding:"required"`
+	// Secret signs the payloads with pkg/sign, empty means unsigned.
+	// It's never sent back, HasSecret tells whether it's set
+	Secret    string `json:"-"`
+	HasSecret bool   `json:"has_secret" gorm:"-"`
+	// Events is the comma separated event types to deliver, e.g. "fs.put,task.*",
+	// empty means all events
+	Events   string    `json:"events"`
+	MaxRetry int       `json:"max_retry"`
+	Disabled bool      `json:"disabled"`
+	Modified time.Time `json:"modified"`
+}
+
+// Match reports whether the event type should be delivered to the webhook
+func (w *Webhook) Match(typ string) bool {
+	if strings.TrimSpace(w.Events) == "" {
+		return true
+	}
+	for _, e := range strings.Split(w.Events, ",") {
+		e = strings.TrimSpace(e)
+		if e == "*" || e == typ {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(e, "*"); ok && strings.HasPrefix(typ, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type WebhookDelivery struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	WebhookID  uint      `json:"webhook_id" gorm:"index"`
+	EventID    string    `json:"event_id" gorm:"size:64"`
+	Event      string    `json:"event" gorm:"size:64"`
+	Payload    string    `json:"payload" gorm:"type:text"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status_code"`
+	Error      string    `json:"error" gorm:"type:text"`
+	Succeeded  bool      `json:"succeeded"`
+	CreatedAt  time.Time `json:"created_at" gorm:"index"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
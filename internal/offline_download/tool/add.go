@@ -150,6 +150,7 @@ func AddURL(ctx context.Context, args *AddURLArgs) (task.TaskExtensionInfo, erro
 		Toolname:     args.Tool,
 		tool:         tool,
 	}
+	t.TrackRoot(ctx)
 	DownloadTaskManager.Add(t)
 	return t, nil
 }
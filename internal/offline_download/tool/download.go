@@ -207,12 +207,23 @@ func (t *DownloadTask) Transfer() error {
 		}
 		tsk.SetTotalBytes(t.GetTotalBytes())
 		task_group.TransferCoordinator.AddTask(tsk.groupID, nil)
+		tsk.TrackRoot(t.Ctx())
 		TransferTaskManager.Add(tsk)
 		return nil
 	}
 	return transferStd(t.Ctx(), t.TempDir, t.DstDirPath, t.DeletePolicy)
 }
 
+func (t *DownloadTask) OnSucceeded() {
+	task.PublishFinished("download", t)
+	t.RootDone(true)
+}
+
+func (t *DownloadTask) OnFailed() {
+	task.PublishFinished("download", t)
+	t.RootDone(false)
+}
+
 func (t *DownloadTask) GetName() string {
 	return fmt.Sprintf("download %s to (%s)", t.Url, t.DstDirPath)
 }
@@ -221,4 +232,8 @@ func (t *DownloadTask) GetStatus() string {
 	return t.Status
 }
 
+func (t *DownloadTask) GetPaths() []string {
+	return []string{t.DstDirPath}
+}
+
 var DownloadTaskManager *tache.Manager[*DownloadTask]
@@ -90,6 +90,7 @@ func (t *TransferTask) GetName() string {
 }
 
 func (t *TransferTask) OnSucceeded() {
+	task.PublishFinished("transfer", t)
 	if t.DeletePolicy == DeleteOnUploadSucceed || t.DeletePolicy == DeleteAlways {
 		if t.SrcStorage == nil {
 			removeStdTemp(t)
@@ -98,9 +99,11 @@ func (t *TransferTask) OnSucceeded() {
 		}
 	}
 	task_group.TransferCoordinator.Done(t.groupID, true)
+	t.RootDone(true)
 }
 
 func (t *TransferTask) OnFailed() {
+	task.PublishFinished("transfer", t)
 	if t.DeletePolicy == DeleteOnUploadFailed || t.DeletePolicy == DeleteAlways {
 		if t.SrcStorage == nil {
 			removeStdTemp(t)
@@ -109,6 +112,7 @@ func (t *TransferTask) OnFailed() {
 		}
 	}
 	task_group.TransferCoordinator.Done(t.groupID, false)
+	t.RootDone(false)
 }
 
 func (t *TransferTask) SetRetry(retry int, maxRetry int) {
@@ -151,6 +155,7 @@ func transferStd(ctx context.Context, tempDir, dstDirPath string, deletePolicy D
 			DeletePolicy: deletePolicy,
 		}
 		task_group.TransferCoordinator.AddTask(dstDirPath, nil)
+		t.TrackRoot(ctx)
 		TransferTaskManager.Add(t)
 	}
 	return nil
@@ -188,6 +193,7 @@ func transferStdPath(t *TransferTask) error {
 				DeletePolicy: t.DeletePolicy,
 			}
 			task_group.TransferCoordinator.AddTask(t.groupID, nil)
+			task.TrackRoot(t.Ctx())
 			TransferTaskManager.Add(task)
 		}
 		t.Status = "src object is dir, added all transfer tasks of files"
@@ -264,6 +270,7 @@ func transferObj(ctx context.Context, tempDir, dstDirPath string, deletePolicy D
 			DeletePolicy: deletePolicy,
 		}
 		task_group.TransferCoordinator.AddTask(dstDirPath, nil)
+		t.TrackRoot(ctx)
 		TransferTaskManager.Add(t)
 	}
 	return nil
@@ -289,7 +296,7 @@ func transferObjPath(t *TransferTask) error {
 			}
 			srcObjPath := stdpath.Join(t.SrcActualPath, obj.GetName())
 			task_group.TransferCoordinator.AddTask(t.groupID, nil)
-			TransferTaskManager.Add(&TransferTask{
+			next := &TransferTask{
 				TaskData: fs.TaskData{
 					TaskExtension: task.TaskExtension{
 						Creator: t.Creator,
@@ -304,7 +311,9 @@ func transferObjPath(t *TransferTask) error {
 				},
 				groupID:      t.groupID,
 				DeletePolicy: t.DeletePolicy,
-			})
+			}
+			next.TrackRoot(t.Ctx())
+			TransferTaskManager.Add(next)
 		}
 		t.Status = "src object is dir, added all transfer tasks of objs"
 		return nil
@@ -66,15 +66,25 @@ func GetArchiveToolAndStream(ctx context.Context, storage driver.Driver, path st
 		_ = l.Close()
 		return nil, nil, nil, errors.Errorf("failed get archive tool: the obj does not have an extension.")
 	}
-	partExt, t, err := tool.GetArchiveTool("." + ext)
-	if err != nil {
-		var e error
-		partExt, t, e = tool.GetArchiveTool(stdpath.Ext(obj.GetName()))
-		if e != nil {
-			_ = l.Close()
-			return nil, nil, nil, errors.WithMessagef(stderrors.Join(err, e), "failed get archive tool: %s", ext)
+	// the longest extension is tried first, e.g. ".tar.gz" of "linux-6.1.tar.gz"
+	var (
+		partExt *tool.MultipartExtension
+		t       tool.Tool
+	)
+	name := obj.GetName()
+	for i := len(baseName); i < len(name); i++ {
+		if name[i] != '.' {
+			continue
+		}
+		if partExt, t, err = tool.GetArchiveTool(name[i:]); err == nil {
+			baseName = name[:i]
+			break
 		}
 	}
+	if err != nil {
+		_ = l.Close()
+		return nil, nil, nil, errors.WithMessagef(err, "failed get archive tool: %s", ext)
+	}
 	ss, err := stream.NewSeekableStream(&stream.FileStream{Ctx: ctx, Obj: obj}, l)
 	if err != nil {
 		_ = l.Close()
@@ -0,0 +1,26 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// WebDAV dead props are keyed by the path in the virtual tree,
+// so they have to follow the objects moved, renamed or removed here.
+
+func moveDeadProps(storage driver.Driver, srcPath, dstPath string) {
+	mountPath := storage.GetStorage().MountPath
+	srcPath, dstPath = utils.GetFullPath(mountPath, srcPath), utils.GetFullPath(mountPath, dstPath)
+	if err := db.MoveDeadProps(srcPath, dstPath); err != nil {
+		log.Errorf("failed move dead props from %s to %s: %+v", srcPath, dstPath, err)
+	}
+}
+
+func removeDeadProps(storage driver.Driver, path string) {
+	path = utils.GetFullPath(storage.GetStorage().MountPath, path)
+	if err := db.DeleteDeadProps(path); err != nil {
+		log.Errorf("failed remove dead props of %s: %+v", path, err)
+	}
+}
@@ -0,0 +1,40 @@
+package op
+
+import (
+	"context"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/event"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// publishObjEvent emits an fs event of obj, the paths are relative to the storage.
+// dstPath is empty unless the obj is moved, renamed or copied to dstPath.
+// The trash is internal, moving into it is published as the removal of path,
+// moving out of it as the put of dstPath, and the rest inside it is not.
+func publishObjEvent(ctx context.Context, typ event.Type, storage driver.Driver, path, dstPath string, obj model.Obj) {
+	switch {
+	case dstPath != "" && IsTrashPath(dstPath):
+		if IsTrashPath(path) {
+			return
+		}
+		typ, dstPath = event.FsRemove, ""
+	case IsTrashPath(path):
+		if typ != event.FsMove || dstPath == "" {
+			return
+		}
+		typ, path, dstPath = event.FsPut, dstPath, ""
+	}
+	mountPath := storage.GetStorage().MountPath
+	data := event.ObjData{
+		Path:  utils.GetFullPath(mountPath, path),
+		Name:  obj.GetName(),
+		Size:  obj.GetSize(),
+		IsDir: obj.IsDir(),
+	}
+	if dstPath != "" {
+		data.DstPath = utils.GetFullPath(mountPath, dstPath)
+	}
+	event.Publish(ctx, typ, data)
+}
@@ -4,10 +4,14 @@ import (
 	"context"
 	stderrors "errors"
 	stdpath "path"
+	"sync"
 	"time"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/event"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
@@ -27,7 +31,9 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 	log.Debugf("op.List %s", path)
 	key := Key(storage, path)
 	if !args.Refresh {
-		if dirCache, exists := Cache.dirCache.Get(key); exists {
+		dirCache, exists := Cache.dirCache.Get(key)
+		metrics.CacheLookup("dir", exists)
+		if exists {
 			log.Debugf("use cache when list %s", path)
 			return dirCache.GetSortedObjects(storage), nil
 		}
@@ -43,7 +49,9 @@ func List(ctx context.Context, storage driver.Driver, path string, args model.Li
 	}
 
 	objs, err, _ := listG.Do(key, func() ([]model.Obj, error) {
+		start := time.Now()
 		files, err := storage.List(ctx, dir, args)
+		observeDriverCall(storage, "List", start, err)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed to list objs")
 		}
@@ -183,9 +191,11 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 	if ol, exists := Cache.linkCache.GetType(key, typeKey); exists {
 		if ol.link.Expiration != nil ||
 			ol.link.SyncClosers.AcquireReference() || !ol.link.RequireReference {
+			metrics.CacheLookup("link", true)
 			return ol.link, ol.obj, nil
 		}
 	}
+	metrics.CacheLookup("link", false)
 
 	fn := func() (*objWithLink, error) {
 		file, err := GetUnwrap(ctx, storage, path)
@@ -196,7 +206,9 @@ func Link(ctx context.Context, storage driver.Driver, path string, args model.Li
 			return nil, errors.WithStack(errs.NotFile)
 		}
 
+		start := time.Now()
 		link, err := storage.Link(ctx, file, args)
+		observeDriverCall(storage, "Link", start, err)
 		if err != nil {
 			return nil, errors.Wrapf(err, "failed get link")
 		}
@@ -288,6 +300,9 @@ func MakeDir(ctx context.Context, storage driver.Driver, path string, lazyCache
 				default:
 					return nil, errs.NotImplement
 				}
+				if err == nil {
+					publishObjEvent(ctx, event.FsMakeDir, storage, path, "", &model.Object{Name: dirName, IsFolder: true})
+				}
 				return nil, errors.WithStack(err)
 			}
 			return nil, errors.WithMessage(err, "failed to check if dir exists")
@@ -345,6 +360,14 @@ func Move(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	default:
 		return errs.NotImplement
 	}
+	if err == nil {
+		dstPath := stdpath.Join(dstDirPath, srcObj.GetName())
+		moveDeadProps(storage, srcPath, dstPath)
+		moveS3ObjectMetas(storage, srcPath, dstPath)
+		objUsageChanged(storage, srcPath, srcObj, -1)
+		objUsageChanged(storage, dstPath, srcObj, 1)
+		publishObjEvent(ctx, event.FsMove, storage, srcPath, dstPath, srcObj)
+	}
 	return errors.WithStack(err)
 }
 
@@ -386,6 +409,12 @@ func Rename(ctx context.Context, storage driver.Driver, srcPath, dstName string,
 	default:
 		return errs.NotImplement
 	}
+	if err == nil {
+		dstPath := stdpath.Join(stdpath.Dir(srcPath), dstName)
+		moveDeadProps(storage, srcPath, dstPath)
+		moveS3ObjectMetas(storage, srcPath, dstPath)
+		publishObjEvent(ctx, event.FsRename, storage, srcPath, dstPath, srcObj)
+	}
 	return errors.WithStack(err)
 }
 
@@ -430,6 +459,11 @@ func Copy(ctx context.Context, storage driver.Driver, srcPath, dstDirPath string
 	default:
 		return errs.NotImplement
 	}
+	if err == nil {
+		dstPath := stdpath.Join(dstDirPath, srcObj.GetName())
+		objUsageChanged(storage, dstPath, srcObj, 1)
+		publishObjEvent(ctx, event.FsCopy, storage, srcPath, dstPath, srcObj)
+	}
 	return errors.WithStack(err)
 }
 
@@ -457,6 +491,10 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 		err = s.Remove(ctx, model.UnwrapObj(rawObj))
 		if err == nil {
 			Cache.removeDirectoryObject(storage, dirPath, rawObj)
+			removeDeadProps(storage, path)
+			removeS3ObjectMetas(storage, path)
+			objUsageChanged(storage, path, rawObj, -1)
+			publishObjEvent(ctx, event.FsRemove, storage, path, "", rawObj)
 		}
 	default:
 		return errs.NotImplement
@@ -464,6 +502,38 @@ func Remove(ctx context.Context, storage driver.Driver, path string) error {
 	return errors.WithStack(err)
 }
 
+type putLock struct {
+	sync.Mutex
+	refs int
+}
+
+var (
+	putLocksMu sync.Mutex
+	putLocks   = make(map[string]*putLock)
+)
+
+// lockPut serializes the puts to the same path, so the file checked not to
+// exist by a put with NoOverwriteKey isn't put by another one meanwhile
+func lockPut(path string) (unlock func()) {
+	putLocksMu.Lock()
+	l, ok := putLocks[path]
+	if !ok {
+		l = &putLock{}
+		putLocks[path] = l
+	}
+	l.refs++
+	putLocksMu.Unlock()
+	l.Lock()
+	return func() {
+		l.Unlock()
+		putLocksMu.Lock()
+		if l.refs--; l.refs == 0 {
+			delete(putLocks, path)
+		}
+		putLocksMu.Unlock()
+	}
+}
+
 func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file model.FileStreamer, up driver.UpdateProgress, lazyCache ...bool) error {
 	close := file.Close
 	defer func() {
@@ -485,8 +555,15 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	dstPath := stdpath.Join(dstDirPath, file.GetName())
 	tempName := file.GetName() + ".openlist_to_delete"
 	tempPath := stdpath.Join(dstDirPath, tempName)
+	unlock := lockPut(Key(storage, dstPath))
+	defer unlock()
+	// replaced is the size of the existing file overwritten in place
+	var replaced int64
 	fi, err := GetUnwrap(ctx, storage, dstPath)
 	if err == nil {
+		if ctx.Value(conf.NoOverwriteKey) != nil {
+			return errors.WithStack(errs.ObjectAlreadyExists)
+		}
 		if fi.GetSize() == 0 {
 			err = Remove(ctx, storage, dstPath)
 			if err != nil {
@@ -500,6 +577,7 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 			}
 		} else {
 			file.SetExist(fi)
+			replaced = fi.GetSize()
 		}
 	}
 	err = MakeDir(ctx, storage, dstDirPath)
@@ -521,10 +599,12 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 		log.Warnf("file size < 0, try to get full size from cache")
 		file.CacheFullAndWriter(nil, nil)
 	}
+	start := time.Now()
 	switch s := storage.(type) {
 	case driver.PutResult:
 		var newObj model.Obj
 		newObj, err = s.Put(ctx, parentDir, file, up)
+		observeDriverCall(storage, "Put", start, err)
 		if err == nil {
 			Cache.linkCache.DeleteKey(Key(storage, dstPath))
 			if newObj != nil {
@@ -535,6 +615,7 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 		}
 	case driver.Put:
 		err = s.Put(ctx, parentDir, file, up)
+		observeDriverCall(storage, "Put", start, err)
 		if err == nil {
 			Cache.linkCache.DeleteKey(Key(storage, dstPath))
 			if !utils.IsBool(lazyCache...) {
@@ -544,6 +625,15 @@ func Put(ctx context.Context, storage driver.Driver, dstDirPath string, file mod
 	default:
 		return errs.NotImplement
 	}
+	if err == nil {
+		if file.GetSize() < 0 {
+			dirtyUsage(storage, dstPath)
+		} else {
+			addUsage(storage, dstPath, file.GetSize()-replaced)
+		}
+		removeS3ObjectMetas(storage, dstPath)
+		publishObjEvent(ctx, event.FsPut, storage, dstPath, "", file)
+	}
 	log.Debugf("put file [%s] done", file.GetName())
 	if storage.Config().NoOverwriteUpload && fi != nil && fi.GetSize() > 0 {
 		if err != nil {
@@ -604,3 +694,7 @@ func PutURL(ctx context.Context, storage driver.Driver, dstDirPath, dstName, url
 	log.Debugf("put url [%s](%s) done", dstName, url)
 	return errors.WithStack(err)
 }
+
+func observeDriverCall(storage driver.Driver, op string, start time.Time, err error) {
+	metrics.ObserveDriverCall(storage.GetStorage().MountPath, storage.Config().Name, op, start, err)
+}
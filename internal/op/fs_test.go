@@ -0,0 +1,56 @@
+package op_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+)
+
+func TestPutNoOverwrite(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/put_test",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, root),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/put_test")
+	if err != nil {
+		t.Fatalf("failed to get storage: %+v", err)
+	}
+	file := func(name string) *stream.FileStream {
+		return &stream.FileStream{
+			Obj:    &model.Object{Name: name, Size: 5},
+			Reader: strings.NewReader("world"),
+		}
+	}
+	ctx = context.WithValue(ctx, conf.NoOverwriteKey, struct{}{})
+	if err = op.Put(ctx, storage, "/", file("a.txt"), nil); !errors.Is(err, errs.ObjectAlreadyExists) {
+		t.Errorf("put to existing file: got %v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(root, "a.txt")); string(b) != "hello" {
+		t.Errorf("existing file is overwritten: %q", b)
+	}
+	if err = op.Put(ctx, storage, "/", file("b.txt"), nil); err != nil {
+		t.Errorf("put to new file: %+v", err)
+	}
+	if b, _ := os.ReadFile(filepath.Join(root, "b.txt")); string(b) != "world" {
+		t.Errorf("new file: got %q", b)
+	}
+}
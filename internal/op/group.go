@@ -0,0 +1,85 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+func GetGroupById(id uint) (*model.Group, error) {
+	return db.GetGroupById(id)
+}
+
+func GetGroupByName(name string) (*model.Group, error) {
+	return db.GetGroupByName(name)
+}
+
+func GetGroups(pageIndex, pageSize int) (groups []model.Group, count int64, err error) {
+	return db.GetGroups(pageIndex, pageSize)
+}
+
+func CreateGroup(g *model.Group) error {
+	if g.BasePath != "" {
+		g.BasePath = utils.FixAndCleanPath(g.BasePath)
+	}
+	return db.CreateGroup(g)
+}
+
+func UpdateGroup(g *model.Group) error {
+	if _, err := db.GetGroupById(g.ID); err != nil {
+		return err
+	}
+	if g.BasePath != "" {
+		g.BasePath = utils.FixAndCleanPath(g.BasePath)
+	}
+	if err := db.UpdateGroup(g); err != nil {
+		return err
+	}
+	clearUsersCache()
+	if g.Quota > 0 {
+		members, err := GetGroupMembers(g.ID)
+		if err != nil {
+			return err
+		}
+		trackQuotaUsages(members...)
+	}
+	return nil
+}
+
+func DeleteGroupById(id uint) error {
+	if err := db.DeleteGroupById(id); err != nil {
+		return err
+	}
+	clearUsersCache()
+	metaCache.Clear()
+	return nil
+}
+
+// GetGroupMembers returns the users in the group
+func GetGroupMembers(id uint) ([]*model.User, error) {
+	members, err := db.GetGroupMembers(id)
+	if err != nil {
+		return nil, err
+	}
+	users := make([]*model.User, len(members))
+	for i := range members {
+		users[i] = &members[i]
+	}
+	return users, nil
+}
+
+// SetUserGroups replaces the groups of the user
+func SetUserGroups(u *model.User, groupIds []uint) error {
+	if err := db.SetUserGroups(u.ID, groupIds); err != nil {
+		return err
+	}
+	trackUserQuotaUsages(u.ID)
+	return DelUserCache(u.Username)
+}
+
+// the effective permissions of every member change with the group
+func clearUsersCache() {
+	adminUser = nil
+	guestUser = nil
+	Cache.userCache.Clear()
+}
@@ -0,0 +1,62 @@
+package op_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestUserGroups(t *testing.T) {
+	readers := &model.Group{Name: "readers", Permission: 1 << 8, BasePath: "/readers"}
+	writers := &model.Group{Name: "writers", Permission: 1<<3 | 1<<9}
+	for _, g := range []*model.Group{readers, writers} {
+		if err := op.CreateGroup(g); err != nil {
+			t.Fatalf("failed to create group %s: %+v", g.Name, err)
+		}
+	}
+	u := &model.User{Username: "grouped", Permission: 1, GroupIDs: []uint{readers.ID, writers.ID}}
+	if err := op.CreateUser(u); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+
+	got, err := op.GetUserByName("grouped")
+	if err != nil {
+		t.Fatalf("failed to get user: %+v", err)
+	}
+	if p := got.EffectivePermission(); p != 1|1<<3|1<<8|1<<9 {
+		t.Errorf("effective permission: got %b", p)
+	}
+	if !got.CanWebdavRead() || !got.CanWrite() || got.CanRemove() {
+		t.Errorf("unexpected permissions of %b", got.EffectivePermission())
+	}
+	if bp := got.EffectiveBasePath(); bp != "/readers" {
+		t.Errorf("effective base path: got %s, want /readers", bp)
+	}
+
+	// updating a group is visible to the cached members
+	writers.Permission |= 1 << 7
+	if err = op.UpdateGroup(writers); err != nil {
+		t.Fatalf("failed to update group: %+v", err)
+	}
+	if got, _ = op.GetUserByName("grouped"); !got.CanRemove() {
+		t.Errorf("permission of updated group is not applied")
+	}
+
+	if err = op.DeleteGroupById(readers.ID); err != nil {
+		t.Fatalf("failed to delete group: %+v", err)
+	}
+	got, _ = op.GetUserByName("grouped")
+	if got.CanWebdavRead() || len(got.GroupIDs) != 1 {
+		t.Errorf("deleted group is still applied: %v", got.GroupIDs)
+	}
+	// the roots were given by the deleted group, nothing is accessible now
+	if roots := got.EffectiveBasePaths(); len(roots) != 0 {
+		t.Errorf("effective base paths: got %v, want none", roots)
+	}
+	if _, err = got.JoinPath("/"); !errors.Is(err, errs.PermissionDenied) {
+		t.Errorf("join path: got %v, want permission denied", err)
+	}
+}
@@ -94,3 +94,50 @@ func GetMetaById(id uint) (*model.Meta, error) {
 func GetMetas(pageIndex, pageSize int) (metas []model.Meta, count int64, err error) {
 	return db.GetMetas(pageIndex, pageSize)
 }
+
+// CheckACLUnder checks the ACL of the metas under the path for the user, it
+// fails if the action is denied on any of them. The operations on a whole
+// folder, e.g. copying it inside a storage, can't skip the denied ones.
+func CheckACLUnder(user *model.User, path, action string) error {
+	if user == nil || user.IsAdmin() {
+		return nil
+	}
+	paths, err := db.GetMetaPathsWithACLUnder(utils.FixAndCleanPath(path))
+	if err != nil {
+		return err
+	}
+	for _, p := range paths {
+		if err = CheckACL(user, p, action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CheckACL checks the ACL of the metas on the path for the user, the nearest
+// meta having an entry that matches the user and action decides. A meta
+// applies to the sub paths only if ACLSub is set. Without any matching
+// entry the action is allowed, leaving it to the global permissions.
+func CheckACL(user *model.User, path, action string) error {
+	if user == nil || user.IsAdmin() {
+		return nil
+	}
+	path = utils.FixAndCleanPath(path)
+	for p := path; ; p = stdpath.Dir(p) {
+		meta, err := getMetaByPath(p)
+		if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			return err
+		}
+		if meta != nil && (p == path || meta.ACLSub) {
+			if allow, ok := meta.CheckACL(user, action); ok {
+				if !allow {
+					return errors.WithStack(errs.PermissionDenied)
+				}
+				return nil
+			}
+		}
+		if p == "/" {
+			return nil
+		}
+	}
+}
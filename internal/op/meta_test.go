@@ -0,0 +1,99 @@
+package op_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestCheckACL(t *testing.T) {
+	team := &model.Group{Name: "team"}
+	if err := op.CreateGroup(team); err != nil {
+		t.Fatalf("failed to create group: %+v", err)
+	}
+	alice := &model.User{Username: "alice", GroupIDs: []uint{team.ID}}
+	bob := &model.User{Username: "bob"}
+	for _, u := range []*model.User{alice, bob} {
+		if err := op.CreateUser(u); err != nil {
+			t.Fatalf("failed to create user %s: %+v", u.Username, err)
+		}
+	}
+	alice, _ = op.GetUserByName("alice")
+	bob, _ = op.GetUserByName("bob")
+
+	metas := []*model.Meta{
+		{Path: "/acl", ACLSub: true, ACL: []model.MetaACL{
+			{Subject: model.ACLSubjectEveryone, Deny: true, Actions: "write,delete"},
+			{Subject: model.ACLSubjectGroup, SubjectID: team.ID, Actions: "write"},
+		}},
+		{Path: "/acl/private", ACL: []model.MetaACL{
+			{Subject: model.ACLSubjectEveryone, Deny: true, Actions: "read"},
+			{Subject: model.ACLSubjectUser, SubjectID: bob.ID, Actions: "read"},
+		}},
+	}
+	for _, m := range metas {
+		if err := op.CreateMeta(m); err != nil {
+			t.Fatalf("failed to create meta %s: %+v", m.Path, err)
+		}
+	}
+
+	testCases := []struct {
+		user   *model.User
+		path   string
+		action string
+		allow  bool
+	}{
+		{alice, "/acl/a.txt", model.ACLWrite, true},
+		{bob, "/acl/a.txt", model.ACLWrite, false},
+		{alice, "/acl/a.txt", model.ACLDelete, false},
+		{alice, "/acl/a.txt", model.ACLRead, true},
+		{alice, "/acl/private", model.ACLRead, false},
+		{bob, "/acl/private", model.ACLRead, true},
+		// the private meta doesn't apply to the sub paths
+		{alice, "/acl/private/b.txt", model.ACLRead, true},
+		{alice, "/other", model.ACLWrite, true},
+	}
+	for _, tc := range testCases {
+		err := op.CheckACL(tc.user, tc.path, tc.action)
+		if tc.allow && err != nil {
+			t.Errorf("%s %s %s: unexpected %v", tc.user.Username, tc.action, tc.path, err)
+		}
+		if !tc.allow && !errors.Is(err, errs.PermissionDenied) {
+			t.Errorf("%s %s %s: got %v, want permission denied", tc.user.Username, tc.action, tc.path, err)
+		}
+	}
+
+	// the folder as a whole can't be read if anything inside is denied
+	if err := op.CheckACLUnder(alice, "/acl", model.ACLRead); !errors.Is(err, errs.PermissionDenied) {
+		t.Errorf("read under /acl: got %v, want permission denied", err)
+	}
+	if err := op.CheckACLUnder(bob, "/acl", model.ACLRead); err != nil {
+		t.Errorf("read under /acl by bob: unexpected %v", err)
+	}
+	if err := op.CheckACLUnder(alice, "/acl/private", model.ACLRead); err != nil {
+		t.Errorf("read under /acl/private: unexpected %v", err)
+	}
+
+	// the entries are replaced on update
+	metas[1].ACL = nil
+	if err := op.UpdateMeta(metas[1]); err != nil {
+		t.Fatalf("failed to update meta: %+v", err)
+	}
+	if err := op.CheckACL(alice, "/acl/private", model.ACLRead); err != nil {
+		t.Errorf("removed entry is still applied: %v", err)
+	}
+	// the entries of a deleted group are removed
+	if err := op.DeleteGroupById(team.ID); err != nil {
+		t.Fatalf("failed to delete group: %+v", err)
+	}
+	m, err := op.GetMetaById(metas[0].ID)
+	if err != nil {
+		t.Fatalf("failed to get meta: %+v", err)
+	}
+	if len(m.ACL) != 1 {
+		t.Errorf("entries of deleted group: got %d, want 1", len(m.ACL))
+	}
+}
@@ -0,0 +1,52 @@
+package op
+
+import (
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/pkg/errors"
+)
+
+// CreateS3AccessKey generates the credential pair of the key, the secret can
+// only be read from the key returned here
+func CreateS3AccessKey(k *model.S3AccessKey) error {
+	_, err := db.GetS3AccessKeyByUserTitle(k.UserId, k.Title)
+	if err == nil {
+		return errors.New("key with the same title already exists")
+	}
+	k.AccessKeyId = "OL" + strings.ToUpper(random.String(18))
+	k.SecretAccessKey = random.String(40)
+	k.AddedTime = time.Now()
+	k.LastUsedTime = k.AddedTime
+	return db.CreateS3AccessKey(k)
+}
+
+func GetS3AccessKeysByUserId(userId uint, pageIndex, pageSize int) (keys []model.S3AccessKey, count int64, err error) {
+	return db.GetS3AccessKeysByUserId(userId, pageIndex, pageSize)
+}
+
+func GetS3AccessKeyByIdAndUserId(id uint, userId uint) (*model.S3AccessKey, error) {
+	key, err := db.GetS3AccessKeyById(id)
+	if err != nil {
+		return nil, err
+	}
+	if key.UserId != userId {
+		return nil, errors.New("failed get s3 key")
+	}
+	return key, nil
+}
+
+func GetS3AccessKeyByAccessKeyId(accessKeyId string) (*model.S3AccessKey, error) {
+	return db.GetS3AccessKeyByAccessKeyId(accessKeyId)
+}
+
+func UpdateS3AccessKey(k *model.S3AccessKey) error {
+	return db.UpdateS3AccessKey(k)
+}
+
+func DeleteS3AccessKeyById(keyId uint) error {
+	return db.DeleteS3AccessKeyById(keyId)
+}
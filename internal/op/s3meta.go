@@ -0,0 +1,74 @@
+package op
+
+import (
+	"sync"
+	"sync/atomic"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// The metadata of the S3 objects is keyed by the path in the virtual tree
+// as the WebDAV dead props, it follows the objects moved, renamed or removed
+// here, and is dropped when the object is overwritten, the S3 server saves
+// it again after the put. The ops skip it if the S3 server is disabled or
+// has never saved any, so they don't query the database for nothing.
+
+var (
+	s3ObjectMetasOnce  sync.Once
+	s3ObjectMetasExist atomic.Bool
+)
+
+func hasS3ObjectMetas() bool {
+	if !conf.Conf.S3.Enable {
+		return false
+	}
+	s3ObjectMetasOnce.Do(func() {
+		exist, err := db.HasS3ObjectMetas()
+		if err != nil {
+			log.Errorf("failed check s3 object metas: %+v", err)
+			exist = true
+		}
+		if exist {
+			s3ObjectMetasExist.Store(true)
+		}
+	})
+	return s3ObjectMetasExist.Load()
+}
+
+func GetS3ObjectMeta(path string) (map[string]string, error) {
+	return db.GetS3ObjectMeta(path)
+}
+
+// SaveS3ObjectMeta replaces the metadata of the object at path, an empty
+// meta deletes it
+func SaveS3ObjectMeta(path string, meta map[string]string) error {
+	if len(meta) > 0 {
+		s3ObjectMetasExist.Store(true)
+	}
+	return db.SaveS3ObjectMeta(path, meta)
+}
+
+func moveS3ObjectMetas(storage driver.Driver, srcPath, dstPath string) {
+	if !hasS3ObjectMetas() {
+		return
+	}
+	mountPath := storage.GetStorage().MountPath
+	srcPath, dstPath = utils.GetFullPath(mountPath, srcPath), utils.GetFullPath(mountPath, dstPath)
+	if err := db.MoveS3ObjectMetas(srcPath, dstPath); err != nil {
+		log.Errorf("failed move s3 object metas from %s to %s: %+v", srcPath, dstPath, err)
+	}
+}
+
+func removeS3ObjectMetas(storage driver.Driver, path string) {
+	if !hasS3ObjectMetas() {
+		return
+	}
+	path = utils.GetFullPath(storage.GetStorage().MountPath, path)
+	if err := db.DeleteS3ObjectMetas(path); err != nil {
+		log.Errorf("failed remove s3 object metas of %s: %+v", path, err)
+	}
+}
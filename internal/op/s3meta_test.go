@@ -0,0 +1,56 @@
+package op_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestS3ObjectMetas(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:    "Local",
+		MountPath: "/s3meta_test",
+		Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, root),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/s3meta_test")
+	if err != nil {
+		t.Fatalf("failed to get storage: %+v", err)
+	}
+	defer func(enable bool) { conf.Conf.S3.Enable = enable }(conf.Conf.S3.Enable)
+	conf.Conf.S3.Enable = true
+
+	meta := map[string]string{"Content-Type": "text/plain"}
+	if err = op.SaveS3ObjectMeta("/s3meta_test/a.txt", meta); err != nil {
+		t.Fatalf("failed to save meta: %+v", err)
+	}
+	if err = op.Rename(ctx, storage, "/a.txt", "b.txt"); err != nil {
+		t.Fatalf("failed to rename: %+v", err)
+	}
+	if got, err := db.GetS3ObjectMeta("/s3meta_test/b.txt"); err != nil || got["Content-Type"] != "text/plain" {
+		t.Errorf("renamed meta: got %v, %v", got, err)
+	}
+
+	// the metadata is left alone while the S3 server is disabled
+	conf.Conf.S3.Enable = false
+	if err = op.Rename(ctx, storage, "/b.txt", "c.txt"); err != nil {
+		t.Fatalf("failed to rename: %+v", err)
+	}
+	if got, err := db.GetS3ObjectMeta("/s3meta_test/b.txt"); err != nil || got == nil {
+		t.Errorf("meta with s3 disabled: got %v, %v", got, err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
@@ -133,6 +134,22 @@ func UpdateSharing(sharing *model.Sharing, skipMarshal ...bool) (err error) {
 	return db.UpdateSharing(sharing.SharingDB)
 }
 
+// AddSharingUploaded counts size more bytes uploaded to the drop folder, or
+// gives them back if size is negative. It fails with QuotaExceeded if they
+// don't fit in the upload quota, checked by the database so the concurrent
+// uploads can't exceed it together.
+func AddSharingUploaded(sharing *model.Sharing, size int64) error {
+	ok, err := db.AddSharingUploaded(sharing.ID, size, sharing.UploadQuota)
+	sharingCache.Del(sharing.ID)
+	if err != nil {
+		return errors.WithMessagef(err, "failed count uploaded size of sharing [%s]", sharing.ID)
+	}
+	if !ok {
+		return errors.WithMessagef(errs.QuotaExceeded, "%d more bytes exceed the upload quota of the sharing", size)
+	}
+	return nil
+}
+
 func DeleteSharing(sid string) error {
 	sharingCache.Del(sid)
 	return db.DeleteSharingById(sid)
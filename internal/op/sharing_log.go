@@ -0,0 +1,35 @@
+package op
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/mq"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	log "github.com/sirupsen/logrus"
+)
+
+// the access logs are queued and written in batches, so the requests to the
+// sharings don't wait for the database
+var sharingLogMQ = mq.NewInMemoryMQ[model.SharingAccessLog]()
+
+// LogSharingAccess queues the access log to be written by FlushSharingAccessLogs
+func LogSharingAccess(l model.SharingAccessLog) {
+	sharingLogMQ.Publish(mq.Message[model.SharingAccessLog]{Content: l})
+}
+
+// FlushSharingAccessLogs writes the queued access logs, it's called
+// periodically, before the logs are read and on shutdown
+func FlushSharingAccessLogs() {
+	var logs []model.SharingAccessLog
+	sharingLogMQ.ConsumeAll(func(messages []mq.Message[model.SharingAccessLog]) {
+		logs = utils.MustSliceConvert(messages, func(m mq.Message[model.SharingAccessLog]) model.SharingAccessLog {
+			return m.Content
+		})
+	})
+	if len(logs) == 0 {
+		return
+	}
+	if err := db.CreateSharingAccessLogs(logs); err != nil {
+		log.Errorf("failed write %d sharing access logs: %+v", len(logs), err)
+	}
+}
@@ -0,0 +1,24 @@
+package op_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestFlushSharingAccessLogs(t *testing.T) {
+	for _, path := range []string{"/a.pdf", "/b.pdf"} {
+		op.LogSharingAccess(model.SharingAccessLog{SharingID: "flush_test", Time: time.Now(), Action: model.SharingActionDownload, Path: path})
+	}
+	if _, count, err := db.GetSharingAccessLogs("flush_test", time.Time{}, time.Time{}, 1, 10); err != nil || count != 0 {
+		t.Fatalf("queued logs are written: got %d, %v", count, err)
+	}
+	op.FlushSharingAccessLogs()
+	op.FlushSharingAccessLogs()
+	if _, count, err := db.GetSharingAccessLogs("flush_test", time.Time{}, time.Time{}, 1, 10); err != nil || count != 2 {
+		t.Errorf("flushed logs: got %d, %v", count, err)
+	}
+}
@@ -13,7 +13,9 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
 	"github.com/OpenListTeam/OpenList/v4/pkg/generic_sync"
 	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
@@ -49,6 +51,9 @@ func CreateStorage(ctx context.Context, storage model.Storage) (uint, error) {
 	storage.Modified = time.Now()
 	storage.MountPath = utils.FixAndCleanPath(storage.MountPath)
 	var err error
+	if err = checkIndexCron(storage); err != nil {
+		return 0, err
+	}
 	// check driver first
 	driverName := storage.Driver
 	driverNew, err := GetDriver(driverName)
@@ -71,6 +76,16 @@ func CreateStorage(ctx context.Context, storage model.Storage) (uint, error) {
 	return storage.ID, nil
 }
 
+func checkIndexCron(storage model.Storage) error {
+	if storage.IndexCron == "" {
+		return nil
+	}
+	if _, err := cron.ParseExpr(storage.IndexCron); err != nil {
+		return errors.WithMessage(err, "invalid index cron")
+	}
+	return nil
+}
+
 // LoadStorage load exist storage in db to memory
 func LoadStorage(ctx context.Context, storage model.Storage) error {
 	storage.MountPath = utils.FixAndCleanPath(storage.MountPath)
@@ -212,6 +227,7 @@ func DisableStorage(ctx context.Context, id uint) error {
 		return errors.WithMessage(err, "failed update storage in db")
 	}
 	storagesMap.Delete(storage.MountPath)
+	metrics.DeleteStorage(storage.MountPath)
 	go callStorageHooks("del", storageDriver)
 	return nil
 }
@@ -227,6 +243,9 @@ func UpdateStorage(ctx context.Context, storage model.Storage) error {
 	if oldStorage.Driver != storage.Driver {
 		return errors.Errorf("driver cannot be changed")
 	}
+	if err = checkIndexCron(storage); err != nil {
+		return err
+	}
 	storage.Modified = time.Now()
 	storage.MountPath = utils.FixAndCleanPath(storage.MountPath)
 	err = db.UpdateStorage(&storage)
@@ -240,6 +259,7 @@ func UpdateStorage(ctx context.Context, storage model.Storage) error {
 	if oldStorage.MountPath != storage.MountPath {
 		// mount path renamed, need to drop the storage
 		storagesMap.Delete(oldStorage.MountPath)
+		metrics.DeleteStorage(oldStorage.MountPath)
 		Cache.DeleteDirectoryTree(storageDriver, "/")
 		Cache.InvalidateStorageDetails(storageDriver)
 	}
@@ -274,6 +294,7 @@ func DeleteStorageById(ctx context.Context, id uint) error {
 		}
 		// delete the storage in the memory
 		storagesMap.Delete(storage.MountPath)
+		metrics.DeleteStorage(storage.MountPath)
 		Cache.DeleteDirectoryTree(storageDriver, "/")
 		Cache.InvalidateStorageDetails(storageDriver)
 		go callStorageHooks("del", storageDriver)
@@ -0,0 +1,143 @@
+package op
+
+import (
+	"context"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+)
+
+// IsTrashPath reports whether the path relative to the storage is in its trash
+func IsTrashPath(path string) bool {
+	path = utils.FixAndCleanPath(path)
+	return path == "/"+model.TrashDirName || strings.HasPrefix(path, "/"+model.TrashDirName+"/")
+}
+
+// TrashEnabled reports whether the objects removed from the storage can be
+// moved into its trash, which needs the driver to support moving
+func TrashEnabled(storage driver.Driver) bool {
+	if !storage.GetStorage().EnableTrash {
+		return false
+	}
+	switch storage.(type) {
+	case driver.Move, driver.MoveResult:
+		return true
+	}
+	return false
+}
+
+// MoveToTrash moves the object into the trash of the storage instead of
+// removing it, the user of the context is recorded as the deleter
+func MoveToTrash(ctx context.Context, storage driver.Driver, path string) error {
+	if utils.PathEqual(path, "/") {
+		return errors.New("delete root folder is not allowed, please goto the manage page to delete the storage instead")
+	}
+	path = utils.FixAndCleanPath(path)
+	obj, err := Get(ctx, storage, path)
+	if err != nil {
+		if errs.IsObjectNotFound(err) {
+			log.Debugf("%s have been removed", path)
+			return nil
+		}
+		return errors.WithMessage(err, "failed to get object")
+	}
+	item := &model.TrashItem{
+		StorageID: storage.GetStorage().ID,
+		Path:      utils.GetFullPath(storage.GetStorage().MountPath, path),
+		Size:      obj.GetSize(),
+		IsDir:     obj.IsDir(),
+		DeletedAt: time.Now(),
+	}
+	if user, ok := ctx.Value(conf.UserKey).(*model.User); ok {
+		item.DeleterID = user.ID
+		item.Deleter = user.Username
+	}
+	if err = db.CreateTrashItem(item); err != nil {
+		return err
+	}
+	if err = MakeDir(ctx, storage, item.Dir()); err == nil {
+		if err = Move(ctx, storage, path, item.Dir()); err != nil {
+			_ = Remove(ctx, storage, item.Dir())
+		}
+	}
+	if err != nil {
+		if e := db.DeleteTrashItemById(item.ID); e != nil {
+			log.Errorf("failed delete trash item: %+v", e)
+		}
+		return errors.WithMessage(err, "failed to move into the trash")
+	}
+	return nil
+}
+
+// RestoreTrash moves the trashed object back to its original path
+func RestoreTrash(ctx context.Context, item *model.TrashItem) error {
+	storage, actualPath, err := GetStorageAndActualPath(item.Path)
+	if err != nil {
+		return errors.WithMessage(err, "failed get storage")
+	}
+	if storage.GetStorage().ID != item.StorageID {
+		return errors.Errorf("the storage of [%s] has been changed", item.Path)
+	}
+	if _, err = GetUnwrap(ctx, storage, actualPath); err == nil {
+		return errors.WithStack(errs.ObjectAlreadyExists)
+	} else if !errs.IsObjectNotFound(err) {
+		return errors.WithMessage(err, "failed to get object")
+	}
+	dstDirPath := stdpath.Dir(actualPath)
+	if err = MakeDir(ctx, storage, dstDirPath); err != nil {
+		return errors.WithMessagef(err, "failed to make dir [%s]", dstDirPath)
+	}
+	if err = Move(ctx, storage, item.ActualPath(), dstDirPath); err != nil {
+		return errors.WithMessage(err, "failed to move out of the trash")
+	}
+	if err = Remove(ctx, storage, item.Dir()); err != nil {
+		log.Warnf("failed remove trash dir [%s]: %+v", item.Dir(), err)
+	}
+	return db.DeleteTrashItemById(item.ID)
+}
+
+// PurgeTrash removes the trashed object permanently
+func PurgeTrash(ctx context.Context, item *model.TrashItem) error {
+	s, err := db.GetStorageById(item.StorageID)
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return err
+	}
+	if err == nil {
+		var storage driver.Driver
+		if storage, err = GetStorageByMountPath(s.MountPath); err != nil {
+			return err
+		}
+		if err = Remove(ctx, storage, item.Dir()); err != nil {
+			return err
+		}
+	}
+	// the storage was deleted with its trash
+	return db.DeleteTrashItemById(item.ID)
+}
+
+// PurgeExpiredTrash removes the objects trashed before the time permanently
+func PurgeExpiredTrash(ctx context.Context, before time.Time) (int, error) {
+	items, err := db.GetTrashItemsBefore(before)
+	if err != nil {
+		return 0, err
+	}
+	n := 0
+	for i := range items {
+		if err = PurgeTrash(ctx, &items[i]); err != nil {
+			log.Warnf("failed purge trashed [%s]: %+v", items[i].Path, err)
+			continue
+		}
+		n++
+	}
+	return n, nil
+}
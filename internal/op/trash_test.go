@@ -0,0 +1,116 @@
+package op_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/event"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+)
+
+func TestTrash(t *testing.T) {
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "a.txt"), []byte("hello"), 0o644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	ctx := context.Background()
+	_, err := op.CreateStorage(ctx, model.Storage{
+		Driver:      "Local",
+		MountPath:   "/trash_test",
+		EnableTrash: true,
+		Addition:    fmt.Sprintf(`{"root_folder_path":%q}`, root),
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/trash_test")
+	if err != nil {
+		t.Fatalf("failed to get storage: %+v", err)
+	}
+	if !op.TrashEnabled(storage) {
+		t.Fatalf("trash of local storage is not enabled")
+	}
+
+	// the trash is hidden from the usage and the events
+	if err = op.TrackUsages([]string{"/trash_test"}); err != nil {
+		t.Fatalf("failed to track usage: %+v", err)
+	}
+	if err = db.SetPathUsage("/trash_test", 5); err != nil {
+		t.Fatalf("failed to set usage: %+v", err)
+	}
+	var events []string
+	event.Subscribe(func(e *event.Event) {
+		if data, ok := e.Data.(event.ObjData); ok && strings.HasPrefix(data.Path, "/trash_test/") {
+			events = append(events, fmt.Sprintf("%s %s%s", e.Type, data.Path, data.DstPath))
+		}
+	})
+	usage := func() int64 {
+		usages, _ := db.GetPathUsages([]string{"/trash_test"})
+		if len(usages) != 1 {
+			t.Fatalf("usages: got %+v", usages)
+		}
+		return usages[0].Used
+	}
+
+	if err = op.MoveToTrash(ctx, storage, "/a.txt"); err != nil {
+		t.Fatalf("failed to move into the trash: %+v", err)
+	}
+	if used := usage(); used != 0 {
+		t.Errorf("usage after trashed: got %d, want 0", used)
+	}
+	items, _, err := db.GetTrashItems(0, 1, 10)
+	if err != nil || len(items) != 1 || items[0].Path != "/trash_test/a.txt" || items[0].Size != 5 {
+		t.Fatalf("trash items: got %+v, %v", items, err)
+	}
+	item := &items[0]
+	if _, err = os.Stat(filepath.Join(root, filepath.FromSlash(item.ActualPath()))); err != nil {
+		t.Errorf("trashed file: %v", err)
+	}
+	if !op.IsTrashPath(item.ActualPath()) || op.IsTrashPath("/"+model.TrashDirName+"x") {
+		t.Errorf("unexpected IsTrashPath")
+	}
+
+	if err = op.RestoreTrash(ctx, item); err != nil {
+		t.Fatalf("failed to restore: %+v", err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "a.txt")); err != nil {
+		t.Errorf("restored file: %v", err)
+	}
+	if _, err = db.GetTrashItemById(item.ID); err == nil {
+		t.Errorf("trash item is kept after restored")
+	}
+	if used := usage(); used != 5 {
+		t.Errorf("usage after restored: got %d, want 5", used)
+	}
+
+	if err = op.MoveToTrash(ctx, storage, "/a.txt"); err != nil {
+		t.Fatalf("failed to move into the trash again: %+v", err)
+	}
+	items, _, _ = db.GetTrashItems(0, 1, 10)
+	if len(items) != 1 {
+		t.Fatalf("trash items: got %+v", items)
+	}
+	if err = op.PurgeTrash(ctx, &items[0]); err != nil {
+		t.Fatalf("failed to purge: %+v", err)
+	}
+	if _, err = os.Stat(filepath.Join(root, filepath.FromSlash(items[0].Dir()))); !os.IsNotExist(err) {
+		t.Errorf("purged dir: got %v", err)
+	}
+	if used := usage(); used != 0 {
+		t.Errorf("usage after purged: got %d, want 0", used)
+	}
+	want := []string{"fs.remove /trash_test/a.txt", "fs.put /trash_test/a.txt", "fs.remove /trash_test/a.txt"}
+	if !slices.Equal(events, want) {
+		t.Errorf("events: got %v, want %v", events, want)
+	}
+	if err = op.UntrackUsages([]string{"/trash_test"}); err != nil {
+		t.Fatalf("failed to untrack usage: %+v", err)
+	}
+}
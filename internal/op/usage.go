@@ -0,0 +1,234 @@
+package op
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// usageTracked caches the tracked paths, so the changes under none of them,
+// which are all of them if no quota is set, cost no query
+var usageTracked struct {
+	sync.RWMutex
+	// nil until loaded
+	paths map[string]struct{}
+}
+
+// UsageTrackedHook is called with the paths just tracked, their usages are
+// unknown until reconciled
+type UsageTrackedHook func(paths []string)
+
+var usageTrackedHooks []UsageTrackedHook
+
+func RegisterUsageTrackedHook(hook UsageTrackedHook) {
+	usageTrackedHooks = append(usageTrackedHooks, hook)
+}
+
+// trackedUsages returns the tracked ones of the paths and, if prefix isn't
+// empty, the tracked paths under prefix
+func trackedUsages(paths []string, prefix string) ([]string, error) {
+	usageTracked.RLock()
+	loaded := usageTracked.paths != nil
+	usageTracked.RUnlock()
+	if !loaded {
+		usages, err := db.GetAllPathUsages()
+		if err != nil {
+			return nil, err
+		}
+		usageTracked.Lock()
+		usageTracked.paths = make(map[string]struct{}, len(usages))
+		for _, u := range usages {
+			usageTracked.paths[u.Path] = struct{}{}
+		}
+		usageTracked.Unlock()
+	}
+	usageTracked.RLock()
+	defer usageTracked.RUnlock()
+	var res []string
+	for _, p := range paths {
+		if _, ok := usageTracked.paths[p]; ok {
+			res = append(res, p)
+		}
+	}
+	if prefix != "" {
+		for p := range usageTracked.paths {
+			if strings.HasPrefix(p, prefix) {
+				res = append(res, p)
+			}
+		}
+	}
+	return res, nil
+}
+
+// TrackUsages starts tracking the usages of the paths not tracked yet,
+// which are reconciled by the hooks
+func TrackUsages(paths []string) error {
+	tracked, err := trackedUsages(paths, "")
+	if err != nil {
+		return err
+	}
+	var added []string
+	for _, p := range paths {
+		if !slices.Contains(tracked, p) && !slices.Contains(added, p) {
+			added = append(added, p)
+		}
+	}
+	if len(added) == 0 {
+		return nil
+	}
+	for _, p := range added {
+		if err = db.CreatePathUsage(p); err != nil {
+			return err
+		}
+		usageTracked.Lock()
+		usageTracked.paths[p] = struct{}{}
+		usageTracked.Unlock()
+	}
+	for _, hook := range usageTrackedHooks {
+		hook(added)
+	}
+	return nil
+}
+
+// UntrackUsages stops tracking the usages of the paths
+func UntrackUsages(paths []string) error {
+	usageTracked.Lock()
+	defer usageTracked.Unlock()
+	if err := db.DeletePathUsages(paths); err != nil {
+		return err
+	}
+	for _, p := range paths {
+		delete(usageTracked.paths, p)
+	}
+	return nil
+}
+
+// addUsage adds delta to the tracked usages of the path and its ancestors,
+// the path is relative to the storage. The trash doesn't count, as it's
+// hidden from the scans reconciling the usages.
+func addUsage(storage driver.Driver, path string, delta int64) {
+	if delta == 0 || IsTrashPath(path) {
+		return
+	}
+	fullPath := utils.GetFullPath(storage.GetStorage().MountPath, path)
+	paths, err := trackedUsages(utils.GetPathHierarchy(fullPath), "")
+	if err == nil && len(paths) > 0 {
+		err = db.AddPathUsage(paths, delta)
+	}
+	if err != nil {
+		log.Errorf("failed update usage of [%s]: %+v", fullPath, err)
+	}
+}
+
+// dirtyUsage marks the tracked usages of the path, its ancestors and
+// descendants dirty, used when the size of the change is unknown
+func dirtyUsage(storage driver.Driver, path string) {
+	if IsTrashPath(path) {
+		return
+	}
+	fullPath := utils.GetFullPath(storage.GetStorage().MountPath, path)
+	paths, err := trackedUsages(utils.GetPathHierarchy(fullPath), utils.PathAddSeparatorSuffix(fullPath))
+	if err == nil && len(paths) > 0 {
+		err = db.MarkPathUsageDirty(paths, "")
+	}
+	if err != nil {
+		log.Errorf("failed mark usage of [%s] dirty: %+v", fullPath, err)
+	}
+}
+
+// objUsageChanged updates the usages after obj was added (sign 1) or removed
+// (sign -1) at the path
+func objUsageChanged(storage driver.Driver, path string, obj model.Obj, sign int64) {
+	if obj.IsDir() {
+		dirtyUsage(storage, path)
+		return
+	}
+	addUsage(storage, path, sign*obj.GetSize())
+}
+
+// GetUserUsage returns the total size of the files under the roots of the
+// user, the roots not tracked yet are tracked from now on and count as 0
+// until reconciled
+func GetUserUsage(user *model.User) (int64, error) {
+	roots := user.EffectiveBasePaths()
+	if err := TrackUsages(roots); err != nil {
+		return 0, err
+	}
+	usages, err := db.GetPathUsages(roots)
+	if err != nil {
+		return 0, err
+	}
+	var used int64
+	for _, u := range usages {
+		used += u.Used
+	}
+	return used, nil
+}
+
+// trackQuotaUsages tracks the roots of the users having a quota, so their
+// usages are reconciled once the quota is set instead of reading 0
+func trackQuotaUsages(users ...*model.User) {
+	var roots []string
+	for _, u := range users {
+		if !u.IsAdmin() && u.EffectiveQuota() > 0 {
+			roots = append(roots, u.EffectiveBasePaths()...)
+		}
+	}
+	if len(roots) == 0 {
+		return
+	}
+	if err := TrackUsages(roots); err != nil {
+		log.Errorf("failed track usages of %v: %+v", roots, err)
+	}
+}
+
+// CheckQuota checks whether the user can store size more bytes,
+// size < 0 means unknown and only checks the current usage
+func CheckQuota(user *model.User, size int64) error {
+	if user == nil || user.IsAdmin() {
+		return nil
+	}
+	quota := user.EffectiveQuota()
+	if quota <= 0 {
+		return nil
+	}
+	used, err := GetUserUsage(user)
+	if err != nil {
+		return err
+	}
+	if used >= quota || (size > 0 && used+size > quota) {
+		return errors.WithMessagef(errs.QuotaExceeded, "%d of %d bytes used, %d more requested", used, quota, max(size, 0))
+	}
+	return nil
+}
+
+// GetQuotaRoots returns the roots of the users having a quota, which are the
+// paths whose usage should be tracked
+func GetQuotaRoots() (map[string]struct{}, error) {
+	roots := make(map[string]struct{})
+	for page := 1; ; page++ {
+		users, count, err := db.GetUsers(page, 100)
+		if err != nil {
+			return nil, err
+		}
+		for i := range users {
+			if users[i].IsAdmin() || users[i].EffectiveQuota() <= 0 {
+				continue
+			}
+			for _, root := range users[i].EffectiveBasePaths() {
+				roots[root] = struct{}{}
+			}
+		}
+		if len(users) == 0 || int64(page*100) >= count {
+			return roots, nil
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package op_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+func TestCheckQuota(t *testing.T) {
+	u := &model.User{Username: "quota", BasePath: "/quota", Quota: 100}
+	if used, err := op.GetUserUsage(u); err != nil || used != 0 {
+		t.Fatalf("usage of new root: got %d, %v", used, err)
+	}
+	// the root is tracked from now on
+	if err := db.AddPathUsage(utils.GetPathHierarchy("/quota/a/b.txt"), 60); err != nil {
+		t.Fatalf("failed to add usage: %+v", err)
+	}
+	if used, _ := op.GetUserUsage(u); used != 60 {
+		t.Errorf("usage: got %d, want 60", used)
+	}
+	if err := op.CheckQuota(u, 40); err != nil {
+		t.Errorf("upload within quota: %v", err)
+	}
+	if err := op.CheckQuota(u, 41); !errors.Is(err, errs.QuotaExceeded) {
+		t.Errorf("upload over quota: got %v", err)
+	}
+
+	// the members without their own quota use the largest of the groups'
+	u.Quota = 0
+	u.Groups = []model.Group{{Quota: 50}, {Quota: 70}}
+	if err := op.CheckQuota(u, -1); err != nil {
+		t.Errorf("unknown size within group quota: %v", err)
+	}
+	u.Groups = u.Groups[:1]
+	if err := op.CheckQuota(u, -1); !errors.Is(err, errs.QuotaExceeded) {
+		t.Errorf("unknown size over group quota: got %v", err)
+	}
+	u.Groups = nil
+	if err := op.CheckQuota(u, 1<<40); err != nil {
+		t.Errorf("upload without quota: %v", err)
+	}
+
+	// a new root is dirty until reconciled
+	if err := db.SetPathUsage("/quota", 60); err != nil {
+		t.Fatalf("failed to set usage: %+v", err)
+	}
+	if usages, _ := db.GetDirtyPathUsages(); len(usages) != 0 {
+		t.Errorf("dirty usages after reconciled: got %+v", usages)
+	}
+	if err := db.MarkPathUsageDirty(utils.GetPathHierarchy("/quota/a"), "/quota/a/"); err != nil {
+		t.Fatalf("failed to mark dirty: %+v", err)
+	}
+	usages, err := db.GetDirtyPathUsages()
+	if err != nil || len(usages) != 1 || usages[0].Path != "/quota" {
+		t.Errorf("dirty usages: got %+v, %v", usages, err)
+	}
+}
+
+func TestTrackQuotaUsages(t *testing.T) {
+	var tracked []string
+	op.RegisterUsageTrackedHook(func(paths []string) {
+		tracked = append(tracked, paths...)
+	})
+	u := &model.User{Username: "untracked", BasePath: "/untracked"}
+	if err := op.CreateUser(u); err != nil {
+		t.Fatalf("failed to create user: %+v", err)
+	}
+	if len(tracked) != 0 {
+		t.Errorf("tracked without quota: %v", tracked)
+	}
+	// no usage row is touched under the untracked paths
+	if usages, _ := db.GetPathUsages([]string{"/untracked"}); len(usages) != 0 {
+		t.Errorf("usages without quota: got %+v", usages)
+	}
+
+	// the root is tracked and reconciled once the quota is assigned
+	u.Quota = 100
+	if err := op.UpdateUser(u); err != nil {
+		t.Fatalf("failed to update user: %+v", err)
+	}
+	if len(tracked) != 1 || tracked[0] != "/untracked" {
+		t.Errorf("tracked after quota set: got %v", tracked)
+	}
+	if usages, _ := db.GetPathUsages([]string{"/untracked"}); len(usages) != 1 || !usages[0].Dirty {
+		t.Errorf("usages after quota set: got %+v", usages)
+	}
+	if err := op.UntrackUsages([]string{"/untracked"}); err != nil {
+		t.Fatalf("failed to untrack: %+v", err)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/pkg/singleflight"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 var userG singleflight.Group[*model.User]
@@ -66,8 +67,23 @@ func GetUsers(pageIndex, pageSize int) (users []model.User, count int64, err err
 }
 
 func CreateUser(u *model.User) error {
-	u.BasePath = utils.FixAndCleanPath(u.BasePath)
-	return db.CreateUser(u)
+	cleanBasePaths(u)
+	if err := db.CreateUser(u); err != nil {
+		return err
+	}
+	trackUserQuotaUsages(u.ID)
+	return nil
+}
+
+// trackUserQuotaUsages tracks the roots of the user if it has a quota of its
+// own or of its groups
+func trackUserQuotaUsages(id uint) {
+	u, err := db.GetUserById(id)
+	if err != nil {
+		log.Errorf("failed get user %d: %+v", id, err)
+		return
+	}
+	trackQuotaUsages(u)
 }
 
 func DeleteUserById(id uint) error {
@@ -82,7 +98,11 @@ func DeleteUserById(id uint) error {
 	if err := DeleteSharingsByCreatorId(id); err != nil {
 		return errors.WithMessage(err, "failed to delete user's sharings")
 	}
-	return db.DeleteUserById(id)
+	if err := db.DeleteUserById(id); err != nil {
+		return err
+	}
+	metaCache.Clear()
+	return nil
 }
 
 func UpdateUser(u *model.User) error {
@@ -97,8 +117,30 @@ func UpdateUser(u *model.User) error {
 		guestUser = nil
 	}
 	Cache.DeleteUser(old.Username)
-	u.BasePath = utils.FixAndCleanPath(u.BasePath)
-	return db.UpdateUser(u)
+	if u.GroupIDs != nil {
+		if err = db.SetUserGroups(u.ID, u.GroupIDs); err != nil {
+			return err
+		}
+	} else {
+		u.GroupIDs = old.GroupIDs
+	}
+	cleanBasePaths(u)
+	if err = db.UpdateUser(u); err != nil {
+		return err
+	}
+	trackUserQuotaUsages(u.ID)
+	return nil
+}
+
+// cleanBasePaths cleans the roots of the user, the base path can be left
+// empty to use the groups' or to only have the extra roots
+func cleanBasePaths(u *model.User) {
+	if u.BasePath != "" || (len(u.BasePaths) == 0 && len(u.GroupIDs) == 0) {
+		u.BasePath = utils.FixAndCleanPath(u.BasePath)
+	}
+	if len(u.BasePaths) > 0 {
+		u.BasePaths = utils.MustSliceConvert(u.BasePaths, utils.FixAndCleanPath)
+	}
 }
 
 func Cancel2FAByUser(u *model.User) error {
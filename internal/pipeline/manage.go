@@ -0,0 +1,162 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/tache"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+func validate(p *model.Pipeline) error {
+	if len(p.Steps) == 0 {
+		return errors.New("pipeline has no step")
+	}
+	for i, step := range p.Steps {
+		var missing string
+		switch step.Action {
+		case model.PipelineOfflineDownload:
+			if step.URL == "" {
+				missing = "url"
+			} else if step.Tool == "" {
+				missing = "tool"
+			} else if step.DstPath == "" {
+				missing = "dst_path"
+			}
+		case model.PipelineDecompress, model.PipelineCopy, model.PipelineMove:
+			if step.SrcPath == "" {
+				missing = "src_path"
+			} else if step.DstPath == "" {
+				missing = "dst_path"
+			}
+		case model.PipelineRemove:
+			if step.SrcPath == "" {
+				missing = "src_path"
+			}
+		default:
+			return errors.Errorf("step %d: unknown action: %s", i+1, step.Action)
+		}
+		if missing != "" {
+			return errors.Errorf("step %d: %s is required", i+1, missing)
+		}
+		for _, dep := range step.DependsOn {
+			if dep < 0 || dep >= i {
+				return errors.Errorf("step %d: can only depend on the steps before it", i+1)
+			}
+		}
+		if step.OnFailure != "" && step.OnFailure != model.PipelineAbort && step.OnFailure != model.PipelineContinue {
+			return errors.Errorf("step %d: unknown failure policy: %s", i+1, step.OnFailure)
+		}
+		if step.Retry < 0 {
+			return errors.Errorf("step %d: retry can't be negative", i+1)
+		}
+	}
+	if p.Cron != "" {
+		if _, err := cron.ParseExpr(p.Cron); err != nil {
+			return errors.WithMessage(err, "invalid cron")
+		}
+	}
+	return nil
+}
+
+// checkPermission checks whether the user is allowed to run all the steps
+func checkPermission(user *model.User, p *model.Pipeline) error {
+	for i, step := range p.Steps {
+		if _, _, err := stepPaths(user, step); err != nil {
+			return errors.WithMessagef(err, "step %d", i+1)
+		}
+	}
+	return nil
+}
+
+func Create(user *model.User, p *model.Pipeline) error {
+	if err := validate(p); err != nil {
+		return err
+	}
+	if err := checkPermission(user, p); err != nil {
+		return err
+	}
+	p.ID = 0
+	p.UserID = user.ID
+	p.LastRunTime = nil
+	p.Modified = time.Now()
+	return db.CreatePipeline(p)
+}
+
+// Update updates the pipeline, the owner of it is kept
+func Update(p *model.Pipeline) error {
+	if err := validate(p); err != nil {
+		return err
+	}
+	old, err := db.GetPipelineById(p.ID)
+	if err != nil {
+		return err
+	}
+	owner, err := op.GetUserById(old.UserID)
+	if err != nil {
+		return errors.WithMessage(err, "failed get owner of the pipeline")
+	}
+	if err = checkPermission(owner, p); err != nil {
+		return err
+	}
+	p.UserID = old.UserID
+	p.LastRunTime = old.LastRunTime
+	p.Modified = time.Now()
+	return db.UpdatePipeline(p)
+}
+
+func Delete(id uint) error {
+	return db.DeletePipelineById(id)
+}
+
+// Submit adds a task running the steps of the pipeline as the user,
+// the pipeline doesn't have to be saved
+func Submit(ctx context.Context, user *model.User, p *model.Pipeline) (*Task, error) {
+	if err := validate(p); err != nil {
+		return nil, err
+	}
+	if err := checkPermission(user, p); err != nil {
+		return nil, err
+	}
+	t := &Task{
+		PipelineID: p.ID,
+		Name:       p.Name,
+		Steps:      p.Steps,
+	}
+	t.Creator = user
+	t.ApiUrl = common.GetApiUrl(ctx)
+	TaskManager.Add(t)
+	if p.ID != 0 {
+		if err := db.UpdatePipelineLastRunTime(p.ID, time.Now()); err != nil {
+			log.Warnf("failed update last run time of pipeline %s: %+v", p.Name, err)
+		}
+	}
+	return t, nil
+}
+
+// Run submits the saved pipeline as its owner
+func Run(ctx context.Context, p *model.Pipeline) (*Task, error) {
+	owner, err := op.GetUserById(p.UserID)
+	if err != nil {
+		return nil, errors.WithMessage(err, "failed get owner of the pipeline")
+	}
+	if owner.Disabled {
+		return nil, errors.Errorf("owner %s of the pipeline is disabled", owner.Username)
+	}
+	return Submit(ctx, owner, p)
+}
+
+// Running reports whether a task of the saved pipeline is not finished
+func Running(id uint) bool {
+	return len(TaskManager.GetByCondition(func(t *Task) bool {
+		return t.PipelineID == id &&
+			!utils.SliceContains([]tache.State{tache.StateSucceeded, tache.StateCanceled, tache.StateFailed}, t.GetState())
+	})) > 0
+}
@@ -0,0 +1,66 @@
+package pipeline
+
+import (
+	"context"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	log "github.com/sirupsen/logrus"
+)
+
+type scheduledPipeline struct {
+	spec string
+	expr *cron.Expr
+	next time.Time
+}
+
+// StartScheduler submits the pipelines at their cron expressions, a pipeline
+// is skipped if the last run of it is not finished yet.
+func StartScheduler() {
+	schedules := make(map[uint]*scheduledPipeline)
+	c := cron.NewCron(time.Minute)
+	c.Do(func() {
+		pipelines, err := db.GetScheduledPipelines()
+		if err != nil {
+			log.Errorf("failed get scheduled pipelines: %+v", err)
+			return
+		}
+		now := time.Now()
+		active := make(map[uint]struct{})
+		for i := range pipelines {
+			p := &pipelines[i]
+			active[p.ID] = struct{}{}
+			sp, ok := schedules[p.ID]
+			if !ok || sp.spec != p.Cron {
+				expr, err := cron.ParseExpr(p.Cron)
+				if err != nil {
+					log.Errorf("invalid cron of pipeline %s: %+v", p.Name, err)
+					delete(schedules, p.ID)
+					continue
+				}
+				schedules[p.ID] = &scheduledPipeline{spec: p.Cron, expr: expr, next: expr.Next(now)}
+				continue
+			}
+			if sp.next.IsZero() || now.Before(sp.next) {
+				continue
+			}
+			sp.next = sp.expr.Next(now)
+			if Running(p.ID) {
+				log.Warnf("skip scheduled pipeline %s, the last run is not finished", p.Name)
+				continue
+			}
+			ctx := context.WithValue(context.Background(), conf.ApiUrlKey, common.GetApiUrlFromRequest(nil))
+			if _, err = Run(ctx, p); err != nil {
+				log.Errorf("failed run scheduled pipeline %s: %+v", p.Name, err)
+			}
+		}
+		for id := range schedules {
+			if _, ok := active[id]; !ok {
+				delete(schedules, id)
+			}
+		}
+	})
+}
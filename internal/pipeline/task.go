@@ -0,0 +1,443 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/tache"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the states of the steps
+const (
+	StepPending   = "pending"
+	StepRunning   = "running"
+	StepSucceeded = "succeeded"
+	StepFailed    = "failed"
+	StepSkipped   = "skipped"
+)
+
+// the interval of checking the state of the task submitted by a step
+var waitInterval = time.Second
+
+type StepStatus struct {
+	State    string `json:"state"`
+	Error    string `json:"error"`
+	Attempts int    `json:"attempts"`
+	// TaskID is the id of the task submitted by the step,
+	// it's used to wait for the task again after restart
+	TaskID    string     `json:"task_id"`
+	StartTime *time.Time `json:"start_time"`
+	EndTime   *time.Time `json:"end_time"`
+}
+
+// Task runs the steps of a pipeline one by one, a step submitting a task
+// is done when the task and all the tasks spawned by it are finished,
+// e.g. the transfers of an offline download.
+type Task struct {
+	task.TaskExtension
+	PipelineID uint                 `json:"pipeline_id"`
+	Name       string               `json:"name"`
+	Steps      []model.PipelineStep `json:"steps"`
+	StepStatus []StepStatus         `json:"step_status"`
+	Current    int                  `json:"current"`
+}
+
+func (t *Task) GetName() string {
+	return fmt.Sprintf("pipeline [%s]", t.Name)
+}
+
+func (t *Task) GetStatus() string {
+	if t.Current >= len(t.Steps) || t.Current >= len(t.StepStatus) {
+		return ""
+	}
+	return fmt.Sprintf("step %d/%d %s: %s", t.Current+1, len(t.Steps), t.stepName(t.Current), t.StepStatus[t.Current].State)
+}
+
+func (t *Task) GetPaths() []string {
+	if t.Creator == nil {
+		return nil
+	}
+	var paths []string
+	for _, step := range t.Steps {
+		for _, p := range []string{step.SrcPath, step.DstPath} {
+			if p == "" {
+				continue
+			}
+			if abs, err := t.Creator.JoinPath(p); err == nil {
+				paths = append(paths, abs)
+			}
+		}
+	}
+	return paths
+}
+
+func (t *Task) stepName(i int) string {
+	if t.Steps[i].Name != "" {
+		return t.Steps[i].Name
+	}
+	return t.Steps[i].Action
+}
+
+func (t *Task) OnSucceeded() {
+	task.PublishFinished("pipeline", t)
+}
+
+func (t *Task) OnFailed() {
+	task.PublishFinished("pipeline", t)
+}
+
+// Run runs the steps not succeeded yet, so a retried pipeline continues
+// from the failed step
+func (t *Task) Run() error {
+	if err := t.ReinitCtx(); err != nil {
+		return err
+	}
+	t.ClearEndTime()
+	t.SetStartTime(time.Now())
+	defer func() { t.SetEndTime(time.Now()) }()
+	if len(t.StepStatus) != len(t.Steps) {
+		t.StepStatus = make([]StepStatus, len(t.Steps))
+		for i := range t.StepStatus {
+			t.StepStatus[i].State = StepPending
+		}
+	}
+	failed := 0
+	for i := range t.Steps {
+		s := &t.StepStatus[i]
+		if s.State == StepSucceeded {
+			continue
+		}
+		t.Current = i
+		if dep, ok := t.depsSucceeded(i); !ok {
+			*s = StepStatus{State: StepSkipped, Error: fmt.Sprintf("step %d is not succeeded", dep+1)}
+			continue
+		}
+		err := t.runStep(i)
+		t.SetProgress(float64(t.succeeded()) * 100 / float64(len(t.Steps)))
+		if err == nil {
+			continue
+		}
+		if t.Ctx().Err() != nil {
+			return t.Ctx().Err()
+		}
+		failed++
+		if t.Steps[i].OnFailure != model.PipelineContinue {
+			return errors.WithMessagef(err, "step %d %s failed", i+1, t.stepName(i))
+		}
+	}
+	if failed > 0 {
+		return errors.Errorf("%d of %d steps failed", failed, len(t.Steps))
+	}
+	return nil
+}
+
+func (t *Task) succeeded() int {
+	n := 0
+	for _, s := range t.StepStatus {
+		if s.State == StepSucceeded {
+			n++
+		}
+	}
+	return n
+}
+
+// depsSucceeded returns the first dependency of the i-th step not succeeded
+func (t *Task) depsSucceeded(i int) (int, bool) {
+	for _, dep := range t.Steps[i].Deps(i) {
+		if t.StepStatus[dep].State != StepSucceeded {
+			return dep, false
+		}
+	}
+	return 0, true
+}
+
+func (t *Task) runStep(i int) error {
+	step, s := t.Steps[i], &t.StepStatus[i]
+	// the task of the step may be restored after restart
+	resume := s.State == StepRunning && s.TaskID != ""
+	start := time.Now()
+	s.State, s.Error, s.StartTime, s.EndTime = StepRunning, "", &start, nil
+	if !resume {
+		s.Attempts, s.TaskID = 0, ""
+	}
+	t.Persist()
+	var err error
+	for {
+		if resume {
+			err = t.resumeStep(i)
+			resume = false
+		} else {
+			s.Attempts++
+			err = t.execStep(i)
+		}
+		if err == nil || t.Ctx().Err() != nil || s.Attempts > step.Retry {
+			break
+		}
+		log.Warnf("pipeline %s step %d failed, retrying: %+v", t.Name, i+1, err)
+		s.TaskID = ""
+	}
+	end := time.Now()
+	s.EndTime = &end
+	if err != nil {
+		s.State, s.Error = StepFailed, err.Error()
+	} else {
+		s.State = StepSucceeded
+	}
+	t.Persist()
+	return err
+}
+
+func (t *Task) execStep(i int) error {
+	step := t.Steps[i]
+	src, dst, err := stepPaths(t.Creator, step)
+	if err != nil {
+		return err
+	}
+	ctx := t.Ctx()
+	if step.Action == model.PipelineRemove {
+		return fs.Remove(ctx, src)
+	}
+	// the submitted task and the ones spawned by it are grouped by the root
+	// id, watch before submitting as they may be done before the submit returns
+	rootID := t.rootID(i)
+	ctx = context.WithValue(ctx, conf.TaskRootKey, rootID)
+	result, stop := task_group.RootCoordinator.Watch(rootID)
+	defer stop()
+	var tsk task.TaskExtensionInfo
+	switch step.Action {
+	case model.PipelineOfflineDownload:
+		deletePolicy := tool.DeletePolicy(step.DeletePolicy)
+		if deletePolicy == "" {
+			deletePolicy = tool.DeleteOnUploadSucceed
+		}
+		tsk, err = tool.AddURL(ctx, &tool.AddURLArgs{
+			URL:          step.URL,
+			DstDirPath:   dst,
+			Tool:         step.Tool,
+			DeletePolicy: deletePolicy,
+		})
+	case model.PipelineDecompress:
+		tsk, err = fs.ArchiveDecompress(ctx, src, dst, model.ArchiveDecompressArgs{
+			ArchiveInnerArgs: model.ArchiveInnerArgs{
+				ArchiveArgs: model.ArchiveArgs{
+					Password: step.ArchivePass,
+				},
+				InnerPath: utils.FixAndCleanPath(step.InnerPath),
+			},
+			CacheFull:     step.CacheFull,
+			PutIntoNewDir: step.PutIntoNewDir,
+		})
+	case model.PipelineCopy:
+		tsk, err = fs.Copy(ctx, src, dst)
+	case model.PipelineMove:
+		tsk, err = fs.Move(ctx, src, dst)
+	}
+	// the operations inside a storage are done without task
+	if err != nil || tsk == nil {
+		return err
+	}
+	t.StepStatus[i].TaskID = tsk.GetID()
+	t.Persist()
+	return t.wait(step.Action, tsk, rootID, result)
+}
+
+// rootID is the root id of the tasks submitted by the attempt of the step
+func (t *Task) rootID(i int) string {
+	return fmt.Sprintf("pipeline-%s-%d-%d", t.GetID(), i, t.StepStatus[i].Attempts)
+}
+
+func (t *Task) resumeStep(i int) error {
+	step, s := t.Steps[i], &t.StepStatus[i]
+	m := managerOf(step.Action)
+	var (
+		tsk task.TaskExtensionInfo
+		ok  bool
+	)
+	if m != nil {
+		tsk, ok = m.get(s.TaskID)
+	}
+	if !ok {
+		// the task is not persisted, run the step again
+		s.Attempts++
+		return t.execStep(i)
+	}
+	if _, _, err := stepPaths(t.Creator, step); err != nil {
+		return err
+	}
+	rootID := t.rootID(i)
+	result, stop := task_group.RootCoordinator.Watch(rootID)
+	defer stop()
+	return t.wait(step.Action, tsk, rootID, result)
+}
+
+// wait waits for the task and the others of its root group
+func (t *Task) wait(action string, tsk task.TaskExtensionInfo, rootID string, result <-chan task_group.GroupResult) error {
+	ticker := time.NewTicker(waitInterval)
+	defer ticker.Stop()
+	var res *task_group.GroupResult
+	idle := 0
+	for {
+		select {
+		case <-t.CtxDone():
+			if m := managerOf(action); m != nil {
+				m.cancel(tsk.GetID())
+			}
+			return t.Ctx().Err()
+		case r := <-result:
+			res = &r
+		case <-ticker.C:
+		}
+		switch tsk.GetState() {
+		case tache.StateSucceeded:
+		case tache.StateFailed, tache.StateCanceled:
+			if err := tsk.GetErr(); err != nil {
+				return err
+			}
+			return errors.Errorf("task %s failed", tsk.GetName())
+		default:
+			continue
+		}
+		if res == nil {
+			// the result is sent before the group is removed
+			pending := task_group.RootCoordinator.Pending(rootID)
+			select {
+			case r := <-result:
+				res = &r
+			default:
+				if pending {
+					if rootLive(rootID) {
+						idle = 0
+						continue
+					}
+					// the tasks left are canceled before running or removed,
+					// wait a tick more for the one finishing to be done
+					if idle++; idle < 2 {
+						continue
+					}
+					task_group.RootCoordinator.Abandon(rootID)
+					continue
+				}
+				// nothing spawned into the destination
+				return nil
+			}
+		}
+		if res.Failed > 0 {
+			return errors.Errorf("%d of %d tasks failed", res.Failed, res.Failed+res.Succeeded)
+		}
+		return nil
+	}
+}
+
+// stepPaths checks the permission of the user to run the step
+// and returns the absolute source and destination paths of it
+func stepPaths(user *model.User, step model.PipelineStep) (src, dst string, err error) {
+	if user == nil {
+		return "", "", errors.New("the creator of the pipeline is unknown")
+	}
+	var allowed bool
+	switch step.Action {
+	case model.PipelineOfflineDownload:
+		allowed = user.CanAddOfflineDownloadTasks()
+	case model.PipelineDecompress:
+		allowed = user.CanDecompress()
+	case model.PipelineCopy:
+		allowed = user.CanCopy()
+	case model.PipelineMove:
+		allowed = user.CanMove()
+	case model.PipelineRemove:
+		allowed = user.CanRemove()
+	default:
+		return "", "", errors.Errorf("unknown action: %s", step.Action)
+	}
+	if !allowed {
+		return "", "", errors.WithMessagef(errs.PermissionDenied, "%s", step.Action)
+	}
+	if step.SrcPath != "" {
+		if src, err = user.JoinPath(step.SrcPath); err != nil {
+			return "", "", err
+		}
+	}
+	if step.DstPath != "" {
+		if dst, err = user.JoinPath(step.DstPath); err != nil {
+			return "", "", err
+		}
+	}
+	return src, dst, nil
+}
+
+type manager interface {
+	get(id string) (task.TaskExtensionInfo, bool)
+	cancel(id string)
+}
+
+type taskManager[T task.TaskExtensionInfo] struct {
+	m task.Manager[T]
+}
+
+func (m taskManager[T]) get(id string) (task.TaskExtensionInfo, bool) {
+	if t, ok := m.m.GetByID(id); ok {
+		return t, true
+	}
+	return nil, false
+}
+
+func (m taskManager[T]) cancel(id string) {
+	m.m.Cancel(id)
+}
+
+// rootLive reports whether a task of the root group is alive in any of the
+// managers the tasks spawned by the steps are added to
+func rootLive(rootID string) bool {
+	return live(tool.DownloadTaskManager, rootID) ||
+		live(tool.TransferTaskManager, rootID) ||
+		live(fs.ArchiveDownloadTaskManager, rootID) ||
+		live(fs.ArchiveContentUploadTaskManager.Manager, rootID) ||
+		live(fs.CopyTaskManager, rootID) ||
+		live(fs.MoveTaskManager, rootID)
+}
+
+// live reports whether a task of the root group is in the manager and not
+// finished, a canceling one won't be done if it's canceled before running
+func live[T task.TaskExtensionInfo](m *tache.Manager[T], rootID string) bool {
+	if m == nil {
+		return false
+	}
+	return len(m.GetByCondition(func(t T) bool {
+		if t.GetRootID() != rootID {
+			return false
+		}
+		switch t.GetState() {
+		case tache.StateSucceeded, tache.StateFailed, tache.StateCanceled, tache.StateCanceling:
+			return false
+		}
+		return true
+	})) > 0
+}
+
+// managerOf returns the manager of the tasks submitted by the action
+func managerOf(action string) manager {
+	switch action {
+	case model.PipelineOfflineDownload:
+		return taskManager[*tool.DownloadTask]{tool.DownloadTaskManager}
+	case model.PipelineDecompress:
+		return taskManager[*fs.ArchiveDownloadTask]{fs.ArchiveDownloadTaskManager}
+	case model.PipelineCopy:
+		return taskManager[*fs.FileTransferTask]{fs.CopyTaskManager}
+	case model.PipelineMove:
+		return taskManager[*fs.FileTransferTask]{fs.MoveTaskManager}
+	}
+	return nil
+}
+
+var TaskManager *tache.Manager[*Task]
@@ -0,0 +1,128 @@
+package pipeline_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/pipeline"
+	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
+	"github.com/OpenListTeam/tache"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers"
+)
+
+func TestPipeline(t *testing.T) {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	fs.CopyTaskManager = tache.NewManager[*fs.FileTransferTask](tache.WithWorks(2))
+	pipeline.TaskManager = tache.NewManager[*pipeline.Task](tache.WithWorks(1))
+
+	src, dst := t.TempDir(), t.TempDir()
+	for _, name := range []string{"dir/a.txt", "dir/sub/b.txt"} {
+		p := filepath.Join(src, filepath.FromSlash(name))
+		if err = os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err = os.WriteFile(p, []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	for mountPath, root := range map[string]string{"/src": src, "/dst": dst} {
+		if _, err = op.CreateStorage(ctx, model.Storage{
+			Driver:    "Local",
+			MountPath: mountPath,
+			Addition:  fmt.Sprintf(`{"root_folder_path":%q}`, root),
+		}); err != nil {
+			t.Fatalf("failed to create storage: %+v", err)
+		}
+	}
+
+	user := &model.User{ID: 1, Username: "admin", Role: model.ADMIN, BasePath: "/", Permission: 0xFFFF}
+	p := &model.Pipeline{
+		Name: "copy then remove",
+		Steps: []model.PipelineStep{
+			{Action: model.PipelineCopy, SrcPath: "/src/missing", DstPath: "/dst", OnFailure: model.PipelineContinue},
+			{Action: model.PipelineRemove, SrcPath: "/dst/missing"},
+			{Action: model.PipelineCopy, SrcPath: "/src/dir", DstPath: "/dst", DependsOn: []int{}},
+			{Action: model.PipelineRemove, SrcPath: "/src/dir"},
+		},
+	}
+	if _, err = pipeline.Submit(ctx, user, &model.Pipeline{Name: "invalid", Steps: []model.PipelineStep{
+		{Action: model.PipelineRemove, SrcPath: "/src/dir", DependsOn: []int{1}},
+	}}); err == nil {
+		t.Fatalf("the step depending on a later one is accepted")
+	}
+	// a transfer of someone else into the same destination isn't waited for
+	task_group.TransferCoordinator.AddTask("/dst", nil)
+	defer task_group.TransferCoordinator.Done("/dst", false)
+	tsk, err := pipeline.Submit(ctx, user, p)
+	if err != nil {
+		t.Fatalf("failed to submit pipeline: %+v", err)
+	}
+	deadline := time.Now().Add(30 * time.Second)
+	for !(tsk.GetState() == tache.StateFailed || tsk.GetState() == tache.StateSucceeded) {
+		if time.Now().After(deadline) {
+			t.Fatalf("pipeline is not finished: %s", tsk.GetStatus())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	// the first step fails but continues, so the pipeline fails at last
+	if tsk.GetState() != tache.StateFailed {
+		t.Fatalf("pipeline state: got %d, want failed", tsk.GetState())
+	}
+	want := []string{pipeline.StepFailed, pipeline.StepSkipped, pipeline.StepSucceeded, pipeline.StepSucceeded}
+	for i, s := range tsk.StepStatus {
+		if s.State != want[i] {
+			t.Errorf("step %d: got %s (%s), want %s", i+1, s.State, s.Error, want[i])
+		}
+	}
+	if b, err := os.ReadFile(filepath.Join(dst, "dir", "sub", "b.txt")); err != nil || string(b) != "dir/sub/b.txt" {
+		t.Errorf("copied file: got %q, %v", b, err)
+	}
+	if _, err = os.Stat(filepath.Join(src, "dir")); !os.IsNotExist(err) {
+		t.Errorf("source is not removed: %v", err)
+	}
+
+	// a task of the step removed before it's done doesn't keep the step waiting
+	if err = os.MkdirAll(filepath.Join(src, "c"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err = os.WriteFile(filepath.Join(src, "c", "c.txt"), []byte("c"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	pipeline.TaskManager.Pause()
+	tsk, err = pipeline.Submit(ctx, user, &model.Pipeline{Name: "removed task", Steps: []model.PipelineStep{
+		{Action: model.PipelineCopy, SrcPath: "/src/c", DstPath: "/dst"},
+	}})
+	if err != nil {
+		t.Fatalf("failed to submit pipeline: %+v", err)
+	}
+	task_group.RootCoordinator.AddTask(fmt.Sprintf("pipeline-%s-0-1", tsk.GetID()), nil)
+	pipeline.TaskManager.Start()
+	deadline = time.Now().Add(30 * time.Second)
+	for !(tsk.GetState() == tache.StateFailed || tsk.GetState() == tache.StateSucceeded) {
+		if time.Now().After(deadline) {
+			t.Fatalf("pipeline with a removed task is not finished: %s", tsk.GetStatus())
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if s := tsk.StepStatus[0]; s.State != pipeline.StepFailed {
+		t.Errorf("step with a removed task: got %s (%s), want %s", s.State, s.Error, pipeline.StepFailed)
+	}
+}
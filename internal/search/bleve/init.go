@@ -4,11 +4,13 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/search/searcher"
 	"github.com/blevesearch/bleve/v2"
+	"github.com/blevesearch/bleve/v2/analysis/analyzer/keyword"
 	log "github.com/sirupsen/logrus"
 )
 
 var config = searcher.Config{
-	Name: "bleve",
+	Name:     "bleve",
+	FullText: true,
 }
 
 func Init(indexPath *string) (bleve.Index, error) {
@@ -19,13 +21,22 @@ func Init(indexPath *string) (bleve.Index, error) {
 		indexMapping := bleve.NewIndexMapping()
 		searchNodeMapping := bleve.NewDocumentMapping()
 		searchNodeMapping.AddFieldMappingsAt("is_dir", bleve.NewBooleanFieldMapping())
-		// TODO: appoint analyzer
-		parentFieldMapping := bleve.NewTextFieldMapping()
+		// the parents are matched by prefix
+		parentFieldMapping := bleve.NewKeywordFieldMapping()
 		searchNodeMapping.AddFieldMappingsAt("parent", parentFieldMapping)
-		// TODO: appoint analyzer
-		nameFieldMapping := bleve.NewKeywordFieldMapping()
+		// the keywords match the words of the names
+		nameFieldMapping := bleve.NewTextFieldMapping()
 		searchNodeMapping.AddFieldMappingsAt("name", nameFieldMapping)
-		indexMapping.AddDocumentMapping("SearchNode", searchNodeMapping)
+		searchNodeMapping.AddFieldMappingsAt("size", bleve.NewNumericFieldMapping())
+		searchNodeMapping.AddFieldMappingsAt("modified", bleve.NewDateTimeFieldMapping())
+		searchNodeMapping.AddFieldMappingsAt("hash", bleve.NewKeywordFieldMapping())
+		searchNodeMapping.AddFieldMappingsAt("type", bleve.NewNumericFieldMapping())
+		searchNodeMapping.AddFieldMappingsAt("ext", bleve.NewKeywordFieldMapping())
+		// stored with the term vectors for highlighting
+		searchNodeMapping.AddFieldMappingsAt("content", bleve.NewTextFieldMapping())
+		// the nodes are indexed by value without their type, so the mapping
+		// is the default one
+		indexMapping.DefaultMapping = searchNodeMapping
 		fileIndex, err = bleve.New(*indexPath, indexMapping)
 		if err != nil {
 			return nil, err
@@ -42,6 +53,12 @@ func init() {
 		if err != nil {
 			return nil, err
 		}
-		return &Bleve{BIndex: b}, nil
+		return &Bleve{BIndex: b, parentIndexed: parentIndexed(b)}, nil
 	})
 }
+
+// parentIndexed reports whether the parents are indexed as keywords, which
+// isn't the case in the index created by the older versions
+func parentIndexed(index bleve.Index) bool {
+	return index.Mapping().AnalyzerNameForPath("parent") == keyword.Name
+}
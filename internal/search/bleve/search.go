@@ -3,6 +3,8 @@ package bleve
 import (
 	"context"
 	"os"
+	"strings"
+	"time"
 
 	query2 "github.com/blevesearch/bleve/v2/search/query"
 
@@ -13,12 +15,16 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/blevesearch/bleve/v2"
 	search2 "github.com/blevesearch/bleve/v2/search"
+	"github.com/blevesearch/bleve/v2/search/highlight/highlighter/html"
 	"github.com/google/uuid"
 	log "github.com/sirupsen/logrus"
 )
 
 type Bleve struct {
 	BIndex bleve.Index
+	// parentIndexed is false for the index created by the older versions,
+	// whose results are filtered by the parents after searching
+	parentIndexed bool
 }
 
 func (b *Bleve) Config() searcher.Config {
@@ -27,36 +33,129 @@ func (b *Bleve) Config() searcher.Config {
 
 func (b *Bleve) Search(ctx context.Context, req model.SearchReq) ([]model.SearchNode, int64, error) {
 	var queries []query2.Query
-	query := bleve.NewMatchQuery(req.Keywords)
-	query.SetField("name")
-	queries = append(queries, query)
+	if strings.TrimSpace(req.Keywords) == "" {
+		queries = append(queries, bleve.NewMatchAllQuery())
+	} else {
+		query := bleve.NewMatchQuery(req.Keywords)
+		query.SetField("name")
+		contentQuery := bleve.NewMatchQuery(req.Keywords)
+		contentQuery.SetField("content")
+		queries = append(queries, bleve.NewDisjunctionQuery(query, contentQuery))
+	}
 	if req.Scope != 0 {
 		isDir := req.Scope == 1
 		isDirQuery := bleve.NewBoolFieldQuery(isDir)
 		queries = append(queries, isDirQuery)
 	}
+	if b.parentIndexed {
+		if q := parentsQuery(req.ParentDirs()); q != nil {
+			queries = append(queries, q)
+		}
+	}
+	queries = append(queries, filterQueries(req.SearchFilter)...)
 	reqQuery := bleve.NewConjunctionQuery(queries...)
 	search := bleve.NewSearchRequest(reqQuery)
 	search.SortBy([]string{"name"})
 	search.From = (req.Page - 1) * req.PerPage
 	search.Size = req.PerPage
-	search.Fields = []string{"*"}
+	// the content is only loaded by the highlighter
+	search.Fields = []string{"parent", "name", "is_dir", "size", "modified", "type", "hash", "ext"}
+	search.Highlight = bleve.NewHighlightWithStyle(html.Name)
+	search.Highlight.AddField("content")
 	searchResults, err := b.BIndex.Search(search)
 	if err != nil {
 		log.Errorf("search error: %+v", err)
 		return nil, 0, err
 	}
 	res, err := utils.SliceConvert(searchResults.Hits, func(src *search2.DocumentMatch) (model.SearchNode, error) {
-		return model.SearchNode{
+		node := model.SearchNode{
 			Parent: src.Fields["parent"].(string),
 			Name:   src.Fields["name"].(string),
 			IsDir:  src.Fields["is_dir"].(bool),
 			Size:   int64(src.Fields["size"].(float64)),
-		}, nil
+		}
+		// the nodes indexed before may have no such fields
+		if modified, ok := src.Fields["modified"].(string); ok {
+			node.Modified, _ = time.Parse(time.RFC3339, modified)
+		}
+		if typ, ok := src.Fields["type"].(float64); ok {
+			node.Type = int(typ)
+		}
+		node.Hash, _ = src.Fields["hash"].(string)
+		node.Ext, _ = src.Fields["ext"].(string)
+		node.Snippet = strings.Join(src.Fragments["content"], " … ")
+		return node, nil
 	})
 	return res, int64(searchResults.Total), nil
 }
 
+// parentsQuery matches the nodes in any of the dirs, it's nil if the root
+// is one of them
+func parentsQuery(parents []string) query2.Query {
+	var queries []query2.Query
+	for _, parent := range parents {
+		parent = utils.FixAndCleanPath(parent)
+		if parent == "/" {
+			return nil
+		}
+		q := bleve.NewTermQuery(parent)
+		q.SetField("parent")
+		sub := bleve.NewPrefixQuery(parent + "/")
+		sub.SetField("parent")
+		queries = append(queries, q, sub)
+	}
+	return bleve.NewDisjunctionQuery(queries...)
+}
+
+func filterQueries(f model.SearchFilter) []query2.Query {
+	var queries []query2.Query
+	inclusive := true
+	if f.MinSize > 0 || f.MaxSize > 0 {
+		var min, max *float64
+		if f.MinSize > 0 {
+			v := float64(f.MinSize)
+			min = &v
+		}
+		if f.MaxSize > 0 {
+			v := float64(f.MaxSize)
+			max = &v
+		}
+		q := bleve.NewNumericRangeInclusiveQuery(min, max, &inclusive, &inclusive)
+		q.SetField("size")
+		queries = append(queries, q)
+	}
+	if !f.ModifiedAfter.IsZero() || !f.ModifiedBefore.IsZero() {
+		q := bleve.NewDateRangeInclusiveQuery(f.ModifiedAfter, f.ModifiedBefore, &inclusive, &inclusive)
+		q.SetField("modified")
+		queries = append(queries, q)
+	}
+	if len(f.Exts) > 0 {
+		exts := make([]query2.Query, len(f.Exts))
+		for i, ext := range f.Exts {
+			q := bleve.NewTermQuery(ext)
+			q.SetField("ext")
+			exts[i] = q
+		}
+		queries = append(queries, bleve.NewDisjunctionQuery(exts...))
+	}
+	if len(f.Types) > 0 {
+		types := make([]query2.Query, len(f.Types))
+		for i, typ := range f.Types {
+			v := float64(typ)
+			q := bleve.NewNumericRangeInclusiveQuery(&v, &v, &inclusive, &inclusive)
+			q.SetField("type")
+			types[i] = q
+		}
+		queries = append(queries, bleve.NewDisjunctionQuery(types...))
+	}
+	if f.Hash != "" {
+		q := bleve.NewTermQuery(f.Hash)
+		q.SetField("hash")
+		queries = append(queries, q)
+	}
+	return queries
+}
+
 func (b *Bleve) Index(ctx context.Context, node model.SearchNode) error {
 	return b.BIndex.Index(uuid.NewString(), node)
 }
@@ -99,6 +198,7 @@ func (b *Bleve) Clear(ctx context.Context) error {
 		return err
 	}
 	b.BIndex = bIndex
+	b.parentIndexed = parentIndexed(bIndex)
 	return nil
 }
 
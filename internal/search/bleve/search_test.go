@@ -0,0 +1,56 @@
+package bleve
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+)
+
+func TestSearchParents(t *testing.T) {
+	indexPath := filepath.Join(t.TempDir(), "bleve")
+	index, err := Init(&indexPath)
+	if err != nil {
+		t.Fatalf("failed to init index: %+v", err)
+	}
+	b := &Bleve{BIndex: index, parentIndexed: parentIndexed(index)}
+	defer b.Release(context.Background())
+	if !b.parentIndexed {
+		t.Fatalf("the parents of the new index are not indexed as keywords")
+	}
+	ctx := context.Background()
+	if err = b.BatchIndex(ctx, []model.SearchNode{
+		{Parent: "/a", Name: "foo 1.txt"},
+		{Parent: "/a/b", Name: "foo 2.txt"},
+		{Parent: "/ab", Name: "foo 3.txt"},
+		{Parent: "/c", Name: "foo 4.txt"},
+	}); err != nil {
+		t.Fatalf("failed to index: %+v", err)
+	}
+	search := func(req model.SearchReq) ([]model.SearchNode, int64) {
+		req.Keywords = "foo"
+		req.PageReq = model.PageReq{Page: 1, PerPage: 2}
+		nodes, total, err := b.Search(ctx, req)
+		if err != nil {
+			t.Fatalf("failed to search: %+v", err)
+		}
+		return nodes, total
+	}
+	// the total counts the nodes in the dirs only, not the ones of the page
+	nodes, total := search(model.SearchReq{Parent: "/", Parents: []string{"/a", "/c"}})
+	if total != 3 || len(nodes) != 2 {
+		t.Fatalf("search in the dirs: got %d of %d nodes", len(nodes), total)
+	}
+	for _, node := range nodes {
+		if node.Parent == "/ab" {
+			t.Errorf("search in the dirs: got %s/%s", node.Parent, node.Name)
+		}
+	}
+	if _, total = search(model.SearchReq{Parent: "/a/b"}); total != 1 {
+		t.Errorf("search in the parent: got %d nodes", total)
+	}
+	if _, total = search(model.SearchReq{Parent: "/"}); total != 4 {
+		t.Errorf("search in the root: got %d nodes", total)
+	}
+}
@@ -203,7 +203,7 @@ func Update(parent string, objs []model.Obj) {
 	if instance == nil || !instance.Config().AutoUpdate || !setting.GetBool(conf.AutoUpdateIndex) || Running() {
 		return
 	}
-	if isIgnorePath(parent) {
+	if isIgnorePath(parent) || inJob(parent) {
 		return
 	}
 	ctx := context.Background()
@@ -0,0 +1,66 @@
+package search
+
+import (
+	"context"
+	"io"
+	"path"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/search/extract"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// fillContent extracts the text of the files to be searched by the content,
+// if it's enabled and supported by the searcher
+func fillContent(ctx context.Context, nodes []model.SearchNode) {
+	if !instance.Config().FullText || !setting.GetBool(conf.IndexContent) {
+		return
+	}
+	maxSize := int64(setting.GetInt(conf.IndexContentMaxSize, 10)) << 20
+	for i := range nodes {
+		node := &nodes[i]
+		if node.IsDir || node.Size <= 0 || node.Size > maxSize || !extract.Supported(node.Name) {
+			continue
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		text, err := fetchContent(ctx, path.Join(node.Parent, node.Name), maxSize)
+		if err != nil {
+			log.Warnf("failed extract content of %s: %+v", path.Join(node.Parent, node.Name), err)
+			continue
+		}
+		node.Content = text
+	}
+}
+
+func fetchContent(ctx context.Context, p string, maxSize int64) (string, error) {
+	storage, actualPath, err := op.GetStorageAndActualPath(p)
+	if err != nil {
+		return "", err
+	}
+	link, obj, err := op.Link(ctx, storage, actualPath, model.LinkArgs{})
+	if err != nil {
+		return "", err
+	}
+	defer link.Close()
+	if obj.GetSize() > maxSize {
+		return "", errors.Errorf("size %d exceeds the limit", obj.GetSize())
+	}
+	rr, err := stream.GetRangeReaderFromLink(obj.GetSize(), link)
+	if err != nil {
+		return "", err
+	}
+	rc, err := rr.RangeRead(ctx, http_range.Range{Length: obj.GetSize()})
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	return extract.Extract(obj.GetName(), io.LimitReader(rc, maxSize))
+}
@@ -0,0 +1,21 @@
+package db_fts
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/search/searcher"
+)
+
+var config = searcher.Config{
+	Name:       "database_fts",
+	AutoUpdate: true,
+	FullText:   true,
+}
+
+func init() {
+	searcher.RegisterSearcher(config, func() (searcher.Searcher, error) {
+		if err := db.InitSearchNodeFTS(); err != nil {
+			return nil, err
+		}
+		return &DB{}, nil
+	})
+}
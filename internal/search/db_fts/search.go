@@ -0,0 +1,56 @@
+package db_fts
+
+import (
+	"context"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/search/searcher"
+)
+
+// DB searches with the native full-text index of the database,
+// the nodes are kept in the same table as the other database searchers
+type DB struct{}
+
+func (D DB) Config() searcher.Config {
+	return config
+}
+
+func (D DB) Search(ctx context.Context, req model.SearchReq) ([]model.SearchNode, int64, error) {
+	nodes, total, err := db.SearchNodeFTS(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	terms := db.FullTextTerms(req.Keywords)
+	for i := range nodes {
+		nodes[i].Snippet = snippet(nodes[i].Content, terms)
+		nodes[i].Content = ""
+	}
+	return nodes, total, nil
+}
+
+func (D DB) Index(ctx context.Context, node model.SearchNode) error {
+	return db.CreateSearchNode(&node)
+}
+
+func (D DB) BatchIndex(ctx context.Context, nodes []model.SearchNode) error {
+	return db.BatchCreateSearchNodes(&nodes)
+}
+
+func (D DB) Get(ctx context.Context, parent string) ([]model.SearchNode, error) {
+	return db.GetSearchNodesByParent(parent)
+}
+
+func (D DB) Del(ctx context.Context, path string) error {
+	return db.DeleteSearchNodesByParent(path)
+}
+
+func (D DB) Release(ctx context.Context) error {
+	return nil
+}
+
+func (D DB) Clear(ctx context.Context) error {
+	return db.ClearSearchNodes()
+}
+
+var _ searcher.Searcher = (*DB)(nil)
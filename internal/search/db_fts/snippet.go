@@ -0,0 +1,77 @@
+package db_fts
+
+import (
+	"html"
+	"strings"
+	"unicode"
+)
+
+const (
+	// runes kept around the first matched term
+	snippetBefore = 40
+	snippetAfter  = 120
+)
+
+// snippet returns the escaped fragment of the content around the first term
+// matched as a prefix of a word, with the matched words highlighted by <mark>
+func snippet(content string, terms []string) string {
+	if content == "" || len(terms) == 0 {
+		return ""
+	}
+	runes := []rune(content)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsNumber(r)
+	}
+	// match reports the length of the word at i if it starts with a term
+	match := func(i int) int {
+		if !isWord(lower[i]) || (i > 0 && isWord(lower[i-1])) {
+			return 0
+		}
+		for _, term := range terms {
+			t := []rune(term)
+			if len(t) <= len(lower)-i && string(lower[i:i+len(t)]) == term {
+				end := i + len(t)
+				for end < len(lower) && isWord(lower[end]) {
+					end++
+				}
+				return end - i
+			}
+		}
+		return 0
+	}
+	first := -1
+	for i := range lower {
+		if match(i) > 0 {
+			first = i
+			break
+		}
+	}
+	if first < 0 {
+		return ""
+	}
+	start, end := max(first-snippetBefore, 0), min(first+snippetAfter, len(runes))
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+	for i := start; i < end; {
+		if n := match(i); n > 0 {
+			n = min(n, end-i)
+			sb.WriteString("<mark>")
+			sb.WriteString(html.EscapeString(string(runes[i : i+n])))
+			sb.WriteString("</mark>")
+			i += n
+			continue
+		}
+		sb.WriteString(html.EscapeString(string(runes[i])))
+		i++
+	}
+	if end < len(runes) {
+		sb.WriteString("…")
+	}
+	return sb.String()
+}
@@ -0,0 +1,27 @@
+package db_fts
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSnippet(t *testing.T) {
+	long := strings.Repeat("lorem ipsum ", 10)
+	tests := []struct {
+		content string
+		terms   []string
+		want    string
+	}{
+		{"", []string{"a"}, ""},
+		{"nothing here", []string{"march"}, ""},
+		{"The <Annual> meeting, annually", []string{"annual"}, "The &lt;<mark>Annual</mark>&gt; meeting, <mark>annually</mark>"},
+		// only the prefix of a word matches
+		{"reannual report", []string{"annual", "rep"}, "reannual <mark>report</mark>"},
+		{long + "target", []string{"tar"}, "…" + long[len(long)-40:] + "<mark>target</mark>"},
+	}
+	for _, tt := range tests {
+		if got := snippet(tt.content, tt.terms); got != tt.want {
+			t.Errorf("snippet(%q, %v): got %q, want %q", tt.content, tt.terms, got, tt.want)
+		}
+	}
+}
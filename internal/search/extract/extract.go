@@ -0,0 +1,81 @@
+package extract
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+)
+
+// MaxTextLength is the max bytes of the text extracted from a file,
+// the rest is dropped
+const MaxTextLength = 1 << 20
+
+type extractor func(data []byte) (string, error)
+
+var extractors = map[string]extractor{
+	"pdf":  pdfText,
+	"docx": docxText,
+	"odt":  odtText,
+}
+
+// Supported reports whether the text of the file can be extracted,
+// which are the text types and pdf, docx, odt files
+func Supported(name string) bool {
+	if _, ok := extractors[utils.Ext(name)]; ok {
+		return true
+	}
+	return utils.GetFileType(name) == conf.TEXT
+}
+
+// Extract reads the file from r and returns the plain text of it
+func Extract(name string, r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	text := ""
+	if e, ok := extractors[utils.Ext(name)]; ok {
+		if text, err = e(data); err != nil {
+			return "", errors.WithMessagef(err, "failed extract text of %s", name)
+		}
+	} else {
+		text = string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	}
+	return normalize(text), nil
+}
+
+// normalize drops the invalid and control characters, collapses the spaces
+// and truncates the text to MaxTextLength
+func normalize(text string) string {
+	var sb strings.Builder
+	space, newline := false, false
+	for _, r := range strings.ToValidUTF8(text, "") {
+		switch {
+		case r == '\n' || r == '\r':
+			newline = true
+		case unicode.IsSpace(r):
+			space = true
+		case unicode.IsControl(r) || r == utf8.RuneError:
+		default:
+			if sb.Len() > 0 {
+				if newline {
+					sb.WriteByte('\n')
+				} else if space {
+					sb.WriteByte(' ')
+				}
+			}
+			space, newline = false, false
+			if sb.Len()+utf8.RuneLen(r) > MaxTextLength {
+				return sb.String()
+			}
+			sb.WriteRune(r)
+		}
+	}
+	return sb.String()
+}
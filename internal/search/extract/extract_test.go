@@ -0,0 +1,89 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+)
+
+func zipFile(t *testing.T, name, content string) []byte {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	zw := zip.NewWriter(buf)
+	w, err := zw.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func pdfFile(t *testing.T, content string) []byte {
+	t.Helper()
+	compressed := &bytes.Buffer{}
+	zw := zlib.NewWriter(compressed)
+	if _, err := zw.Write([]byte(content)); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	buf := &bytes.Buffer{}
+	buf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	fmt.Fprintf(buf, "4 0 obj\n<< /Length %d /Filter /FlateDecode >>\nstream\n", compressed.Len())
+	buf.Write(compressed.Bytes())
+	buf.WriteString("\nendstream\nendobj\n")
+	buf.WriteString("5 0 obj\n<< /Length 10 /Subtype /Image /Filter /DCTDecode >>\nstream\n(Image) Tj\nendstream\nendobj\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func TestExtract(t *testing.T) {
+	conf.SlicesMap[conf.TextTypes] = []string{"txt", "md"}
+	tests := []struct {
+		name string
+		data []byte
+		want string
+	}{
+		{"a.txt", []byte("\xef\xbb\xbfhello   world\r\n\r\nsecond\x00 line"), "hello world\nsecond line"},
+		{"a.docx", zipFile(t, "word/document.xml", `<?xml version="1.0"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Hello</w:t></w:r><w:r><w:tab/><w:t xml:space="preserve">docx </w:t></w:r></w:p>
+<w:p><w:r><w:instrText>PAGE</w:instrText><w:t>next</w:t></w:r></w:p></w:body></w:document>`), "Hello docx\nnext"},
+		{"a.odt", zipFile(t, "content.xml", `<?xml version="1.0"?>
+<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">
+<office:font-face-decls>Arial</office:font-face-decls><office:body><office:text>
+<text:h>Title</text:h><text:p>Hello<text:s/>odt<text:line-break/>end</text:p></office:text></office:body></office:document-content>`), "Title\nHello odt\nend"},
+		{"a.pdf", pdfFile(t, "BT /F1 12 Tf 72 712 Td (Hello \\(PDF\\)) Tj 0 -14 Td [(Wor) 20 (ld) -300 (again)] TJ <FEFF00E9> Tj <0024> Tj ET"),
+			"Hello (PDF)\nWorld againé"},
+	}
+	for _, tt := range tests {
+		if !Supported(tt.name) {
+			t.Errorf("%s is not supported", tt.name)
+			continue
+		}
+		got, err := Extract(tt.name, bytes.NewReader(tt.data))
+		if err != nil {
+			t.Errorf("extract %s: %+v", tt.name, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("extract %s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+	if Supported("a.png") {
+		t.Errorf("png is supported")
+	}
+	if _, err := Extract("bad.docx", strings.NewReader("not a zip")); err == nil {
+		t.Errorf("extract bad docx: expected error")
+	}
+}
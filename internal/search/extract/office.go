@@ -0,0 +1,108 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+func openZipEntry(data []byte, name string) (io.ReadCloser, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	f, err := zr.Open(name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed open %s", name)
+	}
+	return f, nil
+}
+
+// xmlText walks the elements of the xml document, the text of the char data
+// accepted by inText is kept, the spacing elements are written by onElement
+func xmlText(r io.Reader, inText func(stack []string) bool, onElement func(sb *strings.Builder, name string, end bool)) (string, error) {
+	var (
+		sb    strings.Builder
+		stack []string
+	)
+	dec := xml.NewDecoder(r)
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return sb.String(), nil
+		}
+		if err != nil {
+			return "", errors.WithStack(err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			stack = append(stack, t.Name.Local)
+			onElement(&sb, t.Name.Local, false)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+			onElement(&sb, t.Name.Local, true)
+		case xml.CharData:
+			if inText(stack) {
+				sb.Write(t)
+			}
+		}
+		if sb.Len() > MaxTextLength {
+			return sb.String(), nil
+		}
+	}
+}
+
+// docxText reads the text runs in word/document.xml
+func docxText(data []byte) (string, error) {
+	f, err := openZipEntry(data, "word/document.xml")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return xmlText(f, func(stack []string) bool {
+		return len(stack) > 0 && stack[len(stack)-1] == "t"
+	}, func(sb *strings.Builder, name string, end bool) {
+		switch {
+		case !end && name == "tab":
+			sb.WriteByte('\t')
+		case !end && (name == "br" || name == "cr"):
+			sb.WriteByte('\n')
+		case end && name == "p":
+			sb.WriteByte('\n')
+		}
+	})
+}
+
+// odtText reads the text in the body of content.xml
+func odtText(data []byte) (string, error) {
+	f, err := openZipEntry(data, "content.xml")
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	return xmlText(f, func(stack []string) bool {
+		for _, name := range stack {
+			if name == "body" {
+				return true
+			}
+		}
+		return false
+	}, func(sb *strings.Builder, name string, end bool) {
+		switch {
+		case !end && name == "s":
+			sb.WriteByte(' ')
+		case !end && name == "tab":
+			sb.WriteByte('\t')
+		case !end && name == "line-break":
+			sb.WriteByte('\n')
+		case end && (name == "p" || name == "h"):
+			sb.WriteByte('\n')
+		}
+	})
+}
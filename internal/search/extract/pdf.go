@@ -0,0 +1,355 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"io"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+var (
+	streamKeyword    = []byte("stream")
+	endstreamKeyword = []byte("endstream")
+	// the streams can't contain the page text
+	skippedStreams = []string{"/Subtype/Image", "/Length1", "/Length2", "/Length3",
+		"/Type/XRef", "/Type/ObjStm", "/Type/Metadata", "/Subtype/Type1C",
+		"/Subtype/CIDFontType0C", "/Subtype/OpenType"}
+)
+
+// pdfText reads the text shown by the content streams of the pdf. The glyphs
+// of the fonts with custom encodings can't be mapped back to the characters,
+// the strings in them are dropped, so it works for the most of the documents
+// exported by the office softwares, but not for the scanned or subset ones.
+func pdfText(data []byte) (string, error) {
+	var sb strings.Builder
+	for pos := 0; pos < len(data) && sb.Len() < MaxTextLength; {
+		i := bytes.Index(data[pos:], streamKeyword)
+		if i < 0 {
+			break
+		}
+		start := pos + i
+		pos = start + len(streamKeyword)
+		// skip the "endstream" keywords
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			continue
+		}
+		bodyStart := pos
+		if bodyStart < len(data) && data[bodyStart] == '\r' {
+			bodyStart++
+		}
+		if bodyStart >= len(data) || data[bodyStart] != '\n' {
+			continue
+		}
+		bodyStart++
+		j := bytes.Index(data[bodyStart:], endstreamKeyword)
+		if j < 0 {
+			break
+		}
+		body := data[bodyStart : bodyStart+j]
+		pos = bodyStart + j + len(endstreamKeyword)
+
+		dictStart := bytes.LastIndex(data[:start], []byte("obj"))
+		if dictStart < 0 {
+			continue
+		}
+		dict := strings.Join(strings.Fields(string(data[dictStart:start])), "")
+		if skipStream(dict) {
+			continue
+		}
+		content, ok := decodeStream(dict, body)
+		if !ok {
+			continue
+		}
+		readContentText(&sb, content)
+	}
+	return sb.String(), nil
+}
+
+func skipStream(dict string) bool {
+	for _, s := range skippedStreams {
+		for i := 0; ; {
+			j := strings.Index(dict[i:], s)
+			if j < 0 {
+				break
+			}
+			i += j + len(s)
+			// the key or name must end here, "/Length1" is not "/Length10"
+			if i == len(dict) || isPDFDelimiter(dict[i]) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func decodeStream(dict string, body []byte) ([]byte, bool) {
+	if !strings.Contains(dict, "/Filter") {
+		return body, true
+	}
+	// the other filters are used by images and fonts
+	if strings.Count(dict, "Decode") != 1 || !strings.Contains(dict, "/FlateDecode") {
+		return nil, false
+	}
+	zr, err := zlib.NewReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, false
+	}
+	defer zr.Close()
+	content, err := io.ReadAll(io.LimitReader(zr, 16*MaxTextLength))
+	// the truncated streams are still readable
+	if err != nil && len(content) == 0 {
+		return nil, false
+	}
+	return content, true
+}
+
+type pdfToken struct {
+	str     []byte
+	isStr   bool
+	num     float64
+	isNum   bool
+	isArray bool
+	array   []pdfToken
+}
+
+// readContentText runs the text showing operators of the content stream
+func readContentText(sb *strings.Builder, content []byte) {
+	var (
+		operands []pdfToken
+		arrays   [][]pdfToken
+	)
+	push := func(t pdfToken) {
+		if len(arrays) > 0 {
+			arrays[len(arrays)-1] = append(arrays[len(arrays)-1], t)
+		} else {
+			operands = append(operands, t)
+		}
+	}
+	lastNum := func(back int) float64 {
+		if len(operands) >= back && operands[len(operands)-back].isNum {
+			return operands[len(operands)-back].num
+		}
+		return 0
+	}
+	writeStr := func(t pdfToken) {
+		if t.isStr {
+			sb.WriteString(pdfString(t.str))
+		}
+	}
+	for i := 0; i < len(content); {
+		c := content[i]
+		switch {
+		case isPDFSpace(c):
+			i++
+		case c == '%':
+			for i < len(content) && content[i] != '\n' && content[i] != '\r' {
+				i++
+			}
+		case c == '(':
+			s, n := readLiteralString(content[i:])
+			push(pdfToken{str: s, isStr: true})
+			i += n
+		case c == '<' && i+1 < len(content) && content[i+1] == '<':
+			i += 2
+		case c == '>' && i+1 < len(content) && content[i+1] == '>':
+			i += 2
+		case c == '<':
+			s, n := readHexString(content[i:])
+			push(pdfToken{str: s, isStr: s != nil})
+			i += n
+		case c == '[':
+			arrays = append(arrays, nil)
+			i++
+		case c == ']':
+			i++
+			if len(arrays) == 0 {
+				continue
+			}
+			array := arrays[len(arrays)-1]
+			arrays = arrays[:len(arrays)-1]
+			push(pdfToken{isArray: true, array: array})
+		default:
+			j := i + 1
+			for j < len(content) && !isPDFSpace(content[j]) && !isPDFDelimiter(content[j]) {
+				j++
+			}
+			word := string(content[i:j])
+			i = j
+			if c == '/' || c == '{' || c == '}' || c == ')' || c == '>' {
+				push(pdfToken{})
+				continue
+			}
+			if num, err := strconv.ParseFloat(word, 64); err == nil {
+				push(pdfToken{num: num, isNum: true})
+				continue
+			}
+			switch word {
+			case "Tj":
+				if len(operands) > 0 {
+					writeStr(operands[len(operands)-1])
+				}
+			case "'", "\"":
+				sb.WriteByte('\n')
+				if len(operands) > 0 {
+					writeStr(operands[len(operands)-1])
+				}
+			case "TJ":
+				if len(operands) > 0 {
+					for _, t := range operands[len(operands)-1].array {
+						// a large negative adjustment is a space between the words
+						if t.isNum && t.num < -200 {
+							sb.WriteByte(' ')
+						}
+						writeStr(t)
+					}
+				}
+			case "T*", "ET":
+				sb.WriteByte('\n')
+			case "Td", "TD":
+				if lastNum(1) != 0 {
+					sb.WriteByte('\n')
+				} else {
+					sb.WriteByte(' ')
+				}
+			case "Tm":
+				sb.WriteByte(' ')
+			case "ID":
+				// skip the data of the inline image
+				if k := bytes.Index(content[i:], []byte("EI")); k >= 0 {
+					i += k + 2
+				} else {
+					i = len(content)
+				}
+			}
+			operands = operands[:0]
+			arrays = arrays[:0]
+		}
+		if sb.Len() > MaxTextLength {
+			return
+		}
+	}
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// readLiteralString reads the string in parentheses at the beginning of b,
+// returns the unescaped string and the bytes consumed
+func readLiteralString(b []byte) ([]byte, int) {
+	var s []byte
+	depth := 0
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		switch c {
+		case '(':
+			if depth > 0 {
+				s = append(s, c)
+			}
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return s, i + 1
+			}
+			s = append(s, c)
+		case '\\':
+			i++
+			if i >= len(b) {
+				return s, i
+			}
+			switch e := b[i]; e {
+			case 'n':
+				s = append(s, '\n')
+			case 'r':
+				s = append(s, '\r')
+			case 't':
+				s = append(s, '\t')
+			case 'b':
+				s = append(s, '\b')
+			case 'f':
+				s = append(s, '\f')
+			case '\r':
+				if i+1 < len(b) && b[i+1] == '\n' {
+					i++
+				}
+			case '\n':
+			default:
+				if e >= '0' && e <= '7' {
+					v := 0
+					k := 0
+					for ; k < 3 && i+k < len(b) && b[i+k] >= '0' && b[i+k] <= '7'; k++ {
+						v = v*8 + int(b[i+k]-'0')
+					}
+					s = append(s, byte(v))
+					i += k - 1
+				} else {
+					s = append(s, e)
+				}
+			}
+		default:
+			s = append(s, c)
+		}
+	}
+	return s, len(b)
+}
+
+// readHexString reads the hex string at the beginning of b, the string is
+// nil unless it's in utf-16 or printable ascii, since the others are mostly
+// the glyph ids of the fonts
+func readHexString(b []byte) ([]byte, int) {
+	end := bytes.IndexByte(b, '>')
+	if end < 0 {
+		return nil, len(b)
+	}
+	hex := make([]byte, 0, end)
+	for _, c := range b[1:end] {
+		if !isPDFSpace(c) {
+			hex = append(hex, c)
+		}
+	}
+	if len(hex)%2 == 1 {
+		hex = append(hex, '0')
+	}
+	s := make([]byte, len(hex)/2)
+	for i := range s {
+		v, err := strconv.ParseUint(string(hex[i*2:i*2+2]), 16, 8)
+		if err != nil {
+			return nil, end + 1
+		}
+		s[i] = byte(v)
+	}
+	if bytes.HasPrefix(s, []byte{0xfe, 0xff}) {
+		return s, end + 1
+	}
+	for _, c := range s {
+		if c < 0x20 || c > 0x7e {
+			return nil, end + 1
+		}
+	}
+	return s, end + 1
+}
+
+// pdfString decodes the text string in utf-16 with bom or in the single
+// byte encodings, which are close to latin-1 for the text
+func pdfString(s []byte) string {
+	if bytes.HasPrefix(s, []byte{0xfe, 0xff}) {
+		s = s[2:]
+		u := make([]uint16, len(s)/2)
+		for i := range u {
+			u[i] = uint16(s[i*2])<<8 | uint16(s[i*2+1])
+		}
+		return string(utf16.Decode(u))
+	}
+	r := make([]rune, len(s))
+	for i, c := range s {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
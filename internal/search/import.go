@@ -3,6 +3,7 @@ package search
 import (
 	_ "github.com/OpenListTeam/OpenList/v4/internal/search/bleve"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/search/db"
+	_ "github.com/OpenListTeam/OpenList/v4/internal/search/db_fts"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/search/db_non_full_text"
 	_ "github.com/OpenListTeam/OpenList/v4/internal/search/meilisearch"
 )
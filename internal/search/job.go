@@ -0,0 +1,306 @@
+package search
+
+import (
+	"context"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// the interval of saving the progress of a running job
+const jobProgressInterval = 5 * time.Second
+
+type runningJob struct {
+	mountPath string
+	cancel    context.CancelFunc
+}
+
+// jobs holds the running index jobs by storage id
+var jobs sync.Map
+
+// JobRunning reports whether the index job of the storage is running
+func JobRunning(storageId uint) bool {
+	_, ok := jobs.Load(storageId)
+	return ok
+}
+
+// AnyJobRunning reports whether any index job is running
+func AnyJobRunning() bool {
+	running := false
+	jobs.Range(func(_, _ any) bool {
+		running = true
+		return false
+	})
+	return running
+}
+
+// inJob reports whether the path is being indexed by a running job,
+// the objs update hook leaves these paths to the job
+func inJob(p string) bool {
+	covered := false
+	jobs.Range(func(_, value any) bool {
+		mountPath := value.(*runningJob).mountPath
+		if mountPath == "/" || p == mountPath || strings.HasPrefix(p, mountPath+"/") {
+			covered = true
+			return false
+		}
+		return true
+	})
+	return covered
+}
+
+// StopIndexJob cancels the running index job of the storage
+func StopIndexJob(storageId uint) bool {
+	value, ok := jobs.Load(storageId)
+	if ok {
+		value.(*runningJob).cancel()
+	}
+	return ok
+}
+
+// RunIndexJob updates the index of the storage incrementally: every folder
+// is listed and compared with the indexed nodes of it, only the objects
+// added, changed or removed since the last run are written to the index.
+func RunIndexJob(ctx context.Context, storage driver.Driver) error {
+	if instance == nil {
+		return errs.SearchNotAvailable
+	}
+	if !instance.Config().AutoUpdate {
+		return errors.WithMessagef(errs.NotSupport, "incremental index of %s", instance.Config().Name)
+	}
+	if Running() {
+		return errs.BuildIndexIsRunning
+	}
+	s := storage.GetStorage()
+	if s.DisableIndex {
+		return errors.Errorf("index of storage %s is disabled", s.MountPath)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	if _, loaded := jobs.LoadOrStore(s.ID, &runningJob{mountPath: s.MountPath, cancel: cancel}); loaded {
+		return errs.IndexJobIsRunning
+	}
+	defer jobs.Delete(s.ID)
+
+	start := time.Now()
+	j := &indexJob{
+		storage: storage,
+		ignore:  jobIgnorePaths(s),
+		progress: &model.IndexJob{
+			StorageID: s.ID,
+			Running:   true,
+			StartTime: &start,
+		},
+		saved: start,
+	}
+	if last, err := db.GetIndexJobByStorageId(s.ID); err == nil {
+		j.progress.LastDoneTime = last.LastDoneTime
+	}
+	j.save()
+	depth := s.IndexDepth
+	if depth <= 0 {
+		depth = setting.GetInt(conf.MaxIndexDepth, 20)
+	}
+	log.Infof("start index job of %s", s.MountPath)
+	err := j.walk(ctx, "/", depth)
+	done := time.Now()
+	j.progress.Running = false
+	j.progress.LastDoneTime = &done
+	if err != nil {
+		log.Errorf("index job of %s error: %+v", s.MountPath, err)
+		j.progress.Error = err.Error()
+	} else {
+		log.Infof("index job of %s done, objs: %d, added: %d, updated: %d, removed: %d", s.MountPath,
+			j.progress.ObjCount, j.progress.Added, j.progress.Updated, j.progress.Removed)
+	}
+	j.save()
+	return err
+}
+
+func jobIgnorePaths(s *model.Storage) []string {
+	ignore := append([]string{}, conf.SlicesMap[conf.IgnorePaths]...)
+	for _, p := range strings.Split(s.IndexIgnore, "\n") {
+		if p = strings.TrimSpace(p); p != "" {
+			ignore = append(ignore, utils.GetFullPath(s.MountPath, p))
+		}
+	}
+	return ignore
+}
+
+type indexJob struct {
+	storage  driver.Driver
+	ignore   []string
+	progress *model.IndexJob
+	saved    time.Time
+}
+
+func (j *indexJob) save() {
+	j.saved = time.Now()
+	if err := db.SaveIndexJob(j.progress); err != nil {
+		log.Errorf("save index job progress error: %+v", err)
+	}
+}
+
+func (j *indexJob) ignored(p string) bool {
+	for _, ignorePath := range j.ignore {
+		if strings.HasPrefix(p, ignorePath) {
+			return true
+		}
+	}
+	return false
+}
+
+// nodeChanged reports whether the file has to be indexed again, the folders
+// are only compared by type since their modified time changes with the content
+func nodeChanged(node model.SearchNode, obj model.Obj) bool {
+	if node.IsDir || obj.IsDir() {
+		return node.IsDir != obj.IsDir()
+	}
+	return node.Size != obj.GetSize() || !node.Modified.Equal(obj.ModTime())
+}
+
+func (j *indexJob) walk(ctx context.Context, actualPath string, depth int) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	parent := utils.GetFullPath(j.storage.GetStorage().MountPath, actualPath)
+	objs, err := op.List(ctx, j.storage, actualPath, model.ListArgs{Refresh: true})
+	if err != nil {
+		// keep the index of the folder as it is
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Warnf("index job failed list %s: %+v", parent, err)
+		return nil
+	}
+	nodes, err := instance.Get(ctx, parent)
+	if err != nil {
+		return errors.WithMessagef(err, "failed get index of %s", parent)
+	}
+	old := make(map[string]model.SearchNode, len(nodes))
+	for _, node := range nodes {
+		old[node.Name] = node
+	}
+	var (
+		toIndex []model.SearchNode
+		dirs    []string
+	)
+	for _, obj := range objs {
+		name := obj.GetName()
+		p := path.Join(parent, name)
+		if j.ignored(p) || (actualPath == "/" && name == model.TrashDirName) {
+			continue
+		}
+		j.progress.ObjCount++
+		node, ok := old[name]
+		delete(old, name)
+		if !ok {
+			toIndex = append(toIndex, toSearchNode(parent, obj))
+			j.progress.Added++
+		} else if nodeChanged(node, obj) {
+			if err = instance.Del(ctx, p); err != nil {
+				return errors.WithMessagef(err, "failed del index of %s", p)
+			}
+			toIndex = append(toIndex, toSearchNode(parent, obj))
+			j.progress.Updated++
+		}
+		if obj.IsDir() && depth > 1 {
+			dirs = append(dirs, name)
+		}
+	}
+	for name := range old {
+		p := path.Join(parent, name)
+		// the storages mounted inside are indexed by themselves
+		if op.HasStorage(p) {
+			continue
+		}
+		if err = instance.Del(ctx, p); err != nil {
+			return errors.WithMessagef(err, "failed del index of %s", p)
+		}
+		j.progress.Removed++
+	}
+	if len(toIndex) > 0 {
+		fillContent(ctx, toIndex)
+		if err = instance.BatchIndex(ctx, toIndex); err != nil {
+			return errors.WithMessagef(err, "failed index objs in %s", parent)
+		}
+	}
+	if time.Since(j.saved) > jobProgressInterval {
+		j.save()
+	}
+	for _, name := range dirs {
+		if err = j.walk(ctx, path.Join(actualPath, name), depth-1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type scheduledJob struct {
+	spec string
+	expr *cron.Expr
+	next time.Time
+}
+
+// StartIndexScheduler runs the index jobs of the storages at their cron
+// expressions, the storages are checked every minute so the changes of
+// them take effect without restart.
+func StartIndexScheduler() {
+	if err := db.ResetRunningIndexJobs(); err != nil {
+		log.Errorf("failed reset running index jobs: %+v", err)
+	}
+	schedules := make(map[uint]*scheduledJob)
+	c := cron.NewCron(time.Minute)
+	c.Do(func() {
+		now := time.Now()
+		active := make(map[uint]struct{})
+		for _, storage := range op.GetAllStorages() {
+			s := storage.GetStorage()
+			if s.IndexCron == "" || s.DisableIndex {
+				continue
+			}
+			active[s.ID] = struct{}{}
+			sj, ok := schedules[s.ID]
+			if !ok || sj.spec != s.IndexCron {
+				expr, err := cron.ParseExpr(s.IndexCron)
+				if err != nil {
+					log.Errorf("invalid index cron of %s: %+v", s.MountPath, err)
+					delete(schedules, s.ID)
+					continue
+				}
+				schedules[s.ID] = &scheduledJob{spec: s.IndexCron, expr: expr, next: expr.Next(now)}
+				continue
+			}
+			if sj.next.IsZero() || now.Before(sj.next) {
+				continue
+			}
+			sj.next = sj.expr.Next(now)
+			if JobRunning(s.ID) {
+				continue
+			}
+			go func(storage driver.Driver) {
+				if err := RunIndexJob(context.Background(), storage); err != nil {
+					log.Warnf("scheduled index job of %s: %+v", storage.GetStorage().MountPath, err)
+				}
+			}(storage)
+		}
+		for id := range schedules {
+			if _, ok := active[id]; !ok {
+				delete(schedules, id)
+			}
+		}
+	})
+}
@@ -0,0 +1,93 @@
+package search_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/search"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	_ "github.com/OpenListTeam/OpenList/v4/drivers"
+	_ "github.com/OpenListTeam/OpenList/v4/internal/search/db"
+)
+
+func TestRunIndexJob(t *testing.T) {
+	dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("failed to connect database: %v", err)
+	}
+	conf.Conf = conf.DefaultConfig("data")
+	db.Init(dB)
+	if err = search.Init("database"); err != nil {
+		t.Fatalf("failed to init search: %+v", err)
+	}
+	root := t.TempDir()
+	write := func(name, content string) {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("a.txt", "a")
+	write("dir/b.txt", "b")
+	write("skip/c.txt", "c")
+	ctx := context.Background()
+	_, err = op.CreateStorage(ctx, model.Storage{
+		Driver:        "Local",
+		MountPath:     "/job_test",
+		Addition:      fmt.Sprintf(`{"root_folder_path":%q}`, root),
+		IndexSchedule: model.IndexSchedule{IndexCron: "@daily", IndexIgnore: "/skip"},
+	})
+	if err != nil {
+		t.Fatalf("failed to create storage: %+v", err)
+	}
+	storage, err := op.GetStorageByMountPath("/job_test")
+	if err != nil {
+		t.Fatalf("failed to get storage: %+v", err)
+	}
+
+	run := func(added, updated, removed uint64) {
+		t.Helper()
+		if err := search.RunIndexJob(ctx, storage); err != nil {
+			t.Fatalf("failed to run index job: %+v", err)
+		}
+		job, err := db.GetIndexJobByStorageId(storage.GetStorage().ID)
+		if err != nil {
+			t.Fatalf("failed to get index job: %+v", err)
+		}
+		if job.Running || job.Error != "" || job.LastDoneTime == nil ||
+			job.Added != added || job.Updated != updated || job.Removed != removed {
+			t.Fatalf("index job: got %+v, want added %d, updated %d, removed %d", job, added, updated, removed)
+		}
+	}
+	run(3, 0, 0)
+	if nodes, _ := db.GetSearchNodesByParent("/job_test/dir"); len(nodes) != 1 || nodes[0].Name != "b.txt" {
+		t.Errorf("nodes of dir: got %+v", nodes)
+	}
+	if nodes, _ := db.GetSearchNodesByParent("/job_test/skip"); len(nodes) != 0 {
+		t.Errorf("ignored nodes are indexed: %+v", nodes)
+	}
+	run(0, 0, 0)
+
+	write("dir/b.txt", "changed")
+	write("d.txt", "d")
+	if err = os.Remove(filepath.Join(root, "a.txt")); err != nil {
+		t.Fatal(err)
+	}
+	run(1, 1, 1)
+	nodes, _ := db.GetSearchNodesByParent("/job_test/dir")
+	if len(nodes) != 1 || nodes[0].Size != int64(len("changed")) {
+		t.Errorf("updated nodes of dir: got %+v", nodes)
+	}
+}
@@ -15,6 +15,7 @@ import (
 var config = searcher.Config{
 	Name:       "meilisearch",
 	AutoUpdate: true,
+	FullText:   true,
 }
 
 func init() {
@@ -30,8 +31,9 @@ func init() {
 			),
 			IndexUid: indexUid,
 			FilterableAttributes: []string{"parent", "is_dir", "name",
-				"parent_hash", "parent_path_hashes"},
-			SearchableAttributes: []string{"name"},
+				"parent_hash", "parent_path_hashes",
+				"size", "modified_unix", "hash", "type", "ext"},
+			SearchableAttributes: []string{"name", "content"},
 		}
 
 		_, err := m.Client.GetIndex(m.IndexUid)
@@ -25,6 +25,8 @@ type searchDocument struct {
 	// Can be used for filtering all descendants exactly.
 	// Storing path hashes instead of plaintext paths benefits disk usage and case-sensitive filter.
 	ParentPathHashes []string `json:"parent_path_hashes"`
+	// Unix time of modified, as the dates can't be compared in filters
+	ModifiedUnix int64 `json:"modified_unix"`
 	model.SearchNode
 }
 
@@ -44,16 +46,31 @@ func (m *Meilisearch) Search(ctx context.Context, req model.SearchReq) ([]model.
 		AttributesToSearchOn: m.SearchableAttributes,
 		Page:                 int64(req.Page),
 		HitsPerPage:          int64(req.PerPage),
+		// the content is only returned as the cropped snippet
+		AttributesToRetrieve:  []string{"parent", "name", "is_dir", "size", "modified", "type", "hash", "ext"},
+		AttributesToCrop:      []string{"content"},
+		CropLength:            30,
+		AttributesToHighlight: []string{"content"},
+		HighlightPreTag:       highlightPreTag,
+		HighlightPostTag:      highlightPostTag,
 	}
 	var filters []string
 	if req.Scope != 0 {
 		filters = append(filters, fmt.Sprintf("is_dir = %v", req.Scope == 1))
 	}
-	if req.Parent != "" && req.Parent != "/" {
-		// use parent_path_hashes to filter descendants
-		parentHash := hashPath(req.Parent)
-		filters = append(filters, fmt.Sprintf("parent_path_hashes = '%s'", parentHash))
+	// use parent_path_hashes to filter descendants
+	var parentHashes []string
+	for _, parent := range req.ParentDirs() {
+		if parent == "" || parent == "/" {
+			parentHashes = nil
+			break
+		}
+		parentHashes = append(parentHashes, fmt.Sprintf("'%s'", hashPath(parent)))
+	}
+	if len(parentHashes) > 0 {
+		filters = append(filters, fmt.Sprintf("parent_path_hashes IN [%s]", strings.Join(parentHashes, ", ")))
 	}
+	filters = append(filters, buildFilters(req.SearchFilter)...)
 	if len(filters) > 0 {
 		mReq.Filter = strings.Join(filters, " AND ")
 	}
@@ -64,12 +81,26 @@ func (m *Meilisearch) Search(ctx context.Context, req model.SearchReq) ([]model.
 	}
 	nodes, err := utils.SliceConvert(search.Hits, func(src any) (model.SearchNode, error) {
 		srcMap := src.(map[string]any)
-		return model.SearchNode{
+		node := model.SearchNode{
 			Parent: srcMap["parent"].(string),
 			Name:   srcMap["name"].(string),
 			IsDir:  srcMap["is_dir"].(bool),
 			Size:   int64(srcMap["size"].(float64)),
-		}, nil
+		}
+		// the documents indexed before may have no such fields
+		if modified, ok := srcMap["modified"].(string); ok {
+			node.Modified, _ = time.Parse(time.RFC3339, modified)
+		}
+		if typ, ok := srcMap["type"].(float64); ok {
+			node.Type = int(typ)
+		}
+		node.Hash, _ = srcMap["hash"].(string)
+		node.Ext, _ = srcMap["ext"].(string)
+		if formatted, ok := srcMap["_formatted"].(map[string]any); ok {
+			snippet, _ := formatted["content"].(string)
+			node.Snippet = escapeSnippet(snippet)
+		}
+		return node, nil
 	})
 	if err != nil {
 		return nil, 0, err
@@ -95,6 +126,7 @@ func (m *Meilisearch) BatchIndex(ctx context.Context, nodes []model.SearchNode)
 			ID:               nodePathHash,
 			ParentHash:       parentHash,
 			ParentPathHashes: parentPathHashes,
+			ModifiedUnix:     src.Modified.Unix(),
 			SearchNode:       src,
 		}, nil
 	})
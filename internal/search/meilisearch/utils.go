@@ -1,6 +1,11 @@
 package meilisearch
 
 import (
+	"fmt"
+	"html"
+	"strings"
+	"time"
+
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 )
@@ -23,9 +28,66 @@ func buildSearchDocumentFromResults(results map[string]any) *searchDocument {
 	searchNode.Name, _ = results["name"].(string)
 	searchNode.IsDir, _ = results["is_dir"].(bool)
 	searchNode.Size, _ = results["size"].(int64)
+	searchNode.Hash, _ = results["hash"].(string)
+	searchNode.Ext, _ = results["ext"].(string)
+	if typ, ok := results["type"].(float64); ok {
+		searchNode.Type = int(typ)
+	}
+	if modified, ok := results["modified"].(string); ok {
+		searchNode.Modified, _ = time.Parse(time.RFC3339, modified)
+	}
 
 	document.ID, _ = results["id"].(string)
 	document.ParentHash, _ = results["parent_hash"].(string)
 	document.ParentPathHashes, _ = results["parent_path_hashes"].([]string)
 	return document
 }
+
+// quote quotes the string value in the filter expression
+func quote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(s) + "'"
+}
+
+func buildFilters(f model.SearchFilter) []string {
+	var filters []string
+	if f.MinSize > 0 {
+		filters = append(filters, fmt.Sprintf("size >= %d", f.MinSize))
+	}
+	if f.MaxSize > 0 {
+		filters = append(filters, fmt.Sprintf("size <= %d", f.MaxSize))
+	}
+	if !f.ModifiedAfter.IsZero() {
+		filters = append(filters, fmt.Sprintf("modified_unix >= %d", f.ModifiedAfter.Unix()))
+	}
+	if !f.ModifiedBefore.IsZero() {
+		filters = append(filters, fmt.Sprintf("modified_unix <= %d", f.ModifiedBefore.Unix()))
+	}
+	if len(f.Exts) > 0 {
+		exts, _ := utils.SliceConvert(f.Exts, func(ext string) (string, error) {
+			return quote(ext), nil
+		})
+		filters = append(filters, fmt.Sprintf("ext IN [%s]", strings.Join(exts, ", ")))
+	}
+	if len(f.Types) > 0 {
+		types, _ := utils.SliceConvert(f.Types, func(typ int) (string, error) {
+			return fmt.Sprint(typ), nil
+		})
+		filters = append(filters, fmt.Sprintf("type IN [%s]", strings.Join(types, ", ")))
+	}
+	if f.Hash != "" {
+		filters = append(filters, "hash = "+quote(f.Hash))
+	}
+	return filters
+}
+
+// the highlight tags are private use characters, replaced after escaping
+// the snippet, so it's safe to render the same as the ones of bleve
+const (
+	highlightPreTag  = "\ue000"
+	highlightPostTag = "\ue001"
+)
+
+func escapeSnippet(snippet string) string {
+	return strings.NewReplacer(highlightPreTag, "<mark>", highlightPostTag, "</mark>").
+		Replace(html.EscapeString(snippet))
+}
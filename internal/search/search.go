@@ -3,12 +3,14 @@ package search
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/search/searcher"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	log "github.com/sirupsen/logrus"
 )
 
@@ -55,12 +57,42 @@ func Index(ctx context.Context, parent string, obj model.Obj) error {
 	if instance == nil {
 		return errs.SearchNotAvailable
 	}
-	return instance.Index(ctx, model.SearchNode{
-		Parent: parent,
-		Name:   obj.GetName(),
-		IsDir:  obj.IsDir(),
-		Size:   obj.GetSize(),
-	})
+	nodes := []model.SearchNode{toSearchNode(parent, obj)}
+	fillContent(ctx, nodes)
+	return instance.Index(ctx, nodes[0])
+}
+
+// toSearchNode converts the obj in the parent to the search node
+func toSearchNode(parent string, obj model.Obj) model.SearchNode {
+	node := model.SearchNode{
+		Parent:   parent,
+		Name:     obj.GetName(),
+		IsDir:    obj.IsDir(),
+		Size:     obj.GetSize(),
+		Modified: obj.ModTime(),
+		Type:     utils.GetObjType(obj.GetName(), obj.IsDir()),
+	}
+	if !obj.IsDir() {
+		node.Ext = utils.Ext(obj.GetName())
+		node.Hash = nodeHash(obj.GetHash())
+	}
+	return node
+}
+
+// nodeHash picks one hash of the obj to be searched exactly,
+// the common ones are preferred
+func nodeHash(hi utils.HashInfo) string {
+	for _, ht := range []*utils.HashType{utils.MD5, utils.SHA1, utils.SHA256} {
+		if h := hi.GetHash(ht); h != "" {
+			return strings.ToLower(h)
+		}
+	}
+	for _, h := range hi.All() {
+		if h != "" {
+			return strings.ToLower(h)
+		}
+	}
+	return ""
 }
 
 type ObjWithParent struct {
@@ -77,13 +109,9 @@ func BatchIndex(ctx context.Context, objs []ObjWithParent) error {
 	}
 	var searchNodes []model.SearchNode
 	for i := range objs {
-		searchNodes = append(searchNodes, model.SearchNode{
-			Parent: objs[i].Parent,
-			Name:   objs[i].GetName(),
-			IsDir:  objs[i].IsDir(),
-			Size:   objs[i].GetSize(),
-		})
+		searchNodes = append(searchNodes, toSearchNode(objs[i].Parent, objs[i].Obj))
 	}
+	fillContent(ctx, searchNodes)
 	return instance.BatchIndex(ctx, searchNodes)
 }
 
@@ -9,6 +9,8 @@ import (
 type Config struct {
 	Name       string
 	AutoUpdate bool
+	// FullText searchers keep the content of the files
+	FullText bool
 }
 
 type Searcher interface {
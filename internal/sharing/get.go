@@ -24,6 +24,9 @@ func get(ctx context.Context, sid, path string, args model.SharingListArgs) (*mo
 		return sharing, nil, errors.WithStack(errs.WrongShareCode)
 	}
 	path = utils.FixAndCleanPath(path)
+	if !sharing.CanList() && path != "/" {
+		return sharing, nil, errors.WithStack(errs.SharingListNotAllowed)
+	}
 	if len(sharing.Files) == 1 || path != "/" {
 		unwrapPath, err := op.GetSharingUnwrapPath(sharing, path)
 		if err != nil {
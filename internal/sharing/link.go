@@ -24,6 +24,9 @@ func link(ctx context.Context, sid, path string, args *LinkArgs) (*model.Sharing
 		return sharing, nil, nil, errors.WithStack(errs.WrongShareCode)
 	}
 	path = utils.FixAndCleanPath(path)
+	if !sharing.CanList() {
+		return sharing, nil, nil, errors.WithStack(errs.SharingListNotAllowed)
+	}
 	if len(sharing.Files) == 1 || path != "/" {
 		unwrapPath, err := op.GetSharingUnwrapPath(sharing, path)
 		if err != nil {
@@ -23,6 +23,9 @@ func list(ctx context.Context, sid, path string, args model.SharingListArgs) (*m
 		return sharing, nil, errors.WithStack(errs.WrongShareCode)
 	}
 	path = utils.FixAndCleanPath(path)
+	if !sharing.CanList() {
+		return sharing, nil, errors.WithStack(errs.SharingListNotAllowed)
+	}
 	if len(sharing.Files) == 1 || path != "/" {
 		unwrapPath, err := op.GetSharingUnwrapPath(sharing, path)
 		if err != nil {
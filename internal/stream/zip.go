@@ -0,0 +1,540 @@
+package stream
+
+import (
+	"context"
+	"encoding/binary"
+	stderrors "errors"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/cache"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/pkg/errors"
+)
+
+// ZipFile is a file or folder in the zip of ZipStream
+type ZipFile struct {
+	// Name is the slash separated path in the zip
+	Name string
+	Obj  model.Obj
+	// Path identifies the source to cache the crc of it, empty not to cache
+	Path string
+	// Link returns the link of the file content, it's not called for folders
+	Link func(ctx context.Context) (*model.Link, error)
+}
+
+// the crc32 of the files computed by the previous requests, so a resumed
+// download doesn't have to read the files before the range again
+var zipCRCCache = cache.NewKeyedCache[uint32](time.Hour)
+
+const (
+	zipHeaderLen          = 30
+	zipCentralLen         = 46
+	zipDescriptorLen      = 16
+	zipDescriptor64Len    = 24
+	zipExtTimeLen         = 9
+	zip64ExtraLen         = 28
+	zip64EndLen           = 56
+	zip64LocatorLen       = 20
+	zipEndLen             = 22
+	zipVersion20          = 20
+	zipVersion45          = 45
+	zipFlagDescriptor     = 0x8
+	zipFlagUTF8           = 0x800
+	zipUint32Max          = 0xffffffff
+	zipUint16Max          = 0xffff
+	zipCreatorUnix        = 3
+	zipExtTimeID          = 0x5455
+	zip64ExtraID          = 0x0001
+	zipLocalSignature     = 0x04034b50
+	zipCentralSignature   = 0x02014b50
+	zipDescriptorSig      = 0x08074b50
+	zip64EndSignature     = 0x06064b50
+	zip64LocatorSignature = 0x07064b50
+	zipEndSignature       = 0x06054b50
+)
+
+type zipSegmentKind int
+
+const (
+	zipSegmentHeader zipSegmentKind = iota
+	zipSegmentData
+	zipSegmentDescriptor
+	zipSegmentCentral
+	zipSegmentEnd
+)
+
+type zipSegment struct {
+	kind   zipSegmentKind
+	offset int64
+	length int64
+	file   int
+}
+
+type zipEntry struct {
+	ZipFile
+	name   string
+	offset int64
+	zip64  bool
+}
+
+// ZipStream is a store-mode zip of the files generated on the fly, the size
+// is known before reading and any range of it can be read, the crc of a file
+// is computed while the content is read.
+type ZipStream struct {
+	entries  []zipEntry
+	segments []zipSegment
+	size     int64
+	cdOffset int64
+	cdSize   int64
+	end64    bool
+	mu       sync.Mutex
+	crcs     map[int]uint32
+}
+
+func NewZipStream(files []ZipFile) *ZipStream {
+	z := &ZipStream{crcs: make(map[int]uint32)}
+	var offset int64
+	for i, f := range files {
+		e := zipEntry{ZipFile: f, name: f.Name, offset: offset}
+		if f.Obj.IsDir() {
+			e.name += "/"
+		}
+		e.zip64 = offset >= zipUint32Max || (!f.Obj.IsDir() && f.Obj.GetSize() >= zipUint32Max)
+		z.entries = append(z.entries, e)
+		offset = z.add(zipSegmentHeader, offset, int64(zipHeaderLen+len(e.name)+zipExtTimeLen), i)
+		if f.Obj.IsDir() {
+			continue
+		}
+		offset = z.add(zipSegmentData, offset, f.Obj.GetSize(), i)
+		descriptorLen := int64(zipDescriptorLen)
+		if e.zip64 {
+			descriptorLen = zipDescriptor64Len
+		}
+		offset = z.add(zipSegmentDescriptor, offset, descriptorLen, i)
+	}
+	z.cdOffset = offset
+	for _, e := range z.entries {
+		z.cdSize += int64(zipCentralLen + len(e.name) + zipExtTimeLen)
+		if e.zip64 {
+			z.cdSize += zip64ExtraLen
+		}
+	}
+	offset = z.add(zipSegmentCentral, offset, z.cdSize, -1)
+	z.end64 = len(z.entries) >= zipUint16Max || z.cdOffset >= zipUint32Max || z.cdSize >= zipUint32Max
+	endLen := int64(zipEndLen)
+	if z.end64 {
+		endLen += zip64EndLen + zip64LocatorLen
+	}
+	z.size = z.add(zipSegmentEnd, offset, endLen, -1)
+	return z
+}
+
+func (z *ZipStream) add(kind zipSegmentKind, offset, length int64, file int) int64 {
+	if length > 0 {
+		z.segments = append(z.segments, zipSegment{kind: kind, offset: offset, length: length, file: file})
+	}
+	return offset + length
+}
+
+// GetSize returns the size of the whole zip
+func (z *ZipStream) GetSize() int64 {
+	return z.size
+}
+
+func (z *ZipStream) RangeRead(ctx context.Context, httpRange http_range.Range) (io.ReadCloser, error) {
+	if httpRange.Start < 0 || httpRange.Start > z.size {
+		return nil, errors.Errorf("range start %d out of the size %d", httpRange.Start, z.size)
+	}
+	end := z.size
+	if httpRange.Length >= 0 && httpRange.Start+httpRange.Length < end {
+		end = httpRange.Start + httpRange.Length
+	}
+	return &zipReader{z: z, ctx: ctx, pos: httpRange.Start, end: end}, nil
+}
+
+// CanRange reports whether the ranges can be read without reading the files
+// out of them. The central directory has the crc of all the files, so a range
+// reaching it has to read the whole files before the range whose crc isn't
+// cached, e.g. the download is resumed after the cache expired. The file the
+// range starts in is read from the start, that costs one file at most.
+func (z *ZipStream) CanRange(ranges []http_range.Range) bool {
+	for _, r := range ranges {
+		if r.Length >= 0 && r.Start+r.Length <= z.cdOffset {
+			continue
+		}
+		for _, seg := range z.segments {
+			if seg.kind != zipSegmentData || seg.offset+seg.length > r.Start {
+				continue
+			}
+			if _, ok := z.getCRC(seg.file); !ok {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (z *ZipStream) getCRC(i int) (uint32, bool) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	if crc, ok := z.crcs[i]; ok {
+		return crc, true
+	}
+	if key := z.cacheKey(i); key != "" {
+		if crc, ok := zipCRCCache.Get(key); ok {
+			z.crcs[i] = crc
+			return crc, true
+		}
+	}
+	return 0, false
+}
+
+func (z *ZipStream) setCRC(i int, crc uint32) {
+	z.mu.Lock()
+	defer z.mu.Unlock()
+	z.crcs[i] = crc
+	if key := z.cacheKey(i); key != "" {
+		zipCRCCache.Set(key, crc)
+	}
+}
+
+func (z *ZipStream) cacheKey(i int) string {
+	e := z.entries[i]
+	if e.Path == "" {
+		return ""
+	}
+	return fmt.Sprintf("%s:%d:%d", e.Path, e.Obj.GetSize(), e.Obj.ModTime().UnixNano())
+}
+
+// crc returns the crc of the i-th file, the whole file is read if it's unknown
+func (z *ZipStream) crc(ctx context.Context, i int) (uint32, error) {
+	if crc, ok := z.getCRC(i); ok {
+		return crc, nil
+	}
+	if z.entries[i].Obj.GetSize() == 0 {
+		return 0, nil
+	}
+	rc, err := z.openData(ctx, i, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer rc.Close()
+	if _, err = io.Copy(io.Discard, rc); err != nil {
+		return 0, err
+	}
+	crc, _ := z.getCRC(i)
+	return crc, nil
+}
+
+// openData returns the content of the i-th file from the offset, the crc is
+// computed if it's unknown by reading the content before the offset too
+func (z *ZipStream) openData(ctx context.Context, i int, offset int64) (io.ReadCloser, error) {
+	e := z.entries[i]
+	size := e.Obj.GetSize()
+	_, known := z.getCRC(i)
+	start := offset
+	if !known {
+		start = 0
+	}
+	link, err := e.Link(ctx)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed get link of %s", e.Name)
+	}
+	rr, err := GetRangeReaderFromLink(size, link)
+	if err != nil {
+		_ = link.Close()
+		return nil, err
+	}
+	rc, err := rr.RangeRead(ctx, http_range.Range{Start: start, Length: size - start})
+	if err != nil {
+		_ = link.Close()
+		return nil, err
+	}
+	closer := func() error {
+		return stderrors.Join(rc.Close(), link.Close())
+	}
+	if known {
+		return &zipDataReader{Reader: io.LimitReader(rc, size-start), remain: size - start, close: closer}, nil
+	}
+	r := &zipDataReader{
+		Reader: io.LimitReader(rc, size),
+		remain: size,
+		hash:   crc32.NewIEEE(),
+		close:  closer,
+		done:   func(crc uint32) { z.setCRC(i, crc) },
+	}
+	if _, err = io.CopyN(io.Discard, r, offset); err != nil {
+		_ = r.Close()
+		return nil, err
+	}
+	return r, nil
+}
+
+type zipDataReader struct {
+	io.Reader
+	remain int64
+	hash   hash.Hash32
+	close  func() error
+	done   func(crc uint32)
+}
+
+func (r *zipDataReader) Read(p []byte) (int, error) {
+	n, err := r.Reader.Read(p)
+	r.remain -= int64(n)
+	if r.hash != nil {
+		_, _ = r.hash.Write(p[:n])
+	}
+	if r.remain == 0 && r.done != nil {
+		r.done(r.hash.Sum32())
+		r.done = nil
+	}
+	if err == io.EOF && r.remain > 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+	return n, err
+}
+
+func (r *zipDataReader) Close() error {
+	return r.close()
+}
+
+func (z *ZipStream) segmentBytes(ctx context.Context, seg zipSegment) ([]byte, error) {
+	switch seg.kind {
+	case zipSegmentHeader:
+		return z.localHeader(seg.file), nil
+	case zipSegmentDescriptor:
+		crc, err := z.crc(ctx, seg.file)
+		if err != nil {
+			return nil, err
+		}
+		return z.descriptor(seg.file, crc), nil
+	case zipSegmentCentral:
+		return z.centralDirectory(ctx)
+	case zipSegmentEnd:
+		return z.endRecords(), nil
+	}
+	return nil, errors.New("not a generated segment")
+}
+
+func (z *ZipStream) localHeader(i int) []byte {
+	e := z.entries[i]
+	b := zipBuf(make([]byte, 0, zipHeaderLen+len(e.name)+zipExtTimeLen))
+	date, tm := msDosTime(e.Obj.ModTime())
+	flags := uint16(zipFlagUTF8)
+	if !e.Obj.IsDir() {
+		flags |= zipFlagDescriptor
+	}
+	b = b.uint32(zipLocalSignature).uint16(e.version()).uint16(flags).uint16(0) // store
+	b = b.uint16(tm).uint16(date)
+	// the crc and sizes are in the data descriptor
+	b = b.uint32(0).uint32(0).uint32(0)
+	b = b.uint16(uint16(len(e.name))).uint16(zipExtTimeLen)
+	b = append(b, e.name...)
+	return b.extTime(e.Obj.ModTime())
+}
+
+func (z *ZipStream) descriptor(i int, crc uint32) []byte {
+	e := z.entries[i]
+	b := zipBuf(make([]byte, 0, zipDescriptor64Len))
+	b = b.uint32(zipDescriptorSig).uint32(crc)
+	size := uint64(e.Obj.GetSize())
+	if e.zip64 {
+		return b.uint64(size).uint64(size)
+	}
+	return b.uint32(uint32(size)).uint32(uint32(size))
+}
+
+func (z *ZipStream) centralDirectory(ctx context.Context) ([]byte, error) {
+	b := zipBuf(make([]byte, 0, z.cdSize))
+	for i, e := range z.entries {
+		var crc uint32
+		if !e.Obj.IsDir() {
+			var err error
+			if crc, err = z.crc(ctx, i); err != nil {
+				return nil, err
+			}
+		}
+		date, tm := msDosTime(e.Obj.ModTime())
+		flags, mode := uint16(zipFlagUTF8), uint32(0o100644<<16)
+		size, extraLen := uint64(e.Obj.GetSize()), uint16(zipExtTimeLen)
+		if e.Obj.IsDir() {
+			mode, size = 0o40755<<16|0x10, 0
+		} else {
+			flags |= zipFlagDescriptor
+		}
+		if e.zip64 {
+			extraLen += zip64ExtraLen
+		}
+		b = b.uint32(zipCentralSignature).uint16(zipCreatorUnix<<8 | e.version()).uint16(e.version())
+		b = b.uint16(flags).uint16(0).uint16(tm).uint16(date).uint32(crc)
+		if e.zip64 {
+			b = b.uint32(zipUint32Max).uint32(zipUint32Max)
+		} else {
+			b = b.uint32(uint32(size)).uint32(uint32(size))
+		}
+		b = b.uint16(uint16(len(e.name))).uint16(extraLen).uint16(0) // comment
+		b = b.uint16(0).uint16(0).uint32(mode)                       // disk, internal attrs
+		if e.zip64 {
+			b = b.uint32(zipUint32Max)
+		} else {
+			b = b.uint32(uint32(e.offset))
+		}
+		b = append(b, e.name...)
+		if e.zip64 {
+			b = b.uint16(zip64ExtraID).uint16(zip64ExtraLen - 4)
+			b = b.uint64(size).uint64(size).uint64(uint64(e.offset))
+		}
+		b = b.extTime(e.Obj.ModTime())
+	}
+	return b, nil
+}
+
+func (z *ZipStream) endRecords() []byte {
+	b := zipBuf(make([]byte, 0, zipEndLen+zip64EndLen+zip64LocatorLen))
+	records, size, offset := uint64(len(z.entries)), uint64(z.cdSize), uint64(z.cdOffset)
+	if z.end64 {
+		end64Offset := uint64(z.cdOffset + z.cdSize)
+		b = b.uint32(zip64EndSignature).uint64(zip64EndLen - 12)
+		b = b.uint16(zipVersion45).uint16(zipVersion45).uint32(0).uint32(0)
+		b = b.uint64(records).uint64(records).uint64(size).uint64(offset)
+		b = b.uint32(zip64LocatorSignature).uint32(0).uint64(end64Offset).uint32(1)
+		records, size, offset = zipUint16Max, zipUint32Max, zipUint32Max
+	}
+	b = b.uint32(zipEndSignature).uint16(0).uint16(0)
+	b = b.uint16(uint16(records)).uint16(uint16(records)).uint32(uint32(size)).uint32(uint32(offset))
+	return b.uint16(0) // comment
+}
+
+func (e *zipEntry) version() uint16 {
+	if e.zip64 {
+		return zipVersion45
+	}
+	return zipVersion20
+}
+
+type zipReader struct {
+	z        *ZipStream
+	ctx      context.Context
+	pos, end int64
+	cur      io.Reader
+	closer   io.Closer
+	segEnd   int64
+}
+
+func (r *zipReader) Read(p []byte) (int, error) {
+	for {
+		if r.pos >= r.end {
+			return 0, io.EOF
+		}
+		if r.cur == nil {
+			if err := r.next(); err != nil {
+				return 0, err
+			}
+		}
+		if remain := min(r.segEnd, r.end) - r.pos; int64(len(p)) > remain {
+			p = p[:remain]
+		}
+		n, err := r.cur.Read(p)
+		r.pos += int64(n)
+		if r.pos >= r.segEnd || err == io.EOF {
+			if r.pos < min(r.segEnd, r.end) {
+				return n, io.ErrUnexpectedEOF
+			}
+			r.closeCur()
+			err = nil
+		}
+		if n > 0 || err != nil {
+			return n, err
+		}
+	}
+}
+
+// next opens the segment at the current position
+func (r *zipReader) next() error {
+	i := sort.Search(len(r.z.segments), func(i int) bool {
+		s := r.z.segments[i]
+		return s.offset+s.length > r.pos
+	})
+	if i == len(r.z.segments) {
+		return io.EOF
+	}
+	seg := r.z.segments[i]
+	off := r.pos - seg.offset
+	r.segEnd = seg.offset + seg.length
+	if seg.kind == zipSegmentData {
+		rc, err := r.z.openData(r.ctx, seg.file, off)
+		if err != nil {
+			return err
+		}
+		r.cur, r.closer = rc, rc
+		return nil
+	}
+	b, err := r.z.segmentBytes(r.ctx, seg)
+	if err != nil {
+		return err
+	}
+	r.cur = &zipBytesReader{b: b[off:]}
+	return nil
+}
+
+func (r *zipReader) closeCur() {
+	if r.closer != nil {
+		_ = r.closer.Close()
+	}
+	r.cur, r.closer = nil, nil
+}
+
+func (r *zipReader) Close() error {
+	r.closeCur()
+	return nil
+}
+
+type zipBytesReader struct {
+	b []byte
+}
+
+func (r *zipBytesReader) Read(p []byte) (int, error) {
+	if len(r.b) == 0 {
+		return 0, io.EOF
+	}
+	n := copy(p, r.b)
+	r.b = r.b[n:]
+	return n, nil
+}
+
+type zipBuf []byte
+
+func (b zipBuf) uint16(v uint16) zipBuf {
+	return binary.LittleEndian.AppendUint16(b, v)
+}
+
+func (b zipBuf) uint32(v uint32) zipBuf {
+	return binary.LittleEndian.AppendUint32(b, v)
+}
+
+func (b zipBuf) uint64(v uint64) zipBuf {
+	return binary.LittleEndian.AppendUint64(b, v)
+}
+
+// extTime appends the extended timestamp extra field with the modified time
+func (b zipBuf) extTime(t time.Time) zipBuf {
+	b = b.uint16(zipExtTimeID).uint16(zipExtTimeLen - 4)
+	b = append(b, 1) // only the modified time
+	return b.uint32(uint32(max(t.Unix(), 0)))
+}
+
+func msDosTime(t time.Time) (date, tm uint16) {
+	if t.Year() < 1980 {
+		t = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+	date = uint16(t.Day() + int(t.Month())<<5 + (t.Year()-1980)<<9)
+	tm = uint16(t.Second()/2 + t.Minute()<<5 + t.Hour()<<11)
+	return
+}
@@ -0,0 +1,106 @@
+package stream
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+)
+
+func TestZipStream(t *testing.T) {
+	contents := map[string][]byte{
+		"dir/a.txt":     []byte("hello"),
+		"dir/sub/b.bin": bytes.Repeat([]byte("0123456789"), 1000),
+		"dir/empty":     {},
+	}
+	modified := time.Date(2024, 5, 6, 7, 8, 10, 0, time.UTC)
+	file := func(name string) ZipFile {
+		content := contents[name]
+		return ZipFile{
+			Name: name,
+			Obj:  &model.Object{Name: name, Size: int64(len(content)), Modified: modified},
+			Link: func(ctx context.Context) (*model.Link, error) {
+				return &model.Link{RangeReader: RangeReaderFunc(func(ctx context.Context, r http_range.Range) (io.ReadCloser, error) {
+					return io.NopCloser(bytes.NewReader(content[r.Start : r.Start+r.Length])), nil
+				})}, nil
+			},
+		}
+	}
+	dir := func(name string) ZipFile {
+		return ZipFile{Name: name, Obj: &model.Object{Name: name, IsFolder: true, Modified: modified}}
+	}
+	files := []ZipFile{dir("dir"), file("dir/a.txt"), file("dir/empty"), dir("dir/sub"), file("dir/sub/b.bin")}
+
+	read := func(z *ZipStream, start, length int64) []byte {
+		rc, err := z.RangeRead(context.Background(), http_range.Range{Start: start, Length: length})
+		if err != nil {
+			t.Fatalf("failed range read: %+v", err)
+		}
+		defer rc.Close()
+		b, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatalf("failed read: %+v", err)
+		}
+		return b
+	}
+	z := NewZipStream(files)
+	all := read(z, 0, -1)
+	if int64(len(all)) != z.GetSize() {
+		t.Fatalf("size: got %d, want %d", len(all), z.GetSize())
+	}
+	r, err := zip.NewReader(bytes.NewReader(all), int64(len(all)))
+	if err != nil {
+		t.Fatalf("failed open zip: %+v", err)
+	}
+	if len(r.File) != len(files) {
+		t.Fatalf("files: got %d, want %d", len(r.File), len(files))
+	}
+	for _, f := range r.File {
+		if !f.Modified.Equal(modified) {
+			t.Errorf("%s: modified %v", f.Name, f.Modified)
+		}
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatalf("failed open %s: %+v", f.Name, err)
+		}
+		b, err := io.ReadAll(rc)
+		_ = rc.Close()
+		if err != nil || !bytes.Equal(b, contents[f.Name]) {
+			t.Errorf("%s: got %d bytes, %v", f.Name, len(b), err)
+		}
+	}
+
+	// the ranges read by a new stream, which has to compute the crc itself
+	for _, ra := range [][2]int64{{0, 100}, {100, 5000}, {5100, -1}, {int64(len(all)) - 30, 30}} {
+		got := read(NewZipStream(files), ra[0], ra[1])
+		end := int64(len(all))
+		if ra[1] >= 0 {
+			end = ra[0] + ra[1]
+		}
+		if !bytes.Equal(got, all[ra[0]:end]) {
+			t.Errorf("range %v is different", ra)
+		}
+	}
+
+	// the end can be read without the files only once their crc is cached
+	tail := []http_range.Range{{Start: int64(len(all)) - 30, Length: 30}}
+	if z := NewZipStream(files); !z.CanRange([]http_range.Range{{Start: 0, Length: 100}}) || z.CanRange(tail) {
+		t.Errorf("a new stream can only read the ranges before the central directory")
+	}
+	cached := append([]ZipFile(nil), files...)
+	for i := range cached {
+		cached[i].Path = "/zip_test/" + cached[i].Name
+	}
+	read(NewZipStream(cached), 0, -1)
+	if !NewZipStream(cached).CanRange(tail) {
+		t.Errorf("the crc of the files read before isn't cached")
+	}
+}
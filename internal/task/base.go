@@ -6,6 +6,7 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/task_group"
 	"github.com/OpenListTeam/tache"
 )
 
@@ -16,6 +17,10 @@ type TaskExtension struct {
 	endTime    *time.Time
 	TotalBytes int64
 	ApiUrl     string
+	// RootID groups the task with the one submitted with it in the context
+	// and the others spawned from them, empty if it's not watched
+	RootID      string `json:"root_id,omitempty"`
+	rootTracked bool
 }
 
 func (t *TaskExtension) SetCtx(ctx context.Context) {
@@ -25,9 +30,54 @@ func (t *TaskExtension) SetCtx(ctx context.Context) {
 	if len(t.ApiUrl) > 0 {
 		ctx = context.WithValue(ctx, conf.ApiUrlKey, t.ApiUrl)
 	}
+	if len(t.RootID) > 0 {
+		ctx = context.WithValue(ctx, conf.TaskRootKey, t.RootID)
+	}
 	t.Base.SetCtx(ctx)
 }
 
+// TrackRoot counts the task in the root group of ctx, it must be called
+// before the task is added to its manager. The contexts of the tasks carry
+// the root id, so the tasks spawned by them are tracked with theirs.
+func (t *TaskExtension) TrackRoot(ctx context.Context) {
+	rootID, _ := ctx.Value(conf.TaskRootKey).(string)
+	if rootID == "" {
+		return
+	}
+	t.RootID = rootID
+	t.rootTracked = true
+	task_group.RootCoordinator.AddTask(rootID, nil)
+}
+
+func (t *TaskExtension) GetRootID() string {
+	return t.RootID
+}
+
+func (t *TaskExtension) finished() bool {
+	switch t.GetState() {
+	case tache.StateSucceeded, tache.StateFailed, tache.StateCanceled, tache.StateErrored:
+		return true
+	}
+	return false
+}
+
+// RootDone marks the task done in its root group
+func (t *TaskExtension) RootDone(success bool) {
+	if len(t.RootID) > 0 {
+		task_group.RootCoordinator.Done(t.RootID, success)
+	}
+}
+
+func (t *TaskExtension) SetRetry(retry int, maxRetry int) {
+	t.Base.SetRetry(retry, maxRetry)
+	if retry == 0 && len(t.RootID) > 0 &&
+		(!t.rootTracked && !t.finished() || // 重启恢复
+			(t.GetErr() == nil && t.GetState() != tache.StatePending)) { // 手动重试
+		t.rootTracked = true
+		task_group.RootCoordinator.AddTask(t.RootID, nil)
+	}
+}
+
 func (t *TaskExtension) SetCreator(creator *model.User) {
 	t.Creator = creator
 	t.Persist()
@@ -79,9 +129,16 @@ func (t *TaskExtension) ReinitCtx() error {
 	return nil
 }
 
+// TaskWithPaths is implemented by the tasks working on the paths,
+// the tasks can be filtered by the paths
+type TaskWithPaths interface {
+	GetPaths() []string
+}
+
 type TaskExtensionInfo interface {
 	tache.TaskWithInfo
 	GetCreator() *model.User
+	GetRootID() string
 	GetStartTime() *time.Time
 	GetEndTime() *time.Time
 	GetTotalBytes() int64
@@ -0,0 +1,28 @@
+package task
+
+import (
+	"context"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/event"
+)
+
+// PublishFinished emits the task.succeeded or task.failed event of a finished task,
+// typ is the name of the task manager, e.g. upload, copy, download.
+func PublishFinished(typ string, t TaskExtensionInfo) {
+	ctx := context.Background()
+	if creator := t.GetCreator(); creator != nil {
+		ctx = context.WithValue(ctx, conf.UserKey, creator)
+	}
+	data := event.TaskData{
+		ID:   t.GetID(),
+		Type: typ,
+		Name: t.GetName(),
+	}
+	if err := t.GetErr(); err != nil {
+		data.Error = err.Error()
+		event.Publish(ctx, event.TaskFailed, data)
+		return
+	}
+	event.Publish(ctx, event.TaskSucceeded, data)
+}
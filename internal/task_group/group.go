@@ -13,12 +13,21 @@ type TaskGroupCoordinator struct {
 
 	groupPayloads map[string][]any
 	groupStates   map[string]groupState
+	groupWatchers map[string][]chan GroupResult
 	onCompletion  OnCompletionFunc
 }
 
 type groupState struct {
 	pending    int
 	hasSuccess bool
+	succeeded  int
+	failed     int
+}
+
+// GroupResult is the count of the succeeded and failed tasks of a completed group
+type GroupResult struct {
+	Succeeded int
+	Failed    int
 }
 
 func NewTaskGroupCoordinator(name string, f OnCompletionFunc) *TaskGroupCoordinator {
@@ -26,6 +35,7 @@ func NewTaskGroupCoordinator(name string, f OnCompletionFunc) *TaskGroupCoordina
 		name:          name,
 		groupPayloads: map[string][]any{},
 		groupStates:   map[string]groupState{},
+		groupWatchers: map[string][]chan GroupResult{},
 		onCompletion:  f,
 	}
 }
@@ -62,12 +72,19 @@ func (tgc *TaskGroupCoordinator) Done(groupID string, success bool) {
 	}
 	if success {
 		state.hasSuccess = true
+		state.succeeded++
+	} else {
+		state.failed++
 	}
 	logrus.Debugf("Done:%s ,state=%+v", groupID, state)
 	if state.pending == 1 {
 		payloads := tgc.groupPayloads[groupID]
 		delete(tgc.groupStates, groupID)
 		delete(tgc.groupPayloads, groupID)
+		for _, ch := range tgc.groupWatchers[groupID] {
+			ch <- GroupResult{Succeeded: state.succeeded, Failed: state.failed}
+		}
+		delete(tgc.groupWatchers, groupID)
 		if tgc.onCompletion != nil && state.hasSuccess {
 			logrus.Debugf("OnCompletion:%s", groupID)
 			tgc.mu.Unlock()
@@ -79,3 +96,54 @@ func (tgc *TaskGroupCoordinator) Done(groupID string, success bool) {
 	state.pending--
 	tgc.groupStates[groupID] = state
 }
+
+// Abandon ends the group whose tasks left can't be done anymore, e.g. they
+// are canceled before running or removed, the watchers receive them as failed
+func (tgc *TaskGroupCoordinator) Abandon(groupID string) {
+	tgc.mu.Lock()
+	defer tgc.mu.Unlock()
+	state, ok := tgc.groupStates[groupID]
+	if !ok {
+		return
+	}
+	logrus.Debugf("Abandon:%s ,state=%+v", groupID, state)
+	delete(tgc.groupStates, groupID)
+	delete(tgc.groupPayloads, groupID)
+	for _, ch := range tgc.groupWatchers[groupID] {
+		ch <- GroupResult{Succeeded: state.succeeded, Failed: state.failed + state.pending}
+	}
+	delete(tgc.groupWatchers, groupID)
+}
+
+// Pending reports whether the group has tasks not done yet
+func (tgc *TaskGroupCoordinator) Pending(groupID string) bool {
+	tgc.mu.Lock()
+	defer tgc.mu.Unlock()
+	return tgc.groupStates[groupID].pending > 0
+}
+
+// Watch returns a channel receiving the result when all the tasks of the group
+// are done, including the tasks added after the call. The returned func stops
+// watching, it must be called if the result is not received.
+func (tgc *TaskGroupCoordinator) Watch(groupID string) (<-chan GroupResult, func()) {
+	tgc.mu.Lock()
+	defer tgc.mu.Unlock()
+	ch := make(chan GroupResult, 1)
+	tgc.groupWatchers[groupID] = append(tgc.groupWatchers[groupID], ch)
+	return ch, func() {
+		tgc.mu.Lock()
+		defer tgc.mu.Unlock()
+		watchers := tgc.groupWatchers[groupID]
+		for i, w := range watchers {
+			if w == ch {
+				watchers = append(watchers[:i], watchers[i+1:]...)
+				break
+			}
+		}
+		if len(watchers) == 0 {
+			delete(tgc.groupWatchers, groupID)
+		} else {
+			tgc.groupWatchers[groupID] = watchers
+		}
+	}
+}
@@ -100,3 +100,8 @@ func verifyAndRemove(ctx context.Context, srcStorage, dstStorage driver.Driver,
 }
 
 var TransferCoordinator *TaskGroupCoordinator = NewTaskGroupCoordinator("RefreshAndRemove", RefreshAndRemove)
+
+// RootCoordinator groups the tasks by the root id set by whoever submitted
+// them, e.g. a pipeline step, so the tasks into the same path from others
+// are not waited for
+var RootCoordinator = NewTaskGroupCoordinator("Root", nil)
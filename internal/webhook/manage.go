@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/event"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/google/uuid"
+	"github.com/pkg/errors"
+)
+
+// Ping is the event type sent by Test, it's never published to the bus
+const Ping event.Type = "webhook.ping"
+
+func validate(w *model.Webhook) error {
+	u, err := url.Parse(w.URL)
+	if err != nil {
+		return errors.WithMessage(err, "invalid url")
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || u.Host == "" {
+		return errors.Errorf("invalid url: %s", w.URL)
+	}
+	if w.MaxRetry < 0 {
+		return errors.New("max retry can't be negative")
+	}
+	return nil
+}
+
+func Create(w *model.Webhook) error {
+	if err := validate(w); err != nil {
+		return err
+	}
+	w.ID = 0
+	w.Modified = time.Now()
+	if err := db.CreateWebhook(w); err != nil {
+		return err
+	}
+	return reload()
+}
+
+func Update(w *model.Webhook) error {
+	if err := validate(w); err != nil {
+		return err
+	}
+	old, err := db.GetWebhookById(w.ID)
+	if err != nil {
+		return err
+	}
+	// the secret isn't sent to the client, so an empty one keeps the old
+	if w.Secret == "" {
+		w.Secret = old.Secret
+	}
+	w.Modified = time.Now()
+	if err := db.UpdateWebhook(w); err != nil {
+		return err
+	}
+	return reload()
+}
+
+func Delete(id uint) error {
+	if err := db.DeleteWebhookById(id); err != nil {
+		return err
+	}
+	return reload()
+}
+
+// Test delivers a ping event to the webhook regardless of its events filter
+func Test(id uint) error {
+	if queue == nil {
+		return errors.New("webhook is not initialized")
+	}
+	w, err := db.GetWebhookById(id)
+	if err != nil {
+		return err
+	}
+	e := &event.Event{
+		ID:   uuid.NewString(),
+		Type: Ping,
+		Time: time.Now(),
+		Data: map[string]any{"webhook_id": w.ID},
+	}
+	body, err := utils.Json.Marshal(e)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	enqueue(&job{webhook: *w, event: string(e.Type), eventID: e.ID, body: body})
+	return nil
+}
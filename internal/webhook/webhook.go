@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/event"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/OpenListTeam/OpenList/v4/pkg/sign"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	EventHeader     = "X-OpenList-Event"
+	DeliveryHeader  = "X-OpenList-Delivery"
+	SignatureHeader = "X-OpenList-Signature"
+
+	// the signature is produced by pkg/sign over the request body,
+	// and expires after signExpire to prevent replaying
+	signExpire      = 5 * time.Minute
+	defaultMaxRetry = 3
+	maxBackoff      = 10 * time.Minute
+	workers         = 4
+	queueSize       = 1024
+	// deliveries older than deliveryRetention are swept daily
+	deliveryRetention = 7 * 24 * time.Hour
+)
+
+var (
+	mu       sync.RWMutex
+	webhooks []model.Webhook
+
+	client *http.Client
+	queue  chan *job
+	once   sync.Once
+)
+
+type job struct {
+	webhook  model.Webhook
+	event    string
+	eventID  string
+	body     []byte
+	delivery *model.WebhookDelivery
+}
+
+// Init loads the enabled webhooks and starts delivering the published events
+func Init() {
+	once.Do(func() {
+		client = &http.Client{
+			Timeout: 30 * time.Second,
+			Transport: &http.Transport{
+				Proxy:           http.ProxyFromEnvironment,
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: conf.Conf.TlsInsecureSkipVerify},
+			},
+		}
+		queue = make(chan *job, queueSize)
+		if err := reload(); err != nil {
+			log.Errorf("failed load webhooks: %+v", err)
+		}
+		for i := 0; i < workers; i++ {
+			go work()
+		}
+		event.Subscribe(dispatch)
+		sweeper := cron.NewCron(24 * time.Hour)
+		sweeper.Do(func() {
+			n, err := db.DeleteWebhookDeliveriesBefore(time.Now().Add(-deliveryRetention))
+			if err != nil {
+				log.Errorf("failed sweep webhook deliveries: %+v", err)
+			} else if n > 0 {
+				log.Debugf("swept %d webhook deliveries", n)
+			}
+		})
+	})
+}
+
+func reload() error {
+	enabled, err := db.GetEnabledWebhooks()
+	if err != nil {
+		return err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	webhooks = enabled
+	return nil
+}
+
+func dispatch(e *event.Event) {
+	mu.RLock()
+	defer mu.RUnlock()
+	var body []byte
+	for _, w := range webhooks {
+		if !w.Match(string(e.Type)) {
+			continue
+		}
+		if body == nil {
+			var err error
+			if body, err = utils.Json.Marshal(e); err != nil {
+				log.Errorf("failed marshal event %s: %+v", e.Type, err)
+				return
+			}
+		}
+		enqueue(&job{webhook: w, event: string(e.Type), eventID: e.ID, body: body})
+	}
+}
+
+func enqueue(j *job) {
+	select {
+	case queue <- j:
+	default:
+		log.Warnf("webhook queue is full, dropped event %s to webhook [%s]", j.event, j.webhook.Name)
+	}
+}
+
+func work() {
+	for j := range queue {
+		deliver(j)
+	}
+}
+
+func deliver(j *job) {
+	if j.delivery == nil {
+		j.delivery = &model.WebhookDelivery{
+			WebhookID: j.webhook.ID,
+			EventID:   j.eventID,
+			Event:     j.event,
+			Payload:   string(j.body),
+		}
+		if err := db.CreateWebhookDelivery(j.delivery); err != nil {
+			log.Errorf("failed create webhook delivery: %+v", err)
+		}
+	}
+	d := j.delivery
+	d.Attempts++
+	d.StatusCode, d.Error = 0, ""
+	code, err := post(j)
+	d.StatusCode = code
+	d.Succeeded = err == nil
+	if err != nil {
+		d.Error = err.Error()
+	}
+	if e := db.UpdateWebhookDelivery(d); e != nil {
+		log.Errorf("failed update webhook delivery: %+v", e)
+	}
+	if err == nil {
+		return
+	}
+	maxRetry := j.webhook.MaxRetry
+	if maxRetry <= 0 {
+		maxRetry = defaultMaxRetry
+	}
+	if d.Attempts > maxRetry {
+		log.Warnf("failed deliver event %s to webhook [%s] after %d attempts: %v", j.event, j.webhook.Name, d.Attempts, err)
+		return
+	}
+	time.AfterFunc(backoff(d.Attempts), func() {
+		enqueue(j)
+	})
+}
+
+// backoff doubles the delay of every retry: 5s, 10s, 20s, ... up to maxBackoff
+func backoff(attempts int) time.Duration {
+	delay := 5 * time.Second
+	for i := 1; i < attempts && delay < maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, maxBackoff)
+}
+
+func post(j *job) (int, error) {
+	req, err := http.NewRequest(http.MethodPost, j.webhook.URL, bytes.NewReader(j.body))
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "OpenList-Webhook")
+	req.Header.Set(EventHeader, j.event)
+	req.Header.Set(DeliveryHeader, j.eventID)
+	if j.webhook.Secret != "" {
+		s := sign.NewHMACSign([]byte(j.webhook.Secret))
+		req.Header.Set(SignatureHeader, s.Sign(string(j.body), time.Now().Add(signExpire).Unix()))
+	}
+	res, err := client.Do(req)
+	if err != nil {
+		return 0, errors.WithStack(err)
+	}
+	defer res.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, 64*1024))
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return res.StatusCode, fmt.Errorf("unexpected status: %s", res.Status)
+	}
+	return res.StatusCode, nil
+}
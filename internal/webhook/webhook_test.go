@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/sign"
+)
+
+func TestPostSigned(t *testing.T) {
+	const secret = "secret"
+	body := []byte(`{"type":"fs.put"}`)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, _ := io.ReadAll(r.Body)
+		if r.Header.Get(EventHeader) != "fs.put" {
+			t.Errorf("event header: got %q", r.Header.Get(EventHeader))
+		}
+		if err := sign.NewHMACSign([]byte(secret)).Verify(string(got), r.Header.Get(SignatureHeader)); err != nil {
+			t.Errorf("verify signature: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+	client = srv.Client()
+
+	code, err := post(&job{
+		webhook: model.Webhook{URL: srv.URL, Secret: secret},
+		event:   "fs.put",
+		eventID: "1",
+		body:    body,
+	})
+	if err != nil || code != http.StatusNoContent {
+		t.Fatalf("post: got %d %v", code, err)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	testCases := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, 5 * time.Second},
+		{2, 10 * time.Second},
+		{4, 40 * time.Second},
+		{20, maxBackoff},
+	}
+	for _, tc := range testCases {
+		if got := backoff(tc.attempts); got != tc.want {
+			t.Errorf("backoff(%d): got %v, want %v", tc.attempts, got, tc.want)
+		}
+	}
+}
+
+func TestMatch(t *testing.T) {
+	w := model.Webhook{Events: "fs.put, task.*"}
+	for typ, want := range map[string]bool{
+		"fs.put":         true,
+		"fs.remove":      false,
+		"task.failed":    true,
+		"sharing.access": false,
+	} {
+		if got := w.Match(typ); got != want {
+			t.Errorf("Match(%q): got %t, want %t", typ, got, want)
+		}
+	}
+}
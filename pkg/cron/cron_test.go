@@ -14,3 +14,40 @@ func TestCron(t *testing.T) {
 	c.Stop()
 	c.Stop()
 }
+
+func TestExprNext(t *testing.T) {
+	from := time.Date(2024, 1, 31, 10, 17, 30, 0, time.UTC)
+	tests := []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2024, 1, 31, 10, 18, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC)},
+		{"30 2 * * *", time.Date(2024, 2, 1, 2, 30, 0, 0, time.UTC)},
+		{"0 3 * * mon-fri", time.Date(2024, 2, 1, 3, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 2, 4, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 3", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"5,10-12/2 4 * * *", time.Date(2024, 2, 1, 4, 5, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 31, 11, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	}
+	for _, tt := range tests {
+		e, err := ParseExpr(tt.spec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tt.spec, err)
+		}
+		if got := e.Next(from); !got.Equal(tt.want) {
+			t.Errorf("next of %q: got %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func TestParseExprInvalid(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := ParseExpr(spec); err == nil {
+			t.Errorf("parse %q: expected error", spec)
+		}
+	}
+}
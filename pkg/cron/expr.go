@@ -0,0 +1,179 @@
+package cron
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is a standard cron expression with five fields: minute, hour,
+// day of month, month and day of week.
+type Expr struct {
+	minute, hour, dom, month, dow uint64
+	// if both day fields are restricted, a day matches either of them
+	domStar, dowStar bool
+}
+
+type bounds struct {
+	min, max uint
+	names    map[string]uint
+}
+
+var (
+	minuteBounds = bounds{0, 59, nil}
+	hourBounds   = bounds{0, 23, nil}
+	domBounds    = bounds{1, 31, nil}
+	monthBounds  = bounds{1, 12, map[string]uint{
+		"jan": 1, "feb": 2, "mar": 3, "apr": 4, "may": 5, "jun": 6,
+		"jul": 7, "aug": 8, "sep": 9, "oct": 10, "nov": 11, "dec": 12,
+	}}
+	// 7 is also accepted as sunday
+	dowBounds = bounds{0, 7, map[string]uint{
+		"sun": 0, "mon": 1, "tue": 2, "wed": 3, "thu": 4, "fri": 5, "sat": 6,
+	}}
+)
+
+var descriptors = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// ParseExpr parses a five fields cron expression, such as "30 2 * * 1-5",
+// or one of the descriptors @yearly, @monthly, @weekly, @daily and @hourly.
+// Each field accepts *, values, ranges, steps and lists, e.g. "*/15" or "1,3-5".
+func ParseExpr(spec string) (*Expr, error) {
+	spec = strings.TrimSpace(spec)
+	if d, ok := descriptors[strings.ToLower(spec)]; ok {
+		spec = d
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("expected 5 fields in cron expression %q, got %d", spec, len(fields))
+	}
+	var (
+		e   Expr
+		err error
+	)
+	if e.minute, err = parseField(fields[0], minuteBounds); err != nil {
+		return nil, err
+	}
+	if e.hour, err = parseField(fields[1], hourBounds); err != nil {
+		return nil, err
+	}
+	if e.dom, err = parseField(fields[2], domBounds); err != nil {
+		return nil, err
+	}
+	if e.month, err = parseField(fields[3], monthBounds); err != nil {
+		return nil, err
+	}
+	if e.dow, err = parseField(fields[4], dowBounds); err != nil {
+		return nil, err
+	}
+	if e.dow&(1<<7) != 0 {
+		e.dow |= 1
+	}
+	e.domStar = strings.HasPrefix(fields[2], "*")
+	e.dowStar = strings.HasPrefix(fields[4], "*")
+	return &e, nil
+}
+
+func parseField(field string, b bounds) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(field, ",") {
+		rng, step := part, uint(1)
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.ParseUint(part[i+1:], 10, 8)
+			if err != nil || n == 0 {
+				return 0, fmt.Errorf("invalid step in %q", part)
+			}
+			rng, step = part[:i], uint(n)
+		}
+		var start, end uint
+		switch {
+		case rng == "*":
+			start, end = b.min, b.max
+		case strings.Contains(rng, "-"):
+			i := strings.IndexByte(rng, '-')
+			var err error
+			if start, err = parseValue(rng[:i], b); err != nil {
+				return 0, err
+			}
+			if end, err = parseValue(rng[i+1:], b); err != nil {
+				return 0, err
+			}
+			if start > end {
+				return 0, fmt.Errorf("invalid range %q", rng)
+			}
+		default:
+			v, err := parseValue(rng, b)
+			if err != nil {
+				return 0, err
+			}
+			start, end = v, v
+			// "5/10" means from 5 to the max with step 10
+			if step > 1 {
+				end = b.max
+			}
+		}
+		for v := start; v <= end; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+func parseValue(s string, b bounds) (uint, error) {
+	if v, ok := b.names[strings.ToLower(s)]; ok {
+		return v, nil
+	}
+	n, err := strconv.ParseUint(s, 10, 8)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+	if uint(n) < b.min || uint(n) > b.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]", n, b.min, b.max)
+	}
+	return uint(n), nil
+}
+
+func (e *Expr) dayMatches(t time.Time) bool {
+	dom := e.dom&(1<<uint(t.Day())) != 0
+	dow := e.dow&(1<<uint(t.Weekday())) != 0
+	if e.domStar || e.dowStar {
+		return dom && dow
+	}
+	return dom || dow
+}
+
+// Next returns the first time matching the expression after t,
+// or the zero time if there is none within five years, e.g. "0 0 30 2 *".
+func (e *Expr) Next(t time.Time) time.Time {
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		if e.month&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !e.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if e.hour&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if e.minute&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/cmd/flags"
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
@@ -77,6 +79,13 @@ func ErrorPage(c *gin.Context, err error, code int, l ...bool) {
 }
 
 func ErrorWithDataResp(c *gin.Context, err error, code int, data interface{}, l ...bool) {
+	// denied by the meta ACL deep in the fs layer
+	if errors.Is(err, errs.PermissionDenied) {
+		code = 403
+	}
+	if errors.Is(err, errs.QuotaExceeded) {
+		code = http.StatusInsufficientStorage
+	}
 	if len(l) > 0 && l[0] {
 		if flags.Debug || flags.Dev {
 			log.Errorf("%+v", err)
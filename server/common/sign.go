@@ -9,8 +9,10 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/sign"
 )
 
+// Sign returns the sign of the obj if it's required to download it, the
+// folders are signed too for the zip download of them
 func Sign(obj model.Obj, parent string, encrypt bool) string {
-	if obj.IsDir() || (!encrypt && !setting.GetBool(conf.SignAll)) {
+	if !encrypt && !setting.GetBool(conf.SignAll) {
 		return ""
 	}
 	return sign.Sign(stdpath.Join(parent, obj.GetName()))
@@ -116,6 +116,9 @@ func (o *OsFileInfoAdapter) Sys() any {
 
 func Stat(ctx context.Context, path string) (os.FileInfo, error) {
 	user := ctx.Value(conf.UserKey).(*model.User)
+	if user.IsVirtualRoot(path) {
+		return &OsFileInfoAdapter{obj: fs.VirtualRoot()}, nil
+	}
 	reqPath, err := user.JoinPath(path)
 	if err != nil {
 		return nil, err
@@ -142,6 +145,9 @@ func Stat(ctx context.Context, path string) (os.FileInfo, error) {
 
 func List(ctx context.Context, path string) ([]os.FileInfo, error) {
 	user := ctx.Value(conf.UserKey).(*model.User)
+	if user.IsVirtualRoot(path) {
+		return toFileInfos(fs.ListVirtualRoot(ctx, user)), nil
+	}
 	reqPath, err := user.JoinPath(path)
 	if err != nil {
 		return nil, err
@@ -167,9 +173,13 @@ func List(ctx context.Context, path string) ([]os.FileInfo, error) {
 	for _, u := range uploading {
 		objs = append(objs, u)
 	}
+	return toFileInfos(objs), nil
+}
+
+func toFileInfos(objs []model.Obj) []os.FileInfo {
 	ret := make([]os.FileInfo, len(objs))
 	for i, obj := range objs {
 		ret[i] = &OsFileInfoAdapter{obj: obj}
 	}
-	return ret, nil
+	return ret
 }
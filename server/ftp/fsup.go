@@ -44,11 +44,23 @@ func uploadAuth(ctx context.Context, path string) error {
 	return nil
 }
 
+// quotaError converts the quota error to the one replied with 552 by the server
+func quotaError(err error) error {
+	if errors.Is(err, errs.QuotaExceeded) {
+		return fmt.Errorf("%w: %v", ftpserver.ErrStorageExceeded, err)
+	}
+	return err
+}
+
 func OpenUpload(ctx context.Context, path string, trunc bool) (*FileUploadProxy, error) {
 	err := uploadAuth(ctx, path)
 	if err != nil {
 		return nil, err
 	}
+	// the size is unknown until closed, refuse early if already over quota
+	if err = op.CheckQuota(ctx.Value(conf.UserKey).(*model.User), -1); err != nil {
+		return nil, quotaError(err)
+	}
 	tmpFile, err := os.CreateTemp(conf.Conf.TempDir, "file-*")
 	if err != nil {
 		return nil, err
@@ -126,7 +138,7 @@ func (f *FileUploadProxy) Close() error {
 	task, err := fs.PutAsTask(f.ctx, dir, s)
 	if err != nil {
 		_ = s.Close()
-		return err
+		return quotaError(err)
 	}
 	sf.SetRemoveCallback(func() {
 		fs.UploadTaskManager.Cancel(task.GetID())
@@ -150,6 +162,9 @@ func OpenUploadWithLength(ctx context.Context, path string, trunc bool, length i
 	if err != nil {
 		return nil, err
 	}
+	if err = op.CheckQuota(ctx.Value(conf.UserKey).(*model.User), length); err != nil {
+		return nil, quotaError(err)
+	}
 	if trunc {
 		_ = fs.Remove(ctx, path)
 	}
@@ -164,7 +179,7 @@ func (f *FileUploadWithLengthProxy) write(p []byte) (n int, err error) {
 	if f.pipeWriter != nil {
 		select {
 		case e := <-f.errChan:
-			return 0, e
+			return 0, quotaError(e)
 		default:
 			return f.pipeWriter.Write(p)
 		}
@@ -227,7 +242,7 @@ func (f *FileUploadWithLengthProxy) Close() error {
 			return err
 		}
 		err = <-f.errChan
-		return err
+		return quotaError(err)
 	} else {
 		data := f.first512Bytes[:f.pFirst]
 		contentType := http.DetectContentType(data)
@@ -242,6 +257,6 @@ func (f *FileUploadWithLengthProxy) Close() error {
 			WebPutAsTask: false,
 			Reader:       bytes.NewReader(data),
 		}
-		return fs.PutDirectly(f.ctx, dir, s)
+		return quotaError(fs.PutDirectly(f.ctx, dir, s))
 	}
 }
@@ -313,6 +313,52 @@ func FsArchiveDecompress(c *gin.Context) {
 	})
 }
 
+type ArchiveCompressReq struct {
+	SrcPaths    StringOrArray `json:"src_paths" form:"src_paths"`
+	DstDir      string        `json:"dst_dir" form:"dst_dir"`
+	Name        string        `json:"name" form:"name"`
+	ArchivePass string        `json:"archive_pass" form:"archive_pass"`
+}
+
+// FsArchiveCompress creates an archive of the format of the name extension,
+// the sources may be in different storages
+func FsArchiveCompress(c *gin.Context) {
+	var req ArchiveCompressReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !user.CanWrite() {
+		common.ErrorResp(c, errs.PermissionDenied, 403)
+		return
+	}
+	srcPaths := make([]string, 0, len(req.SrcPaths))
+	for _, p := range req.SrcPaths {
+		srcPath, err := user.JoinPath(p)
+		if err != nil {
+			common.ErrorResp(c, err, 403)
+			return
+		}
+		srcPaths = append(srcPaths, srcPath)
+	}
+	dstDir, err := user.JoinPath(req.DstDir)
+	if err != nil {
+		common.ErrorResp(c, err, 403)
+		return
+	}
+	t, err := fs.ArchiveCompress(c.Request.Context(), srcPaths, dstDir, req.Name, model.ArchiveCompressArgs{
+		Password: req.ArchivePass,
+	})
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"task": getTaskInfo(t),
+	})
+}
+
 func ArchiveDown(c *gin.Context) {
 	archiveRawPath := c.Request.Context().Value(conf.PathKey).(string)
 	innerPath := utils.FixAndCleanPath(c.Query("inner"))
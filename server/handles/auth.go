@@ -83,6 +83,8 @@ func loginHash(c *gin.Context, req *LoginReq) {
 type UserResp struct {
 	model.User
 	Otp bool `json:"otp"`
+	// Used is the bytes stored under the roots, only counted with a quota
+	Used int64 `json:"used"`
 }
 
 // CurrentUser get current user by token
@@ -93,6 +95,19 @@ func CurrentUser(c *gin.Context) {
 		User: *user,
 	}
 	userResp.Password = ""
+	// the frontend only knows the effective values
+	userResp.Permission = user.EffectivePermission()
+	userResp.BasePath = user.EffectiveBasePath()
+	userResp.BasePaths = user.EffectiveBasePaths()
+	userResp.Quota = user.EffectiveQuota()
+	if userResp.Quota > 0 {
+		used, err := op.GetUserUsage(user)
+		if err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+		userResp.Used = used
+	}
 	if userResp.OtpSecret != "" {
 		userResp.Otp = true
 	}
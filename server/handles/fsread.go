@@ -78,6 +78,16 @@ func FsListSplit(c *gin.Context) {
 }
 
 func FsList(c *gin.Context, req *ListReq, user *model.User) {
+	if user.IsVirtualRoot(req.Path) {
+		objs := fs.ListVirtualRoot(c.Request.Context(), user)
+		total, objs := pagination(objs, &req.PageReq)
+		common.SuccessResp(c, FsListResp{
+			Content:  toObjsResp(objs, "/", false),
+			Total:    int64(total),
+			Provider: "unknown",
+		})
+		return
+	}
 	reqPath, err := user.JoinPath(req.Path)
 	if err != nil {
 		common.ErrorResp(c, err, 403)
@@ -118,7 +128,7 @@ func FsList(c *gin.Context, req *ListReq, user *model.User) {
 		Total:    int64(total),
 		Readme:   getReadme(meta, reqPath),
 		Header:   getHeader(meta, reqPath),
-		Write:    user.CanWrite() || common.CanWrite(meta, reqPath),
+		Write:    (user.CanWrite() || common.CanWrite(meta, reqPath)) && op.CheckACL(user, reqPath, model.ACLWrite) == nil,
 		Provider: provider,
 	})
 }
@@ -136,6 +146,9 @@ func FsDirs(c *gin.Context) {
 			common.ErrorStrResp(c, "Permission denied", 403)
 			return
 		}
+	} else if user.IsVirtualRoot(req.Path) {
+		common.SuccessResp(c, filterDirs(fs.ListVirtualRoot(c.Request.Context(), user)))
+		return
 	} else {
 		tmp, err := user.JoinPath(req.Path)
 		if err != nil {
@@ -282,6 +295,18 @@ func FsGetSplit(c *gin.Context) {
 }
 
 func FsGet(c *gin.Context, req *FsGetReq, user *model.User) {
+	if user.IsVirtualRoot(req.Path) {
+		obj := fs.VirtualRoot()
+		common.SuccessResp(c, FsGetResp{
+			ObjResp: ObjResp{
+				Name:  obj.GetName(),
+				IsDir: obj.IsDir(),
+				Type:  utils.GetFileType(obj.GetName()),
+			},
+			Provider: "unknown",
+		})
+		return
+	}
 	reqPath, err := user.JoinPath(req.Path)
 	if err != nil {
 		common.ErrorResp(c, err, 403)
@@ -352,9 +377,11 @@ func FsGet(c *gin.Context, req *FsGetReq, user *model.User) {
 	}
 	var related []model.Obj
 	parentPath := stdpath.Dir(reqPath)
-	sameLevelFiles, err := fs.List(c.Request.Context(), parentPath, &fs.ListArgs{})
-	if err == nil {
-		related = filterRelated(sameLevelFiles, obj)
+	if user.InBasePaths(parentPath) {
+		sameLevelFiles, err := fs.List(c.Request.Context(), parentPath, &fs.ListArgs{})
+		if err == nil {
+			related = filterRelated(sameLevelFiles, obj)
+		}
 	}
 	parentMeta, _ := op.GetNearestMeta(parentPath)
 	thumb, _ := model.GetThumb(obj)
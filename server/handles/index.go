@@ -2,11 +2,16 @@ package handles
 
 import (
 	"context"
+	"sort"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/search"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
@@ -19,7 +24,7 @@ type UpdateIndexReq struct {
 }
 
 func BuildIndex(c *gin.Context) {
-	if search.Running() {
+	if search.Running() || search.AnyJobRunning() {
 		common.ErrorStrResp(c, "index is running", 400)
 		return
 	}
@@ -45,7 +50,7 @@ func UpdateIndex(c *gin.Context) {
 		common.ErrorResp(c, err, 400)
 		return
 	}
-	if search.Running() {
+	if search.Running() || search.AnyJobRunning() {
 		common.ErrorStrResp(c, "index is running", 400)
 		return
 	}
@@ -85,7 +90,7 @@ func StopIndex(c *gin.Context) {
 }
 
 func ClearIndex(c *gin.Context) {
-	if search.Running() {
+	if search.Running() || search.AnyJobRunning() {
 		common.ErrorStrResp(c, "index is running", 400)
 		return
 	}
@@ -107,3 +112,97 @@ func GetProgress(c *gin.Context) {
 	}
 	common.SuccessResp(c, progress)
 }
+
+type IndexJobResp struct {
+	model.IndexJob
+	MountPath   string     `json:"mount_path"`
+	IndexCron   string     `json:"index_cron"`
+	NextRunTime *time.Time `json:"next_run_time"`
+}
+
+func ListIndexJobs(c *gin.Context) {
+	jobs, err := db.GetIndexJobs()
+	if err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	jobsMap := make(map[uint]model.IndexJob, len(jobs))
+	for _, job := range jobs {
+		jobsMap[job.StorageID] = job
+	}
+	now := time.Now()
+	resp := make([]IndexJobResp, 0)
+	for _, storage := range op.GetAllStorages() {
+		s := storage.GetStorage()
+		job, ok := jobsMap[s.ID]
+		if !ok && s.IndexCron == "" {
+			continue
+		}
+		job.StorageID = s.ID
+		job.Running = search.JobRunning(s.ID)
+		r := IndexJobResp{
+			IndexJob:  job,
+			MountPath: s.MountPath,
+			IndexCron: s.IndexCron,
+		}
+		if expr, err := cron.ParseExpr(s.IndexCron); err == nil && !s.DisableIndex {
+			if next := expr.Next(now); !next.IsZero() {
+				r.NextRunTime = &next
+			}
+		}
+		resp = append(resp, r)
+	}
+	sort.Slice(resp, func(i, j int) bool {
+		return resp[i].MountPath < resp[j].MountPath
+	})
+	common.SuccessResp(c, resp)
+}
+
+type IndexJobReq struct {
+	StorageID uint `json:"storage_id"`
+}
+
+func RunIndexJob(c *gin.Context) {
+	var req IndexJobReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	storage, err := db.GetStorageById(req.StorageID)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	storageDriver, err := op.GetStorageByMountPath(storage.MountPath)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if search.Running() || search.JobRunning(storage.ID) {
+		common.ErrorStrResp(c, "index is running", 400)
+		return
+	}
+	if !search.Config(c).AutoUpdate {
+		common.ErrorStrResp(c, "update is not supported for current index", 400)
+		return
+	}
+	go func() {
+		if err := search.RunIndexJob(context.Background(), storageDriver); err != nil {
+			log.Errorf("run index job of %s error: %+v", storage.MountPath, err)
+		}
+	}()
+	common.SuccessResp(c)
+}
+
+func StopIndexJob(c *gin.Context) {
+	var req IndexJobReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if !search.StopIndexJob(req.StorageID) {
+		common.ErrorStrResp(c, "index job is not running", 400)
+		return
+	}
+	common.SuccessResp(c)
+}
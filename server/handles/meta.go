@@ -7,6 +7,7 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/dlclark/regexp2"
 	"github.com/gin-gonic/gin"
@@ -43,6 +44,10 @@ func CreateMeta(c *gin.Context) {
 		common.ErrorStrResp(c, fmt.Sprintf("%s is illegal: %s", r, err.Error()), 400)
 		return
 	}
+	if err := validACL(req.ACL); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
 	if err := op.CreateMeta(&req); err != nil {
 		common.ErrorResp(c, err, 500, true)
 	} else {
@@ -61,6 +66,10 @@ func UpdateMeta(c *gin.Context) {
 		common.ErrorStrResp(c, fmt.Sprintf("%s is illegal: %s", r, err.Error()), 400)
 		return
 	}
+	if err := validACL(req.ACL); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
 	if err := op.UpdateMeta(&req); err != nil {
 		common.ErrorResp(c, err, 500, true)
 	} else {
@@ -68,6 +77,22 @@ func UpdateMeta(c *gin.Context) {
 	}
 }
 
+func validACL(acl []model.MetaACL) error {
+	for _, a := range acl {
+		switch a.Subject {
+		case model.ACLSubjectUser, model.ACLSubjectGroup, model.ACLSubjectEveryone:
+		default:
+			return fmt.Errorf("invalid acl subject: %s", a.Subject)
+		}
+		for _, action := range strings.Split(a.Actions, ",") {
+			if !utils.SliceContains(model.ACLActions, strings.TrimSpace(action)) {
+				return fmt.Errorf("invalid acl action: %s", action)
+			}
+		}
+	}
+	return nil
+}
+
 func validHide(hide string) (string, error) {
 	rs := strings.Split(hide, "\n")
 	for _, r := range rs {
@@ -0,0 +1,153 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/pipeline"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// getPipeline returns the pipeline of the id query if the user owns it or is admin
+func getPipeline(c *gin.Context, id uint) (*model.Pipeline, bool) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	p, err := db.GetPipelineById(id)
+	if err != nil || (!user.IsAdmin() && p.UserID != user.ID) {
+		common.ErrorStrResp(c, "pipeline not found", 404)
+		return nil, false
+	}
+	return p, true
+}
+
+func pipelineId(c *gin.Context) (uint, bool) {
+	id, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+func ListPipelines(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	userId := user.ID
+	if user.IsAdmin() {
+		userId = 0
+	}
+	pipelines, total, err := db.GetPipelines(userId, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: pipelines,
+		Total:   total,
+	})
+}
+
+func GetPipeline(c *gin.Context) {
+	id, ok := pipelineId(c)
+	if !ok {
+		return
+	}
+	p, ok := getPipeline(c, id)
+	if !ok {
+		return
+	}
+	common.SuccessResp(c, p)
+}
+
+func CreatePipeline(c *gin.Context) {
+	var req model.Pipeline
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if err := pipeline.Create(user, &req); err != nil {
+		common.ErrorResp(c, err, 400)
+	} else {
+		common.SuccessResp(c, gin.H{
+			"id": req.ID,
+		})
+	}
+}
+
+func UpdatePipeline(c *gin.Context) {
+	var req model.Pipeline
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if _, ok := getPipeline(c, req.ID); !ok {
+		return
+	}
+	if err := pipeline.Update(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+	} else {
+		common.SuccessResp(c)
+	}
+}
+
+func DeletePipeline(c *gin.Context) {
+	id, ok := pipelineId(c)
+	if !ok {
+		return
+	}
+	if _, ok = getPipeline(c, id); !ok {
+		return
+	}
+	if err := pipeline.Delete(id); err != nil {
+		common.ErrorResp(c, err, 500)
+	} else {
+		common.SuccessResp(c)
+	}
+}
+
+// RunPipeline runs the saved pipeline as its owner
+func RunPipeline(c *gin.Context) {
+	id, ok := pipelineId(c)
+	if !ok {
+		return
+	}
+	p, ok := getPipeline(c, id)
+	if !ok {
+		return
+	}
+	t, err := pipeline.Run(c.Request.Context(), p)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"task": getTaskInfo(t),
+	})
+}
+
+// SubmitPipeline runs the steps in the request once without saving them
+func SubmitPipeline(c *gin.Context) {
+	var req model.Pipeline
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.ID = 0
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	t, err := pipeline.Submit(c.Request.Context(), user, &req)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	common.SuccessResp(c, gin.H{
+		"task": getTaskInfo(t),
+	})
+}
@@ -0,0 +1,120 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+type S3KeyAddReq struct {
+	Title string `json:"title" binding:"required"`
+}
+
+type S3KeyAddResp struct {
+	model.S3AccessKey
+	// SecretAccessKey is only returned once when the key is created
+	SecretAccessKey string `json:"secret_access_key"`
+}
+
+func AddMyS3AccessKey(c *gin.Context) {
+	userObj, ok := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	var req S3KeyAddReq
+	if err := c.ShouldBind(&req); err != nil || req.Title == "" {
+		common.ErrorStrResp(c, "request invalid", 400)
+		return
+	}
+	key := &model.S3AccessKey{
+		Title:  req.Title,
+		UserId: userObj.ID,
+	}
+	if err := op.CreateS3AccessKey(key); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, S3KeyAddResp{S3AccessKey: *key, SecretAccessKey: key.SecretAccessKey})
+}
+
+func ListMyS3AccessKeys(c *gin.Context) {
+	userObj, ok := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	listS3AccessKeys(c, userObj)
+}
+
+func DeleteMyS3AccessKey(c *gin.Context) {
+	userObj, ok := c.Request.Context().Value(conf.UserKey).(*model.User)
+	if !ok || userObj.IsGuest() {
+		common.ErrorStrResp(c, "user invalid", 401)
+		return
+	}
+	keyId, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return
+	}
+	key, err := op.GetS3AccessKeyByIdAndUserId(uint(keyId), userObj.ID)
+	if err != nil {
+		common.ErrorStrResp(c, "failed to get s3 key", 404)
+		return
+	}
+	if err = op.DeleteS3AccessKeyById(key.ID); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func ListS3AccessKeys(c *gin.Context) {
+	userId, err := strconv.Atoi(c.Query("uid"))
+	if err != nil {
+		common.ErrorStrResp(c, "user id format invalid", 400)
+		return
+	}
+	userObj, err := op.GetUserById(uint(userId))
+	if err != nil {
+		common.ErrorStrResp(c, "user invalid", 404)
+		return
+	}
+	listS3AccessKeys(c, userObj)
+}
+
+func DeleteS3AccessKey(c *gin.Context) {
+	keyId, err := strconv.Atoi(c.Query("id"))
+	if err != nil {
+		common.ErrorStrResp(c, "id format invalid", 400)
+		return
+	}
+	if err = op.DeleteS3AccessKeyById(uint(keyId)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func listS3AccessKeys(c *gin.Context, userObj *model.User) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	keys, total, err := op.GetS3AccessKeysByUserId(userObj.ID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: keys,
+		Total:   total,
+	})
+}
@@ -2,7 +2,6 @@ package handles
 
 import (
 	"path"
-	"strings"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
@@ -22,7 +21,6 @@ type SearchReq struct {
 
 type SearchResp struct {
 	model.SearchNode
-	Type int `json:"type"`
 }
 
 func Search(c *gin.Context) {
@@ -35,8 +33,10 @@ func Search(c *gin.Context) {
 		return
 	}
 	user := c.Request.Context().Value(conf.UserKey).(*model.User)
-	req.Parent, err = user.JoinPath(req.Parent)
-	if err != nil {
+	if user.IsVirtualRoot(req.Parent) {
+		req.Parent = "/"
+		req.Parents = user.EffectiveBasePaths()
+	} else if req.Parent, err = user.JoinPath(req.Parent); err != nil {
 		common.ErrorResp(c, err, 400)
 		return
 	}
@@ -51,7 +51,9 @@ func Search(c *gin.Context) {
 	}
 	var filteredNodes []model.SearchNode
 	for _, node := range nodes {
-		if !strings.HasPrefix(node.Parent, user.BasePath) {
+		// the searchers filter by the roots, but the bleve index created by the
+		// older versions can't match the parents
+		if !user.InBasePaths(node.Parent) {
 			continue
 		}
 		meta, err := op.GetNearestMeta(node.Parent)
@@ -61,6 +63,13 @@ func Search(c *gin.Context) {
 		if !common.CanAccess(user, meta, path.Join(node.Parent, node.Name), req.Password) {
 			continue
 		}
+		if op.CheckACL(user, path.Join(node.Parent, node.Name), model.ACLRead) != nil {
+			continue
+		}
+		// the paths are relative to the virtual root, not the base path
+		if user.VirtualRoots() != nil {
+			node.Parent, _ = user.RelPath(node.Parent)
+		}
 		filteredNodes = append(filteredNodes, node)
 	}
 	common.SuccessResp(c, common.PageResp{
@@ -70,8 +79,10 @@ func Search(c *gin.Context) {
 }
 
 func nodeToSearchResp(node model.SearchNode) SearchResp {
+	// the nodes indexed before have no type
+	node.Type = utils.GetObjType(node.Name, node.IsDir)
+	node.Content = ""
 	return SearchResp{
 		SearchNode: node,
-		Type:       utils.GetObjType(node.Name, node.IsDir),
 	}
 }
@@ -1,7 +1,9 @@
 package handles
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	stdpath "path"
 	"strings"
 	"time"
@@ -9,15 +11,19 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
 	"github.com/OpenListTeam/OpenList/v4/internal/driver"
 	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/event"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/OpenListTeam/OpenList/v4/internal/sharing"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/OpenListTeam/go-cache"
 	"github.com/gin-gonic/gin"
 	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
 )
 
 func SharingGet(c *gin.Context, req *FsGetReq) {
@@ -34,6 +40,7 @@ func SharingGet(c *gin.Context, req *FsGetReq) {
 		return
 	}
 	_ = countAccess(c.ClientIP(), s)
+	logAccess(c, s, model.SharingActionGet, path, 0)
 	fakePath := fmt.Sprintf("/%s/%s", sid, path)
 	url := ""
 	if !obj.IsDir() {
@@ -80,6 +87,7 @@ func SharingList(c *gin.Context, req *ListReq) {
 		return
 	}
 	_ = countAccess(c.ClientIP(), s)
+	logAccess(c, s, model.SharingActionList, path, 0)
 	fakePath := fmt.Sprintf("/%s/%s", sid, path)
 	total, objs := pagination(objs, &req.PageReq)
 	common.SuccessResp(c, FsListResp{
@@ -103,7 +111,7 @@ func SharingList(c *gin.Context, req *ListReq) {
 		Total:    int64(total),
 		Readme:   s.Readme,
 		Header:   s.Header,
-		Write:    false,
+		Write:    s.Upload,
 		Provider: "unknown",
 	})
 }
@@ -136,6 +144,7 @@ func SharingArchiveMeta(c *gin.Context, req *ArchiveMetaReq) {
 		return
 	}
 	_ = countAccess(c.ClientIP(), s)
+	logAccess(c, s, model.SharingActionArchive, path, 0)
 	fakePath := fmt.Sprintf("/%s/%s", sid, path)
 	url := fmt.Sprintf("%s/sad%s", common.GetApiUrl(c), utils.EncodePath(fakePath, true))
 	if s.Pwd != "" {
@@ -182,6 +191,7 @@ func SharingArchiveList(c *gin.Context, req *ArchiveListReq) {
 		return
 	}
 	_ = countAccess(c.ClientIP(), s)
+	logAccess(c, s, model.SharingActionArchive, stdpath.Join(path, innerArgs.InnerPath), 0)
 	total, objs := pagination(objs, &req.PageReq)
 	ret, _ := utils.SliceConvert(objs, func(src model.Obj) (ObjResp, error) {
 		return toObjsRespWithoutSignAndThumb(src), nil
@@ -197,19 +207,24 @@ func SharingDown(c *gin.Context) {
 	path := c.Request.Context().Value(conf.PathKey).(string)
 	path = utils.FixAndCleanPath(path)
 	pwd := c.Query("pwd")
+	_, zip := c.GetQuery("zip")
 	s, err := op.GetSharingById(sid)
 	if err == nil {
 		if !s.Valid() {
 			err = errs.InvalidSharing
 		} else if !s.Verify(pwd) {
 			err = errs.WrongShareCode
-		} else if len(s.Files) != 1 && path == "/" {
+		} else if len(s.Files) != 1 && path == "/" && !zip {
 			err = errors.New("cannot get sharing root link")
 		}
 	}
 	if dealErrorPage(c, err) {
 		return
 	}
+	if zip {
+		SharingZipDown(c, s, path)
+		return
+	}
 	unwrapPath, err := op.GetSharingUnwrapPath(s, path)
 	if err != nil {
 		common.ErrorPage(c, errors.New("failed get sharing unwrap path"), 500)
@@ -224,6 +239,7 @@ func SharingDown(c *gin.Context) {
 			if url := common.GenerateDownProxyURL(storage.GetStorage(), unwrapPath); url != "" {
 				c.Redirect(302, url)
 				_ = countAccess(c.ClientIP(), s)
+				logAccess(c, s, model.SharingActionDownload, path, 0)
 				return
 			}
 		}
@@ -237,6 +253,7 @@ func SharingDown(c *gin.Context) {
 		}
 		_ = countAccess(c.ClientIP(), s)
 		proxy(c, link, obj, storage.GetStorage().ProxyRange)
+		logAccess(c, s, model.SharingActionDownload, path, int64(c.Writer.Size()))
 	} else {
 		link, _, err := op.Link(c.Request.Context(), storage, actualPath, model.LinkArgs{
 			IP:       c.ClientIP(),
@@ -250,7 +267,89 @@ func SharingDown(c *gin.Context) {
 		}
 		_ = countAccess(c.ClientIP(), s)
 		redirect(c, link)
+		logAccess(c, s, model.SharingActionDownload, path, 0)
+	}
+}
+
+// SharingUpload puts the request body into the drop folder as the creator of
+// the sharing, the request is checked by SharingIdParse
+func SharingUpload(c *gin.Context) {
+	// the body left by a failed upload isn't drained, net/http closes the
+	// connection instead of reading what a visitor keeps sending
+	defer c.Request.Body.Close()
+	sid := c.Request.Context().Value(conf.SharingIDKey).(string)
+	path := utils.FixAndCleanPath(c.Request.Context().Value(conf.PathKey).(string))
+	s, err := op.GetSharingById(sid)
+	if dealError(c, err) {
+		return
+	}
+	unwrapPath, err := op.GetSharingUnwrapPath(s, path)
+	if err != nil {
+		common.ErrorResp(c, errors.WithMessage(err, "failed get sharing unwrap path"), 500)
+		return
+	}
+	// the visitors may not see the files, so they never overwrite them and
+	// aren't told if the name is taken, the file is put as another name
+	dir, name := stdpath.Split(unwrapPath)
+	name, err = freeUploadName(c.Request.Context(), dir, name)
+	if err != nil {
+		log.Errorf("failed find name for upload to sharing %s: %+v", sid, err)
+		common.ErrorStrResp(c, "failed upload", 500)
+		return
+	}
+	size := c.Request.ContentLength
+	if err = op.AddSharingUploaded(s, size); err != nil {
+		common.ErrorResp(c, err, 500)
+		return
+	}
+	mimetype := c.GetHeader("Content-Type")
+	if len(mimetype) == 0 {
+		mimetype = utils.GetMimeType(name)
+	}
+	file := &stream.FileStream{
+		Obj: &model.Object{
+			Name:     name,
+			Size:     size,
+			Modified: getLastModified(c),
+		},
+		Reader:   c.Request.Body,
+		Mimetype: mimetype,
+	}
+	// the name may be taken since it's checked, then the put fails
+	ctx := context.WithValue(c.Request.Context(), conf.NoOverwriteKey, struct{}{})
+	if err = fs.PutDirectly(ctx, dir, file); err != nil {
+		if e := op.AddSharingUploaded(s, -size); e != nil {
+			log.Errorf("failed give back uploaded size of sharing %s: %+v", sid, e)
+		}
+		common.ErrorStrResp(c, "failed upload", 500)
+		return
+	}
+	_ = countAccess(c.ClientIP(), s)
+	logAccess(c, s, model.SharingActionUpload, stdpath.Join(stdpath.Dir(path), name), size)
+	common.SuccessResp(c)
+}
+
+// freeUploadName returns the name, or the first of "name (n).ext" not taken
+// in the dir. The storage is checked directly, so the files hidden from the
+// creator are taken too. The name failed to get is returned as not taken,
+// the put refuses to overwrite it anyway.
+func freeUploadName(ctx context.Context, dir, name string) (string, error) {
+	storage, actualDir, err := op.GetStorageAndActualPath(dir)
+	if err != nil {
+		return "", err
+	}
+	ext := stdpath.Ext(name)
+	base := strings.TrimSuffix(name, ext)
+	for i := 0; i < 100; i++ {
+		n := name
+		if i > 0 {
+			n = fmt.Sprintf("%s (%d)%s", base, i, ext)
+		}
+		if _, err = op.GetUnwrap(ctx, storage, stdpath.Join(actualDir, n)); err != nil {
+			return n, nil
+		}
 	}
+	return "", errors.Errorf("too many files named like %s", name)
 }
 
 func SharingArchiveExtract(c *gin.Context) {
@@ -303,6 +402,7 @@ func SharingArchiveExtract(c *gin.Context) {
 				return
 			}
 			proxy(c, link, obj, storage.GetStorage().ProxyRange)
+			logAccess(c, s, model.SharingActionArchive, stdpath.Join(path, innerPath), int64(c.Writer.Size()))
 		} else {
 			args.Redirect = true
 			link, _, err := op.DriverExtract(c.Request.Context(), storage, actualPath, args)
@@ -310,6 +410,7 @@ func SharingArchiveExtract(c *gin.Context) {
 				return
 			}
 			redirect(c, link)
+			logAccess(c, s, model.SharingActionArchive, stdpath.Join(path, innerPath), 0)
 		}
 	} else {
 		rc, size, err := op.InternalExtract(c.Request.Context(), storage, actualPath, args)
@@ -318,6 +419,7 @@ func SharingArchiveExtract(c *gin.Context) {
 		}
 		fileName := stdpath.Base(innerPath)
 		proxyInternalExtract(c, rc, size, fileName)
+		logAccess(c, s, model.SharingActionArchive, stdpath.Join(path, innerPath), int64(c.Writer.Size()))
 	}
 }
 
@@ -328,7 +430,7 @@ func dealError(c *gin.Context, err error) bool {
 		common.ErrorStrResp(c, "the share does not exist", 500)
 	} else if errors.Is(err, errs.InvalidSharing) {
 		common.ErrorStrResp(c, "the share has expired or is no longer valid", 500)
-	} else if errors.Is(err, errs.WrongShareCode) {
+	} else if errors.Is(err, errs.WrongShareCode) || errors.Is(err, errs.SharingListNotAllowed) {
 		common.ErrorResp(c, err, 403)
 	} else if errors.Is(err, errs.WrongArchivePassword) {
 		common.ErrorResp(c, err, 202)
@@ -345,7 +447,7 @@ func dealErrorPage(c *gin.Context, err error) bool {
 		common.ErrorPage(c, errors.New("the share does not exist"), 500)
 	} else if errors.Is(err, errs.InvalidSharing) {
 		common.ErrorPage(c, errors.New("the share has expired or is no longer valid"), 500)
-	} else if errors.Is(err, errs.WrongShareCode) {
+	} else if errors.Is(err, errs.WrongShareCode) || errors.Is(err, errs.SharingListNotAllowed) {
 		common.ErrorPage(c, err, 403)
 	} else if errors.Is(err, errs.WrongArchivePassword) {
 		common.ErrorPage(c, err, 202)
@@ -417,6 +519,12 @@ type CreateSharingReq struct {
 	Remark      string     `json:"remark"`
 	Readme      string     `json:"readme"`
 	Header      string     `json:"header"`
+	// the options of the drop folder, see model.SharingDB
+	Upload        bool   `json:"upload"`
+	UploadMaxSize int64  `json:"upload_max_size"`
+	UploadExts    string `json:"upload_exts"`
+	UploadNoList  bool   `json:"upload_no_list"`
+	UploadQuota   int64  `json:"upload_quota"`
 	model.Sort
 }
 
@@ -444,10 +552,17 @@ func UpdateSharing(c *gin.Context) {
 	for i, s := range req.Files {
 		s = utils.FixAndCleanPath(s)
 		req.Files[i] = s
-		if !user.IsAdmin() && !strings.HasPrefix(s, user.BasePath) {
+		if !user.IsAdmin() && !user.InBasePaths(s) {
 			common.ErrorStrResp(c, fmt.Sprintf("permission denied to share path [%s]", s), 500)
 			return
 		}
+		if err := op.CheckACL(user, s, model.ACLShare); err != nil {
+			common.ErrorStrResp(c, fmt.Sprintf("permission denied to share path [%s]", s), 403)
+			return
+		}
+	}
+	if req.Upload && !checkUploadSharing(c, req.Files) {
+		return
 	}
 	s, err := op.GetSharingById(req.ID)
 	if err != nil || (!user.IsAdmin() && s.CreatorId != user.ID) {
@@ -464,6 +579,11 @@ func UpdateSharing(c *gin.Context) {
 	s.Header = req.Header
 	s.Readme = req.Readme
 	s.Remark = req.Remark
+	s.Upload = req.Upload
+	s.UploadMaxSize = req.UploadMaxSize
+	s.UploadExts = req.UploadExts
+	s.UploadNoList = req.UploadNoList
+	s.UploadQuota = req.UploadQuota
 	if err = op.UpdateSharing(s); err != nil {
 		common.ErrorResp(c, err, 500)
 	} else {
@@ -494,22 +614,34 @@ func CreateSharing(c *gin.Context) {
 	for i, s := range req.Files {
 		s = utils.FixAndCleanPath(s)
 		req.Files[i] = s
-		if !user.IsAdmin() && !strings.HasPrefix(s, user.BasePath) {
+		if !user.IsAdmin() && !user.InBasePaths(s) {
 			common.ErrorStrResp(c, fmt.Sprintf("permission denied to share path [%s]", s), 500)
 			return
 		}
+		if err := op.CheckACL(user, s, model.ACLShare); err != nil {
+			common.ErrorStrResp(c, fmt.Sprintf("permission denied to share path [%s]", s), 403)
+			return
+		}
+	}
+	if req.Upload && !checkUploadSharing(c, req.Files) {
+		return
 	}
 	s := &model.Sharing{
 		SharingDB: &model.SharingDB{
-			Expires:     req.Expires,
-			Pwd:         req.Pwd,
-			Accessed:    0,
-			MaxAccessed: req.MaxAccessed,
-			Disabled:    req.Disabled,
-			Sort:        req.Sort,
-			Remark:      req.Remark,
-			Readme:      req.Readme,
-			Header:      req.Header,
+			Expires:       req.Expires,
+			Pwd:           req.Pwd,
+			Accessed:      0,
+			MaxAccessed:   req.MaxAccessed,
+			Disabled:      req.Disabled,
+			Sort:          req.Sort,
+			Remark:        req.Remark,
+			Readme:        req.Readme,
+			Header:        req.Header,
+			Upload:        req.Upload,
+			UploadMaxSize: req.UploadMaxSize,
+			UploadExts:    req.UploadExts,
+			UploadNoList:  req.UploadNoList,
+			UploadQuota:   req.UploadQuota,
 		},
 		Files:   req.Files,
 		Creator: user,
@@ -527,6 +659,24 @@ func CreateSharing(c *gin.Context) {
 	}
 }
 
+// checkUploadSharing checks the drop folder shares exactly one folder
+func checkUploadSharing(c *gin.Context, files []string) bool {
+	if len(files) != 1 {
+		common.ErrorStrResp(c, "a drop folder must share exactly 1 folder", 400)
+		return false
+	}
+	obj, err := fs.Get(c.Request.Context(), files[0], &fs.GetArgs{NoLog: true})
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return false
+	}
+	if !obj.IsDir() {
+		common.ErrorStrResp(c, "a drop folder must share a folder", 400)
+		return false
+	}
+	return true
+}
+
 func DeleteSharing(c *gin.Context) {
 	sid := c.Query("id")
 	user := c.Request.Context().Value(conf.UserKey).(*model.User)
@@ -571,7 +721,34 @@ func countAccess(ip string, s *model.Sharing) error {
 	if !ok {
 		AccessCache.Set(key, struct{}{}, cache.WithEx[interface{}](AccessCountDelay))
 		s.Accessed += 1
+		event.Publish(context.Background(), event.SharingAccess, event.SharingData{
+			ID:       s.ID,
+			Files:    s.Files,
+			IP:       ip,
+			Accessed: s.Accessed,
+		})
 		return op.UpdateSharing(s, true)
 	}
 	return nil
 }
+
+// logAccess records the access to the path inside the sharing, bytes is the
+// size served or received by the server. The HEAD requests are not recorded.
+func logAccess(c *gin.Context, s *model.Sharing, action, path string, bytes int64) {
+	if c.Request.Method == http.MethodHead {
+		return
+	}
+	userAgent := c.Request.UserAgent()
+	if len(userAgent) > 512 {
+		userAgent = userAgent[:512]
+	}
+	op.LogSharingAccess(model.SharingAccessLog{
+		SharingID: s.ID,
+		Time:      time.Now(),
+		IP:        c.ClientIP(),
+		UserAgent: userAgent,
+		Action:    action,
+		Path:      utils.FixAndCleanPath(path),
+		Bytes:     max(bytes, 0),
+	})
+}
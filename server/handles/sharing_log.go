@@ -0,0 +1,130 @@
+package handles
+
+import (
+	"encoding/csv"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// SharingLogReq selects the access logs of a sharing, Since and Until are
+// unix seconds and 0 means unbounded
+type SharingLogReq struct {
+	ID    string `json:"id" form:"id"`
+	Since int64  `json:"since" form:"since"`
+	Until int64  `json:"until" form:"until"`
+	model.PageReq
+}
+
+func (r *SharingLogReq) times() (since, until time.Time) {
+	if r.Since > 0 {
+		since = time.Unix(r.Since, 0)
+	}
+	if r.Until > 0 {
+		until = time.Unix(r.Until, 0)
+	}
+	return since, until
+}
+
+// bindSharingLogReq binds the request and checks the sharing is of the user
+func bindSharingLogReq(c *gin.Context) (*SharingLogReq, bool) {
+	var req SharingLogReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return nil, false
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	s, err := op.GetSharingById(req.ID)
+	if err != nil || (!user.IsAdmin() && s.CreatorId != user.ID) {
+		common.ErrorStrResp(c, "sharing not found", 404)
+		return nil, false
+	}
+	// the queued logs are read too
+	op.FlushSharingAccessLogs()
+	return &req, true
+}
+
+func ListSharingAccessLogs(c *gin.Context) {
+	req, ok := bindSharingLogReq(c)
+	if !ok {
+		return
+	}
+	req.Validate()
+	since, until := req.times()
+	logs, total, err := db.GetSharingAccessLogs(req.ID, since, until, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: logs,
+		Total:   total,
+	})
+}
+
+func GetSharingAccessStats(c *gin.Context) {
+	req, ok := bindSharingLogReq(c)
+	if !ok {
+		return
+	}
+	since, until := req.times()
+	stats, err := db.GetSharingAccessStats(req.ID, since, until)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, stats)
+}
+
+// csvCell escapes the value starting like a formula, so it isn't run when
+// the CSV is opened in a spreadsheet
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// ExportSharingAccessLogs writes the access logs of the sharing as CSV from
+// the oldest, the logs are read in batches so all of them can be exported
+func ExportSharingAccessLogs(c *gin.Context) {
+	req, ok := bindSharingLogReq(c)
+	if !ok {
+		return
+	}
+	since, until := req.times()
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="sharing-%s-access.csv"`, req.ID))
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write([]string{"time", "ip", "user_agent", "action", "path", "bytes"})
+	err := db.WalkSharingAccessLogs(req.ID, since, until, func(logs []model.SharingAccessLog) error {
+		for _, l := range logs {
+			if err := w.Write([]string{
+				l.Time.Format(time.RFC3339),
+				csvCell(l.IP),
+				csvCell(l.UserAgent),
+				csvCell(l.Action),
+				csvCell(l.Path),
+				strconv.FormatInt(l.Bytes, 10),
+			}); err != nil {
+				return err
+			}
+		}
+		w.Flush()
+		return w.Error()
+	})
+	w.Flush()
+	// the response has been started, the error can only be logged
+	if err != nil {
+		log.Errorf("failed export access logs of sharing %s: %+v", req.ID, err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 
 	"github.com/OpenListTeam/OpenList/v4/internal/fs"
 	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/pipeline"
 	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/OpenListTeam/tache"
@@ -18,6 +19,7 @@ import (
 
 type TaskInfo struct {
 	ID          string      `json:"id"`
+	Type        string      `json:"type,omitempty"`
 	Name        string      `json:"name"`
 	Creator     string      `json:"creator"`
 	CreatorRole int         `json:"creator_role"`
@@ -134,9 +136,7 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 		}
 		common.SuccessResp(c, getTaskInfos(manager.GetByCondition(func(task T) bool {
 			// avoid directly passing the user object into the function to reduce closure size
-			return (isAdmin || uid == task.GetCreator().ID) &&
-				argsContains(task.GetState(), tache.StatePending, tache.StateRunning, tache.StateCanceling,
-					tache.StateErrored, tache.StateFailing, tache.StateWaitingRetry, tache.StateBeforeRetry)
+			return (isAdmin || uid == task.GetCreator().ID) && argsContains(task.GetState(), undoneStates...)
 		})))
 	})
 	g.GET("/done", func(c *gin.Context) {
@@ -147,8 +147,7 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 			return
 		}
 		common.SuccessResp(c, getTaskInfos(manager.GetByCondition(func(task T) bool {
-			return (isAdmin || uid == task.GetCreator().ID) &&
-				argsContains(task.GetState(), tache.StateCanceled, tache.StateFailed, tache.StateSucceeded)
+			return (isAdmin || uid == task.GetCreator().ID) && argsContains(task.GetState(), doneStates...)
 		})))
 	})
 	g.POST("/info", getTargetedHandler(manager, func(c *gin.Context, task T) {
@@ -183,8 +182,7 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 			return
 		}
 		manager.RemoveByCondition(func(task T) bool {
-			return (isAdmin || uid == task.GetCreator().ID) &&
-				argsContains(task.GetState(), tache.StateCanceled, tache.StateFailed, tache.StateSucceeded)
+			return (isAdmin || uid == task.GetCreator().ID) && argsContains(task.GetState(), doneStates...)
 		})
 		common.SuccessResp(c)
 	})
@@ -218,6 +216,8 @@ func taskRoute[T task.TaskExtensionInfo](g *gin.RouterGroup, manager task.Manage
 }
 
 func SetupTaskRoute(g *gin.RouterGroup) {
+	g.Any("/list", ListTasks)
+	g.GET("/events", StreamTasks)
 	taskRoute(g.Group("/upload"), fs.UploadTaskManager)
 	taskRoute(g.Group("/copy"), fs.CopyTaskManager)
 	taskRoute(g.Group("/move"), fs.MoveTaskManager)
@@ -225,4 +225,13 @@ func SetupTaskRoute(g *gin.RouterGroup) {
 	taskRoute(g.Group("/offline_download_transfer"), tool.TransferTaskManager)
 	taskRoute(g.Group("/decompress"), fs.ArchiveDownloadTaskManager)
 	taskRoute(g.Group("/decompress_upload"), fs.ArchiveContentUploadTaskManager)
+	taskRoute(g.Group("/compress"), fs.ArchiveCompressTaskManager)
+	pipelineGroup := g.Group("/pipeline")
+	taskRoute(pipelineGroup, pipeline.TaskManager)
+	pipelineGroup.POST("/steps", getTargetedHandler(pipeline.TaskManager, func(c *gin.Context, t *pipeline.Task) {
+		common.SuccessResp(c, gin.H{
+			"steps":       t.Steps,
+			"step_status": t.StepStatus,
+		})
+	}))
 }
@@ -0,0 +1,292 @@
+package handles
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/fs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/offline_download/tool"
+	"github.com/OpenListTeam/OpenList/v4/internal/pipeline"
+	"github.com/OpenListTeam/OpenList/v4/internal/task"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/OpenListTeam/tache"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+)
+
+var (
+	undoneStates = []tache.State{tache.StatePending, tache.StateRunning, tache.StateCanceling,
+		tache.StateErrored, tache.StateFailing, tache.StateWaitingRetry, tache.StateBeforeRetry}
+	doneStates = []tache.State{tache.StateCanceled, tache.StateFailed, tache.StateSucceeded}
+
+	stateNames = map[string][]tache.State{
+		"undone":        undoneStates,
+		"done":          doneStates,
+		"pending":       {tache.StatePending},
+		"running":       {tache.StateRunning},
+		"succeeded":     {tache.StateSucceeded},
+		"canceling":     {tache.StateCanceling},
+		"canceled":      {tache.StateCanceled},
+		"errored":       {tache.StateErrored},
+		"failing":       {tache.StateFailing},
+		"failed":        {tache.StateFailed},
+		"waiting_retry": {tache.StateWaitingRetry},
+		"before_retry":  {tache.StateBeforeRetry},
+	}
+)
+
+type typedTasks struct {
+	typ   string
+	tasks func() []task.TaskExtensionInfo
+}
+
+func tasksOf[T task.TaskExtensionInfo](manager task.Manager[T]) func() []task.TaskExtensionInfo {
+	return func() []task.TaskExtensionInfo {
+		return utils.MustSliceConvert(manager.GetAll(), func(t T) task.TaskExtensionInfo {
+			return t
+		})
+	}
+}
+
+// allTasks lists the managers by the types, the same as the route groups
+func allTasks() []typedTasks {
+	return []typedTasks{
+		{"upload", tasksOf(fs.UploadTaskManager)},
+		{"copy", tasksOf(fs.CopyTaskManager)},
+		{"move", tasksOf(fs.MoveTaskManager)},
+		{"offline_download", tasksOf(tool.DownloadTaskManager)},
+		{"offline_download_transfer", tasksOf(tool.TransferTaskManager)},
+		{"decompress", tasksOf(fs.ArchiveDownloadTaskManager)},
+		{"decompress_upload", tasksOf(fs.ArchiveContentUploadTaskManager)},
+		{"compress", tasksOf(fs.ArchiveCompressTaskManager)},
+		{"pipeline", tasksOf(pipeline.TaskManager)},
+	}
+}
+
+type TaskListReq struct {
+	model.PageReq
+	// Type is the comma separated types, e.g. "copy,move", empty means all
+	Type string `json:"type" form:"type"`
+	// State is the comma separated states, e.g. "running,failed",
+	// "undone" and "done" are the same as the routes
+	State string `json:"state" form:"state"`
+	// Creator is the username of the creator, only admin can filter by it
+	Creator string `json:"creator" form:"creator"`
+	// From and To are the range of the start time
+	From *time.Time `json:"from" form:"from"`
+	To   *time.Time `json:"to" form:"to"`
+	// Path is the path the tasks work in or under
+	Path string `json:"path" form:"path"`
+}
+
+type taskFilter struct {
+	types   []string
+	states  []tache.State
+	isAdmin bool
+	uid     uint
+	creator string
+	from    *time.Time
+	to      *time.Time
+	path    string
+}
+
+func newTaskFilter(c *gin.Context, req *TaskListReq) (*taskFilter, error) {
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	f := &taskFilter{
+		isAdmin: user.IsAdmin(),
+		uid:     user.ID,
+		from:    req.From,
+		to:      req.To,
+	}
+	for _, typ := range strings.Split(req.Type, ",") {
+		if typ = strings.TrimSpace(typ); typ != "" {
+			f.types = append(f.types, typ)
+		}
+	}
+	for _, name := range strings.Split(req.State, ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		states, ok := stateNames[name]
+		if !ok {
+			return nil, errors.Errorf("unknown state: %s", name)
+		}
+		f.states = append(f.states, states...)
+	}
+	if req.Creator != "" {
+		if !f.isAdmin {
+			return nil, errors.New("only admin can filter by creator")
+		}
+		f.creator = req.Creator
+	}
+	if req.Path != "" {
+		p, err := user.JoinPath(req.Path)
+		if err != nil {
+			return nil, err
+		}
+		f.path = p
+	}
+	return f, nil
+}
+
+func (f *taskFilter) match(typ string, t task.TaskExtensionInfo) bool {
+	if len(f.types) > 0 && !utils.SliceContains(f.types, typ) {
+		return false
+	}
+	creator := t.GetCreator()
+	if !f.isAdmin && (creator == nil || creator.ID != f.uid) {
+		return false
+	}
+	if f.creator != "" && (creator == nil || creator.Username != f.creator) {
+		return false
+	}
+	if len(f.states) > 0 && !utils.SliceContains(f.states, t.GetState()) {
+		return false
+	}
+	if f.from != nil || f.to != nil {
+		start := t.GetStartTime()
+		if start == nil || (f.from != nil && start.Before(*f.from)) || (f.to != nil && start.After(*f.to)) {
+			return false
+		}
+	}
+	if f.path != "" {
+		tp, ok := t.(task.TaskWithPaths)
+		if !ok {
+			return false
+		}
+		found := false
+		for _, p := range tp.GetPaths() {
+			if utils.IsSubPath(f.path, p) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// tasks returns the matched tasks from the newest, the ones not started first
+func (f *taskFilter) tasks() []TaskInfo {
+	var infos []TaskInfo
+	for _, m := range allTasks() {
+		for _, t := range m.tasks() {
+			if f.match(m.typ, t) {
+				info := getTaskInfo(t)
+				info.Type = m.typ
+				infos = append(infos, info)
+			}
+		}
+	}
+	sort.SliceStable(infos, func(i, j int) bool {
+		a, b := infos[i].StartTime, infos[j].StartTime
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+		return a.After(*b)
+	})
+	return infos
+}
+
+// ListTasks lists the tasks of all the types with the filter
+func ListTasks(c *gin.Context) {
+	var req TaskListReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	f, err := newTaskFilter(c, &req)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	infos := f.tasks()
+	total := len(infos)
+	start := min((req.Page-1)*req.PerPage, total)
+	end := min(start+req.PerPage, total)
+	common.SuccessResp(c, common.PageResp{
+		Content: infos[start:end],
+		Total:   int64(total),
+	})
+}
+
+// the intervals of pushing the changed tasks and the keepalive comment
+var (
+	taskEventsInterval  = time.Second
+	taskEventsKeepalive = 15 * time.Second
+)
+
+// StreamTasks pushes the tasks matching the filter as server-sent events:
+// "tasks" with the tasks added or changed since the last event, including
+// all of them at first, and "removed" with the ids of the tasks gone.
+func StreamTasks(c *gin.Context) {
+	var req TaskListReq
+	if err := c.ShouldBindQuery(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	f, err := newTaskFilter(c, &req)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	last := make(map[string]TaskInfo)
+	lastSent := time.Time{}
+	push := func(w io.Writer) {
+		var changed []TaskInfo
+		current := make(map[string]TaskInfo)
+		for _, info := range f.tasks() {
+			current[info.ID] = info
+			if old, ok := last[info.ID]; !ok || old != info {
+				changed = append(changed, info)
+			}
+		}
+		var removed []string
+		for id := range last {
+			if _, ok := current[id]; !ok {
+				removed = append(removed, id)
+			}
+		}
+		last = current
+		if len(changed) > 0 || lastSent.IsZero() {
+			if changed == nil {
+				changed = []TaskInfo{}
+			}
+			c.SSEvent("tasks", changed)
+			lastSent = time.Now()
+		}
+		if len(removed) > 0 {
+			c.SSEvent("removed", removed)
+			lastSent = time.Now()
+		}
+		if time.Since(lastSent) > taskEventsKeepalive {
+			_, _ = io.WriteString(w, ": keepalive\n\n")
+			lastSent = time.Now()
+		}
+	}
+	push(c.Writer)
+	c.Writer.Flush()
+	ticker := time.NewTicker(taskEventsInterval)
+	defer ticker.Stop()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case <-ticker.C:
+			push(w)
+			return true
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package handles
+
+import (
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// ListTrash lists the objects trashed by the user, or all for the admin
+func ListTrash(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	var deleterId uint
+	if !user.IsAdmin() {
+		deleterId = user.ID
+	}
+	items, total, err := db.GetTrashItems(deleterId, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	if !user.IsAdmin() {
+		// show the original paths as the user sees them
+		for i := range items {
+			if rel, ok := user.RelPath(items[i].Path); ok {
+				items[i].Path = rel
+			}
+		}
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: items,
+		Total:   total,
+	})
+}
+
+type TrashReq struct {
+	IDs []uint `json:"ids"`
+}
+
+// getTrashItems returns the items of the request that the user can handle by action
+func getTrashItems(c *gin.Context, action string) ([]*model.TrashItem, bool) {
+	var req TrashReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return nil, false
+	}
+	if len(req.IDs) == 0 {
+		common.ErrorStrResp(c, "Empty trash item ids", 400)
+		return nil, false
+	}
+	user := c.Request.Context().Value(conf.UserKey).(*model.User)
+	items := make([]*model.TrashItem, 0, len(req.IDs))
+	for _, id := range req.IDs {
+		item, err := db.GetTrashItemById(id)
+		if err != nil {
+			common.ErrorResp(c, err, 404)
+			return nil, false
+		}
+		if !user.IsAdmin() && (item.DeleterID != user.ID || !user.InBasePaths(item.Path) ||
+			op.CheckACL(user, item.Path, action) != nil) {
+			common.ErrorResp(c, errs.PermissionDenied, 403)
+			return nil, false
+		}
+		items = append(items, item)
+	}
+	return items, true
+}
+
+func RestoreTrash(c *gin.Context) {
+	items, ok := getTrashItems(c, model.ACLWrite)
+	if !ok {
+		return
+	}
+	for _, item := range items {
+		if err := op.RestoreTrash(c.Request.Context(), item); err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+	}
+	common.SuccessResp(c)
+}
+
+func PurgeTrash(c *gin.Context) {
+	items, ok := getTrashItems(c, model.ACLDelete)
+	if !ok {
+		return
+	}
+	for _, item := range items {
+		if err := op.PurgeTrash(c.Request.Context(), item); err != nil {
+			common.ErrorResp(c, err, 500)
+			return
+		}
+	}
+	common.SuccessResp(c)
+}
@@ -0,0 +1,144 @@
+package handles
+
+import (
+	"strconv"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/event"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/webhook"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+)
+
+// WebhookReq is the webhook to create or update, the secret of
+// model.Webhook is hidden from json so it's bound here
+type WebhookReq struct {
+	model.Webhook
+	Secret string `json:"secret" form:"secret"`
+}
+
+func (r *WebhookReq) webhook() *model.Webhook {
+	r.Webhook.Secret = r.Secret
+	return &r.Webhook
+}
+
+func ListWebhooks(c *gin.Context) {
+	var req model.PageReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	webhooks, total, err := db.GetWebhooks(req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	for i := range webhooks {
+		webhooks[i].HasSecret = webhooks[i].Secret != ""
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: webhooks,
+		Total:   total,
+	})
+}
+
+func GetWebhook(c *gin.Context) {
+	idStr := c.Query("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	w, err := db.GetWebhookById(uint(id))
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	w.HasSecret = w.Secret != ""
+	common.SuccessResp(c, w)
+}
+
+func ListWebhookEvents(c *gin.Context) {
+	common.SuccessResp(c, event.Types)
+}
+
+func CreateWebhook(c *gin.Context) {
+	var req WebhookReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := webhook.Create(req.webhook()); err != nil {
+		common.ErrorResp(c, err, 500, true)
+	} else {
+		common.SuccessResp(c, gin.H{
+			"id": req.ID,
+		})
+	}
+}
+
+func UpdateWebhook(c *gin.Context) {
+	var req WebhookReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := webhook.Update(req.webhook()); err != nil {
+		common.ErrorResp(c, err, 500, true)
+	} else {
+		common.SuccessResp(c)
+	}
+}
+
+func DeleteWebhook(c *gin.Context) {
+	idStr := c.Query("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := webhook.Delete(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+func TestWebhook(c *gin.Context) {
+	idStr := c.Query("id")
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	if err := webhook.Test(uint(id)); err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c)
+}
+
+type ListWebhookDeliveriesReq struct {
+	model.PageReq
+	WebhookID uint `json:"webhook_id" form:"webhook_id"`
+}
+
+func ListWebhookDeliveries(c *gin.Context) {
+	var req ListWebhookDeliveriesReq
+	if err := c.ShouldBind(&req); err != nil {
+		common.ErrorResp(c, err, 400)
+		return
+	}
+	req.Validate()
+	deliveries, total, err := db.GetWebhookDeliveries(req.WebhookID, req.Page, req.PerPage)
+	if err != nil {
+		common.ErrorResp(c, err, 500, true)
+		return
+	}
+	common.SuccessResp(c, common.PageResp{
+		Content: deliveries,
+		Total:   total,
+	})
+}
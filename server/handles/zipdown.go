@@ -0,0 +1,238 @@
+package handles
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	stdpath "path"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/driver"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/net"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/stream"
+	"github.com/OpenListTeam/OpenList/v4/pkg/http_range"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+// ZipDown streams the folder of the path, or the children of it selected by
+// the `name` queries, as a store mode zip. Anyone with the sign is the same
+// as the guest, so the objects the guest can't see are left out.
+func ZipDown(c *gin.Context) {
+	rawPath := c.Request.Context().Value(conf.PathKey).(string)
+	meta, _ := c.Request.Context().Value(conf.MetaKey).(*model.Meta)
+	paths, err := zipSelect([]string{rawPath}, c.QueryArray("name"))
+	if err != nil {
+		common.ErrorPage(c, err, 400)
+		return
+	}
+	guest, err := op.GetGuest()
+	if err != nil {
+		common.ErrorPage(c, err, 500)
+		return
+	}
+	// the sign only proves the access to the root, so the sub folders
+	// protected by the other metas and the acl are skipped
+	rootReadable := op.CheckACL(guest, rawPath, model.ACLRead) == nil
+	skip := func(path string) (bool, error) {
+		m, err := op.GetNearestMeta(path)
+		if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+			return false, err
+		}
+		password := ""
+		if m != nil && meta != nil && m.Path == meta.Path {
+			password = m.Password
+		}
+		if !common.CanAccess(guest, m, path, password) {
+			return true, nil
+		}
+		return rootReadable && op.CheckACL(guest, path, model.ACLRead) != nil, nil
+	}
+	for _, p := range paths {
+		if p == rawPath {
+			continue
+		}
+		s, err := skip(p)
+		if err != nil {
+			common.ErrorPage(c, err, 500)
+			return
+		}
+		if s {
+			common.ErrorPage(c, errors.WithMessagef(errs.ObjectNotFound, "failed get %s", p), 404)
+			return
+		}
+	}
+	files, err := zipFiles(c.Request.Context(), paths, skip)
+	if err != nil {
+		common.ErrorPage(c, err, 500)
+		return
+	}
+	name := stdpath.Base(rawPath)
+	if rawPath == "/" {
+		name = "download"
+	}
+	serveZip(c, name+".zip", files)
+}
+
+// SharingZipDown streams the sharing files, or the folder of the path in the
+// sharing, as a store mode zip
+func SharingZipDown(c *gin.Context, s *model.Sharing, path string) {
+	var roots []string
+	name := s.ID
+	if len(s.Files) != 1 && path == "/" {
+		roots = s.Files
+	} else {
+		unwrapPath, err := op.GetSharingUnwrapPath(s, path)
+		if err != nil {
+			common.ErrorPage(c, errors.New("failed get sharing unwrap path"), 500)
+			return
+		}
+		roots = []string{unwrapPath}
+		if path != "/" {
+			name = stdpath.Base(path)
+		}
+	}
+	paths, err := zipSelect(roots, c.QueryArray("name"))
+	if err != nil {
+		common.ErrorPage(c, err, 400)
+		return
+	}
+	files, err := zipFiles(c.Request.Context(), paths, nil)
+	if dealErrorPage(c, err) {
+		return
+	}
+	_ = countAccess(c.ClientIP(), s)
+	serveZip(c, name+".zip", files)
+	logAccess(c, s, model.SharingActionDownload, path, int64(c.Writer.Size()))
+}
+
+// zipSelect returns the roots selected by the names. If there is only one
+// root, the names are of the children of it.
+func zipSelect(roots []string, names []string) ([]string, error) {
+	if len(names) == 0 {
+		return roots, nil
+	}
+	paths := make([]string, 0, len(names))
+	for _, name := range names {
+		if name == "" || name == "." || name == ".." || strings.Contains(name, "/") {
+			return nil, errors.Errorf("invalid name: %s", name)
+		}
+		if len(roots) == 1 {
+			paths = append(paths, stdpath.Join(roots[0], name))
+			continue
+		}
+		found := false
+		for _, root := range roots {
+			if stdpath.Base(root) == name {
+				paths = append(paths, root)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, errors.WithMessagef(errs.ObjectNotFound, "failed get %s", name)
+		}
+	}
+	return paths, nil
+}
+
+// zipFiles returns the objects of the paths and all the objects under them,
+// the objects for which skip returns true are left out with their children
+func zipFiles(ctx context.Context, paths []string, skip func(path string) (bool, error)) ([]stream.ZipFile, error) {
+	var files []stream.ZipFile
+	var walk func(storage driver.Driver, name, path, actualPath string, obj model.Obj) error
+	walk = func(storage driver.Driver, name, path, actualPath string, obj model.Obj) error {
+		if utils.IsCanceled(ctx) {
+			return ctx.Err()
+		}
+		file := stream.ZipFile{Name: name, Obj: obj, Path: path}
+		if !obj.IsDir() {
+			file.Link = func(ctx context.Context) (*model.Link, error) {
+				link, _, err := op.Link(ctx, storage, actualPath, model.LinkArgs{})
+				return link, err
+			}
+			files = append(files, file)
+			return nil
+		}
+		files = append(files, file)
+		objs, err := op.List(ctx, storage, actualPath, model.ListArgs{})
+		if err != nil {
+			return err
+		}
+		for _, o := range objs {
+			p := stdpath.Join(path, o.GetName())
+			if skip != nil {
+				if s, err := skip(p); err != nil {
+					return err
+				} else if s {
+					continue
+				}
+			}
+			if err = walk(storage, stdpath.Join(name, o.GetName()), p, stdpath.Join(actualPath, o.GetName()), o); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, path := range paths {
+		storage, actualPath, err := op.GetStorageAndActualPath(path)
+		if err != nil {
+			return nil, err
+		}
+		obj, err := op.Get(ctx, storage, actualPath)
+		if err != nil {
+			return nil, err
+		}
+		name := obj.GetName()
+		if actualPath == "/" {
+			name = stdpath.Base(storage.GetStorage().MountPath)
+		}
+		if err = walk(storage, name, path, actualPath, obj); err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+func serveZip(c *gin.Context, name string, files []stream.ZipFile) {
+	// the etag changes with the files, so a resumed download of a changed
+	// folder gets the whole new zip by If-Range
+	h := sha1.New()
+	var modTime time.Time
+	for _, f := range files {
+		_, _ = fmt.Fprintf(h, "%s\x00%d\x00%d\x00", f.Name, f.Obj.GetSize(), f.Obj.ModTime().UnixNano())
+		if f.Obj.ModTime().After(modTime) {
+			modTime = f.Obj.ModTime()
+		}
+	}
+	z := stream.NewZipStream(files)
+	// the range that would read the whole files before it is served as the
+	// whole zip, the client downloads it again instead of waiting long
+	if r := c.GetHeader("Range"); r != "" {
+		if ranges, err := http_range.ParseRange(r, z.GetSize()); err == nil && !z.CanRange(ranges) {
+			c.Request.Header.Del("Range")
+		}
+	}
+	c.Header("Content-Disposition", utils.GenerateContentDisposition(name))
+	c.Header("Content-Type", "application/zip")
+	c.Header("Etag", `"`+hex.EncodeToString(h.Sum(nil))+`"`)
+	w := &common.WrittenResponseWriter{ResponseWriter: c.Writer}
+	err := net.ServeHTTP(w, c.Request, name, modTime, z.GetSize(), &model.RangeReadCloser{RangeReader: z})
+	if err == nil {
+		return
+	}
+	if w.IsWritten() {
+		log.Errorf("%s %s zip error: %+v", c.Request.Method, c.Request.URL.Path, err)
+	} else {
+		common.ErrorPage(c, err, 500, true)
+	}
+}
@@ -0,0 +1,27 @@
+package server
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+func Metrics(g *gin.RouterGroup) {
+	handler := gin.WrapH(promhttp.HandlerFor(metrics.Registry, promhttp.HandlerOpts{}))
+	g.GET("/metrics", func(c *gin.Context) {
+		if conf.Conf.Metrics.Token != "" {
+			token := strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if subtle.ConstantTimeCompare([]byte(token), []byte(conf.Conf.Metrics.Token)) != 1 {
+				common.ErrorStrResp(c, "invalid metrics token", 401)
+				c.Abort()
+				return
+			}
+		}
+		handler(c)
+	})
+}
@@ -59,6 +59,10 @@ func needSign(meta *model.Meta, path string) bool {
 	if common.IsStorageSignEnabled(path) {
 		return true
 	}
+	// anyone without a sign is the same as the guest
+	if guest, err := op.GetGuest(); err == nil && op.CheckACL(guest, path, model.ACLRead) != nil {
+		return true
+	}
 	if meta == nil || meta.Password == "" {
 		return false
 	}
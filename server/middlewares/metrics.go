@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+func Metrics(c *gin.Context) {
+	start := time.Now()
+	c.Next()
+	// label by route pattern instead of the raw path to bound the cardinality
+	route := c.FullPath()
+	if route == "" {
+		route = "unmatched"
+	}
+	metrics.ObserveHTTPRequest(c.Request.Method, route, strconv.Itoa(c.Writer.Status()), start)
+}
@@ -1,17 +1,84 @@
 package middlewares
 
 import (
+	"net/http"
+	stdpath "path"
+
 	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
 	"github.com/OpenListTeam/OpenList/v4/server/common"
 	"github.com/gin-gonic/gin"
+	"github.com/pkg/errors"
 )
 
+// SharingIdParse puts the sharing id into the context. It also enforces the
+// options of the drop folders: the files can't be downloaded if the listing
+// is disabled, and the uploads are checked against the limits and the
+// permissions of the creator, whom they are put as.
 func SharingIdParse(c *gin.Context) {
 	sid := c.Param("sid")
 	common.GinWithValue(c, conf.SharingIDKey, sid)
+	if c.Request.Method == http.MethodPut {
+		if code, err := checkSharingUpload(c, sid); err != nil {
+			common.ErrorResp(c, err, code)
+			return
+		}
+	} else if s, err := op.GetSharingById(sid); err == nil && !s.CanList() {
+		common.ErrorPage(c, errs.SharingListNotAllowed, 403)
+		return
+	}
 	c.Next()
 }
 
+// checkSharingUpload checks the file put into the drop folder, and replaces
+// the user of the context with the creator
+func checkSharingUpload(c *gin.Context, sid string) (int, error) {
+	s, err := op.GetSharingById(sid)
+	if err != nil {
+		return 500, errs.SharingNotFound
+	}
+	if !s.Valid() {
+		return 500, errs.InvalidSharing
+	}
+	pwd := c.GetHeader("Password")
+	if pwd == "" {
+		pwd = c.Query("pwd")
+	}
+	if !s.Verify(pwd) {
+		return 403, errs.WrongShareCode
+	}
+	if !s.Upload {
+		return 403, errs.SharingUploadNotAllowed
+	}
+	path := utils.FixAndCleanPath(c.Request.Context().Value(conf.PathKey).(string))
+	if path == "/" {
+		return 400, errors.New("the file name is required")
+	}
+	if err = s.CheckUpload(stdpath.Base(path), c.Request.ContentLength); err != nil {
+		return 403, err
+	}
+	unwrapPath, err := op.GetSharingUnwrapPath(s, path)
+	if err != nil {
+		return 500, errors.WithMessage(err, "failed get sharing unwrap path")
+	}
+	creator := s.Creator
+	if !creator.IsAdmin() && !creator.InBasePaths(unwrapPath) {
+		return 403, errs.PermissionDenied
+	}
+	dir := stdpath.Dir(unwrapPath)
+	meta, err := op.GetNearestMeta(dir)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return 500, err
+	}
+	if !creator.CanWrite() && !common.CanWrite(meta, dir) {
+		return 403, errs.PermissionDenied
+	}
+	common.GinWithValue(c, conf.UserKey, creator)
+	return 0, nil
+}
+
 func EmptyPathParse(c *gin.Context) {
 	common.GinWithValue(c, conf.PathKey, "/")
 	c.Next()
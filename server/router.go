@@ -27,6 +27,10 @@ func Init(e *gin.Engine) {
 	if conf.Conf.Scheme.HttpPort != -1 && conf.Conf.Scheme.HttpsPort != -1 && conf.Conf.Scheme.ForceHttps {
 		e.Use(middlewares.ForceHttps)
 	}
+	if conf.Conf.Metrics.Enable {
+		g.Use(middlewares.Metrics)
+		Metrics(g)
+	}
 	g.Any("/ping", func(c *gin.Context) {
 		c.String(200, "pong")
 	})
@@ -48,6 +52,8 @@ func Init(e *gin.Engine) {
 	g.GET("/p/*path", middlewares.PathParse, signCheck, downloadLimiter, handles.Proxy)
 	g.HEAD("/d/*path", middlewares.PathParse, signCheck, handles.Down)
 	g.HEAD("/p/*path", middlewares.PathParse, signCheck, handles.Proxy)
+	g.GET("/dz/*path", middlewares.PathParse, signCheck, downloadLimiter, handles.ZipDown)
+	g.HEAD("/dz/*path", middlewares.PathParse, signCheck, handles.ZipDown)
 	archiveSignCheck := middlewares.Down(sign.VerifyArchive)
 	g.GET("/ad/*path", middlewares.PathParse, archiveSignCheck, downloadLimiter, handles.ArchiveDown)
 	g.GET("/ap/*path", middlewares.PathParse, archiveSignCheck, downloadLimiter, handles.ArchiveProxy)
@@ -64,6 +70,7 @@ func Init(e *gin.Engine) {
 	g.GET("/sad/:sid/*path", middlewares.PathParse, middlewares.SharingIdParse, downloadLimiter, handles.SharingArchiveExtract)
 	g.HEAD("/sad/:sid", middlewares.EmptyPathParse, middlewares.SharingIdParse, handles.SharingArchiveExtract)
 	g.HEAD("/sad/:sid/*path", middlewares.PathParse, middlewares.SharingIdParse, handles.SharingArchiveExtract)
+	g.PUT("/sd/:sid/*path", middlewares.PathParse, middlewares.SharingIdParse, middlewares.UploadRateLimiter(stream.ClientUploadLimit), handles.SharingUpload)
 
 	api := g.Group("/api")
 	auth := api.Group("", middlewares.Auth(false))
@@ -77,6 +84,9 @@ func Init(e *gin.Engine) {
 	auth.GET("/me/sshkey/list", handles.ListMyPublicKey)
 	auth.POST("/me/sshkey/add", handles.AddMyPublicKey)
 	auth.POST("/me/sshkey/delete", handles.DeleteMyPublicKey)
+	auth.GET("/me/s3key/list", handles.ListMyS3AccessKeys)
+	auth.POST("/me/s3key/add", handles.AddMyS3AccessKey)
+	auth.POST("/me/s3key/delete", handles.DeleteMyS3AccessKey)
 	auth.POST("/auth/2fa/generate", handles.Generate2FA)
 	auth.POST("/auth/2fa/verify", handles.Verify2FA)
 	auth.GET("/auth/logout", handles.LogOut)
@@ -105,6 +115,7 @@ func Init(e *gin.Engine) {
 	fsAndShare(api.Group("/fs", middlewares.Auth(true)))
 	_task(auth.Group("/task", middlewares.AuthNotGuest))
 	_sharing(auth.Group("/share", middlewares.AuthNotGuest))
+	_pipeline(auth.Group("/pipeline", middlewares.AuthNotGuest))
 	admin(auth.Group("/admin", middlewares.AuthAdmin))
 	if flags.Debug || flags.Dev {
 		debug(g.Group("/debug"))
@@ -132,6 +143,16 @@ func admin(g *gin.RouterGroup) {
 	user.POST("/del_cache", handles.DelUserCache)
 	user.GET("/sshkey/list", handles.ListPublicKeys)
 	user.POST("/sshkey/delete", handles.DeletePublicKey)
+	user.GET("/s3key/list", handles.ListS3AccessKeys)
+	user.POST("/s3key/delete", handles.DeleteS3AccessKey)
+
+	group := g.Group("/group")
+	group.GET("/list", handles.ListGroups)
+	group.GET("/get", handles.GetGroup)
+	group.GET("/members", handles.ListGroupMembers)
+	group.POST("/create", handles.CreateGroup)
+	group.POST("/update", handles.UpdateGroup)
+	group.POST("/delete", handles.DeleteGroup)
 
 	storage := g.Group("/storage")
 	storage.GET("/list", handles.ListStorages)
@@ -166,6 +187,16 @@ func admin(g *gin.RouterGroup) {
 	setting.POST("/set_thunderx", handles.SetThunderX)
 	setting.POST("/set_thunder_browser", handles.SetThunderBrowser)
 
+	webhook := g.Group("/webhook")
+	webhook.GET("/list", handles.ListWebhooks)
+	webhook.GET("/get", handles.GetWebhook)
+	webhook.GET("/events", handles.ListWebhookEvents)
+	webhook.POST("/create", handles.CreateWebhook)
+	webhook.POST("/update", handles.UpdateWebhook)
+	webhook.POST("/delete", handles.DeleteWebhook)
+	webhook.POST("/test", handles.TestWebhook)
+	webhook.GET("/deliveries", handles.ListWebhookDeliveries)
+
 	// retain /admin/task API to ensure compatibility with legacy automation scripts
 	_task(g.Group("/task"))
 
@@ -179,6 +210,9 @@ func admin(g *gin.RouterGroup) {
 	index.POST("/stop", middlewares.SearchIndex, handles.StopIndex)
 	index.POST("/clear", middlewares.SearchIndex, handles.ClearIndex)
 	index.GET("/progress", middlewares.SearchIndex, handles.GetProgress)
+	index.GET("/jobs", middlewares.SearchIndex, handles.ListIndexJobs)
+	index.POST("/jobs/run", middlewares.SearchIndex, handles.RunIndexJob)
+	index.POST("/jobs/stop", middlewares.SearchIndex, handles.StopIndexJob)
 }
 
 func fsAndShare(g *gin.RouterGroup) {
@@ -211,6 +245,11 @@ func _fs(g *gin.RouterGroup) {
 	// g.POST("/add_transmission", handles.SetTransmission)
 	g.POST("/add_offline_download", handles.AddOfflineDownload)
 	g.POST("/archive/decompress", handles.FsArchiveDecompress)
+	g.POST("/archive/compress", handles.FsArchiveCompress)
+	trash := g.Group("/trash")
+	trash.Any("/list", handles.ListTrash)
+	trash.POST("/restore", handles.RestoreTrash)
+	trash.POST("/purge", handles.PurgeTrash)
 }
 
 func _task(g *gin.RouterGroup) {
@@ -225,6 +264,19 @@ func _sharing(g *gin.RouterGroup) {
 	g.POST("/delete", handles.DeleteSharing)
 	g.POST("/enable", handles.SetEnableSharing(false))
 	g.POST("/disable", handles.SetEnableSharing(true))
+	g.GET("/log", handles.ListSharingAccessLogs)
+	g.GET("/log/stats", handles.GetSharingAccessStats)
+	g.GET("/log/export", handles.ExportSharingAccessLogs)
+}
+
+func _pipeline(g *gin.RouterGroup) {
+	g.Any("/list", handles.ListPipelines)
+	g.GET("/get", handles.GetPipeline)
+	g.POST("/create", handles.CreatePipeline)
+	g.POST("/update", handles.UpdatePipeline)
+	g.POST("/delete", handles.DeletePipeline)
+	g.POST("/run", handles.RunPipeline)
+	g.POST("/submit", handles.SubmitPipeline)
 }
 
 func Cors(r *gin.Engine) {
@@ -0,0 +1,263 @@
+// Package s3 implements a fake s3 server for openlist
+package s3
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/errs"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/internal/op"
+	"github.com/OpenListTeam/OpenList/v4/internal/setting"
+	"github.com/OpenListTeam/OpenList/v4/server/common"
+	"github.com/itsHenry35/gofakes3"
+	"github.com/itsHenry35/gofakes3/signature"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type keyUserKey struct{}
+
+// authError is written as the S3 error response before the request reaches gofakes3
+type authError signature.APIError
+
+func (e authError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+var (
+	errAccessDenied     = authError{Code: "AccessDenied", Description: "Access Denied.", HTTPStatusCode: http.StatusForbidden}
+	errInvalidAccessKey = authError{Code: "InvalidAccessKeyId", Description: "The access key ID you provided does not exist in our records.", HTTPStatusCode: http.StatusForbidden}
+	errRequestExpired   = authError{Code: "AccessDenied", Description: "Request has expired", HTTPStatusCode: http.StatusForbidden}
+	errExpiresTooLong   = authError{Code: "AuthorizationQueryParametersError", Description: "X-Amz-Expires must be less than a week (in seconds) that is 604800", HTTPStatusCode: http.StatusBadRequest}
+	// gofakes3 responds 500 to the codes it doesn't know, e.g. AccessDenied,
+	// which the clients retry, so it's a 400 like the quota error
+	errPermissionDenied = gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, errs.PermissionDenied.Error())
+)
+
+// authenticate verifies the signature of the request by the secret of its
+// access key, and returns the user to run the request as. keyUser is true if
+// the access key is of the user rather than the global one, or the request
+// is an anonymous read of a bucket with AnonymousRead, which is checked as
+// the guest.
+func authenticate(r *http.Request) (user *model.User, keyUser bool, err error) {
+	guest, err := op.GetGuest()
+	if err != nil {
+		return nil, false, err
+	}
+	globalKey := setting.GetStr(conf.S3AccessKeyId)
+	globalSecret := setting.GetStr(conf.S3SecretAccessKey)
+	// the signatures are not checked if there is no global key, as before
+	// the access keys of the users were added
+	open := globalKey == "" && globalSecret == ""
+	accessKey := getAccessKey(r)
+	var secret string
+	var key *model.S3AccessKey
+	switch {
+	case accessKey == "":
+		if open {
+			return guest, false, nil
+		}
+		if !anonymousRead(r) {
+			return nil, false, errAccessDenied
+		}
+		return guest, true, nil
+	case accessKey == globalKey:
+		user, secret = guest, globalSecret
+	default:
+		key, err = op.GetS3AccessKeyByAccessKeyId(accessKey)
+		if err != nil {
+			if open {
+				return guest, false, nil
+			}
+			return nil, false, errInvalidAccessKey
+		}
+		user, err = op.GetUserById(key.UserId)
+		if err != nil || user.Disabled {
+			return nil, false, errInvalidAccessKey
+		}
+		secret, keyUser = key.SecretAccessKey, true
+	}
+	signature.StoreKeys(map[string]string{accessKey: secret})
+	if err = verifySignature(r); err != nil {
+		return nil, false, err
+	}
+	if key != nil && time.Since(key.LastUsedTime) > time.Minute {
+		key.UpdateLastUsedTime()
+		if err := op.UpdateS3AccessKey(key); err != nil {
+			log.Warnf("failed update last used time of s3 key %d: %+v", key.ID, err)
+		}
+	}
+	return user, keyUser, nil
+}
+
+// verifySignature verifies the signature v4, or v2 if the request isn't
+// signed by v4, in the header or in the query of the presigned URLs
+func verifySignature(r *http.Request) error {
+	query := r.URL.Query()
+	presigned := r.Header.Get("Authorization") == ""
+	if presigned && query.Get("X-Amz-Signature") != "" {
+		// gofakes3 checks the expiry but not the limit of S3
+		if expires, err := strconv.ParseInt(query.Get("X-Amz-Expires"), 10, 64); err == nil && expires > 7*24*60*60 {
+			return errExpiresTooLong
+		}
+	}
+	code := signature.V4SignVerify(r)
+	if code == signature.ErrUnsupportAlgorithm {
+		v2 := r
+		if presigned {
+			// gofakes3 checks neither the expiry of the presigned v2 URLs nor
+			// unescapes their signature only once
+			expires, err := strconv.ParseInt(query.Get("Expires"), 10, 64)
+			if err != nil || time.Now().Unix() > expires {
+				return errRequestExpired
+			}
+			v2 = r.Clone(r.Context())
+			query.Set("Signature", url.QueryEscape(query.Get("Signature")))
+			v2.URL.RawQuery = query.Encode()
+		}
+		code = signature.V2SignVerify(v2)
+	}
+	if code != signature.ErrNone {
+		return authError(signature.GetAPIError(code))
+	}
+	return nil
+}
+
+// requestBucket returns the bucket and the object of the request path, the
+// bucket is empty if it doesn't exist
+func requestBucket(r *http.Request) (Bucket, string) {
+	bucketName, object, _ := strings.Cut(strings.Trim(r.URL.Path, "/"), "/")
+	if bucketName == "" {
+		return Bucket{}, object
+	}
+	bucket, err := getBucketByName(bucketName)
+	if err != nil {
+		return Bucket{}, object
+	}
+	return bucket, object
+}
+
+func isRead(r *http.Request) bool {
+	return r.Method == http.MethodGet || r.Method == http.MethodHead
+}
+
+// anonymousRead reports whether the request reads an object of a bucket
+// with AnonymousRead, the listing and the multipart uploads are excluded
+func anonymousRead(r *http.Request) bool {
+	bucket, object := requestBucket(r)
+	_, uploadID := r.URL.Query()["uploadId"]
+	return bucket.AnonymousRead && object != "" && isRead(r) && !uploadID
+}
+
+// checkReadOnly rejects the writes to the buckets with ReadOnly, including
+// the presigned PUTs and the ones signed by the global access key
+func checkReadOnly(r *http.Request) error {
+	if isRead(r) || r.Method == http.MethodOptions {
+		return nil
+	}
+	if bucket, _ := requestBucket(r); bucket.ReadOnly {
+		return errAccessDenied
+	}
+	return nil
+}
+
+// getAccessKey returns the access key of the signature v4 or v2 in the
+// header or the query, empty for the anonymous requests
+func getAccessKey(r *http.Request) string {
+	auth := r.Header.Get("Authorization")
+	if auth == "" {
+		if cred := r.URL.Query().Get("X-Amz-Credential"); cred != "" {
+			accessKey, _, _ := strings.Cut(cred, "/")
+			return accessKey
+		}
+		return r.URL.Query().Get("AWSAccessKeyId")
+	}
+	if v2, ok := strings.CutPrefix(auth, "AWS "); ok {
+		accessKey, _, _ := strings.Cut(v2, ":")
+		return accessKey
+	}
+	if _, cred, ok := strings.Cut(auth, "Credential="); ok {
+		accessKey, _, _ := strings.Cut(cred, "/")
+		return accessKey
+	}
+	return ""
+}
+
+func writeAuthError(w http.ResponseWriter, r *http.Request, err error) {
+	var e authError
+	if !errors.As(err, &e) {
+		log.Errorf("s3 auth %s %s: %+v", r.Method, r.URL.Path, err)
+		e = authError{Code: "InternalError", Description: "Internal Error", HTTPStatusCode: http.StatusInternalServerError}
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(e.HTTPStatusCode)
+	if r.Method != http.MethodHead {
+		_, _ = w.Write(signature.EncodeAPIErrorToResponse(signature.APIError(e)))
+	}
+}
+
+// getKeyUser returns the user of the access key, or the guest for the
+// anonymous reads of the buckets with AnonymousRead. nil for the global
+// access key and the anonymous requests if there is no global access key,
+// which are not bound to any user.
+func getKeyUser(ctx context.Context) *model.User {
+	user, _ := ctx.Value(keyUserKey{}).(*model.User)
+	return user
+}
+
+// canRead reports whether the user of the access key can read the path, the
+// path must be under the base path of the user and pass the meta hide and
+// password, as there is no way to give the password by S3
+func canRead(ctx context.Context, fp string) bool {
+	user := getKeyUser(ctx)
+	if user == nil {
+		return true
+	}
+	if !user.InBasePaths(fp) {
+		return false
+	}
+	meta, err := op.GetNearestMeta(fp)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return false
+	}
+	return common.CanAccess(user, meta, fp, "")
+}
+
+// checkWrite checks the user of the access key can create or overwrite the object
+func checkWrite(ctx context.Context, fp string) error {
+	user := getKeyUser(ctx)
+	if user == nil {
+		return nil
+	}
+	if !canRead(ctx, fp) {
+		return errPermissionDenied
+	}
+	dir := path.Dir(fp)
+	meta, err := op.GetNearestMeta(dir)
+	if err != nil && !errors.Is(errors.Cause(err), errs.MetaNotFound) {
+		return err
+	}
+	if !user.CanWrite() && !common.CanWrite(meta, dir) {
+		return errPermissionDenied
+	}
+	return nil
+}
+
+// checkRemove checks the user of the access key can delete the object
+func checkRemove(ctx context.Context, fp string) error {
+	user := getKeyUser(ctx)
+	if user == nil {
+		return nil
+	}
+	if !canRead(ctx, fp) || !user.CanRemove() {
+		return errPermissionDenied
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package s3
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itsHenry35/gofakes3/signature"
+)
+
+func presignV2(secret, method, resource string, expires int64) string {
+	h := hmac.New(sha1.New, []byte(secret))
+	_, _ = fmt.Fprintf(h, "%s\n\n\n%d\n%s", method, expires, resource)
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+func TestVerifyPresignedV2(t *testing.T) {
+	signature.StoreKeys(map[string]string{"presignv2": "secret"})
+	// the signatures with '+' are unescaped twice by gofakes3
+	expires := time.Now().Add(time.Hour).Unix()
+	sig := presignV2("secret", "GET", "/bucket/key.txt", expires)
+	for !strings.Contains(sig, "+") {
+		expires++
+		sig = presignV2("secret", "GET", "/bucket/key.txt", expires)
+	}
+	expired := time.Now().Add(-time.Hour).Unix()
+	for name, c := range map[string]struct {
+		expires int64
+		sig     string
+		code    string
+	}{
+		"valid":   {expires, sig, ""},
+		"expired": {expired, presignV2("secret", "GET", "/bucket/key.txt", expired), errRequestExpired.Code},
+		"forged":  {expires, presignV2("other", "GET", "/bucket/key.txt", expires), "SignatureDoesNotMatch"},
+	} {
+		query := url.Values{"AWSAccessKeyId": {"presignv2"}, "Expires": {strconv.FormatInt(c.expires, 10)}, "Signature": {c.sig}}
+		r := httptest.NewRequest("GET", "/bucket/key.txt?"+query.Encode(), nil)
+		var code string
+		if err := verifySignature(r); err != nil {
+			code = err.(authError).Code
+		}
+		if code != c.code {
+			t.Errorf("%s: got %q, want %q", name, code, c.code)
+		}
+	}
+}
+
+func TestVerifyPresignedV4Expires(t *testing.T) {
+	query := url.Values{
+		"X-Amz-Algorithm":  {"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential": {"presignv4/20240101/us-east-1/s3/aws4_request"},
+		"X-Amz-Date":       {"20240101T000000Z"},
+		"X-Amz-Expires":    {"604801"},
+		"X-Amz-Signature":  {strings.Repeat("0", 64)},
+	}
+	r := httptest.NewRequest("GET", "/bucket/key.txt?"+query.Encode(), nil)
+	if err := verifySignature(r); err != errExpiresTooLong {
+		t.Errorf("got %v", err)
+	}
+}
@@ -9,7 +9,6 @@ import (
 	"io"
 	"path"
 	"strings"
-	"sync"
 	"time"
 
 	"github.com/pkg/errors"
@@ -34,15 +33,11 @@ var (
 
 // s3Backend implements the gofacess3.Backend interface to make an S3
 // backend for gofakes3
-type s3Backend struct {
-	meta *sync.Map
-}
+type s3Backend struct{}
 
 // newBackend creates a new SimpleBucketBackend.
-func newBackend() gofakes3.Backend {
-	return &s3Backend{
-		meta: new(sync.Map),
-	}
+func newBackend() *s3Backend {
+	return &s3Backend{}
 }
 
 // ListBuckets always returns the default bucket.
@@ -53,6 +48,9 @@ func (b *s3Backend) ListBuckets(ctx context.Context) ([]gofakes3.BucketInfo, err
 	}
 	var response []gofakes3.BucketInfo
 	for _, b := range buckets {
+		if !canRead(ctx, b.Path) {
+			continue
+		}
 		node, _ := fs.Get(ctx, b.Path, &fs.GetArgs{})
 		response = append(response, gofakes3.BucketInfo{
 			// Name:         gofakes3.URLEncode(b.Name),
@@ -86,7 +84,7 @@ func (b *s3Backend) ListBucket(ctx context.Context, bucketName string, prefix *g
 	response := gofakes3.NewObjectList()
 	path, remaining := prefixParser(prefix)
 
-	err = b.entryListR(bucketPath, path, remaining, prefix.HasDelimiter, response)
+	err = b.entryListR(ctx, bucketPath, path, remaining, prefix.HasDelimiter, response)
 	if err == gofakes3.ErrNoSuchKey {
 		// AWS just returns an empty list
 		response = gofakes3.NewObjectList()
@@ -98,8 +96,6 @@ func (b *s3Backend) ListBucket(ctx context.Context, bucketName string, prefix *g
 }
 
 // HeadObject returns the fileinfo for the given object name.
-//
-// Note that the metadata is not supported yet.
 func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName string) (*gofakes3.Object, error) {
 	bucket, err := getBucketByName(bucketName)
 	if err != nil {
@@ -108,6 +104,9 @@ func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName strin
 	bucketPath := bucket.Path
 
 	fp := path.Join(bucketPath, objectName)
+	if !canRead(ctx, fp) {
+		return nil, gofakes3.KeyNotFound(objectName)
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	node, err := fs.Get(context.WithValue(ctx, conf.MetaKey, fmeta), fp, &fs.GetArgs{})
 	if err != nil {
@@ -125,12 +124,8 @@ func (b *s3Backend) HeadObject(ctx context.Context, bucketName, objectName strin
 		"Last-Modified": node.ModTime().Format(timeFormat),
 		"Content-Type":  utils.GetMimeType(fp),
 	}
-
-	if val, ok := b.meta.Load(fp); ok {
-		metaMap := val.(map[string]string)
-		for k, v := range metaMap {
-			meta[k] = v
-		}
+	for k, v := range getObjectMeta(fp) {
+		meta[k] = v
 	}
 
 	return &gofakes3.Object{
@@ -151,6 +146,9 @@ func (b *s3Backend) GetObject(ctx context.Context, bucketName, objectName string
 	bucketPath := bucket.Path
 
 	fp := path.Join(bucketPath, objectName)
+	if !canRead(ctx, fp) {
+		return nil, gofakes3.KeyNotFound(objectName)
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	node, err := fs.Get(context.WithValue(ctx, conf.MetaKey, fmeta), fp, &fs.GetArgs{})
 	if err != nil {
@@ -200,12 +198,8 @@ func (b *s3Backend) GetObject(ctx context.Context, bucketName, objectName string
 		"Content-Disposition": utils.GenerateContentDisposition(file.GetName()),
 		"Content-Type":        utils.GetMimeType(fp),
 	}
-
-	if val, ok := b.meta.Load(fp); ok {
-		metaMap := val.(map[string]string)
-		for k, v := range metaMap {
-			meta[k] = v
-		}
+	for k, v := range getObjectMeta(fp) {
+		meta[k] = v
 	}
 
 	return &gofakes3.Object{
@@ -242,6 +236,9 @@ func (b *s3Backend) PutObject(
 
 	fp := path.Join(bucketPath, objectName)
 	log.Debugf("fp: %s, bucketPath: %s, objectName: %s", fp, bucketPath, objectName)
+	if err = checkWrite(ctx, fp); err != nil {
+		return result, err
+	}
 
 	var reqPath string
 	if isDir {
@@ -294,6 +291,10 @@ func (b *s3Backend) PutObject(
 
 	err = fs.PutDirectly(ctx, reqPath, stream)
 	if err != nil {
+		// gofakes3 has no code for quota, a 400 tells the client not to retry
+		if errors.Is(err, errs.QuotaExceeded) {
+			return result, gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, err.Error())
+		}
 		return result, err
 	}
 
@@ -303,7 +304,7 @@ func (b *s3Backend) PutObject(
 	// 	return result, err
 	// }
 
-	b.meta.Store(fp, meta)
+	saveObjectMeta(fp, meta)
 
 	return result, nil
 }
@@ -342,6 +343,9 @@ func (b *s3Backend) deleteObject(ctx context.Context, bucketName, objectName str
 	bucketPath := bucket.Path
 
 	fp := path.Join(bucketPath, objectName)
+	if err = checkRemove(ctx, fp); err != nil {
+		return err
+	}
 	fmeta, _ := op.GetNearestMeta(fp)
 	// S3 does not report an error when attemping to delete a key that does not exist, so
 	// we need to skip IsNotExist errors.
@@ -380,8 +384,7 @@ func (b *s3Backend) BucketExists(ctx context.Context, name string) (exists bool,
 // CopyObject copy specified object from srcKey to dstKey.
 func (b *s3Backend) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket, dstKey string, meta map[string]string) (result gofakes3.CopyObjectResult, err error) {
 	if srcBucket == dstBucket && srcKey == dstKey {
-		//TODO: update meta
-		return result, nil
+		return b.replaceObjectMeta(ctx, dstBucket, dstKey, meta)
 	}
 
 	srcB, err := getBucketByName(srcBucket)
@@ -402,8 +405,10 @@ func (b *s3Backend) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket
 		_ = c.Contents.Close()
 	}()
 
-	for k, v := range c.Metadata {
-		if _, found := meta[k]; !found && k != "X-Amz-Acl" {
+	// the generated headers of the source, e.g. Content-Disposition, are
+	// not copied, only the saved metadata
+	for k, v := range getObjectMeta(srcFp) {
+		if _, found := meta[k]; !found {
 			meta[k] = v
 		}
 	}
@@ -421,3 +426,28 @@ func (b *s3Backend) CopyObject(ctx context.Context, srcBucket, srcKey, dstBucket
 		LastModified: gofakes3.NewContentTime(srcNode.ModTime()),
 	}, nil
 }
+
+// replaceObjectMeta replaces the metadata of the object copied to itself
+// with the REPLACE directive, e.g. the mtime set by rclone, the content of
+// the object isn't touched
+func (b *s3Backend) replaceObjectMeta(ctx context.Context, bucketName, objectName string, meta map[string]string) (result gofakes3.CopyObjectResult, err error) {
+	bucket, err := getBucketByName(bucketName)
+	if err != nil {
+		return result, err
+	}
+	fp := path.Join(bucket.Path, objectName)
+	if err = checkWrite(ctx, fp); err != nil {
+		return result, err
+	}
+	fmeta, _ := op.GetNearestMeta(fp)
+	node, err := fs.Get(context.WithValue(ctx, conf.MetaKey, fmeta), fp, &fs.GetArgs{})
+	if err != nil || node.IsDir() {
+		return result, gofakes3.KeyNotFound(objectName)
+	}
+	if meta["X-Amz-Metadata-Directive"] == "REPLACE" {
+		saveObjectMeta(fp, meta)
+	}
+	return gofakes3.CopyObjectResult{
+		LastModified: gofakes3.NewContentTime(node.ModTime()),
+	}, nil
+}
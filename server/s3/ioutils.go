@@ -2,7 +2,13 @@
 // Package s3 implements a fake s3 server for openlist
 package s3
 
-import "io"
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
 
 type noOpReadCloser struct{}
 
@@ -34,3 +40,49 @@ func (rwc *readerWithCloser) Close() error {
 	}
 	return nil
 }
+
+// chunkedReader decodes the aws-chunked body of the streaming uploads. The
+// chunk signatures are skipped as gofakes3 does, and the trailers after the
+// last chunk of the unsigned ones are ignored.
+type chunkedReader struct {
+	r      *bufio.Reader
+	remain int64
+	done   bool
+}
+
+func newChunkedReader(r io.Reader) *chunkedReader {
+	return &chunkedReader{r: bufio.NewReader(r)}
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	for c.remain == 0 {
+		if c.done {
+			return 0, io.EOF
+		}
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		line = strings.TrimSpace(line)
+		// the CRLF after the data of the previous chunk
+		if line == "" {
+			continue
+		}
+		size, _, _ := strings.Cut(line, ";")
+		n, err := strconv.ParseInt(size, 16, 64)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid chunk header: %q", line)
+		}
+		c.remain = n
+		c.done = n == 0
+	}
+	if int64(len(p)) > c.remain {
+		p = p[:c.remain]
+	}
+	n, err := c.r.Read(p)
+	c.remain -= int64(n)
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return n, err
+}
@@ -3,6 +3,7 @@
 package s3
 
 import (
+	"context"
 	"path"
 	"strings"
 	"time"
@@ -11,10 +12,10 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-func (b *s3Backend) entryListR(bucket, fdPath, name string, addPrefix bool, response *gofakes3.ObjectList) error {
+func (b *s3Backend) entryListR(ctx context.Context, bucket, fdPath, name string, addPrefix bool, response *gofakes3.ObjectList) error {
 	fp := path.Join(bucket, fdPath)
 
-	dirEntries, err := getDirEntries(fp)
+	dirEntries, err := getDirEntries(ctx, fp)
 	if err != nil {
 		return err
 	}
@@ -43,6 +44,10 @@ func (b *s3Backend) entryListR(bucket, fdPath, name string, addPrefix bool, resp
 		if !strings.HasPrefix(object, name) {
 			continue
 		}
+		// e.g. the folders protected by the meta password
+		if !canRead(ctx, path.Join(bucket, objectPath)) {
+			continue
+		}
 
 		if entry.IsDir() {
 			if addPrefix {
@@ -50,7 +55,7 @@ func (b *s3Backend) entryListR(bucket, fdPath, name string, addPrefix bool, resp
 				response.AddPrefix(objectPath)
 				continue
 			}
-			err := b.entryListR(bucket, path.Join(fdPath, object), "", false, response)
+			err := b.entryListR(ctx, bucket, path.Join(fdPath, object), "", false, response)
 			if err != nil {
 				return err
 			}
@@ -0,0 +1,336 @@
+// Package s3 implements a fake s3 server for openlist
+package s3
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/itsHenry35/gofakes3"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+type initiateMultipartUploadResult struct {
+	XMLName xml.Name `xml:"InitiateMultipartUploadResult"`
+	gofakes3.InitiateMultipartUpload
+}
+
+// multipart serves the multipart uploads instead of gofakes3, which keeps
+// the parts in memory and assembles the object into a byte slice. The parts
+// are staged on disk by the uploader, and the completed object is streamed
+// into the storage by PutObject of the backend as a single stream.
+type multipart struct {
+	backend  *s3Backend
+	uploader *uploader
+}
+
+// withMultipart routes the multipart upload requests to m, and the others to h
+func withMultipart(m *multipart, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		bucket, object, _ := strings.Cut(strings.Trim(r.URL.Path, "/"), "/")
+		uploadID := query.Get("uploadId")
+		_, uploads := query["uploads"]
+		var err error
+		switch {
+		case uploadID != "" && r.Method == http.MethodGet:
+			err = m.listParts(w, r, bucket, object, uploadID)
+		case uploadID != "" && r.Method == http.MethodPut:
+			err = m.putPart(w, r, bucket, object, uploadID)
+		case uploadID != "" && r.Method == http.MethodDelete:
+			err = m.abort(w, r, bucket, object, uploadID)
+		case uploadID != "" && r.Method == http.MethodPost:
+			err = m.complete(w, r, bucket, object, uploadID)
+		case uploads && r.Method == http.MethodGet:
+			err = m.listUploads(w, r, bucket)
+		case uploads && r.Method == http.MethodPost:
+			err = m.initiate(w, r, bucket, object)
+		default:
+			h.ServeHTTP(w, r)
+			return
+		}
+		if err != nil {
+			writeError(w, r, err)
+		}
+	})
+}
+
+// getUpload returns the upload of the id if it's of the object and began by
+// the same access key user, so the users can't see the uploads of the others
+func (m *multipart) getUpload(ctx context.Context, bucket, object, id string) (*multipartUpload, error) {
+	mpu, err := m.uploader.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if mpu.Bucket != bucket || mpu.Object != object || mpu.UserId != keyUserId(ctx) {
+		return nil, gofakes3.ErrNoSuchUpload
+	}
+	return mpu, nil
+}
+
+func (m *multipart) initiate(w http.ResponseWriter, r *http.Request, bucketName, object string) error {
+	if object == "" || strings.HasSuffix(object, "/") {
+		return gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, "invalid object name")
+	}
+	bucket, err := getBucketByName(bucketName)
+	if err != nil {
+		return err
+	}
+	// checked again on completion, this is to fail before the parts are sent
+	if err = checkWrite(r.Context(), path.Join(bucket.Path, object)); err != nil {
+		return err
+	}
+	mpu, err := m.uploader.Begin(bucketName, object, keyUserId(r.Context()), multipartMeta(r.Header))
+	if err != nil {
+		return err
+	}
+	log.Debugf("s3 initiate multipart upload %s of %s/%s", mpu.ID, bucketName, object)
+	return writeXML(w, initiateMultipartUploadResult{InitiateMultipartUpload: gofakes3.InitiateMultipartUpload{
+		Bucket:   bucketName,
+		Key:      object,
+		UploadID: gofakes3.UploadID(mpu.ID),
+	}})
+}
+
+func (m *multipart) putPart(w http.ResponseWriter, r *http.Request, bucket, object, id string) error {
+	defer r.Body.Close()
+	if r.Header.Get("X-Amz-Copy-Source") != "" {
+		return gofakes3.ErrNotImplemented
+	}
+	number, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || number < 1 || number > gofakes3.MaxUploadPartNumber {
+		return gofakes3.ErrInvalidPart
+	}
+	if _, err = m.getUpload(r.Context(), bucket, object, id); err != nil {
+		return err
+	}
+	var body io.Reader = r.Body
+	size := r.ContentLength
+	if strings.HasPrefix(r.Header.Get("X-Amz-Content-Sha256"), "STREAMING-") {
+		body = newChunkedReader(r.Body)
+		size, err = strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil {
+			return gofakes3.ErrMissingContentLength
+		}
+	}
+	if size < 0 {
+		return gofakes3.ErrMissingContentLength
+	}
+	etag, err := m.uploader.PutPart(id, number, body, size, r.Header.Get("Content-Md5"))
+	if err != nil {
+		return err
+	}
+	w.Header().Set("ETag", etag)
+	return nil
+}
+
+func (m *multipart) abort(w http.ResponseWriter, r *http.Request, bucket, object, id string) error {
+	if _, err := m.getUpload(r.Context(), bucket, object, id); err != nil {
+		return err
+	}
+	if err := m.uploader.Remove(id); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+func (m *multipart) complete(w http.ResponseWriter, r *http.Request, bucket, object, id string) error {
+	defer r.Body.Close()
+	mpu, err := m.getUpload(r.Context(), bucket, object, id)
+	if err != nil {
+		return err
+	}
+	var in gofakes3.CompleteMultipartUploadRequest
+	if err = xml.NewDecoder(io.LimitReader(r.Body, 4*utils.MB)).Decode(&in); err != nil {
+		return gofakes3.ErrorMessage(gofakes3.ErrMalformedXML, err.Error())
+	}
+	if !m.uploader.Acquire(id) {
+		return gofakes3.ErrorMessage(gofakes3.ErrInvalidArgument, "the upload is being completed")
+	}
+	defer m.uploader.Release(id)
+	rc, size, etag, err := m.uploader.Open(mpu, in.Parts)
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	// the upload is kept if the put fails, so the client can retry it
+	if _, err = m.backend.PutObject(r.Context(), bucket, object, mpu.Meta, rc, size); err != nil {
+		return err
+	}
+	if err = m.uploader.Remove(id); err != nil {
+		log.Warnf("failed remove completed multipart upload %s: %+v", id, err)
+	}
+	return writeXML(w, gofakes3.CompleteMultipartUploadResult{
+		Bucket: bucket,
+		Key:    object,
+		ETag:   etag,
+	})
+}
+
+func (m *multipart) listParts(w http.ResponseWriter, r *http.Request, bucket, object, id string) error {
+	mpu, err := m.getUpload(r.Context(), bucket, object, id)
+	if err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	marker, err := parseQueryInt(query.Get("part-number-marker"), 0, 0, gofakes3.MaxUploadPartNumber)
+	if err != nil {
+		return err
+	}
+	maxParts, err := parseQueryInt(query.Get("max-parts"), gofakes3.DefaultMaxUploadParts, 0, gofakes3.MaxUploadPartsLimit)
+	if err != nil {
+		return err
+	}
+	numbers := make([]int, 0, len(mpu.Parts))
+	for number := range mpu.Parts {
+		if number > marker {
+			numbers = append(numbers, number)
+		}
+	}
+	sort.Ints(numbers)
+	out := gofakes3.ListMultipartUploadPartsResult{
+		Bucket:           bucket,
+		Key:              object,
+		UploadID:         gofakes3.UploadID(id),
+		PartNumberMarker: marker,
+		MaxParts:         int64(maxParts),
+	}
+	if len(numbers) > maxParts {
+		numbers = numbers[:maxParts]
+		out.IsTruncated = true
+	}
+	for _, number := range numbers {
+		part := mpu.Parts[number]
+		out.Parts = append(out.Parts, gofakes3.ListMultipartUploadPartItem{
+			PartNumber:   number,
+			LastModified: gofakes3.NewContentTime(part.Modified),
+			ETag:         part.ETag,
+			Size:         part.Size,
+		})
+		out.NextPartNumberMarker = number
+	}
+	return writeXML(w, out)
+}
+
+func (m *multipart) listUploads(w http.ResponseWriter, r *http.Request, bucket string) error {
+	if _, err := getBucketByName(bucket); err != nil {
+		return err
+	}
+	query := r.URL.Query()
+	prefix := query.Get("prefix")
+	keyMarker := query.Get("key-marker")
+	uploadIDMarker := query.Get("upload-id-marker")
+	maxUploads, err := parseQueryInt(query.Get("max-uploads"), gofakes3.DefaultMaxUploads, 1, gofakes3.MaxUploadsLimit)
+	if err != nil {
+		return err
+	}
+	uploads, err := m.uploader.List(bucket)
+	if err != nil {
+		return err
+	}
+	out := gofakes3.ListMultipartUploadsResult{
+		Bucket:         bucket,
+		KeyMarker:      keyMarker,
+		UploadIDMarker: gofakes3.UploadID(uploadIDMarker),
+		MaxUploads:     int64(maxUploads),
+		Prefix:         prefix,
+	}
+	userId := keyUserId(r.Context())
+	// the uploads of the key marker are after the upload id marker if any
+	passed := keyMarker == ""
+	for _, mpu := range uploads {
+		if mpu.UserId != userId || !strings.HasPrefix(mpu.Object, prefix) {
+			continue
+		}
+		if !passed {
+			if mpu.Object < keyMarker || (mpu.Object == keyMarker && uploadIDMarker == "") {
+				continue
+			}
+			if mpu.Object == keyMarker {
+				passed = mpu.ID == uploadIDMarker
+				continue
+			}
+			passed = true
+		}
+		if len(out.Uploads) == maxUploads {
+			last := out.Uploads[len(out.Uploads)-1]
+			out.IsTruncated = true
+			out.NextKeyMarker = last.Key
+			out.NextUploadIDMarker = last.UploadID
+			break
+		}
+		out.Uploads = append(out.Uploads, gofakes3.ListMultipartUploadItem{
+			Key:       mpu.Object,
+			UploadID:  gofakes3.UploadID(mpu.ID),
+			Initiated: gofakes3.NewContentTime(mpu.Initiated),
+		})
+	}
+	return writeXML(w, out)
+}
+
+// keyUserId returns the id of the user of the access key, 0 if there is none
+func keyUserId(ctx context.Context) uint {
+	if user := getKeyUser(ctx); user != nil {
+		return user.ID
+	}
+	return 0
+}
+
+// multipartMeta returns the headers of the initiate request kept as the
+// meta of the object, the same as gofakes3 keeps for PutObject
+func multipartMeta(header http.Header) map[string]string {
+	meta := make(map[string]string)
+	for k, v := range header {
+		if k == "Content-Length" || k == "Content-Md5" {
+			continue
+		}
+		if strings.HasPrefix(k, "X-Amz-Meta-") || strings.HasPrefix(k, "Content-") || k == "Cache-Control" {
+			meta[k] = v[0]
+		}
+	}
+	return meta
+}
+
+func parseQueryInt(value string, def, lo, hi int) (int, error) {
+	if value == "" {
+		return def, nil
+	}
+	v, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, gofakes3.ErrInvalidURI
+	}
+	return min(max(v, lo), hi), nil
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) error {
+	w.Header().Set("Content-Type", "application/xml")
+	_, _ = w.Write([]byte(xml.Header))
+	return xml.NewEncoder(w).Encode(v)
+}
+
+// writeError writes the S3 error response as gofakes3 does for the errors of
+// the backend, the unknown errors are internal errors
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	var e gofakes3.Error
+	if !errors.As(err, &e) {
+		log.Errorf("s3 %s %s: %+v", r.Method, r.URL.Path, err)
+		e = &gofakes3.ErrorResponse{Code: gofakes3.ErrInternal, Message: "Internal Error"}
+	}
+	if code, ok := e.(gofakes3.ErrorCode); ok {
+		e = &gofakes3.ErrorResponse{Code: code, Message: code.Message()}
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(e.ErrorCode().Status())
+	if r.Method != http.MethodHead {
+		_, _ = w.Write([]byte(xml.Header))
+		_ = xml.NewEncoder(w).Encode(e)
+	}
+}
@@ -6,22 +6,61 @@ import (
 	"context"
 	"math/rand"
 	"net/http"
+	"path/filepath"
 
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
 	"github.com/itsHenry35/gofakes3"
 )
 
 // Make a new S3 Server to serve the remote
 func NewServer(ctx context.Context) (h http.Handler, err error) {
 	var newLogger logger
+	backend := newBackend()
+	u, err := newUploader(filepath.Join(conf.Conf.TempDir, conf.S3MultipartDir))
+	if err != nil {
+		return nil, err
+	}
+	// the uploads expired while the server was down, then the ones expired
+	// while it runs
+	u.Clean()
+	cleaner := cron.NewCron(multipartCleanInterval)
+	cleaner.Do(u.Clean)
+	go func() {
+		<-ctx.Done()
+		cleaner.Stop()
+	}()
 	faker := gofakes3.New(
-		newBackend(),
+		backend,
 		// gofakes3.WithHostBucket(!opt.pathBucketMode),
 		gofakes3.WithLogger(newLogger),
 		gofakes3.WithRequestID(rand.Uint64()),
 		gofakes3.WithoutVersioning(),
-		gofakes3.WithV4Auth(authlistResolver()),
 		gofakes3.WithIntegrityCheck(true), // Check Content-MD5 if supplied
 	)
 
-	return faker.Server(), nil
+	return withUser(withMultipart(&multipart{backend: backend, uploader: u}, faker.Server())), nil
+}
+
+// withUser verifies the signature of the request and runs it as the user of
+// the access key. The global access key isn't bound to any user, so its
+// requests and the anonymous ones are run as the guest, only the meta ACL
+// for the guest and everyone apply to them. The writes to the read only
+// buckets are rejected here for all of them.
+func withUser(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, keyUser, err := authenticate(r)
+		if err == nil {
+			err = checkReadOnly(r)
+		}
+		if err != nil {
+			writeAuthError(w, r, err)
+			return
+		}
+		ctx := context.WithValue(r.Context(), conf.UserKey, user)
+		if keyUser {
+			ctx = context.WithValue(ctx, keyUserKey{}, user)
+		}
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
 }
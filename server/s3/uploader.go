@@ -0,0 +1,316 @@
+// Package s3 implements a fake s3 server for openlist
+package s3
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils/random"
+	"github.com/itsHenry35/gofakes3"
+	"github.com/pkg/errors"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	manifestName = "upload.json"
+	partPrefix   = "part-"
+)
+
+// multipartExpiry is how long an upload not completed or aborted is kept,
+// like the AbortIncompleteMultipartUpload lifecycle rule of S3
+var multipartExpiry = 7 * 24 * time.Hour
+
+// multipartCleanInterval is how often the expired uploads are deleted
+var multipartCleanInterval = time.Hour
+
+type multipartPart struct {
+	ETag     string    `json:"etag"`
+	Size     int64     `json:"size"`
+	Modified time.Time `json:"modified"`
+}
+
+type multipartUpload struct {
+	ID     string `json:"id"`
+	Bucket string `json:"bucket"`
+	Object string `json:"object"`
+	// UserId is the user of the access key which began the upload, 0 for
+	// the global access key and the anonymous requests
+	UserId    uint                  `json:"user_id"`
+	Meta      map[string]string     `json:"meta"`
+	Initiated time.Time             `json:"initiated"`
+	Parts     map[int]multipartPart `json:"parts"`
+}
+
+// uploader stages the parts of the multipart uploads on disk instead of the
+// memory of gofakes3. Each upload is a dir of the part files and a manifest,
+// so the uploads survive restarts.
+type uploader struct {
+	dir string
+	// mu guards the manifests, the parts are written outside of it
+	mu         sync.Mutex
+	completing sync.Map
+}
+
+func newUploader(dir string) (*uploader, error) {
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return nil, errors.WithMessage(err, "failed create multipart dir")
+	}
+	return &uploader{dir: dir}, nil
+}
+
+func (u *uploader) uploadDir(id string) string {
+	return filepath.Join(u.dir, id)
+}
+
+func (u *uploader) partFile(id string, number int) string {
+	return filepath.Join(u.uploadDir(id), fmt.Sprintf("%s%05d", partPrefix, number))
+}
+
+// Begin creates the dir and the manifest of a new upload
+func (u *uploader) Begin(bucket, object string, userId uint, meta map[string]string) (*multipartUpload, error) {
+	mpu := &multipartUpload{
+		ID:        random.String(32),
+		Bucket:    bucket,
+		Object:    object,
+		UserId:    userId,
+		Meta:      meta,
+		Initiated: time.Now(),
+		Parts:     make(map[int]multipartPart),
+	}
+	if err := os.Mkdir(u.uploadDir(mpu.ID), 0o777); err != nil {
+		return nil, errors.WithMessage(err, "failed create upload dir")
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if err := u.save(mpu); err != nil {
+		_ = os.RemoveAll(u.uploadDir(mpu.ID))
+		return nil, err
+	}
+	return mpu, nil
+}
+
+// Get returns the upload of the id, ErrNoSuchUpload if it doesn't exist
+func (u *uploader) Get(id string) (*multipartUpload, error) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	return u.load(id)
+}
+
+func (u *uploader) load(id string) (*multipartUpload, error) {
+	// the id is a part of the path, reject anything but the generated ones
+	if id == "" || strings.ContainsAny(id, `/\.`) {
+		return nil, gofakes3.ErrNoSuchUpload
+	}
+	b, err := os.ReadFile(filepath.Join(u.uploadDir(id), manifestName))
+	if os.IsNotExist(err) {
+		return nil, gofakes3.ErrNoSuchUpload
+	} else if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	var mpu multipartUpload
+	if err = utils.Json.Unmarshal(b, &mpu); err != nil {
+		return nil, errors.WithMessagef(err, "failed parse manifest of upload %s", id)
+	}
+	if mpu.Parts == nil {
+		mpu.Parts = make(map[int]multipartPart)
+	}
+	return &mpu, nil
+}
+
+// save writes the manifest by a rename, so it's never seen half written
+func (u *uploader) save(mpu *multipartUpload) error {
+	b, err := utils.Json.Marshal(mpu)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	tmp := filepath.Join(u.uploadDir(mpu.ID), manifestName+".tmp")
+	if err = os.WriteFile(tmp, b, 0o666); err != nil {
+		return errors.WithStack(err)
+	}
+	return errors.WithStack(os.Rename(tmp, filepath.Join(u.uploadDir(mpu.ID), manifestName)))
+}
+
+// PutPart writes the part of the size from r, a part uploaded again with the
+// same number replaces the previous one. If md5Base64 isn't empty, it's
+// checked against the content as the Content-MD5 header.
+func (u *uploader) PutPart(id string, number int, r io.Reader, size int64, md5Base64 string) (etag string, err error) {
+	if _, err = u.Get(id); err != nil {
+		return "", err
+	}
+	f, err := os.CreateTemp(u.uploadDir(id), partPrefix+"*.tmp")
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer func() {
+		_ = f.Close()
+		if err != nil {
+			_ = os.Remove(f.Name())
+		}
+	}()
+	h := md5.New()
+	n, err := utils.CopyWithBuffer(io.MultiWriter(f, h), io.LimitReader(r, size))
+	if err != nil {
+		return "", err
+	}
+	if n != size {
+		return "", gofakes3.ErrIncompleteBody
+	}
+	sum := h.Sum(nil)
+	if md5Base64 != "" && md5Base64 != base64.StdEncoding.EncodeToString(sum) {
+		return "", gofakes3.ErrBadDigest
+	}
+	if err = f.Close(); err != nil {
+		return "", errors.WithStack(err)
+	}
+	etag = `"` + hex.EncodeToString(sum) + `"`
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	// the upload may be aborted while the part was being written
+	mpu, err := u.load(id)
+	if err != nil {
+		return "", err
+	}
+	if err = os.Rename(f.Name(), u.partFile(id, number)); err != nil {
+		return "", errors.WithStack(err)
+	}
+	mpu.Parts[number] = multipartPart{ETag: etag, Size: size, Modified: time.Now()}
+	if err = u.save(mpu); err != nil {
+		return "", err
+	}
+	return etag, nil
+}
+
+// List returns the uploads of the bucket sorted by the object and the
+// initiated time, as ListMultipartUploads of S3
+func (u *uploader) List(bucket string) ([]*multipartUpload, error) {
+	entries, err := os.ReadDir(u.dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	var uploads []*multipartUpload
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		mpu, err := u.load(entry.Name())
+		if err != nil {
+			continue
+		}
+		if mpu.Bucket == bucket {
+			uploads = append(uploads, mpu)
+		}
+	}
+	sort.Slice(uploads, func(i, j int) bool {
+		if uploads[i].Object != uploads[j].Object {
+			return uploads[i].Object < uploads[j].Object
+		}
+		return uploads[i].Initiated.Before(uploads[j].Initiated)
+	})
+	return uploads, nil
+}
+
+// Open returns the content of the parts assembled in the order of the
+// complete request, their total size and the ETag of the object, which is
+// the md5 of the md5s of the parts followed by the count of them as S3 does
+func (u *uploader) Open(mpu *multipartUpload, parts []gofakes3.CompletedPart) (io.ReadCloser, int64, string, error) {
+	if len(parts) == 0 {
+		return nil, 0, "", gofakes3.ErrorMessage(gofakes3.ErrInvalidPart, "no part in complete request")
+	}
+	var size int64
+	h := md5.New()
+	for i, p := range parts {
+		if i > 0 && p.PartNumber <= parts[i-1].PartNumber {
+			return nil, 0, "", gofakes3.ErrInvalidPartOrder
+		}
+		part, ok := mpu.Parts[p.PartNumber]
+		if !ok || strings.Trim(p.ETag, `"`) != strings.Trim(part.ETag, `"`) {
+			return nil, 0, "", gofakes3.ErrorMessagef(gofakes3.ErrInvalidPart, "unexpected part %d in complete request", p.PartNumber)
+		}
+		sum, err := hex.DecodeString(strings.Trim(part.ETag, `"`))
+		if err != nil {
+			return nil, 0, "", errors.WithStack(err)
+		}
+		h.Write(sum)
+		size += part.Size
+	}
+	files := make([]*os.File, 0, len(parts))
+	closeAll := func() (err error) {
+		for _, f := range files {
+			if e := f.Close(); e != nil && err == nil {
+				err = e
+			}
+		}
+		return err
+	}
+	readers := make([]io.Reader, 0, len(parts))
+	for _, p := range parts {
+		f, err := os.Open(u.partFile(mpu.ID, p.PartNumber))
+		if err != nil {
+			_ = closeAll()
+			return nil, 0, "", errors.WithStack(err)
+		}
+		files = append(files, f)
+		readers = append(readers, f)
+	}
+	etag := `"` + hex.EncodeToString(h.Sum(nil)) + "-" + strconv.Itoa(len(parts)) + `"`
+	return utils.NewReadCloser(io.MultiReader(readers...), closeAll), size, etag, nil
+}
+
+// Acquire marks the upload as being completed, false if it already is
+func (u *uploader) Acquire(id string) bool {
+	_, loaded := u.completing.LoadOrStore(id, struct{}{})
+	return !loaded
+}
+
+func (u *uploader) Release(id string) {
+	u.completing.Delete(id)
+}
+
+// Remove deletes the upload with its parts
+func (u *uploader) Remove(id string) error {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	if _, err := u.load(id); err != nil {
+		return err
+	}
+	return errors.WithStack(os.RemoveAll(u.uploadDir(id)))
+}
+
+// Clean deletes the uploads initiated before the expiry, and the dirs
+// without a manifest left by a crash in Begin. The uploads being completed
+// are kept.
+func (u *uploader) Clean() {
+	entries, err := os.ReadDir(u.dir)
+	if err != nil {
+		log.Errorf("failed list multipart uploads: %+v", err)
+		return
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	for _, entry := range entries {
+		if _, ok := u.completing.Load(entry.Name()); ok {
+			continue
+		}
+		mpu, err := u.load(entry.Name())
+		if err == nil && time.Since(mpu.Initiated) < multipartExpiry {
+			continue
+		}
+		if err = os.RemoveAll(filepath.Join(u.dir, entry.Name())); err != nil {
+			log.Errorf("failed delete multipart upload %s: %+v", entry.Name(), err)
+		}
+	}
+}
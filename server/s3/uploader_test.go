@@ -0,0 +1,154 @@
+package s3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/itsHenry35/gofakes3"
+)
+
+func TestUploader(t *testing.T) {
+	dir := t.TempDir()
+	u, err := newUploader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mpu, err := u.Begin("bucket", "dir/file.bin", 1, map[string]string{"Content-Type": "text/plain"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	parts := map[int][]byte{
+		1: bytes.Repeat([]byte("a"), 1000),
+		2: bytes.Repeat([]byte("b"), 2000),
+		3: []byte("c"),
+	}
+	etags := make(map[int]string)
+	// the part 2 is uploaded again with the content replaced
+	for _, number := range []int{3, 2, 1, 2} {
+		if etags[number], err = u.PutPart(mpu.ID, number, bytes.NewReader(parts[number]), int64(len(parts[number])), ""); err != nil {
+			t.Fatalf("failed put part %d: %+v", number, err)
+		}
+	}
+	if _, err = u.PutPart(mpu.ID, 4, strings.NewReader("short"), 10, ""); !gofakes3.HasErrorCode(err, gofakes3.ErrIncompleteBody) {
+		t.Errorf("short part: got %v", err)
+	}
+	if _, err = u.PutPart(mpu.ID, 4, strings.NewReader("data"), 4, "AAAAAAAAAAAAAAAAAAAAAA=="); !gofakes3.HasErrorCode(err, gofakes3.ErrBadDigest) {
+		t.Errorf("bad digest: got %v", err)
+	}
+
+	// the uploads are read from the disk again after a restart
+	u, err = newUploader(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uploads, err := u.List("bucket")
+	if err != nil || len(uploads) != 1 || uploads[0].ID != mpu.ID {
+		t.Fatalf("got uploads %v, %v", uploads, err)
+	}
+	mpu = uploads[0]
+	if len(mpu.Parts) != 3 || mpu.UserId != 1 || mpu.Meta["Content-Type"] != "text/plain" {
+		t.Fatalf("got upload %+v", mpu)
+	}
+
+	if _, _, _, err = u.Open(mpu, []gofakes3.CompletedPart{{PartNumber: 2, ETag: etags[2]}, {PartNumber: 1, ETag: etags[1]}}); !gofakes3.HasErrorCode(err, gofakes3.ErrInvalidPartOrder) {
+		t.Errorf("unsorted parts: got %v", err)
+	}
+	if _, _, _, err = u.Open(mpu, []gofakes3.CompletedPart{{PartNumber: 1, ETag: etags[2]}}); !gofakes3.HasErrorCode(err, gofakes3.ErrInvalidPart) {
+		t.Errorf("wrong etag: got %v", err)
+	}
+	rc, size, etag, err := u.Open(mpu, []gofakes3.CompletedPart{
+		{PartNumber: 1, ETag: etags[1]},
+		{PartNumber: 2, ETag: strings.Trim(etags[2], `"`)},
+		{PartNumber: 3, ETag: etags[3]},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(rc)
+	_ = rc.Close()
+	want := append(append(append([]byte{}, parts[1]...), parts[2]...), parts[3]...)
+	if err != nil || !bytes.Equal(b, want) || size != int64(len(want)) {
+		t.Errorf("got %d bytes of size %d, %v", len(b), size, err)
+	}
+	h := md5.New()
+	for _, number := range []int{1, 2, 3} {
+		sum := md5.Sum(parts[number])
+		h.Write(sum[:])
+	}
+	if wantETag := fmt.Sprintf(`"%s-3"`, hex.EncodeToString(h.Sum(nil))); etag != wantETag {
+		t.Errorf("got etag %s, want %s", etag, wantETag)
+	}
+
+	if err = u.Remove(mpu.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = u.Get(mpu.ID); !gofakes3.HasErrorCode(err, gofakes3.ErrNoSuchUpload) {
+		t.Errorf("removed upload: got %v", err)
+	}
+	if _, err = u.PutPart(mpu.ID, 1, strings.NewReader("a"), 1, ""); !gofakes3.HasErrorCode(err, gofakes3.ErrNoSuchUpload) {
+		t.Errorf("part of removed upload: got %v", err)
+	}
+	if _, err = u.Get("../" + mpu.ID); !gofakes3.HasErrorCode(err, gofakes3.ErrNoSuchUpload) {
+		t.Errorf("invalid id: got %v", err)
+	}
+}
+
+func TestUploaderClean(t *testing.T) {
+	u, err := newUploader(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired, err := u.Begin("bucket", "expired", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expired.Initiated = time.Now().Add(-multipartExpiry - time.Hour)
+	if err = u.save(expired); err != nil {
+		t.Fatal(err)
+	}
+	kept, err := u.Begin("bucket", "kept", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	completing, err := u.Begin("bucket", "completing", 0, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	completing.Initiated = expired.Initiated
+	if err = u.save(completing); err != nil {
+		t.Fatal(err)
+	}
+	u.Acquire(completing.ID)
+	u.Clean()
+	if _, err = u.Get(expired.ID); !gofakes3.HasErrorCode(err, gofakes3.ErrNoSuchUpload) {
+		t.Errorf("expired upload: got %v", err)
+	}
+	if _, err = u.Get(kept.ID); err != nil {
+		t.Errorf("kept upload: got %v", err)
+	}
+	if _, err = u.Get(completing.ID); err != nil {
+		t.Errorf("upload being completed: got %v", err)
+	}
+}
+
+func TestChunkedReader(t *testing.T) {
+	sig := ";chunk-signature=" + strings.Repeat("0", 64)
+	for name, body := range map[string]string{
+		"signed":   "5" + sig + "\r\nhello\r\n6" + sig + "\r\n world\r\n0" + sig + "\r\n\r\n",
+		"unsigned": "5\r\nhello\r\n6\r\n world\r\n0\r\nx-amz-checksum-crc32:AAAAAA==\r\n\r\n",
+	} {
+		b, err := io.ReadAll(newChunkedReader(strings.NewReader(body)))
+		if err != nil || string(b) != "hello world" {
+			t.Errorf("%s: got %q, %v", name, b, err)
+		}
+	}
+	if _, err := io.ReadAll(newChunkedReader(strings.NewReader("5\r\nhel"))); err != io.ErrUnexpectedEOF {
+		t.Errorf("truncated: got %v", err)
+	}
+}
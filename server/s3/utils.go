@@ -14,11 +14,17 @@ import (
 	"github.com/OpenListTeam/OpenList/v4/internal/op"
 	"github.com/OpenListTeam/OpenList/v4/internal/setting"
 	"github.com/itsHenry35/gofakes3"
+	log "github.com/sirupsen/logrus"
 )
 
 type Bucket struct {
 	Name string `json:"name"`
 	Path string `json:"path"`
+	// ReadOnly rejects the writes to the bucket, whoever signed them
+	ReadOnly bool `json:"read_only,omitempty"`
+	// AnonymousRead allows the objects of the bucket to be read without a
+	// signature, as the guest, e.g. to publish static assets
+	AnonymousRead bool `json:"anonymous_read,omitempty"`
 }
 
 const emptyObjectName = "ThisIsAnEmptyFolderInTheS3Bucket"
@@ -42,8 +48,36 @@ func getBucketByName(name string) (Bucket, error) {
 	return Bucket{}, gofakes3.BucketNotFound(name)
 }
 
-func getDirEntries(path string) ([]model.Obj, error) {
-	ctx := context.Background()
+// getObjectMeta returns the metadata saved by PutObject for the object at fp
+func getObjectMeta(fp string) map[string]string {
+	meta, err := op.GetS3ObjectMeta(fp)
+	if err != nil {
+		log.Warnf("failed get s3 object meta of %s: %+v", fp, err)
+	}
+	return meta
+}
+
+// saveObjectMeta saves the metadata of the put request to be returned by
+// HeadObject and GetObject, the other headers of the request are dropped
+func saveObjectMeta(fp string, meta map[string]string) {
+	saved := make(map[string]string)
+	for k, v := range meta {
+		switch {
+		case strings.HasPrefix(k, "X-Amz-Meta-"),
+			k == "Content-Type", k == "Content-Encoding", k == "Content-Language",
+			k == "Content-Disposition", k == "Cache-Control", k == "Expires":
+			saved[k] = v
+		}
+	}
+	if err := op.SaveS3ObjectMeta(fp, saved); err != nil {
+		log.Warnf("failed save s3 object meta of %s: %+v", fp, err)
+	}
+}
+
+func getDirEntries(ctx context.Context, path string) ([]model.Obj, error) {
+	if !canRead(ctx, path) {
+		return nil, gofakes3.ErrNoSuchKey
+	}
 	meta, _ := op.GetNearestMeta(path)
 	fi, err := fs.Get(context.WithValue(ctx, conf.MetaKey, meta), path, &fs.GetArgs{})
 	if errs.IsNotFoundError(err) {
@@ -149,14 +183,3 @@ func prefixParser(p *gofakes3.Prefix) (path, remaining string) {
 // 		rmdirRecursive(dir, VFS)
 // 	}
 // }
-
-func authlistResolver() map[string]string {
-	s3accesskeyid := setting.GetStr(conf.S3AccessKeyId)
-	s3secretaccesskey := setting.GetStr(conf.S3SecretAccessKey)
-	if s3accesskeyid == "" && s3secretaccesskey == "" {
-		return nil
-	}
-	authList := make(map[string]string)
-	authList[s3accesskeyid] = s3secretaccesskey
-	return authList
-}
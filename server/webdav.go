@@ -5,6 +5,7 @@ import (
 	"net/http"
 	"path"
 	"strings"
+	"time"
 
 	"github.com/OpenListTeam/OpenList/v4/internal/model"
 	"github.com/OpenListTeam/OpenList/v4/internal/stream"
@@ -24,7 +25,7 @@ var handler *webdav.Handler
 func WebDav(dav *gin.RouterGroup) {
 	handler = &webdav.Handler{
 		Prefix:     path.Join(conf.URL.Path, "/dav"),
-		LockSystem: webdav.NewMemLS(),
+		LockSystem: newLockSystem(),
 		Logger: func(request *http.Request, err error) {
 			log.Errorf("%s %s %+v", request.Method, request.URL.Path, err)
 		},
@@ -44,6 +45,21 @@ func WebDav(dav *gin.RouterGroup) {
 	dav.Handle("MOVE", "/*path", ServeWebDAV)
 }
 
+func newLockSystem() webdav.LockSystem {
+	switch conf.Conf.WebDAV.LockSystem {
+	case "db":
+		maxTimeout := time.Duration(conf.Conf.WebDAV.MaxLockTimeout) * time.Second
+		if maxTimeout <= 0 {
+			maxTimeout = 24 * time.Hour
+		}
+		return webdav.NewDBLS(maxTimeout, time.Minute)
+	case "memory", "":
+	default:
+		log.Warnf("unknown webdav lock system [%s], fallback to memory", conf.Conf.WebDAV.LockSystem)
+	}
+	return webdav.NewMemLS()
+}
+
 func ServeWebDAV(c *gin.Context) {
 	handler.ServeHTTP(c.Writer, c.Request)
 }
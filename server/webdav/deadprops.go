@@ -0,0 +1,139 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"net/http"
+	stdpath "path"
+	"sync"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/utils"
+)
+
+// dbDeadProps is a DeadPropsHolder that persists the dead properties of
+// the object at path in the database.
+type dbDeadProps struct {
+	path string
+}
+
+func newDeadPropsHolder(path string) DeadPropsHolder {
+	return &dbDeadProps{path: utils.FixAndCleanPath(path)}
+}
+
+func (d *dbDeadProps) DeadProps() (map[xml.Name]Property, error) {
+	props, err := db.GetDeadPropsByPath(d.path)
+	if err != nil {
+		return nil, err
+	}
+	return toProperties(props), nil
+}
+
+func toProperties(props []model.DeadProp) map[xml.Name]Property {
+	ret := make(map[xml.Name]Property, len(props))
+	for _, p := range props {
+		name := xml.Name{Space: p.Space, Local: p.Name}
+		ret[name] = Property{
+			XMLName:  name,
+			Lang:     p.Lang,
+			InnerXML: []byte(p.InnerXML),
+		}
+	}
+	return ret
+}
+
+type deadPropsCacheKey struct{}
+
+// deadPropsCache holds the dead props read by a PROPFIND, which are loaded
+// a dir at a time instead of a query for each entry
+type deadPropsCache struct {
+	mu sync.Mutex
+	// dirs maps the loaded dirs to the props of them and their children
+	dirs map[string]map[string][]model.DeadProp
+}
+
+// withDeadPropsCache returns ctx with a cache preloaded with the dead props
+// of dir and its children
+func withDeadPropsCache(ctx context.Context, dir string) (context.Context, error) {
+	c := &deadPropsCache{dirs: make(map[string]map[string][]model.DeadProp)}
+	if _, err := c.load(utils.FixAndCleanPath(dir)); err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, deadPropsCacheKey{}, c), nil
+}
+
+func (c *deadPropsCache) load(dir string) (map[string][]model.DeadProp, error) {
+	if props, ok := c.dirs[dir]; ok {
+		return props, nil
+	}
+	list, err := db.GetDeadPropsInDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	props := make(map[string][]model.DeadProp)
+	for _, p := range list {
+		props[p.Path] = append(props[p.Path], p)
+	}
+	c.dirs[dir] = props
+	return props, nil
+}
+
+func (c *deadPropsCache) get(path string) (map[xml.Name]Property, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// the dir itself may be loaded as the root of the PROPFIND
+	props, ok := c.dirs[path]
+	if !ok {
+		var err error
+		if props, err = c.load(stdpath.Dir(path)); err != nil {
+			return nil, err
+		}
+	}
+	return toProperties(props[path]), nil
+}
+
+// deadPropsOf returns the dead props of the path, from the cache of ctx if any
+func deadPropsOf(ctx context.Context, path string) (map[xml.Name]Property, error) {
+	if c, ok := ctx.Value(deadPropsCacheKey{}).(*deadPropsCache); ok {
+		return c.get(utils.FixAndCleanPath(path))
+	}
+	return newDeadPropsHolder(path).DeadProps()
+}
+
+func (d *dbDeadProps) Patch(patches []Proppatch) ([]Propstat, error) {
+	// later patches override the earlier ones on the same property
+	var names []xml.Name
+	latest := make(map[xml.Name]*model.DeadProp)
+	pstat := Propstat{Status: http.StatusOK}
+	for _, patch := range patches {
+		for _, p := range patch.Props {
+			pstat.Props = append(pstat.Props, Property{XMLName: p.XMLName})
+			if _, ok := latest[p.XMLName]; !ok {
+				names = append(names, p.XMLName)
+			}
+			if patch.Remove {
+				latest[p.XMLName] = nil
+				continue
+			}
+			latest[p.XMLName] = &model.DeadProp{
+				Space:    p.XMLName.Space,
+				Name:     p.XMLName.Local,
+				Lang:     p.Lang,
+				InnerXML: string(p.InnerXML),
+			}
+		}
+	}
+	var set, remove []model.DeadProp
+	for _, name := range names {
+		if prop := latest[name]; prop != nil {
+			set = append(set, *prop)
+		} else {
+			remove = append(remove, model.DeadProp{Space: name.Space, Name: name.Local})
+		}
+	}
+	if err := db.PatchDeadProps(d.path, set, remove); err != nil {
+		return nil, err
+	}
+	return []Propstat{pstat}, nil
+}
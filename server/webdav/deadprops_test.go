@@ -0,0 +1,80 @@
+package webdav
+
+import (
+	"context"
+	"encoding/xml"
+	"testing"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+)
+
+func TestDBDeadProps(t *testing.T) {
+	initTestDB(t)
+	color := xml.Name{Space: "http://example.com/ns", Local: "color"}
+	tags := xml.Name{Space: "http://example.com/ns", Local: "tags"}
+
+	h := newDeadPropsHolder("/dav/a/file.txt")
+	pstats, err := h.Patch([]Proppatch{
+		{Props: []Property{{XMLName: color, InnerXML: []byte("red")}, {XMLName: tags, InnerXML: []byte("x")}}},
+		{Remove: true, Props: []Property{{XMLName: tags}}},
+	})
+	if err != nil {
+		t.Fatalf("patch: %v", err)
+	}
+	if len(pstats) != 1 || len(pstats[0].Props) != 3 {
+		t.Fatalf("patch: got %v", pstats)
+	}
+	props, err := h.DeadProps()
+	if err != nil {
+		t.Fatalf("dead props: %v", err)
+	}
+	if len(props) != 1 || string(props[color].InnerXML) != "red" {
+		t.Fatalf("dead props: got %v", props)
+	}
+
+	// props follow the parent folder being moved
+	if err = db.MoveDeadProps("/dav/a", "/dav/b"); err != nil {
+		t.Fatalf("move: %v", err)
+	}
+	if props, _ = newDeadPropsHolder("/dav/a/file.txt").DeadProps(); len(props) != 0 {
+		t.Errorf("props left at source: %v", props)
+	}
+	if props, _ = newDeadPropsHolder("/dav/b/file.txt").DeadProps(); len(props) != 1 {
+		t.Errorf("props not moved: %v", props)
+	}
+
+	if err = db.DeleteDeadProps("/dav/b"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if props, _ = newDeadPropsHolder("/dav/b/file.txt").DeadProps(); len(props) != 0 {
+		t.Errorf("props not deleted: %v", props)
+	}
+}
+
+func TestDeadPropsCache(t *testing.T) {
+	initTestDB(t)
+	color := xml.Name{Space: "http://example.com/ns", Local: "color"}
+	for _, p := range []string{"/dav/d", "/dav/d/x", "/dav/d/sub/y", "/dav/d_x"} {
+		if _, err := newDeadPropsHolder(p).Patch([]Proppatch{{Props: []Property{{XMLName: color, InnerXML: []byte(p)}}}}); err != nil {
+			t.Fatalf("patch %s: %v", p, err)
+		}
+	}
+	props, err := db.GetDeadPropsInDir("/dav/d")
+	if err != nil || len(props) != 2 {
+		t.Fatalf("props in dir: got %v, %v", props, err)
+	}
+	ctx, err := withDeadPropsCache(context.Background(), "/dav/d")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the entries of the sub dir are loaded with it on demand
+	for _, p := range []string{"/dav/d", "/dav/d/x", "/dav/d/sub/y", "/dav/d_x"} {
+		got, err := deadPropsOf(ctx, p)
+		if err != nil || len(got) != 1 || string(got[color].InnerXML) != p {
+			t.Errorf("%s: got %v, %v", p, got, err)
+		}
+	}
+	if got, err := deadPropsOf(ctx, "/dav/d/none"); err != nil || len(got) != 0 {
+		t.Errorf("no props: got %v, %v", got, err)
+	}
+}
@@ -0,0 +1,240 @@
+package webdav
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"github.com/OpenListTeam/OpenList/v4/internal/model"
+	"github.com/OpenListTeam/OpenList/v4/pkg/cron"
+	"github.com/google/uuid"
+	log "github.com/sirupsen/logrus"
+)
+
+// NewDBLS returns a new LockSystem that stores the locks in the database.
+//
+// Since most storages can't hold a lock forever, a lock with infinite timeout
+// is saved with maxDuration instead, as permitted by RFC 4918 section 6.6.
+// The expired locks are swept every sweepInterval.
+func NewDBLS(maxDuration, sweepInterval time.Duration) LockSystem {
+	d := &dbLS{
+		maxDuration: maxDuration,
+		held:        make(map[string]struct{}),
+		cron:        cron.NewCron(sweepInterval),
+	}
+	d.cron.Do(func() {
+		n, err := db.DeleteExpiredWebdavLocks(time.Now())
+		if err != nil {
+			log.Errorf("failed sweep expired webdav locks: %+v", err)
+		} else if n > 0 {
+			log.Debugf("swept %d expired webdav locks", n)
+		}
+	})
+	return d
+}
+
+type dbLS struct {
+	// mu serializes the lock operations of this instance, the uniqueness of
+	// lock roots across instances is guaranteed by the database index.
+	mu          sync.Mutex
+	maxDuration time.Duration
+	// held records the tokens confirmed by in-flight requests of this instance.
+	held map[string]struct{}
+	cron *cron.Cron
+}
+
+func (d *dbLS) expiry(now time.Time, duration time.Duration) time.Time {
+	if duration < 0 || duration > d.maxDuration {
+		duration = d.maxDuration
+	}
+	return now.Add(duration)
+}
+
+func (d *dbLS) Confirm(now time.Time, name0, name1 string, conditions ...Condition) (func(), error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var l0, l1 *model.WebdavLock
+	if name0 != "" {
+		if l0 = d.lookup(now, slashClean(name0), conditions...); l0 == nil {
+			return nil, ErrConfirmationFailed
+		}
+	}
+	if name1 != "" {
+		if l1 = d.lookup(now, slashClean(name1), conditions...); l1 == nil {
+			return nil, ErrConfirmationFailed
+		}
+	}
+
+	// Don't hold the same lock twice.
+	if l0 != nil && l1 != nil && l0.Token == l1.Token {
+		l1 = nil
+	}
+
+	if l0 != nil {
+		d.held[l0.Token] = struct{}{}
+	}
+	if l1 != nil {
+		d.held[l1.Token] = struct{}{}
+	}
+	return func() {
+		d.mu.Lock()
+		defer d.mu.Unlock()
+		if l1 != nil {
+			delete(d.held, l1.Token)
+		}
+		if l0 != nil {
+			delete(d.held, l0.Token)
+		}
+	}, nil
+}
+
+// lookup is the same as memLS.lookup, but reads the locks from the database.
+func (d *dbLS) lookup(now time.Time, name string, conditions ...Condition) *model.WebdavLock {
+	for _, c := range conditions {
+		if c.Token == "" {
+			continue
+		}
+		if _, ok := d.held[c.Token]; ok {
+			continue
+		}
+		l, err := db.GetWebdavLockByToken(c.Token, now)
+		if err != nil {
+			continue
+		}
+		if name == l.Root {
+			return l
+		}
+		if l.ZeroDepth {
+			continue
+		}
+		if l.Root == "/" || strings.HasPrefix(name, l.Root+"/") {
+			return l
+		}
+	}
+	return nil
+}
+
+func (d *dbLS) Create(now time.Time, details LockDetails) (string, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	details.Root = slashClean(details.Root)
+
+	ok, err := d.canCreate(now, details.Root, details.ZeroDepth)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return "", ErrLocked
+	}
+	// the unique index of root would be violated by an expired lock
+	// that has not been swept yet
+	if _, err = db.DeleteExpiredWebdavLocks(now); err != nil {
+		return "", err
+	}
+	l := &model.WebdavLock{
+		Token:     uuid.NewString(),
+		Root:      details.Root,
+		ZeroDepth: details.ZeroDepth,
+		OwnerXML:  details.OwnerXML,
+		Duration:  int64(details.Duration / time.Second),
+		ExpiresAt: d.expiry(now, details.Duration),
+	}
+	if details.Duration < 0 {
+		l.Duration = infiniteTimeout
+	}
+	if err = db.CreateWebdavLock(l); err != nil {
+		// another instance may have just locked the same resource
+		if ok, _ = d.canCreate(now, details.Root, details.ZeroDepth); !ok {
+			return "", ErrLocked
+		}
+		return "", err
+	}
+	return l.Token, nil
+}
+
+func (d *dbLS) Refresh(now time.Time, token string, duration time.Duration) (LockDetails, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	l, err := db.GetWebdavLockByToken(token, now)
+	if err != nil {
+		return LockDetails{}, ErrNoSuchLock
+	}
+	if _, ok := d.held[token]; ok {
+		return LockDetails{}, ErrLocked
+	}
+	l.Duration = int64(duration / time.Second)
+	if duration < 0 {
+		l.Duration = infiniteTimeout
+	}
+	l.ExpiresAt = d.expiry(now, duration)
+	if err = db.UpdateWebdavLock(l); err != nil {
+		return LockDetails{}, err
+	}
+	return toLockDetails(l), nil
+}
+
+func (d *dbLS) Unlock(now time.Time, token string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := db.GetWebdavLockByToken(token, now); err != nil {
+		return ErrNoSuchLock
+	}
+	if _, ok := d.held[token]; ok {
+		return ErrLocked
+	}
+	return db.DeleteWebdavLockByToken(token)
+}
+
+// canCreate follows the same rules as memLS.canCreate: the target must not be
+// locked, no ancestor may hold an infinite depth lock, and an infinite depth
+// lock can't be created over a locked descendant.
+func (d *dbLS) canCreate(now time.Time, name string, zeroDepth bool) (bool, error) {
+	var roots []string
+	walkToRoot(name, func(name0 string, first bool) bool {
+		roots = append(roots, name0)
+		return true
+	})
+	locks, err := db.GetWebdavLocksByRoots(roots, now)
+	if err != nil {
+		return false, err
+	}
+	for _, l := range locks {
+		if l.Root == name || !l.ZeroDepth {
+			return false, nil
+		}
+	}
+	if zeroDepth {
+		return true, nil
+	}
+	prefix := name + "/"
+	if name == "/" {
+		prefix = "/"
+	}
+	locks, err = db.GetWebdavLocksByRootPrefix(prefix, now)
+	if err != nil {
+		return false, err
+	}
+	for _, l := range locks {
+		if l.Root != name && strings.HasPrefix(l.Root, prefix) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func toLockDetails(l *model.WebdavLock) LockDetails {
+	duration := time.Duration(l.Duration) * time.Second
+	if l.Duration < 0 {
+		duration = infiniteTimeout
+	}
+	return LockDetails{
+		Root:      l.Root,
+		Duration:  duration,
+		OwnerXML:  l.OwnerXML,
+		ZeroDepth: l.ZeroDepth,
+	}
+}
@@ -0,0 +1,99 @@
+package webdav
+
+import (
+	"testing"
+	"time"
+
+	"github.com/OpenListTeam/OpenList/v4/internal/conf"
+	"github.com/OpenListTeam/OpenList/v4/internal/db"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func initTestDB(t *testing.T) {
+	t.Helper()
+	if db.GetDb() == nil {
+		dB, err := gorm.Open(sqlite.Open("file::memory:?cache=shared"), &gorm.Config{})
+		if err != nil {
+			t.Fatalf("failed to connect database: %v", err)
+		}
+		conf.Conf = conf.DefaultConfig("data")
+		db.Init(dB)
+	}
+}
+
+func newTestDBLS(t *testing.T) *dbLS {
+	t.Helper()
+	initTestDB(t)
+	if _, err := db.DeleteExpiredWebdavLocks(time.Now().Add(365 * 24 * time.Hour)); err != nil {
+		t.Fatalf("failed to clean locks: %v", err)
+	}
+	d := NewDBLS(time.Hour, time.Hour).(*dbLS)
+	t.Cleanup(d.cron.Stop)
+	return d
+}
+
+func TestDBLSCanCreate(t *testing.T) {
+	d := newTestDBLS(t)
+	now := time.Now()
+	if _, err := d.Create(now, LockDetails{Root: "/a/b", Duration: time.Minute, ZeroDepth: false}); err != nil {
+		t.Fatalf("create /a/b: %v", err)
+	}
+	testCases := []struct {
+		name      string
+		zeroDepth bool
+		want      bool
+	}{
+		{"/a/b", true, false},
+		{"/a/b/c", true, false},
+		{"/a", true, true},
+		{"/a", false, false},
+		{"/", false, false},
+		{"/a/bc", false, true},
+		{"/x", false, true},
+	}
+	for _, tc := range testCases {
+		got, err := d.canCreate(now, tc.name, tc.zeroDepth)
+		if err != nil {
+			t.Fatalf("canCreate(%q, %t): %v", tc.name, tc.zeroDepth, err)
+		}
+		if got != tc.want {
+			t.Errorf("canCreate(%q, %t): got %t, want %t", tc.name, tc.zeroDepth, got, tc.want)
+		}
+	}
+}
+
+func TestDBLSSharedAndExpiry(t *testing.T) {
+	d0 := newTestDBLS(t)
+	now := time.Now()
+	token, err := d0.Create(now, LockDetails{Root: "/doc.docx", Duration: 10 * time.Second, ZeroDepth: true})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// another instance sharing the database sees the lock
+	d1 := NewDBLS(time.Hour, time.Hour).(*dbLS)
+	defer d1.cron.Stop()
+	if _, err = d1.Create(now, LockDetails{Root: "/doc.docx", Duration: time.Minute, ZeroDepth: true}); err != ErrLocked {
+		t.Fatalf("create on second instance: got %v, want %v", err, ErrLocked)
+	}
+	release, err := d1.Confirm(now, "/doc.docx", "", Condition{Token: token})
+	if err != nil {
+		t.Fatalf("confirm on second instance: %v", err)
+	}
+	release()
+
+	if _, err = d1.Refresh(now.Add(5*time.Second), token, 10*time.Second); err != nil {
+		t.Fatalf("refresh: %v", err)
+	}
+	if release, err = d0.Confirm(now.Add(12*time.Second), "/doc.docx", "", Condition{Token: token}); err != nil {
+		t.Fatalf("confirm after refresh: %v", err)
+	}
+	release()
+	if _, err = d0.Confirm(now.Add(20*time.Second), "/doc.docx", "", Condition{Token: token}); err != ErrConfirmationFailed {
+		t.Fatalf("confirm after expiry: got %v, want %v", err, ErrConfirmationFailed)
+	}
+	if _, err = d0.Create(now.Add(20*time.Second), LockDetails{Root: "/doc.docx", Duration: infiniteTimeout, ZeroDepth: true}); err != nil {
+		t.Fatalf("create after expiry: %v", err)
+	}
+}
@@ -173,26 +173,21 @@ var liveProps = map[xml.Name]struct {
 //
 // Each Propstat has a unique status and each property name will only be part
 // of one Propstat element.
-func props(ctx context.Context, ls LockSystem, fi model.Obj, pnames []xml.Name) ([]Propstat, error) {
-	//f, err := fs.OpenFile(ctx, name, os.O_RDONLY, 0)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//defer f.Close()
-	//fi, err := f.Stat()
-	//if err != nil {
-	//	return nil, err
-	//}
+func props(ctx context.Context, ls LockSystem, name string, fi model.Obj, pnames []xml.Name) ([]Propstat, error) {
 	isDir := fi.IsDir()
 
 	var deadProps map[xml.Name]Property
-	// ??? what is this for?
-	//if dph, ok := f.(DeadPropsHolder); ok {
-	//	deadProps, err = dph.DeadProps()
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//}
+	// only look up the dead props when some of pnames may be one of them
+	for _, pn := range pnames {
+		if _, ok := liveProps[pn]; !ok {
+			var err error
+			deadProps, err = deadPropsOf(ctx, name)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
 
 	pstatOK := Propstat{Status: http.StatusOK}
 	pstatNotFound := Propstat{Status: http.StatusNotFound}
@@ -222,26 +217,13 @@ func props(ctx context.Context, ls LockSystem, fi model.Obj, pnames []xml.Name)
 }
 
 // Propnames returns the property names defined for resource name.
-func propnames(ctx context.Context, ls LockSystem, fi model.Obj) ([]xml.Name, error) {
-	//f, err := fs.OpenFile(ctx, name, os.O_RDONLY, 0)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//defer f.Close()
-	//fi, err := f.Stat()
-	//if err != nil {
-	//	return nil, err
-	//}
+func propnames(ctx context.Context, ls LockSystem, name string, fi model.Obj) ([]xml.Name, error) {
 	isDir := fi.IsDir()
 
-	var deadProps map[xml.Name]Property
-	// ??? what is this for?
-	//if dph, ok := f.(DeadPropsHolder); ok {
-	//	deadProps, err = dph.DeadProps()
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//}
+	deadProps, err := deadPropsOf(ctx, name)
+	if err != nil {
+		return nil, err
+	}
 
 	pnames := make([]xml.Name, 0, len(liveProps)+len(deadProps))
 	for pn, prop := range liveProps {
@@ -263,8 +245,8 @@ func propnames(ctx context.Context, ls LockSystem, fi model.Obj) ([]xml.Name, er
 // returned if they are named in 'include'.
 //
 // See http://www.webdav.org/specs/rfc4918.html#METHOD_PROPFIND
-func allprop(ctx context.Context, ls LockSystem, fi model.Obj, include []xml.Name) ([]Propstat, error) {
-	pnames, err := propnames(ctx, ls, fi)
+func allprop(ctx context.Context, ls LockSystem, name string, fi model.Obj, include []xml.Name) ([]Propstat, error) {
+	pnames, err := propnames(ctx, ls, name, fi)
 	if err != nil {
 		return nil, err
 	}
@@ -278,7 +260,7 @@ func allprop(ctx context.Context, ls LockSystem, fi model.Obj, include []xml.Nam
 			pnames = append(pnames, pn)
 		}
 	}
-	return props(ctx, ls, fi, pnames)
+	return props(ctx, ls, name, fi, pnames)
 }
 
 // Patch patches the properties of resource name. The return values are
@@ -314,38 +296,19 @@ loop:
 		return makePropstats(pstatForbidden, pstatFailedDep), nil
 	}
 
-	// ------------------------------------------------------------
-	//f, err := fs.OpenFile(ctx, name, os.O_RDWR, 0)
-	//if err != nil {
-	//	return nil, err
-	//}
-	//defer f.Close()
-	//if dph, ok := f.(DeadPropsHolder); ok {
-	//	ret, err := dph.Patch(patches)
-	//	if err != nil {
-	//		return nil, err
-	//	}
-	//	// http://www.webdav.org/specs/rfc4918.html#ELEMENT_propstat says that
-	//	// "The contents of the prop XML element must only list the names of
-	//	// properties to which the result in the status element applies."
-	//	for _, pstat := range ret {
-	//		for i, p := range pstat.Props {
-	//			pstat.Props[i] = Property{XMLName: p.XMLName}
-	//		}
-	//	}
-	//	return ret, nil
-	//}
-	// ------------------------------------------------------------
-
-	// The file doesn't implement the optional DeadPropsHolder interface, so
-	// all patches are forbidden.
-	pstat := Propstat{Status: http.StatusForbidden}
-	for _, patch := range patches {
-		for _, p := range patch.Props {
-			pstat.Props = append(pstat.Props, Property{XMLName: p.XMLName})
+	ret, err := newDeadPropsHolder(name).Patch(patches)
+	if err != nil {
+		return nil, err
+	}
+	// http://www.webdav.org/specs/rfc4918.html#ELEMENT_propstat says that
+	// "The contents of the prop XML element must only list the names of
+	// properties to which the result in the status element applies."
+	for _, pstat := range ret {
+		for i, p := range pstat.Props {
+			pstat.Props[i] = Property{XMLName: p.XMLName}
 		}
 	}
-	return []Propstat{pstat}, nil
+	return ret, nil
 }
 
 func escapeXML(s string) string {
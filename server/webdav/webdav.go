@@ -87,6 +87,13 @@ func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		case "PROPPATCH":
 			status, err = h.handleProppatch(brw, r)
 		}
+		// the paths denied by the meta ACL, except for PROPFIND which pretends not found as well
+		if status != 0 && r.Method != "PROPFIND" && errors.Is(err, errs.PermissionDenied) {
+			status = http.StatusForbidden
+		}
+		if status != 0 && errors.Is(err, errs.QuotaExceeded) {
+			status = StatusInsufficientStorage
+		}
 	}
 
 	if status != 0 {
@@ -197,16 +204,20 @@ func (h *Handler) handleOptions(w http.ResponseWriter, r *http.Request) (status
 	}
 	ctx := r.Context()
 	user := ctx.Value(conf.UserKey).(*model.User)
-	reqPath, err = user.JoinPath(reqPath)
-	if err != nil {
-		return 403, err
-	}
 	allow := "OPTIONS, LOCK, PUT, MKCOL"
-	if fi, err := fs.Get(ctx, reqPath, &fs.GetArgs{}); err == nil {
-		if fi.IsDir() {
-			allow = "OPTIONS, LOCK, DELETE, PROPPATCH, COPY, MOVE, UNLOCK, PROPFIND"
-		} else {
-			allow = "OPTIONS, LOCK, GET, HEAD, POST, DELETE, PROPPATCH, COPY, MOVE, UNLOCK, PROPFIND, PUT"
+	if user.IsVirtualRoot(reqPath) {
+		allow = "OPTIONS, PROPFIND"
+	} else {
+		reqPath, err = user.JoinPath(reqPath)
+		if err != nil {
+			return 403, err
+		}
+		if fi, err := fs.Get(ctx, reqPath, &fs.GetArgs{}); err == nil {
+			if fi.IsDir() {
+				allow = "OPTIONS, LOCK, DELETE, PROPPATCH, COPY, MOVE, UNLOCK, PROPFIND"
+			} else {
+				allow = "OPTIONS, LOCK, GET, HEAD, POST, DELETE, PROPPATCH, COPY, MOVE, UNLOCK, PROPFIND, PUT"
+			}
 		}
 	}
 	w.Header().Set("Allow", allow)
@@ -225,13 +236,18 @@ func (h *Handler) handleGetHeadPost(w http.ResponseWriter, r *http.Request) (sta
 	// TODO: check locks for read-only access??
 	ctx := r.Context()
 	user := ctx.Value(conf.UserKey).(*model.User)
-	reqPath, err = user.JoinPath(reqPath)
-	if err != nil {
-		return http.StatusForbidden, err
-	}
-	fi, err := fs.Get(ctx, reqPath, &fs.GetArgs{})
-	if err != nil {
-		return http.StatusNotFound, err
+	var fi model.Obj
+	if user.IsVirtualRoot(reqPath) {
+		fi = fs.VirtualRoot()
+	} else {
+		reqPath, err = user.JoinPath(reqPath)
+		if err != nil {
+			return http.StatusForbidden, err
+		}
+		fi, err = fs.Get(ctx, reqPath, &fs.GetArgs{})
+		if err != nil {
+			return http.StatusNotFound, err
+		}
 	}
 	if fi.IsDir() {
 		if r.Method == http.MethodHead {
@@ -648,16 +664,22 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request) (status
 	userAgent := r.Header.Get("User-Agent")
 	ctx = context.WithValue(ctx, conf.UserAgentKey, userAgent)
 	user := ctx.Value(conf.UserKey).(*model.User)
-	reqPath, err = user.JoinPath(reqPath)
-	if err != nil {
-		return 403, err
-	}
-	fi, err := fs.Get(ctx, reqPath, &fs.GetArgs{})
-	if err != nil {
-		if errs.IsNotFoundError(err) {
-			return http.StatusNotFound, err
+	virtualRoot := user.IsVirtualRoot(reqPath)
+	var fi model.Obj
+	if virtualRoot {
+		fi = fs.VirtualRoot()
+	} else {
+		reqPath, err = user.JoinPath(reqPath)
+		if err != nil {
+			return 403, err
+		}
+		fi, err = fs.Get(ctx, reqPath, &fs.GetArgs{})
+		if err != nil {
+			if errs.IsNotFoundError(err) {
+				return http.StatusNotFound, err
+			}
+			return http.StatusMethodNotAllowed, err
 		}
-		return http.StatusMethodNotAllowed, err
 	}
 	depth := infiniteDepth
 	if hdr := r.Header.Get("Depth"); hdr != "" {
@@ -670,16 +692,19 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request) (status
 	if err != nil {
 		return status, err
 	}
+	if depth != 0 && !virtualRoot {
+		// the dead props are loaded a dir at a time for the entries listed
+		if ctx, err = withDeadPropsCache(ctx, reqPath); err != nil {
+			return http.StatusInternalServerError, err
+		}
+	}
 
 	mw := multistatusWriter{w: w}
 
-	walkFn := func(reqPath string, info model.Obj, err error) error {
-		if err != nil {
-			return err
-		}
+	writeFn := func(href, reqPath string, info model.Obj) error {
 		var pstats []Propstat
 		if pf.Propname != nil {
-			pnames, err := propnames(ctx, h.LockSystem, info)
+			pnames, err := propnames(ctx, h.LockSystem, reqPath, info)
 			if err != nil {
 				return err
 			}
@@ -689,21 +714,32 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request) (status
 			}
 			pstats = append(pstats, pstat)
 		} else if pf.Allprop != nil {
-			pstats, err = allprop(ctx, h.LockSystem, info, pf.Prop)
+			pstats, err = allprop(ctx, h.LockSystem, reqPath, info, pf.Prop)
 		} else {
-			pstats, err = props(ctx, h.LockSystem, info, pf.Prop)
+			pstats, err = props(ctx, h.LockSystem, reqPath, info, pf.Prop)
 		}
 		if err != nil {
 			return err
 		}
-		href := path.Join(h.Prefix, strings.TrimPrefix(reqPath, user.BasePath))
 		if href != "/" && info.IsDir() {
 			href += "/"
 		}
 		return mw.write(makePropstatResponse(href, pstats))
 	}
+	walkFn := func(reqPath string, info model.Obj, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, _ := user.RelPath(reqPath)
+		return writeFn(path.Join(h.Prefix, relPath), reqPath, info)
+	}
 
-	walkErr := walkFS(ctx, depth, reqPath, fi, walkFn)
+	var walkErr error
+	if virtualRoot {
+		walkErr = h.walkVirtualRoot(ctx, user, depth, fi, writeFn, walkFn)
+	} else {
+		walkErr = walkFS(ctx, depth, reqPath, fi, walkFn)
+	}
 	closeErr := mw.close()
 	if walkErr != nil {
 		return http.StatusInternalServerError, walkErr
@@ -714,6 +750,31 @@ func (h *Handler) handlePropfind(w http.ResponseWriter, r *http.Request) (status
 	return 0, nil
 }
 
+// walkVirtualRoot walks the virtual root of the user and its roots
+func (h *Handler) walkVirtualRoot(ctx context.Context, user *model.User, depth int, fi model.Obj,
+	writeFn func(href, reqPath string, info model.Obj) error, walkFn func(reqPath string, info model.Obj, err error) error) error {
+	if err := writeFn(path.Join(h.Prefix, "/"), "/", fi); err != nil {
+		return err
+	}
+	if depth == 0 {
+		return nil
+	}
+	if depth == 1 {
+		depth = 0
+	}
+	for _, obj := range fs.ListVirtualRoot(ctx, user) {
+		root := model.UnwrapObj(obj)
+		reqPath, err := user.JoinPath(path.Join("/", obj.GetName()))
+		if err != nil {
+			return err
+		}
+		if err = walkFS(ctx, depth, reqPath, root, walkFn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func (h *Handler) handleProppatch(w http.ResponseWriter, r *http.Request) (status int, err error) {
 	reqPath, status, err := h.stripPrefix(r.URL.Path)
 	if err != nil {